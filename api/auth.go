@@ -16,7 +16,9 @@ import (
 	"time"
 
 	"dd-ui/common"
+	"dd-ui/database"
 	"dd-ui/middleware"
+	"dd-ui/services"
 	"github.com/alexedwards/scs/v2"
 	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
@@ -43,14 +45,23 @@ type idTokenEntry struct {
 	exp   time.Time
 }
 type idTokenStore struct {
-	mu sync.RWMutex
-	m  map[string]idTokenEntry // sid -> entry
+	mu      sync.RWMutex
+	m       map[string]idTokenEntry // sid -> entry
+	persist bool                    // kept in session_id_tokens alongside Postgres sessions
 }
 
 func (s *idTokenStore) put(sid, token string, exp time.Time) {
 	if sid == "" || token == "" {
 		return
 	}
+	if s.persist {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := database.PutSessionIDToken(ctx, sid, token, exp); err != nil {
+			warnLog("auth: store id_token failed: %v", err)
+		}
+		return
+	}
 	s.mu.Lock()
 	if s.m == nil {
 		s.m = make(map[string]idTokenEntry)
@@ -62,6 +73,15 @@ func (s *idTokenStore) pop(sid string) string {
 	if sid == "" {
 		return ""
 	}
+	if s.persist {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		tok, err := database.PopSessionIDToken(ctx, sid)
+		if err != nil {
+			warnLog("auth: load id_token failed: %v", err)
+		}
+		return tok
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	ent, ok := s.m[sid]
@@ -198,35 +218,23 @@ func InitAuthFromEnv() (*scs.SessionManager, error) {
 		PostLogoutRedirectURL: env("OIDC_POST_LOGOUT_REDIRECT_URL", ""),
 	}
 
-	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.ClientSecret == "" || cfg.RedirectURL == "" {
-		return nil, errors.New("OIDC_ISSUER_URL, OIDC_CLIENT_ID{/_FILE}, OIDC_CLIENT_SECRET{/_FILE}, OIDC_REDIRECT_URL are required")
-	}
-
-	// ---- OIDC wiring
-	ctx := context.Background()
-	oidcProv, err = oidc.NewProvider(ctx, cfg.Issuer)
-	if err != nil {
-		return nil, err
-	}
-
-	// Try to discover end_session_endpoint (not all providers expose it)
-	var disc struct {
-		EndSessionEndpoint string `json:"end_session_endpoint"`
-	}
-	if err := oidcProv.Claims(&disc); err == nil {
-		endSessionEndpoint = strings.TrimSpace(disc.EndSessionEndpoint)
-	}
-	if endSessionEndpoint == "" {
-		infoLog("auth: no end_session_endpoint found in discovery; RP-logout will fall back to local clear")
+	localMode := services.LocalAuthMode()
+	if !oidcConfigured() {
+		if localMode == services.LocalAuthOff {
+			return nil, errors.New("OIDC_ISSUER_URL, OIDC_CLIENT_ID{/_FILE}, OIDC_CLIENT_SECRET{/_FILE}, OIDC_REDIRECT_URL are required")
+		}
+		warnLog("auth: OIDC not configured; only local accounts (DD_UI_LOCAL_AUTH=%s) can log in", localMode)
+	} else if err := initOIDC(context.Background()); err != nil {
+		if localMode == services.LocalAuthOff {
+			return nil, err
+		}
+		// the IdP may be down; LoginHandler retries and local login covers the gap
+		warnLog("auth: OIDC provider unavailable, will retry on login: %v", err)
 	}
-
-	oidcVerifier = oidcProv.Verifier(&oidc.Config{ClientID: cfg.ClientID})
-	oauthCfg = &oauth2.Config{
-		ClientID:     cfg.ClientID,
-		ClientSecret: cfg.ClientSecret,
-		Endpoint:     oidcProv.Endpoint(),
-		RedirectURL:  cfg.RedirectURL,
-		Scopes:       cfg.Scopes,
+	if oidcConfigured() {
+		if msg := middleware.AdminsConfigProblem(localMode != services.LocalAuthOff); msg != "" {
+			errorLog("auth: %s", msg)
+		}
 	}
 
 	// ---- Session manager setup
@@ -245,6 +253,7 @@ func InitAuthFromEnv() (*scs.SessionManager, error) {
 	
 	// Also initialize the global SessionManager in common package so handlers can use it
 	common.SessionManager = sessionManager
+	middleware.BreakGlassAllowed = breakGlassAllowed
 	
 	infoLog("AUTH: Session manager initialized successfully")
 	infoLog("AUTH: common.SessionManager = %v", common.SessionManager != nil)
@@ -261,12 +270,99 @@ func InitAuthFromEnv() (*scs.SessionManager, error) {
 	return sessionManager, nil
 }
 
+// UseSessionStore moves sessions and id_tokens into Postgres (DD_UI_SESSION_STORE=postgres,
+// the default) so logins survive restarts and are shared by replicas; "memory" keeps the
+// in-process store. Must run after the DB is up and before the server starts.
+func UseSessionStore(ctx context.Context) {
+	if sessionManager == nil {
+		return
+	}
+	switch strings.ToLower(env("DD_UI_SESSION_STORE", "postgres")) {
+	case "memory":
+		infoLog("auth: sessions kept in memory (lost on restart, not shared by replicas)")
+		return
+	case "postgres":
+	default:
+		warnLog("auth: unknown DD_UI_SESSION_STORE, using postgres")
+	}
+	sessionManager.Store = database.SessionStore{}
+	idtStore.persist = true
+	infoLog("auth: sessions stored in Postgres")
+
+	go func() {
+		t := time.NewTicker(10 * time.Minute)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+			if n, err := database.DeleteExpiredSessions(ctx); err != nil {
+				debugLog("auth: expired session cleanup failed: %v", err)
+			} else if n > 0 {
+				debugLog("auth: removed %d expired sessions", n)
+			}
+		}
+	}()
+}
+
+func oidcConfigured() bool {
+	return cfg.Issuer != "" && cfg.ClientID != "" && cfg.ClientSecret != "" && cfg.RedirectURL != ""
+}
+
+var oidcInitMu sync.Mutex
+
+// initOIDC discovers the provider and wires the verifier and oauth2 config (once)
+func initOIDC(ctx context.Context) error {
+	oidcInitMu.Lock()
+	defer oidcInitMu.Unlock()
+	if oauthCfg != nil {
+		return nil
+	}
+
+	prov, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return err
+	}
+
+	// Try to discover end_session_endpoint (not all providers expose it)
+	var disc struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := prov.Claims(&disc); err == nil {
+		endSessionEndpoint = strings.TrimSpace(disc.EndSessionEndpoint)
+	}
+	if endSessionEndpoint == "" {
+		infoLog("auth: no end_session_endpoint found in discovery; RP-logout will fall back to local clear")
+	}
+
+	oidcProv = prov
+	oidcVerifier = prov.Verifier(&oidc.Config{ClientID: cfg.ClientID})
+	oauthCfg = &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Endpoint:     prov.Endpoint(),
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	}
+	return nil
+}
+
 func scopes(s string) []string { return strings.Fields(s) }
 func randHex(n int) string     { b := make([]byte, n/2); _, _ = rand.Read(b); return hex.EncodeToString(b) }
 
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	if oauthCfg == nil || oidcProv == nil {
-		http.Error(w, "auth not initialized", http.StatusInternalServerError)
+	if !oidcConfigured() {
+		http.Error(w, "OIDC login is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	ictx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	err := initOIDC(ictx)
+	cancel()
+	if err != nil {
+		warnLog("auth: OIDC provider unavailable: %v", err)
+		http.Error(w, "OIDC provider unavailable", http.StatusServiceUnavailable)
 		return
 	}
 
@@ -358,13 +454,7 @@ func CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save minimal session + sid; store id_token server-side keyed by sid
-	sid := sessionManager.GetString(r.Context(), "sid")
-	if strings.TrimSpace(sid) == "" {
-		sid = randHex(32)
-		sessionManager.Put(r.Context(), "sid", sid)
-	}
-	sessionManager.Put(r.Context(), "user", u)
-	sessionManager.Put(r.Context(), "exp", time.Now().Add(7 * 24 * time.Hour).Unix())
+	sid := establishSession(r, u)
 
 	// expiry = min(session 7d, token exp if present)
 	exp := time.Now().Add(7 * 24 * time.Hour)
@@ -380,6 +470,18 @@ func CallbackHandler(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/", http.StatusFound)
 }
 
+// establishSession stores the logged-in user with a 7d expiry and returns the session id
+func establishSession(r *http.Request, u middleware.User) string {
+	sid := sessionManager.GetString(r.Context(), "sid")
+	if strings.TrimSpace(sid) == "" {
+		sid = randHex(32)
+		sessionManager.Put(r.Context(), "sid", sid)
+	}
+	sessionManager.Put(r.Context(), "user", u)
+	sessionManager.Put(r.Context(), "exp", time.Now().Add(7 * 24 * time.Hour).Unix())
+	return sid
+}
+
 func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	// retrieve sid/id_token for RP-initiated logout BEFORE clearing
 	sid := sessionManager.GetString(r.Context(), "sid")
@@ -430,7 +532,8 @@ func SessionHandler(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"user": u})
+	middleware.TouchSession(r, u)
+	writeJSON(w, http.StatusOK, map[string]any{"user": u, "admin": middleware.IsAdmin(u)})
 }
 
 // --- auth helpers ---
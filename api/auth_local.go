@@ -0,0 +1,215 @@
+// src/api/auth_local.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+)
+
+// ---- local (built-in) accounts: DD_UI_LOCAL_AUTH=on|breakglass ----
+
+// oidcProbe caches whether the IdP's discovery document answered recently
+var oidcProbe struct {
+	mu      sync.Mutex
+	checked time.Time
+	up      bool
+	probing bool
+}
+
+// oidcAvailable reports whether OIDC login can currently work: configured, discovered, and
+// the issuer's discovery endpoint reachable (re-probed at most every 30s). The probe runs
+// outside the lock; callers arriving meanwhile get the previous answer.
+func oidcAvailable(ctx context.Context) bool {
+	if !oidcConfigured() {
+		return false
+	}
+	oidcProbe.mu.Lock()
+	if oidcProbe.probing || time.Since(oidcProbe.checked) < 30*time.Second {
+		up := oidcProbe.up
+		oidcProbe.mu.Unlock()
+		return up
+	}
+	oidcProbe.probing = true
+	oidcProbe.mu.Unlock()
+
+	up := probeOIDC(context.WithoutCancel(ctx))
+
+	oidcProbe.mu.Lock()
+	first, was := oidcProbe.checked.IsZero(), oidcProbe.up
+	oidcProbe.checked, oidcProbe.up, oidcProbe.probing = time.Now(), up, false
+	oidcProbe.mu.Unlock()
+
+	if up != was && !first {
+		if up {
+			infoLog("auth: OIDC provider reachable again")
+		} else {
+			warnLog("auth: OIDC provider unreachable")
+		}
+	}
+	if up && (first || !was) && services.LocalAuthMode() == services.LocalAuthBreakGlass {
+		go revokeBreakGlassSessions()
+	}
+	return up
+}
+
+// probeOIDC discovers the provider if needed and fetches its discovery document
+func probeOIDC(ctx context.Context) bool {
+	pctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if initOIDC(pctx) != nil {
+		return false
+	}
+	wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(pctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// breakGlassSessionTTL bounds sessions opened by break-glass logins:
+// DD_UI_BREAKGLASS_SESSION_TTL (default 1h)
+func breakGlassSessionTTL() time.Duration {
+	if d := envDur("DD_UI_BREAKGLASS_SESSION_TTL", "1h"); d > 0 {
+		return d
+	}
+	return time.Hour
+}
+
+// breakGlassAllowed keeps break-glass sessions usable only while OIDC stays down; RequireAuth
+// also catches sessions on replicas that have not seen the recovery
+func breakGlassAllowed(ctx context.Context) bool {
+	return !oidcAvailable(ctx)
+}
+
+// revokeBreakGlassSessions drops stored local-account sessions once OIDC is back. In
+// breakglass mode every local session is a break-glass one.
+func revokeBreakGlassSessions() {
+	if _, ok := sessionManager.Store.(database.SessionStore); !ok {
+		return // memory store: RequireAuth rejects them on next use
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	n, err := database.DeleteProviderSessions(ctx, "local")
+	if err != nil {
+		errorLog("auth: revoking break-glass sessions failed: %v", err)
+		return
+	}
+	if n > 0 {
+		infoLog("auth: OIDC available, revoked %d break-glass sessions", n)
+	}
+}
+
+// localLoginAllowed applies DD_UI_LOCAL_AUTH: "on" always, "breakglass" only while OIDC is down
+func localLoginAllowed(ctx context.Context) bool {
+	switch services.LocalAuthMode() {
+	case services.LocalAuthOn:
+		return true
+	case services.LocalAuthBreakGlass:
+		return !oidcAvailable(ctx)
+	}
+	return false
+}
+
+// LocalAuthStatusHandler tells the login page which methods to offer
+func LocalAuthStatusHandler(w http.ResponseWriter, r *http.Request) {
+	mode := services.LocalAuthMode()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"oidc":           oidcConfigured(),
+		"oidc_available": mode != services.LocalAuthOff && oidcAvailable(r.Context()),
+		"local_mode":     mode,
+		"local":          localLoginAllowed(r.Context()),
+		"totp_required":  services.LocalTOTPRequired(),
+	})
+}
+
+// LocalLoginHandler: POST {username, password, totp}. On success the session is the same as
+// an OIDC login. An account without a second factor (and DD_UI_LOCAL_REQUIRE_TOTP on) gets
+// 200 {"status":"enroll", secret, otpauth_uri} and must post again with a code to finish.
+func LocalLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !localLoginAllowed(r.Context()) {
+		writeJSON(w, http.StatusForbidden, map[string]any{
+			"status":  "error",
+			"message": "Local login is disabled. Use single sign-on.",
+		})
+		return
+	}
+
+	var body struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&body); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(body.Username) == "" || body.Password == "" {
+		http.Error(w, "username and password required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := services.AuthenticateLocal(r.Context(), body.Username, body.Password, body.TOTP)
+	switch {
+	case errors.Is(err, services.ErrLocalTOTPRequired):
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "totp_required", "message": "Enter the code from your authenticator."})
+		return
+	case errors.Is(err, services.ErrLocalLocked):
+		writeJSON(w, http.StatusTooManyRequests, map[string]any{"status": "error", "message": "Too many failed attempts. Try again later."})
+		return
+	case errors.Is(err, services.ErrLocalBadCredentials), errors.Is(err, services.ErrLocalBadTOTP):
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"status": "error", "message": "Invalid username, password or code."})
+		return
+	case err != nil:
+		errorLog("auth: local login error: %v", err)
+		http.Error(w, "login failed", http.StatusInternalServerError)
+		return
+	}
+	if res.Enroll {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status":      "enroll",
+			"message":     "Add this account to your authenticator, then log in again with a code.",
+			"secret":      res.Secret,
+			"otpauth_uri": res.URI,
+		})
+		return
+	}
+
+	// new token on privilege change (session fixation)
+	if err := sessionManager.RenewToken(r.Context()); err != nil {
+		errorLog("auth: renew session token: %v", err)
+		http.Error(w, "login failed", http.StatusInternalServerError)
+		return
+	}
+	u := middleware.User{
+		Sub:      "local:" + strconv.FormatInt(res.User.ID, 10),
+		Email:    strings.ToLower(res.User.Email),
+		Name:     res.User.Name,
+		Provider: "local",
+	}
+	if u.Name == "" {
+		u.Name = res.User.Username
+	}
+	u.BreakGlass = services.LocalAuthMode() == services.LocalAuthBreakGlass
+	establishSession(r, u)
+	if u.BreakGlass {
+		sessionManager.Put(r.Context(), "exp", time.Now().Add(breakGlassSessionTTL()).Unix())
+	}
+	infoLog("auth: local login ok user=%s sub=%s", res.User.Username, u.Sub)
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "user": u})
+}
@@ -0,0 +1,130 @@
+// cmd/dd-ui-agent/main.go
+//
+// dd-ui-agent runs on a Docker host and dials OUT to DD-UI over a websocket, so the
+// host needs no inbound SSH. DD-UI multiplexes Docker API connections (requests,
+// events, logs, exec) over that one connection; the agent pipes each to the local
+// Docker (or Podman) socket.
+//
+// Environment:
+//
+//	DD_UI_AGENT_URL           wss://dd-ui.example.com/api/agent/connect
+//	DD_UI_AGENT_TOKEN         token from POST /api/agents/hosts/{hostname}/token
+//	DD_UI_AGENT_HOST          inventory hostname this agent serves (default: os hostname)
+//	DD_UI_AGENT_DOCKER_SOCK   local API socket (default /var/run/docker.sock)
+//	DD_UI_AGENT_INSECURE_TLS  true to skip TLS verification (testing only)
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"dd-ui/common"
+	"dd-ui/utils"
+)
+
+const agentVersion = "2"
+
+func main() {
+	url := common.Env("DD_UI_AGENT_URL", "")
+	token := common.Env("DD_UI_AGENT_TOKEN", "")
+	if url == "" || token == "" {
+		log.Fatal("dd-ui-agent: DD_UI_AGENT_URL and DD_UI_AGENT_TOKEN are required")
+	}
+	host := common.Env("DD_UI_AGENT_HOST", "")
+	if host == "" {
+		h, err := os.Hostname()
+		if err != nil {
+			log.Fatalf("dd-ui-agent: DD_UI_AGENT_HOST not set and hostname unavailable: %v", err)
+		}
+		host = h
+	}
+	sock := common.Env("DD_UI_AGENT_DOCKER_SOCK", "/var/run/docker.sock")
+
+	dialer := *websocket.DefaultDialer
+	dialer.HandshakeTimeout = 15 * time.Second
+	if common.EnvBool("DD_UI_AGENT_INSECURE_TLS", "false") {
+		dialer.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	header.Set("X-DD-UI-Agent-Host", host)
+	header.Set("X-DD-UI-Agent-Version", agentVersion)
+
+	backoff := time.Second
+	for {
+		started := time.Now()
+		err := serve(&dialer, url, header, sock)
+		log.Printf("dd-ui-agent: connection ended: %v", err)
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// serve runs one websocket session until it drops
+func serve(dialer *websocket.Dialer, url string, header http.Header, sock string) error {
+	conn, resp, err := dialer.Dial(url, header)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("dial %s: %v (HTTP %d)", url, err, resp.StatusCode)
+		}
+		return fmt.Errorf("dial %s: %v", url, err)
+	}
+	log.Printf("dd-ui-agent: connected to %s as %s", url, header.Get("X-DD-UI-Agent-Host"))
+
+	mux := utils.NewAgentMux(conn, false)
+	// servers that predate window frames do not announce a version
+	mux.SetFlowControl(utils.MuxFlowControl(resp.Header.Get("X-DD-UI-Agent-Version")))
+	go func() {
+		for {
+			st, target, err := mux.Accept()
+			if err != nil {
+				return
+			}
+			if target != "docker" {
+				log.Printf("dd-ui-agent: unknown stream target %q", target)
+				_ = st.Close()
+				continue
+			}
+			go pipe(st, sock)
+		}
+	}()
+	return mux.Run()
+}
+
+// pipe connects one stream to the local API socket, honouring half-closes
+func pipe(st *utils.MuxStream, sock string) {
+	defer st.Close()
+	c, err := net.DialTimeout("unix", sock, 10*time.Second)
+	if err != nil {
+		log.Printf("dd-ui-agent: dial %s: %v", sock, err)
+		return
+	}
+	uc := c.(*net.UnixConn)
+	defer uc.Close()
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(uc, st)
+		_ = uc.CloseWrite()
+		close(done)
+	}()
+	if _, err := io.Copy(st, uc); err != nil && !strings.Contains(err.Error(), "closed") {
+		log.Printf("dd-ui-agent: stream copy: %v", err)
+	}
+	_ = st.CloseWrite()
+	<-done
+}
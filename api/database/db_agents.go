@@ -0,0 +1,89 @@
+// src/api/db_agents.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// AgentTokenRow is the enrollment record for a host's dd-ui-agent (hash only, never the token).
+type AgentTokenRow struct {
+	HostID       int64      `json:"host_id"`
+	HostName     string     `json:"hostname"`
+	TokenPrefix  string     `json:"token_prefix"`
+	CreatedBy    string     `json:"created_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastSeenAt   *time.Time `json:"last_seen_at,omitempty"`
+	LastAddr     string     `json:"last_addr,omitempty"`
+	AgentVersion string     `json:"agent_version,omitempty"`
+}
+
+// SetAgentToken stores (or rotates) the token hash for a host.
+func SetAgentToken(ctx context.Context, hostID int64, tokenHash, prefix, createdBy string) error {
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO agent_tokens (host_id, token_hash, token_prefix, created_by)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (host_id) DO UPDATE
+		  SET token_hash   = EXCLUDED.token_hash,
+		      token_prefix = EXCLUDED.token_prefix,
+		      created_by   = EXCLUDED.created_by,
+		      created_at   = now()
+	`, hostID, tokenHash, prefix, createdBy)
+	return err
+}
+
+// DeleteAgentToken revokes a host's agent token. Returns false when none existed.
+func DeleteAgentToken(ctx context.Context, hostID int64) (bool, error) {
+	cmd, err := common.DB.Exec(ctx, `DELETE FROM agent_tokens WHERE host_id=$1`, hostID)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() > 0, nil
+}
+
+// GetAgentTokenHash returns the stored hash for a host by name.
+func GetAgentTokenHash(ctx context.Context, hostName string) (int64, string, error) {
+	var hostID int64
+	var hash string
+	err := common.DB.QueryRow(ctx, `
+		SELECT t.host_id, t.token_hash
+		FROM agent_tokens t JOIN hosts h ON h.id = t.host_id
+		WHERE h.name = $1
+	`, hostName).Scan(&hostID, &hash)
+	return hostID, hash, err
+}
+
+// TouchAgentToken records the agent's last connection.
+func TouchAgentToken(ctx context.Context, hostID int64, addr, version string) error {
+	_, err := common.DB.Exec(ctx, `
+		UPDATE agent_tokens SET last_seen_at = now(), last_addr = $2, agent_version = $3
+		WHERE host_id = $1
+	`, hostID, addr, version)
+	return err
+}
+
+// ListAgentTokens returns all enrolled hosts.
+func ListAgentTokens(ctx context.Context) ([]AgentTokenRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT t.host_id, h.name, t.token_prefix, t.created_by, t.created_at, t.last_seen_at, t.last_addr, t.agent_version
+		FROM agent_tokens t JOIN hosts h ON h.id = t.host_id
+		ORDER BY h.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []AgentTokenRow{}
+	for rows.Next() {
+		var t AgentTokenRow
+		if err := rows.Scan(&t.HostID, &t.HostName, &t.TokenPrefix, &t.CreatedBy, &t.CreatedAt,
+			&t.LastSeenAt, &t.LastAddr, &t.AgentVersion); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
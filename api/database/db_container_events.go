@@ -0,0 +1,122 @@
+// src/api/db_container_events.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// ContainerEventRow is one Docker lifecycle event of a container
+type ContainerEventRow struct {
+	ID            int64     `json:"id"`
+	HostID        int64     `json:"host_id"`
+	ContainerID   string    `json:"container_id"`
+	ContainerName string    `json:"container_name"`
+	StackName     string    `json:"stack,omitempty"`
+	Action        string    `json:"action"`
+	ExitCode      *int      `json:"exit_code,omitempty"`
+	Signal        string    `json:"signal,omitempty"`
+	Health        string    `json:"health,omitempty"`
+	Image         string    `json:"image,omitempty"`
+	TimeNano      int64     `json:"-"`
+	OccurredAt    time.Time `json:"time"`
+}
+
+// InsertContainerEvent stores an event; replays of an already stored event are ignored
+func InsertContainerEvent(ctx context.Context, e ContainerEventRow) (bool, error) {
+	tag, err := common.DB.Exec(ctx, `
+		INSERT INTO container_events
+			(host_id, container_id, container_name, stack_name, action, exit_code, signal, health, image, time_nano, occurred_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT (host_id, container_id, action, time_nano) DO NOTHING
+	`, e.HostID, e.ContainerID, e.ContainerName, e.StackName, e.Action, e.ExitCode, e.Signal, e.Health, e.Image,
+		e.TimeNano, e.OccurredAt)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// LastContainerEventTime returns the newest stored event time of a host (zero if none)
+func LastContainerEventTime(ctx context.Context, hostID int64) (time.Time, error) {
+	var t *time.Time
+	err := common.DB.QueryRow(ctx, `SELECT MAX(occurred_at) FROM container_events WHERE host_id=$1`, hostID).Scan(&t)
+	if err != nil || t == nil {
+		return time.Time{}, err
+	}
+	return *t, nil
+}
+
+// ListContainerEvents returns a container's events (matched by name or id of at least 12 chars), newest first
+func ListContainerEvents(ctx context.Context, hostID int64, ctr string, since time.Time, actions []string, limit int) ([]ContainerEventRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT id, host_id, container_id, container_name, stack_name, action, exit_code, signal, health, image, time_nano, occurred_at
+		FROM container_events
+		WHERE host_id = $1 AND (container_name = $2 OR (length($2) >= 12 AND container_id LIKE $2 || '%'))
+		  AND occurred_at >= $3 AND (cardinality($4::text[]) = 0 OR action = ANY($4))
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $5
+	`, hostID, ctr, since, actions, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ContainerEventRow{}
+	for rows.Next() {
+		var e ContainerEventRow
+		if err := rows.Scan(&e.ID, &e.HostID, &e.ContainerID, &e.ContainerName, &e.StackName, &e.Action, &e.ExitCode,
+			&e.Signal, &e.Health, &e.Image, &e.TimeNano, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// ContainerEventCounts are a container's die/oom counts in a window
+type ContainerEventCounts struct {
+	Dies         int        `json:"dies"`
+	OOMs         int        `json:"ooms"`
+	LastExitCode *int       `json:"last_exit_code,omitempty"`
+	LastDieAt    *time.Time `json:"last_die_at,omitempty"`
+}
+
+// CountContainerEvents counts die and oom events per container name on a host since the
+// given times (dies and ooms use separate windows)
+func CountContainerEvents(ctx context.Context, hostID int64, diesSince, oomsSince time.Time) (map[string]ContainerEventCounts, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT container_name,
+		       COUNT(*) FILTER (WHERE action = 'die' AND occurred_at >= $2),
+		       COUNT(*) FILTER (WHERE action = 'oom' AND occurred_at >= $3),
+		       (ARRAY_AGG(exit_code ORDER BY occurred_at DESC) FILTER (WHERE action = 'die'))[1],
+		       MAX(occurred_at) FILTER (WHERE action = 'die')
+		FROM container_events
+		WHERE host_id = $1 AND action IN ('die', 'oom') AND occurred_at >= LEAST($2::timestamptz, $3::timestamptz)
+		GROUP BY container_name
+	`, hostID, diesSince, oomsSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]ContainerEventCounts{}
+	for rows.Next() {
+		var name string
+		var c ContainerEventCounts
+		if err := rows.Scan(&name, &c.Dies, &c.OOMs, &c.LastExitCode, &c.LastDieAt); err != nil {
+			return nil, err
+		}
+		out[name] = c
+	}
+	return out, rows.Err()
+}
+
+// PruneContainerEvents deletes events older than the cutoff
+func PruneContainerEvents(ctx context.Context, olderThan time.Time) (int64, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM container_events WHERE occurred_at < $1`, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
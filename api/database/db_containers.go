@@ -213,11 +213,15 @@ func ListContainersByHost(ctx context.Context, hostName string) ([]ContainerRow,
             cr.ComposeProj = v
         } else if v, ok := cr.Labels["com.docker.stack.namespace"]; ok && v != "" {
             cr.ComposeProj = v
+        } else if v, ok := cr.Labels["io.podman.compose.project"]; ok && v != "" {
+            cr.ComposeProj = v
         }
         if v, ok := cr.Labels["com.docker.compose.service"]; ok && v != "" {
             cr.ComposeSvc = v
         } else if v, ok := cr.Labels["com.docker.service.name"]; ok && v != "" {
             cr.ComposeSvc = v
+        } else if v, ok := cr.Labels["io.podman.compose.service"]; ok && v != "" {
+            cr.ComposeSvc = v
         }
 
 		// Add health from labels if available
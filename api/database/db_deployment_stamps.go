@@ -48,15 +48,15 @@ func CreateDeploymentStamp(ctx context.Context, stackID int64, method, user stri
 	var stamp DeploymentStamp
 	err = common.DB.QueryRow(ctx, `
 		INSERT INTO deployment_stamps 
-			(host_id, stack_id, deployment_hash, deployment_method, deployment_user, deployment_env_hash, deployment_status)
-		SELECT h.id, s.id, $2, $3, $4, $5, 'pending'
+			(host_id, stack_id, deployment_hash, deployment_method, deployment_user, deployment_env_hash, deployment_status, bundle_hash)
+		SELECT h.id, s.id, $2, $3, $4, $5, 'pending', NULLIF($6, '')
 		FROM iac_stacks s
 		LEFT JOIN hosts h ON (s.scope_kind='host' AND s.scope_name=h.name)
 		WHERE s.id = $1
 		RETURNING id, stack_id, deployment_hash, deployment_timestamp, deployment_method, 
 		          COALESCE(deployment_user, ''), COALESCE(deployment_env_hash, ''), deployment_status, 
 		          created_at, updated_at
-	`, stackID, deploymentHash, method, user, envHash).Scan(
+	`, stackID, deploymentHash, method, user, envHash, envVars["bundle_hash"]).Scan(
 		&stamp.ID, &stamp.StackID, &stamp.DeploymentHash, &stamp.DeploymentTimestamp,
 		&stamp.DeploymentMethod, &stamp.DeploymentUser, &stamp.DeploymentEnvHash,
 		&stamp.DeploymentStatus, &stamp.CreatedAt, &stamp.UpdatedAt,
@@ -74,15 +74,15 @@ func CreateDeploymentStampWithHash(ctx context.Context, stackID int64, method, u
 	var stamp DeploymentStamp
 	err := common.DB.QueryRow(ctx, `
 		INSERT INTO deployment_stamps 
-			(host_id, stack_id, deployment_hash, deployment_method, deployment_user, deployment_env_hash, deployment_status)
-		SELECT h.id, s.id, $2, $3, $4, $5, 'pending'
+			(host_id, stack_id, deployment_hash, deployment_method, deployment_user, deployment_env_hash, deployment_status, bundle_hash)
+		SELECT h.id, s.id, $2, $3, $4, $5, 'pending', NULLIF($6, '')
 		FROM iac_stacks s
 		LEFT JOIN hosts h ON (s.scope_kind='host' AND s.scope_name=h.name)
 		WHERE s.id = $1
 		RETURNING id, stack_id, deployment_hash, deployment_timestamp, deployment_method, 
 		          COALESCE(deployment_user, ''), COALESCE(deployment_env_hash, ''), deployment_status, 
 		          created_at, updated_at
-	`, stackID, deploymentHash, method, user, envHash).Scan(
+	`, stackID, deploymentHash, method, user, envHash, envVars["bundle_hash"]).Scan(
 		&stamp.ID, &stamp.StackID, &stamp.DeploymentHash, &stamp.DeploymentTimestamp,
 		&stamp.DeploymentMethod, &stamp.DeploymentUser, &stamp.DeploymentEnvHash,
 		&stamp.DeploymentStatus, &stamp.CreatedAt, &stamp.UpdatedAt,
@@ -137,6 +137,42 @@ func UpdateDeploymentStampStatus(ctx context.Context, stampID int64, status stri
 	return err
 }
 
+// SetDeploymentStampBundleHash records the bundle a reused stamp (same compose content) applied
+func SetDeploymentStampBundleHash(ctx context.Context, stampID int64, bundleHash string) error {
+	_, err := common.DB.Exec(ctx, `UPDATE deployment_stamps SET bundle_hash = NULLIF($1, '') WHERE id = $2`,
+		bundleHash, stampID)
+	return err
+}
+
+// LastDeployedBundleHashes returns, per stack, the bundle hash of its most recent successful
+// deploy ("" when that stamp predates bundle tracking). Stacks never deployed are absent.
+func LastDeployedBundleHashes(ctx context.Context) (map[int64]string, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT DISTINCT ON (stack_id) stack_id, COALESCE(bundle_hash, '')
+		FROM deployment_stamps
+		WHERE deployment_status = 'success' AND NOT (deployment_method = ANY($1))
+		ORDER BY stack_id, updated_at DESC
+	`, StackOperationMethods)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var h string
+		if err := rows.Scan(&id, &h); err != nil {
+			return nil, err
+		}
+		out[id] = h
+	}
+	return out, rows.Err()
+}
+
+// StackOperationMethods are the deployment_method values of whole-stack lifecycle operations
+// (stop, start, ...). Their stamps record history only and never count as the latest deploy.
+var StackOperationMethods = []string{"stop", "start", "restart", "down", "pull"}
+
 // GetLatestDeploymentStamp gets the most recent successful deployment stamp for a stack.
 func GetLatestDeploymentStamp(ctx context.Context, stackID int64) (*DeploymentStamp, error) {
 	var stamp DeploymentStamp
@@ -145,10 +181,10 @@ func GetLatestDeploymentStamp(ctx context.Context, stackID int64) (*DeploymentSt
 		       COALESCE(deployment_user, ''), COALESCE(deployment_env_hash, ''), deployment_status,
 		       created_at, updated_at
 		FROM deployment_stamps
-		WHERE stack_id = $1 AND deployment_status = 'success'
+		WHERE stack_id = $1 AND deployment_status = 'success' AND NOT (deployment_method = ANY($2))
 		ORDER BY deployment_timestamp DESC
 		LIMIT 1
-	`, stackID).Scan(
+	`, stackID, StackOperationMethods).Scan(
 		&stamp.ID, &stamp.StackID, &stamp.DeploymentHash, &stamp.DeploymentTimestamp,
 		&stamp.DeploymentMethod, &stamp.DeploymentUser, &stamp.DeploymentEnvHash,
 		&stamp.DeploymentStatus, &stamp.CreatedAt, &stamp.UpdatedAt,
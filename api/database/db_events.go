@@ -0,0 +1,328 @@
+// src/api/db_events.go
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"dd-ui/common"
+	"github.com/jackc/pgx/v5"
+)
+
+// EventsChannel is the Postgres NOTIFY channel carrying new event ids to every replica
+const EventsChannel = "ddui_events"
+
+// EventRow is one state-change event
+type EventRow struct {
+	ID      int64          `json:"id"`
+	Type    string         `json:"type"`
+	Time    time.Time      `json:"time"`
+	Host    string         `json:"host,omitempty"`
+	Stack   string         `json:"stack,omitempty"`
+	Subject string         `json:"subject,omitempty"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// InsertEvent stores an event and returns its id
+func InsertEvent(ctx context.Context, e EventRow) (int64, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil || e.Data == nil {
+		data = []byte("{}")
+	}
+	var id int64
+	err = common.DB.QueryRow(ctx, `
+		INSERT INTO events (type, host_name, stack_name, subject, data, created_at)
+		VALUES ($1,$2,$3,$4,$5::jsonb,$6) RETURNING id
+	`, e.Type, e.Host, e.Stack, e.Subject, string(data), e.Time).Scan(&id)
+	return id, err
+}
+
+// NotifyEvent announces a stored event to listening replicas
+func NotifyEvent(ctx context.Context, id int64) error {
+	_, err := common.DB.Exec(ctx, `SELECT pg_notify($1, $2::text)`, EventsChannel, id)
+	return err
+}
+
+const eventCols = `id, type, created_at, host_name, stack_name, subject, data`
+
+func scanEvent(r rowScanner) (EventRow, error) {
+	var e EventRow
+	var data []byte
+	if err := r.Scan(&e.ID, &e.Type, &e.Time, &e.Host, &e.Stack, &e.Subject, &data); err != nil {
+		return e, err
+	}
+	_ = json.Unmarshal(data, &e.Data)
+	return e, nil
+}
+
+// GetEvent loads one event
+func GetEvent(ctx context.Context, id int64) (EventRow, error) {
+	return scanEvent(common.DB.QueryRow(ctx, `SELECT `+eventCols+` FROM events WHERE id=$1`, id))
+}
+
+// ListEventsSince returns up to limit events with id > afterID, oldest first
+func ListEventsSince(ctx context.Context, afterID int64, limit int) ([]EventRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT `+eventCols+` FROM events WHERE id > $1 ORDER BY id LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []EventRow{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// PruneEvents deletes events (and their deliveries) older than the cutoff
+func PruneEvents(ctx context.Context, olderThan time.Time) (int64, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM events WHERE created_at < $1`, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ---- webhooks ----
+
+// WebhookRow is a webhook subscription; Secret is decrypted and never serialized
+type WebhookRow struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"events"`
+	Enabled    bool      `json:"enabled"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const webhookCols = `id, name, url, secret, event_types, enabled, created_by, created_at, updated_at`
+
+func scanWebhook(r rowScanner) (WebhookRow, error) {
+	var w WebhookRow
+	if err := r.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, &w.EventTypes, &w.Enabled, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return w, err
+	}
+	if dec, err := common.DecryptIfNeeded(w.Secret); err == nil {
+		w.Secret = dec
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	return w, nil
+}
+
+// ListWebhooks returns all webhook subscriptions
+func ListWebhooks(ctx context.Context) ([]WebhookRow, error) {
+	rows, err := common.DB.Query(ctx, `SELECT `+webhookCols+` FROM webhooks ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []WebhookRow{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// GetWebhook loads one webhook
+func GetWebhook(ctx context.Context, id int64) (WebhookRow, error) {
+	return scanWebhook(common.DB.QueryRow(ctx, `SELECT `+webhookCols+` FROM webhooks WHERE id=$1`, id))
+}
+
+// SaveWebhook inserts (ID 0) or updates a webhook and returns its id
+func SaveWebhook(ctx context.Context, w WebhookRow) (int64, error) {
+	secret, err := common.EncryptIfAvailable(w.Secret)
+	if err != nil {
+		return 0, err
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	if w.ID == 0 {
+		err = common.DB.QueryRow(ctx, `
+			INSERT INTO webhooks (name, url, secret, event_types, enabled, created_by)
+			VALUES ($1,$2,$3,$4,$5,$6) RETURNING id
+		`, w.Name, w.URL, secret, w.EventTypes, w.Enabled, w.CreatedBy).Scan(&w.ID)
+		return w.ID, err
+	}
+	tag, err := common.DB.Exec(ctx, `
+		UPDATE webhooks SET name=$2, url=$3, secret=$4, event_types=$5, enabled=$6, updated_at=NOW()
+		WHERE id=$1
+	`, w.ID, w.Name, w.URL, secret, w.EventTypes, w.Enabled)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = pgx.ErrNoRows
+	}
+	return w.ID, err
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(ctx context.Context, id int64) (bool, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM webhooks WHERE id=$1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ---- deliveries ----
+
+// WebhookDeliveryRow is one (possibly retried) delivery of an event to a webhook
+type WebhookDeliveryRow struct {
+	ID             int64     `json:"id"`
+	WebhookID      int64     `json:"webhook_id"`
+	EventID        int64     `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode *int      `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	ResponseBody   string    `json:"response_body,omitempty"`
+	DurationMs     *int      `json:"duration_ms,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+const deliveryCols = `d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+	d.last_status_code, d.last_error, d.response_body, d.duration_ms, d.created_at, d.updated_at`
+
+func scanDelivery(r rowScanner) (WebhookDeliveryRow, error) {
+	var d WebhookDeliveryRow
+	err := r.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.ResponseBody, &d.DurationMs, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
+
+// EnqueueWebhookDeliveries queues an event for every enabled webhook whose patterns match
+// its type ("*" matches any characters; no patterns means every event). Returns how many.
+func EnqueueWebhookDeliveries(ctx context.Context, eventID int64, eventType string) (int64, error) {
+	tag, err := common.DB.Exec(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id)
+		SELECT w.id, $1 FROM webhooks w
+		WHERE w.enabled AND (
+			cardinality(w.event_types) = 0 OR EXISTS (
+				SELECT 1 FROM unnest(w.event_types) p
+				WHERE $2 LIKE replace(replace(replace(p, '\', '\\'), '_', '\_'), '*', '%')
+			)
+		)
+	`, eventID, eventType)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// CreateWebhookDelivery queues one event for one webhook (test pings)
+func CreateWebhookDelivery(ctx context.Context, webhookID, eventID int64) (int64, error) {
+	var id int64
+	err := common.DB.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id) VALUES ($1,$2) RETURNING id
+	`, webhookID, eventID).Scan(&id)
+	return id, err
+}
+
+// ClaimDueWebhookDeliveries pushes the next attempt of up to limit due deliveries out by
+// lease and returns them, so a delivery is attempted once even if the claim outlives this replica
+func ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDeliveryRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+			FROM due WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT `+deliveryCols+` FROM claimed d JOIN events e ON e.id = d.event_id
+		ORDER BY d.id
+	`, limit, int64(lease.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookDeliveryRow
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// WebhookAttempt is the outcome of one delivery attempt
+type WebhookAttempt struct {
+	StatusCode   *int
+	Error        string
+	ResponseBody string
+	DurationMs   int
+	Status       string    // pending (retry) | succeeded | failed
+	NextAttempt  time.Time // when Status is pending
+}
+
+// RecordWebhookAttempt stores the outcome of an attempt
+func RecordWebhookAttempt(ctx context.Context, id int64, a WebhookAttempt) error {
+	next := a.NextAttempt
+	if next.IsZero() {
+		next = time.Now()
+	}
+	_, err := common.DB.Exec(ctx, `
+		UPDATE webhook_deliveries SET
+			status=$2, attempts=attempts+1, next_attempt_at=$3, last_status_code=$4,
+			last_error=$5, response_body=$6, duration_ms=$7, updated_at=NOW()
+		WHERE id=$1
+	`, id, a.Status, next, a.StatusCode, a.Error, a.ResponseBody, a.DurationMs)
+	return err
+}
+
+// ListWebhookDeliveries returns a webhook's delivery log, newest first
+func ListWebhookDeliveries(ctx context.Context, webhookID int64, limit int) ([]WebhookDeliveryRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT `+deliveryCols+` FROM webhook_deliveries d JOIN events e ON e.id = d.event_id
+		WHERE d.webhook_id = $1 ORDER BY d.id DESC LIMIT $2
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []WebhookDeliveryRow{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RequeueWebhookDelivery resets a delivery so it is attempted again right away
+func RequeueWebhookDelivery(ctx context.Context, webhookID, id int64) error {
+	tag, err := common.DB.Exec(ctx, `
+		UPDATE webhook_deliveries SET status='pending', attempts=0, next_attempt_at=NOW(), updated_at=NOW()
+		WHERE id=$1 AND webhook_id=$2
+	`, id, webhookID)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = pgx.ErrNoRows
+	}
+	return err
+}
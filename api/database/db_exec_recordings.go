@@ -0,0 +1,127 @@
+// src/api/db_exec_recordings.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// ExecRecording is the metadata for a recorded console session (cast data excluded).
+type ExecRecording struct {
+	ID            int64     `json:"id"`
+	HostID        *int64    `json:"host_id,omitempty"`
+	HostName      string    `json:"hostname"`
+	ContainerName string    `json:"container_name"`
+	Username      string    `json:"username"`
+	Shell         string    `json:"shell"`
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	DurationMs    int64     `json:"duration_ms"`
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	EventCount    int       `json:"event_count"`
+	RawBytes      int64     `json:"raw_bytes"`
+	StoredBytes   int64     `json:"stored_bytes"`
+	Truncated     bool      `json:"truncated"`
+}
+
+const execRecordingCols = `id, host_id, hostname, container_name, username, shell, started_at, ended_at,
+		       duration_ms, width, height, event_count, raw_bytes, stored_bytes, truncated`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanExecRecording(row rowScanner) (ExecRecording, error) {
+	var r ExecRecording
+	err := row.Scan(
+		&r.ID, &r.HostID, &r.HostName, &r.ContainerName, &r.Username, &r.Shell,
+		&r.StartedAt, &r.EndedAt, &r.DurationMs, &r.Width, &r.Height,
+		&r.EventCount, &r.RawBytes, &r.StoredBytes, &r.Truncated,
+	)
+	return r, err
+}
+
+// InsertExecRecording stores a finished recording; castGz is the gzip-compressed asciicast.
+func InsertExecRecording(ctx context.Context, rec ExecRecording, castGz []byte) (int64, error) {
+	var id int64
+	err := common.DB.QueryRow(ctx, `
+		INSERT INTO exec_recordings
+			(host_id, hostname, container_name, username, shell, started_at, ended_at,
+			 duration_ms, width, height, event_count, raw_bytes, stored_bytes, truncated, cast_gz)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
+		RETURNING id
+	`, rec.HostID, rec.HostName, rec.ContainerName, rec.Username, rec.Shell, rec.StartedAt, rec.EndedAt,
+		rec.DurationMs, rec.Width, rec.Height, rec.EventCount, rec.RawBytes, int64(len(castGz)), rec.Truncated,
+		castGz).Scan(&id)
+	return id, err
+}
+
+// ListExecRecordings returns recordings newest first; empty host/container means no filter.
+func ListExecRecordings(ctx context.Context, hostName, containerName string, limit int) ([]ExecRecording, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := common.DB.Query(ctx, `
+		SELECT `+execRecordingCols+`
+		FROM exec_recordings
+		WHERE ($1 = '' OR hostname = $1)
+		  AND ($2 = '' OR container_name = $2)
+		ORDER BY started_at DESC
+		LIMIT $3
+	`, hostName, containerName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []ExecRecording{}
+	for rows.Next() {
+		r, err := scanExecRecording(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// GetExecRecording returns the metadata for a single recording.
+func GetExecRecording(ctx context.Context, id int64) (*ExecRecording, error) {
+	r, err := scanExecRecording(common.DB.QueryRow(ctx, `
+		SELECT `+execRecordingCols+`
+		FROM exec_recordings
+		WHERE id = $1
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetExecRecordingCast returns the gzip-compressed asciicast for a recording.
+func GetExecRecordingCast(ctx context.Context, id int64) ([]byte, error) {
+	var b []byte
+	err := common.DB.QueryRow(ctx, `SELECT cast_gz FROM exec_recordings WHERE id = $1`, id).Scan(&b)
+	return b, err
+}
+
+// DeleteExecRecording removes a recording.
+func DeleteExecRecording(ctx context.Context, id int64) (bool, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM exec_recordings WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// PruneExecRecordings deletes recordings that started before the cutoff.
+func PruneExecRecordings(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM exec_recordings WHERE started_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,114 @@
+// src/api/db_host_facts.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// HostFactsRow is one facts collection for a host. Pointer fields are nil when the
+// value could not be read (e.g. no shell access to the host beyond the Docker API).
+type HostFactsRow struct {
+	ID             int64     `json:"id"`
+	HostID         int64     `json:"host_id"`
+	HostName       string    `json:"hostname"`
+	CollectedAt    time.Time `json:"collected_at"`
+	Runtime        string    `json:"runtime"`
+	EngineVersion  string    `json:"engine_version"`
+	APIVersion     string    `json:"api_version"`
+	StorageDriver  string    `json:"storage_driver"`
+	OS             string    `json:"os"`
+	Kernel         string    `json:"kernel"`
+	Architecture   string    `json:"architecture"`
+	CPUs           int       `json:"cpus"`
+	MemTotalBytes  int64     `json:"mem_total_bytes"`
+	MemUsedBytes   *int64    `json:"mem_used_bytes"`
+	DockerRootDir  string    `json:"docker_root_dir"`
+	DiskTotalBytes *int64    `json:"disk_total_bytes"`
+	DiskUsedBytes  *int64    `json:"disk_used_bytes"`
+	UptimeSeconds  *int64    `json:"uptime_seconds"`
+	Error          string    `json:"error,omitempty"`
+}
+
+const hostFactsCols = `f.id, f.host_id, h.name, f.collected_at, f.runtime, f.engine_version, f.api_version,
+		       f.storage_driver, f.os, f.kernel, f.architecture, f.cpus, f.mem_total_bytes, f.mem_used_bytes,
+		       f.docker_root_dir, f.disk_total_bytes, f.disk_used_bytes, f.uptime_seconds, f.error`
+
+func scanHostFacts(row rowScanner) (HostFactsRow, error) {
+	var f HostFactsRow
+	err := row.Scan(
+		&f.ID, &f.HostID, &f.HostName, &f.CollectedAt, &f.Runtime, &f.EngineVersion, &f.APIVersion,
+		&f.StorageDriver, &f.OS, &f.Kernel, &f.Architecture, &f.CPUs, &f.MemTotalBytes, &f.MemUsedBytes,
+		&f.DockerRootDir, &f.DiskTotalBytes, &f.DiskUsedBytes, &f.UptimeSeconds, &f.Error,
+	)
+	return f, err
+}
+
+// InsertHostFacts appends a facts collection to the host's history
+func InsertHostFacts(ctx context.Context, f HostFactsRow) (int64, error) {
+	var id int64
+	err := common.DB.QueryRow(ctx, `
+		INSERT INTO host_facts
+			(host_id, runtime, engine_version, api_version, storage_driver, os, kernel, architecture, cpus,
+			 mem_total_bytes, mem_used_bytes, docker_root_dir, disk_total_bytes, disk_used_bytes, uptime_seconds, error)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+		RETURNING id
+	`, f.HostID, f.Runtime, f.EngineVersion, f.APIVersion, f.StorageDriver, f.OS, f.Kernel, f.Architecture, f.CPUs,
+		f.MemTotalBytes, f.MemUsedBytes, f.DockerRootDir, f.DiskTotalBytes, f.DiskUsedBytes, f.UptimeSeconds, f.Error).Scan(&id)
+	return id, err
+}
+
+// ListLatestHostFacts returns the most recent collection of every host
+func ListLatestHostFacts(ctx context.Context) ([]HostFactsRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT DISTINCT ON (f.host_id) `+hostFactsCols+`
+		FROM host_facts f JOIN hosts h ON h.id = f.host_id
+		ORDER BY f.host_id, f.collected_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []HostFactsRow
+	for rows.Next() {
+		f, err := scanHostFacts(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// ListHostFactsHistory returns a host's collections, newest first
+func ListHostFactsHistory(ctx context.Context, hostID int64, limit int) ([]HostFactsRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT `+hostFactsCols+`
+		FROM host_facts f JOIN hosts h ON h.id = f.host_id
+		WHERE f.host_id = $1
+		ORDER BY f.collected_at DESC
+		LIMIT $2`, hostID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []HostFactsRow
+	for rows.Next() {
+		f, err := scanHostFacts(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// PruneHostFacts deletes collections older than the cutoff
+func PruneHostFacts(ctx context.Context, olderThan time.Time) (int64, error) {
+	cmd, err := common.DB.Exec(ctx, `DELETE FROM host_facts WHERE collected_at < $1`, olderThan)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
@@ -0,0 +1,62 @@
+// src/api/db_host_status.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// HostStatusRow is a host's connection state as last observed by the scanner.
+type HostStatusRow struct {
+	HostID              int64      `json:"host_id"`
+	HostName            string     `json:"hostname"`
+	State               string     `json:"state"` // online|degraded|offline|unknown
+	LastSeen            *time.Time `json:"last_seen"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LatencyMs           *int       `json:"latency_ms"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// UpsertHostStatus stores a host's current connection state
+func UpsertHostStatus(ctx context.Context, s HostStatusRow) error {
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO host_status (host_id, state, last_seen, last_error, last_error_at, latency_ms, consecutive_failures, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,NOW())
+		ON CONFLICT (host_id) DO UPDATE SET
+			state = EXCLUDED.state,
+			last_seen = EXCLUDED.last_seen,
+			last_error = EXCLUDED.last_error,
+			last_error_at = EXCLUDED.last_error_at,
+			latency_ms = EXCLUDED.latency_ms,
+			consecutive_failures = EXCLUDED.consecutive_failures,
+			updated_at = NOW()
+	`, s.HostID, s.State, s.LastSeen, s.LastError, s.LastErrorAt, s.LatencyMs, s.ConsecutiveFailures)
+	return err
+}
+
+// ListHostStatus returns the stored state of every host that has been scanned
+func ListHostStatus(ctx context.Context) ([]HostStatusRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT s.host_id, h.name, s.state, s.last_seen, s.last_error, s.last_error_at,
+		       s.latency_ms, s.consecutive_failures, s.updated_at
+		FROM host_status s JOIN hosts h ON h.id = s.host_id
+		ORDER BY h.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []HostStatusRow
+	for rows.Next() {
+		var s HostStatusRow
+		if err := rows.Scan(&s.HostID, &s.HostName, &s.State, &s.LastSeen, &s.LastError, &s.LastErrorAt,
+			&s.LatencyMs, &s.ConsecutiveFailures, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
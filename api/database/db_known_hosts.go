@@ -0,0 +1,116 @@
+// src/api/db_known_hosts.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// KnownHostRow is a pinned (or offered) SSH host key.
+type KnownHostRow struct {
+	ID          int64      `json:"id"`
+	Host        string     `json:"host"`
+	KeyType     string     `json:"key_type"`
+	PublicKey   string     `json:"public_key"`
+	Fingerprint string     `json:"fingerprint"`
+	Status      string     `json:"status"` // trusted|pending|mismatch
+	FirstSeen   time.Time  `json:"first_seen"`
+	LastSeen    time.Time  `json:"last_seen"`
+	ApprovedBy  string     `json:"approved_by,omitempty"`
+	ApprovedAt  *time.Time `json:"approved_at,omitempty"`
+}
+
+const knownHostCols = `id, host, key_type, public_key, fingerprint, status, first_seen, last_seen, approved_by, approved_at`
+
+func scanKnownHost(row rowScanner) (KnownHostRow, error) {
+	var k KnownHostRow
+	err := row.Scan(&k.ID, &k.Host, &k.KeyType, &k.PublicKey, &k.Fingerprint, &k.Status,
+		&k.FirstSeen, &k.LastSeen, &k.ApprovedBy, &k.ApprovedAt)
+	return k, err
+}
+
+// ListKnownHostKeys returns keys; empty host/status means no filter.
+func ListKnownHostKeys(ctx context.Context, host, status string) ([]KnownHostRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT `+knownHostCols+`
+		FROM ssh_known_hosts
+		WHERE ($1 = '' OR host = $1)
+		  AND ($2 = '' OR status = $2)
+		ORDER BY host, status, last_seen DESC
+	`, host, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []KnownHostRow{}
+	for rows.Next() {
+		k, err := scanKnownHost(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// GetKnownHostKey returns one key by id.
+func GetKnownHostKey(ctx context.Context, id int64) (KnownHostRow, error) {
+	return scanKnownHost(common.DB.QueryRow(ctx, `SELECT `+knownHostCols+` FROM ssh_known_hosts WHERE id=$1`, id))
+}
+
+// InsertKnownHostKey records an offered key with the given status unless it is
+// already known; returns the stored row either way.
+func InsertKnownHostKey(ctx context.Context, k KnownHostRow) (KnownHostRow, error) {
+	var approvedAt *time.Time
+	if k.Status == "trusted" {
+		now := time.Now()
+		approvedAt = &now
+	}
+	return scanKnownHost(common.DB.QueryRow(ctx, `
+		INSERT INTO ssh_known_hosts (host, key_type, public_key, fingerprint, status, approved_by, approved_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		ON CONFLICT (host, fingerprint) DO UPDATE SET last_seen = now()
+		RETURNING `+knownHostCols,
+		k.Host, k.KeyType, k.PublicKey, k.Fingerprint, k.Status, k.ApprovedBy, approvedAt))
+}
+
+// TouchKnownHostKey bumps last_seen for a key.
+func TouchKnownHostKey(ctx context.Context, id int64) error {
+	_, err := common.DB.Exec(ctx, `UPDATE ssh_known_hosts SET last_seen = now() WHERE id=$1`, id)
+	return err
+}
+
+// ApproveKnownHostKey trusts a key and drops any other key of the same type for the host
+// (the key it replaces after a legitimate re-key).
+func ApproveKnownHostKey(ctx context.Context, id int64, by string) (KnownHostRow, error) {
+	tx, err := common.DB.Begin(ctx)
+	if err != nil {
+		return KnownHostRow{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	k, err := scanKnownHost(tx.QueryRow(ctx, `
+		UPDATE ssh_known_hosts SET status='trusted', approved_by=$2, approved_at=now()
+		WHERE id=$1
+		RETURNING `+knownHostCols, id, by))
+	if err != nil {
+		return KnownHostRow{}, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM ssh_known_hosts WHERE host=$1 AND key_type=$2 AND id<>$3`,
+		k.Host, k.KeyType, k.ID); err != nil {
+		return KnownHostRow{}, err
+	}
+	return k, tx.Commit(ctx)
+}
+
+// DeleteKnownHostKey forgets a key. Returns false when it did not exist.
+func DeleteKnownHostKey(ctx context.Context, id int64) (bool, error) {
+	cmd, err := common.DB.Exec(ctx, `DELETE FROM ssh_known_hosts WHERE id=$1`, id)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() > 0, nil
+}
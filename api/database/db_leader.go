@@ -0,0 +1,63 @@
+// src/api/db_leader.go
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dd-ui/common"
+	"github.com/jackc/pgx/v5"
+)
+
+// LeaseRow is the current holder of a named lease
+type LeaseRow struct {
+	Name       string    `json:"name"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// AcquireLease takes or renews a lease for holder. It succeeds when the lease is free,
+// expired or already held by holder; otherwise ok is false and the row shows the holder.
+func AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (LeaseRow, bool, error) {
+	var l LeaseRow
+	err := common.DB.QueryRow(ctx, `
+		INSERT INTO leader_lease (name, holder, acquired_at, renewed_at, expires_at)
+		VALUES ($1, $2, NOW(), NOW(), NOW() + $3 * INTERVAL '1 millisecond')
+		ON CONFLICT (name) DO UPDATE SET
+			holder = EXCLUDED.holder,
+			acquired_at = CASE WHEN leader_lease.holder = EXCLUDED.holder THEN leader_lease.acquired_at ELSE NOW() END,
+			renewed_at = NOW(),
+			expires_at = EXCLUDED.expires_at
+		WHERE leader_lease.holder = EXCLUDED.holder OR leader_lease.expires_at < NOW()
+		RETURNING name, holder, acquired_at, renewed_at, expires_at
+	`, name, holder, ttl.Milliseconds()).Scan(&l.Name, &l.Holder, &l.AcquiredAt, &l.RenewedAt, &l.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		cur, err := GetLease(ctx, name)
+		return cur, false, err
+	}
+	if err != nil {
+		return l, false, err
+	}
+	return l, true, nil
+}
+
+// GetLease returns the lease row (zero value when it was never taken)
+func GetLease(ctx context.Context, name string) (LeaseRow, error) {
+	var l LeaseRow
+	err := common.DB.QueryRow(ctx, `
+		SELECT name, holder, acquired_at, renewed_at, expires_at FROM leader_lease WHERE name = $1
+	`, name).Scan(&l.Name, &l.Holder, &l.AcquiredAt, &l.RenewedAt, &l.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return LeaseRow{Name: name}, nil
+	}
+	return l, err
+}
+
+// ReleaseLease gives up a lease if holder still owns it
+func ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := common.DB.Exec(ctx, `DELETE FROM leader_lease WHERE name = $1 AND holder = $2`, name, holder)
+	return err
+}
@@ -0,0 +1,115 @@
+// src/api/db_local_users.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// LocalUserRow is a built-in account. TOTPSecret is returned decrypted.
+type LocalUserRow struct {
+	ID             int64      `json:"id"`
+	Username       string     `json:"username"`
+	Email          string     `json:"email"`
+	Name           string     `json:"name"`
+	PasswordHash   string     `json:"-"`
+	TOTPSecret     string     `json:"-"`
+	TOTPEnabled    bool       `json:"totp_enabled"`
+	TOTPLastStep   int64      `json:"-"`
+	Disabled       bool       `json:"disabled"`
+	FailedAttempts int        `json:"failed_attempts"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt    *time.Time `json:"last_login_at,omitempty"`
+}
+
+// GetLocalUser loads an account by username (case-insensitive)
+func GetLocalUser(ctx context.Context, username string) (LocalUserRow, error) {
+	var u LocalUserRow
+	err := common.DB.QueryRow(ctx, `
+		SELECT id, username, email, name, password_hash, totp_secret, totp_enabled, totp_last_step,
+		       disabled, failed_attempts, locked_until, last_login_at
+		FROM local_users WHERE lower(username) = lower($1)
+	`, username).Scan(&u.ID, &u.Username, &u.Email, &u.Name, &u.PasswordHash, &u.TOTPSecret, &u.TOTPEnabled,
+		&u.TOTPLastStep, &u.Disabled, &u.FailedAttempts, &u.LockedUntil, &u.LastLoginAt)
+	if err != nil {
+		return u, err
+	}
+	if u.TOTPSecret != "" {
+		if dec, derr := common.DecryptIfNeeded(u.TOTPSecret); derr == nil {
+			u.TOTPSecret = dec
+		}
+	}
+	return u, nil
+}
+
+// UpsertLocalUser creates an account or resets its password (and, when totpSecret is
+// given, its TOTP enrollment)
+func UpsertLocalUser(ctx context.Context, username, email, name, passwordHash, totpSecret string, totpEnabled bool) error {
+	if totpSecret != "" {
+		enc, err := common.EncryptIfAvailable(totpSecret)
+		if err != nil {
+			return err
+		}
+		totpSecret = enc
+	}
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO local_users (username, email, name, password_hash, totp_secret, totp_enabled)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (username) DO UPDATE SET
+			password_hash = EXCLUDED.password_hash,
+			email = EXCLUDED.email,
+			name = EXCLUDED.name,
+			totp_secret = EXCLUDED.totp_secret,
+			totp_enabled = EXCLUDED.totp_enabled,
+			totp_last_step = 0,
+			failed_attempts = 0,
+			locked_until = NULL,
+			updated_at = NOW()
+	`, username, email, name, passwordHash, totpSecret, totpEnabled)
+	return err
+}
+
+// SetLocalUserTOTP stores a (pending or confirmed) TOTP secret
+func SetLocalUserTOTP(ctx context.Context, id int64, secret string, enabled bool) error {
+	enc, err := common.EncryptIfAvailable(secret)
+	if err != nil {
+		return err
+	}
+	_, err = common.DB.Exec(ctx, `
+		UPDATE local_users SET totp_secret=$2, totp_enabled=$3, updated_at=NOW() WHERE id=$1
+	`, id, enc, enabled)
+	return err
+}
+
+// RecordLocalLoginFailure counts a failed attempt and locks the account for lockFor once
+// maxAttempts is reached
+func RecordLocalLoginFailure(ctx context.Context, id int64, maxAttempts int, lockFor time.Duration) error {
+	_, err := common.DB.Exec(ctx, `
+		UPDATE local_users SET
+			failed_attempts = failed_attempts + 1,
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() + $3 * INTERVAL '1 second' ELSE locked_until END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, id, maxAttempts, int64(lockFor.Seconds()))
+	return err
+}
+
+// RecordLocalLoginSuccess clears failures and remembers the TOTP step used
+func RecordLocalLoginSuccess(ctx context.Context, id int64, totpStep int64) error {
+	_, err := common.DB.Exec(ctx, `
+		UPDATE local_users SET
+			failed_attempts = 0, locked_until = NULL, last_login_at = NOW(),
+			totp_last_step = GREATEST(totp_last_step, $2), updated_at = NOW()
+		WHERE id = $1
+	`, id, totpStep)
+	return err
+}
+
+// CountLocalUsers returns the number of built-in accounts
+func CountLocalUsers(ctx context.Context) (int, error) {
+	var n int
+	err := common.DB.QueryRow(ctx, `SELECT COUNT(*) FROM local_users`).Scan(&n)
+	return n, err
+}
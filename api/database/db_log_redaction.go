@@ -0,0 +1,61 @@
+// src/api/db_log_redaction.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+)
+
+// LogRedactionRuleRow is a per-stack regex applied to container log lines
+type LogRedactionRuleRow struct {
+	ID          int64     `json:"id"`
+	StackID     int64     `json:"stack_id"`
+	Pattern     string    `json:"pattern"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListLogRedactionRules returns a stack's rules, oldest first
+func ListLogRedactionRules(ctx context.Context, stackID int64) ([]LogRedactionRuleRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT id, stack_id, pattern, description, created_by, created_at
+		FROM log_redaction_rules
+		WHERE stack_id = $1
+		ORDER BY id
+	`, stackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []LogRedactionRuleRow{}
+	for rows.Next() {
+		var r LogRedactionRuleRow
+		if err := rows.Scan(&r.ID, &r.StackID, &r.Pattern, &r.Description, &r.CreatedBy, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// CreateLogRedactionRule stores a rule and returns it with its id
+func CreateLogRedactionRule(ctx context.Context, r LogRedactionRuleRow) (LogRedactionRuleRow, error) {
+	err := common.DB.QueryRow(ctx, `
+		INSERT INTO log_redaction_rules (stack_id, pattern, description, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, r.StackID, r.Pattern, r.Description, r.CreatedBy).Scan(&r.ID, &r.CreatedAt)
+	return r, err
+}
+
+// DeleteLogRedactionRule deletes a stack's rule; false if it did not exist
+func DeleteLogRedactionRule(ctx context.Context, stackID, id int64) (bool, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM log_redaction_rules WHERE stack_id = $1 AND id = $2`, stackID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
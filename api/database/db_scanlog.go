@@ -3,17 +3,183 @@ package database
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
 	
 	"dd-ui/common"
 )
 
-// ScanLog logs scan operations for a host
+// scanLogLast remembers the fingerprint of the last info row per (host, message, subject)
+// so DD_UI_SCAN_LOG_INFO=changes only records info rows whose data actually changed.
+// Subjects not seen for scanLogForgetAfter are dropped (recreated containers get new IDs).
+var (
+	scanLogMu    sync.Mutex
+	scanLogLast  = map[string]scanLogSeen{}
+	scanLogSwept time.Time
+)
+
+const scanLogForgetAfter = time.Hour
+
+type scanLogSeen struct {
+	fp   string
+	seen time.Time
+}
+
+// scanLogVolatile are data keys that change on every scan without being a state change
+var scanLogVolatile = map[string]bool{"status": true}
+
+// ScanLog logs scan operations for a host. warn/error rows are always kept; info rows follow
+// DD_UI_SCAN_LOG_INFO: "changes" (default) keeps only rows that differ from the previous one
+// for the same subject, "all" keeps every row and "none" drops them.
 func ScanLog(ctx context.Context, hostID int64, level, msg string, data map[string]any) {
 	if data == nil { data = map[string]any{} }
+	if level == "info" && !scanLogSampleInfo(hostID, msg, data) {
+		return
+	}
 	b, _ := json.Marshal(data)
 	if _, err := common.DB.Exec(ctx, `INSERT INTO scan_logs (host_id, level, message, data) VALUES ($1,$2,$3,$4::jsonb)`,
 		hostID, level, msg, string(b)); err != nil {
 		common.ErrorLog("scanlog insert failed: %v (msg=%s)", err, msg)
 	}
-}
\ No newline at end of file
+}
+
+func scanLogSampleInfo(hostID int64, msg string, data map[string]any) bool {
+	switch strings.ToLower(common.Env("DD_UI_SCAN_LOG_INFO", "changes")) {
+	case "all":
+		return true
+	case "none":
+		return false
+	}
+
+	subject := ""
+	for _, k := range []string{"id", "name", "node", "service", "task"} {
+		if v, ok := data[k]; ok {
+			subject = fmt.Sprint(v)
+			break
+		}
+	}
+	stable := make(map[string]any, len(data))
+	for k, v := range data {
+		if !scanLogVolatile[k] {
+			stable[k] = v
+		}
+	}
+	b, _ := json.Marshal(stable) // map keys are marshalled sorted
+	sum := sha1.Sum(b)
+	fp := hex.EncodeToString(sum[:])
+	key := fmt.Sprintf("%d\x00%s\x00%s", hostID, msg, subject)
+
+	now := time.Now()
+	scanLogMu.Lock()
+	defer scanLogMu.Unlock()
+	if now.Sub(scanLogSwept) >= scanLogForgetAfter/4 {
+		for k, e := range scanLogLast {
+			if now.Sub(e.seen) >= scanLogForgetAfter {
+				delete(scanLogLast, k)
+			}
+		}
+		scanLogSwept = now
+	}
+	prev, ok := scanLogLast[key]
+	scanLogLast[key] = scanLogSeen{fp: fp, seen: now}
+	return !ok || prev.fp != fp
+}
+
+// ScanLogRow is one scan_logs entry
+type ScanLogRow struct {
+	ID       int64           `json:"id"`
+	HostID   int64           `json:"host_id"`
+	HostName string          `json:"hostname"`
+	At       time.Time       `json:"at"`
+	Level    string          `json:"level"`
+	Message  string          `json:"message"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// ScanLogFilter narrows ListScanLogs; zero values mean no filter
+type ScanLogFilter struct {
+	Host   string
+	Levels []string
+	Query  string // substring of the message
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// ListScanLogs returns matching rows, newest first
+func ListScanLogs(ctx context.Context, f ScanLogFilter) ([]ScanLogRow, error) {
+	where := []string{"TRUE"}
+	args := []any{}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Host != "" {
+		add("h.name = $%d", f.Host)
+	}
+	if len(f.Levels) > 0 {
+		add("l.level = ANY($%d)", f.Levels)
+	}
+	if f.Query != "" {
+		add("l.message ILIKE '%%' || $%d || '%%'", f.Query)
+	}
+	if !f.Since.IsZero() {
+		add("l.at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("l.at < $%d", f.Until)
+	}
+	args = append(args, f.Limit, f.Offset)
+
+	rows, err := common.DB.Query(ctx, `
+		SELECT l.id, l.host_id, h.name, l.at, l.level, l.message, l.data
+		FROM scan_logs l JOIN hosts h ON h.id = l.host_id
+		WHERE `+strings.Join(where, " AND ")+fmt.Sprintf(`
+		ORDER BY l.at DESC, l.id DESC
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ScanLogRow{}
+	for rows.Next() {
+		var r ScanLogRow
+		var data []byte
+		if err := rows.Scan(&r.ID, &r.HostID, &r.HostName, &r.At, &r.Level, &r.Message, &data); err != nil {
+			return nil, err
+		}
+		r.Data = json.RawMessage(data)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// PruneScanLogs deletes rows older than the cutoff, restricted to the given levels when
+// non-empty. Deletes run in batches so a large backlog doesn't hold one long transaction.
+func PruneScanLogs(ctx context.Context, olderThan time.Time, levels []string) (int64, error) {
+	if levels == nil {
+		levels = []string{}
+	}
+	var total int64
+	for {
+		cmd, err := common.DB.Exec(ctx, `
+			DELETE FROM scan_logs WHERE id IN (
+				SELECT id FROM scan_logs
+				WHERE at < $1 AND (cardinality($2::text[]) = 0 OR level = ANY($2))
+				LIMIT 10000
+			)`, olderThan, levels)
+		if err != nil {
+			return total, err
+		}
+		total += cmd.RowsAffected()
+		if cmd.RowsAffected() < 10000 || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// src/api/db_sessions.go
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"dd-ui/common"
+	"github.com/jackc/pgx/v5"
+)
+
+// SessionStore persists scs sessions in the sessions table so they survive restarts and are
+// shared by replicas. Deleting a row revokes the session on the next request.
+type SessionStore struct{}
+
+func (SessionStore) Find(token string) ([]byte, bool, error) {
+	return SessionStore{}.FindCtx(context.Background(), token)
+}
+
+func (SessionStore) Commit(token string, b []byte, expiry time.Time) error {
+	return SessionStore{}.CommitCtx(context.Background(), token, b, expiry)
+}
+
+func (SessionStore) Delete(token string) error {
+	return SessionStore{}.DeleteCtx(context.Background(), token)
+}
+
+// FindCtx returns the session data, or found=false when missing or expired
+func (SessionStore) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	var b []byte
+	err := common.DB.QueryRow(ctx, `SELECT data FROM sessions WHERE token=$1 AND expiry > NOW()`, token).Scan(&b)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// CommitCtx upserts the session data; metadata columns are kept
+func (SessionStore) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO sessions (token, data, expiry) VALUES ($1,$2,$3)
+		ON CONFLICT (token) DO UPDATE SET data = EXCLUDED.data, expiry = EXCLUDED.expiry
+	`, token, b, expiry)
+	return err
+}
+
+func (SessionStore) DeleteCtx(ctx context.Context, token string) error {
+	_, err := common.DB.Exec(ctx, `DELETE FROM sessions WHERE token=$1`, token)
+	return err
+}
+
+// SessionRow is a session as listed by the sessions API (the token is never exposed)
+type SessionRow struct {
+	SID       string     `json:"id"`
+	UserSub   string     `json:"user_sub"`
+	UserEmail string     `json:"user_email"`
+	UserName  string     `json:"user_name"`
+	Provider  string     `json:"provider,omitempty"`
+	IP        string     `json:"ip"`
+	UserAgent string     `json:"user_agent"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+	Expiry    time.Time  `json:"expires_at"`
+	Current   bool       `json:"current"`
+}
+
+// SessionMeta is what TouchSession records for a session
+type SessionMeta struct {
+	SID, UserSub, UserEmail, UserName, Provider, IP, UserAgent string
+}
+
+// TouchSession records who owns a session and where it was last used
+func TouchSession(ctx context.Context, token string, m SessionMeta) error {
+	_, err := common.DB.Exec(ctx, `
+		UPDATE sessions SET sid=$2, user_sub=$3, user_email=$4, user_name=$5, provider=$6,
+			ip=$7, user_agent=$8, last_seen=NOW()
+		WHERE token=$1
+	`, token, m.SID, m.UserSub, m.UserEmail, m.UserName, m.Provider, m.IP, m.UserAgent)
+	return err
+}
+
+// ListSessions returns live sessions, newest activity first; userSub "" lists everyone's.
+// currentToken marks the caller's own session.
+func ListSessions(ctx context.Context, userSub, currentToken string) ([]SessionRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT sid, user_sub, user_email, user_name, provider, ip, user_agent, created_at, last_seen, expiry,
+		       token = $2
+		FROM sessions
+		WHERE expiry > NOW() AND user_sub <> '' AND ($1 = '' OR user_sub = $1)
+		ORDER BY COALESCE(last_seen, created_at) DESC
+	`, userSub, currentToken)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []SessionRow{}
+	for rows.Next() {
+		var s SessionRow
+		if err := rows.Scan(&s.SID, &s.UserSub, &s.UserEmail, &s.UserName, &s.Provider, &s.IP, &s.UserAgent,
+			&s.CreatedAt, &s.LastSeen, &s.Expiry, &s.Current); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSessionBySID revokes one session; userSub "" allows any owner
+func DeleteSessionBySID(ctx context.Context, sid, userSub string) (bool, error) {
+	tag, err := common.DB.Exec(ctx, `
+		DELETE FROM sessions WHERE sid=$1 AND sid <> '' AND ($2 = '' OR user_sub = $2)
+	`, sid, userSub)
+	if err != nil {
+		return false, err
+	}
+	_, _ = common.DB.Exec(ctx, `DELETE FROM session_id_tokens WHERE sid=$1`, sid)
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteUserSessions revokes every session of a user (matched by sub or email) except
+// exceptToken, returning how many were removed
+func DeleteUserSessions(ctx context.Context, user, exceptToken string) (int64, error) {
+	rows, err := common.DB.Query(ctx, `
+		DELETE FROM sessions
+		WHERE $1 <> '' AND (user_sub = $1 OR lower(user_email) = lower($1)) AND token <> $2
+		RETURNING sid
+	`, user, exceptToken)
+	if err != nil {
+		return 0, err
+	}
+	var sids []string
+	for rows.Next() {
+		var sid string
+		if err := rows.Scan(&sid); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sids = append(sids, sid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(sids) > 0 {
+		_, _ = common.DB.Exec(ctx, `DELETE FROM session_id_tokens WHERE sid = ANY($1)`, sids)
+	}
+	return int64(len(sids)), nil
+}
+
+// DeleteProviderSessions revokes every session recorded with the given login provider
+// ("local" for built-in accounts), returning how many were removed
+func DeleteProviderSessions(ctx context.Context, provider string) (int64, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM sessions WHERE provider = $1 AND $1 <> ''`, provider)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteExpiredSessions removes expired sessions and id_tokens
+func DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	tag, err := common.DB.Exec(ctx, `DELETE FROM sessions WHERE expiry <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	_, err = common.DB.Exec(ctx, `DELETE FROM session_id_tokens WHERE expires_at <= NOW()`)
+	return tag.RowsAffected(), err
+}
+
+// PutSessionIDToken stores the OIDC id_token of a session for RP-initiated logout
+func PutSessionIDToken(ctx context.Context, sid, token string, exp time.Time) error {
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO session_id_tokens (sid, id_token, expires_at) VALUES ($1,$2,$3)
+		ON CONFLICT (sid) DO UPDATE SET id_token = EXCLUDED.id_token, expires_at = EXCLUDED.expires_at
+	`, sid, token, exp)
+	return err
+}
+
+// PopSessionIDToken removes and returns a session's id_token ("" if absent or expired)
+func PopSessionIDToken(ctx context.Context, sid string) (string, error) {
+	var tok string
+	var exp time.Time
+	err := common.DB.QueryRow(ctx, `DELETE FROM session_id_tokens WHERE sid=$1 RETURNING id_token, expires_at`, sid).Scan(&tok, &exp)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil || time.Now().After(exp) {
+		return "", err
+	}
+	return tok, nil
+}
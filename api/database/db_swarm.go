@@ -0,0 +1,231 @@
+// src/api/db_swarm.go
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"dd-ui/common"
+)
+
+// SwarmNodeRow is a node as seen from a swarm manager host.
+type SwarmNodeRow struct {
+	NodeID        string            `json:"node_id"`
+	Hostname      string            `json:"hostname"`
+	Role          string            `json:"role"`
+	Availability  string            `json:"availability"`
+	State         string            `json:"state"`
+	Addr          string            `json:"addr"`
+	Leader        bool              `json:"leader"`
+	EngineVersion string            `json:"engine_version"`
+	Labels        map[string]string `json:"labels"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// SwarmServiceRow is a swarm service with its desired/running task counts.
+type SwarmServiceRow struct {
+	ServiceID       string            `json:"service_id"`
+	Name            string            `json:"name"`
+	StackNamespace  string            `json:"stack_namespace"`
+	Image           string            `json:"image"`
+	Mode            string            `json:"mode"`
+	DesiredReplicas int               `json:"desired_replicas"`
+	RunningTasks    int               `json:"running_tasks"`
+	Ports           []map[string]any  `json:"ports"`
+	Labels          map[string]string `json:"labels"`
+	UpdateState     string            `json:"update_state,omitempty"`
+	CreatedTS       *time.Time        `json:"created_ts,omitempty"`
+	UpdatedTS       *time.Time        `json:"updated_ts,omitempty"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// SwarmTaskRow is a single task (service slot instance).
+type SwarmTaskRow struct {
+	TaskID       string     `json:"task_id"`
+	ServiceID    string     `json:"service_id"`
+	NodeID       string     `json:"node_id"`
+	Slot         int        `json:"slot"`
+	Image        string     `json:"image"`
+	DesiredState string     `json:"desired_state"`
+	State        string     `json:"state"`
+	Message      string     `json:"message,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	ContainerID  string     `json:"container_id,omitempty"`
+	UpdatedTS    *time.Time `json:"updated_ts,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// UpsertSwarmNode records a node seen from the manager host.
+func UpsertSwarmNode(ctx context.Context, hostID int64, n SwarmNodeRow) error {
+	labelsB, _ := json.Marshal(n.Labels)
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO swarm_nodes (host_id, node_id, hostname, role, availability, state, addr, leader, engine_version, labels)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10::jsonb)
+		ON CONFLICT (host_id, node_id) DO UPDATE
+		  SET hostname       = EXCLUDED.hostname,
+		      role           = EXCLUDED.role,
+		      availability   = EXCLUDED.availability,
+		      state          = EXCLUDED.state,
+		      addr           = EXCLUDED.addr,
+		      leader         = EXCLUDED.leader,
+		      engine_version = EXCLUDED.engine_version,
+		      labels         = EXCLUDED.labels,
+		      updated_at     = now()
+	`, hostID, n.NodeID, n.Hostname, n.Role, n.Availability, n.State, n.Addr, n.Leader, n.EngineVersion, string(labelsB))
+	return err
+}
+
+// UpsertSwarmService records a service seen from the manager host.
+func UpsertSwarmService(ctx context.Context, hostID int64, s SwarmServiceRow) error {
+	portsB, _ := json.Marshal(s.Ports)
+	labelsB, _ := json.Marshal(s.Labels)
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO swarm_services (host_id, service_id, name, stack_namespace, image, mode, desired_replicas,
+		                            running_tasks, ports, labels, update_state, created_ts, updated_ts)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9::jsonb,$10::jsonb,$11,$12,$13)
+		ON CONFLICT (host_id, service_id) DO UPDATE
+		  SET name             = EXCLUDED.name,
+		      stack_namespace  = EXCLUDED.stack_namespace,
+		      image            = EXCLUDED.image,
+		      mode             = EXCLUDED.mode,
+		      desired_replicas = EXCLUDED.desired_replicas,
+		      running_tasks    = EXCLUDED.running_tasks,
+		      ports            = EXCLUDED.ports,
+		      labels           = EXCLUDED.labels,
+		      update_state     = EXCLUDED.update_state,
+		      created_ts       = EXCLUDED.created_ts,
+		      updated_ts       = EXCLUDED.updated_ts,
+		      updated_at       = now()
+	`, hostID, s.ServiceID, s.Name, s.StackNamespace, s.Image, s.Mode, s.DesiredReplicas,
+		s.RunningTasks, string(portsB), string(labelsB), s.UpdateState, s.CreatedTS, s.UpdatedTS)
+	return err
+}
+
+// UpsertSwarmTask records a task seen from the manager host.
+func UpsertSwarmTask(ctx context.Context, hostID int64, t SwarmTaskRow) error {
+	_, err := common.DB.Exec(ctx, `
+		INSERT INTO swarm_tasks (host_id, task_id, service_id, node_id, slot, image, desired_state, state,
+		                         message, error, container_id, updated_ts)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+		ON CONFLICT (host_id, task_id) DO UPDATE
+		  SET service_id    = EXCLUDED.service_id,
+		      node_id       = EXCLUDED.node_id,
+		      slot          = EXCLUDED.slot,
+		      image         = EXCLUDED.image,
+		      desired_state = EXCLUDED.desired_state,
+		      state         = EXCLUDED.state,
+		      message       = EXCLUDED.message,
+		      error         = EXCLUDED.error,
+		      container_id  = EXCLUDED.container_id,
+		      updated_ts    = EXCLUDED.updated_ts,
+		      updated_at    = now()
+	`, hostID, t.TaskID, t.ServiceID, t.NodeID, t.Slot, t.Image, t.DesiredState, t.State,
+		t.Message, t.Error, t.ContainerID, t.UpdatedTS)
+	return err
+}
+
+// PruneSwarmRows deletes swarm rows for a host whose ids are not in keepIDs.
+// kind is one of "nodes", "services", "tasks".
+func PruneSwarmRows(ctx context.Context, hostID int64, kind string, keepIDs []string) (int64, error) {
+	var table, col string
+	switch kind {
+	case "nodes":
+		table, col = "swarm_nodes", "node_id"
+	case "services":
+		table, col = "swarm_services", "service_id"
+	case "tasks":
+		table, col = "swarm_tasks", "task_id"
+	default:
+		return 0, fmt.Errorf("unknown swarm kind %q", kind)
+	}
+	if keepIDs == nil {
+		keepIDs = []string{}
+	}
+	cmd, err := common.DB.Exec(ctx, `DELETE FROM `+table+` WHERE host_id=$1 AND NOT (`+col+` = ANY($2))`, hostID, keepIDs)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// ListSwarmNodes returns nodes seen from a manager host.
+func ListSwarmNodes(ctx context.Context, hostID int64) ([]SwarmNodeRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT node_id, hostname, role, availability, state, addr, leader, engine_version, labels, updated_at
+		FROM swarm_nodes WHERE host_id=$1 ORDER BY role, hostname
+	`, hostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SwarmNodeRow{}
+	for rows.Next() {
+		var n SwarmNodeRow
+		var labelsB []byte
+		if err := rows.Scan(&n.NodeID, &n.Hostname, &n.Role, &n.Availability, &n.State, &n.Addr,
+			&n.Leader, &n.EngineVersion, &labelsB, &n.UpdatedAt); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal(labelsB, &n.Labels)
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// ListSwarmServices returns services seen from a manager host; empty namespace means all.
+func ListSwarmServices(ctx context.Context, hostID int64, namespace string) ([]SwarmServiceRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT service_id, name, stack_namespace, image, mode, desired_replicas, running_tasks,
+		       ports, labels, update_state, created_ts, updated_ts, updated_at
+		FROM swarm_services
+		WHERE host_id=$1 AND ($2 = '' OR stack_namespace = $2)
+		ORDER BY stack_namespace, name
+	`, hostID, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SwarmServiceRow{}
+	for rows.Next() {
+		var s SwarmServiceRow
+		var portsB, labelsB []byte
+		if err := rows.Scan(&s.ServiceID, &s.Name, &s.StackNamespace, &s.Image, &s.Mode, &s.DesiredReplicas,
+			&s.RunningTasks, &portsB, &labelsB, &s.UpdateState, &s.CreatedTS, &s.UpdatedTS, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal(portsB, &s.Ports)
+		_ = json.Unmarshal(labelsB, &s.Labels)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// ListSwarmTasks returns tasks seen from a manager host; empty serviceID means all.
+func ListSwarmTasks(ctx context.Context, hostID int64, serviceID string) ([]SwarmTaskRow, error) {
+	rows, err := common.DB.Query(ctx, `
+		SELECT task_id, service_id, node_id, slot, image, desired_state, state, message, error,
+		       container_id, updated_ts, updated_at
+		FROM swarm_tasks
+		WHERE host_id=$1 AND ($2 = '' OR service_id = $2)
+		ORDER BY service_id, slot, updated_ts DESC NULLS LAST
+	`, hostID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SwarmTaskRow{}
+	for rows.Next() {
+		var t SwarmTaskRow
+		if err := rows.Scan(&t.TaskID, &t.ServiceID, &t.NodeID, &t.Slot, &t.Image, &t.DesiredState, &t.State,
+			&t.Message, &t.Error, &t.ContainerID, &t.UpdatedTS, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
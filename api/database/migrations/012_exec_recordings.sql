@@ -0,0 +1,25 @@
+-- Recorded interactive exec (console) sessions in asciinema v2 format
+CREATE TABLE IF NOT EXISTS exec_recordings (
+    id BIGSERIAL PRIMARY KEY,
+    host_id BIGINT REFERENCES hosts(id) ON DELETE SET NULL,
+    hostname VARCHAR(255) NOT NULL,
+    container_name VARCHAR(255) NOT NULL,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    shell VARCHAR(255) NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ NOT NULL,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    width INTEGER NOT NULL DEFAULT 80,
+    height INTEGER NOT NULL DEFAULT 24,
+    event_count INTEGER NOT NULL DEFAULT 0,
+    raw_bytes BIGINT NOT NULL DEFAULT 0,     -- uncompressed .cast size
+    stored_bytes BIGINT NOT NULL DEFAULT 0,  -- gzip size
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    cast_gz BYTEA NOT NULL,                  -- gzip-compressed asciicast v2
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_exec_recordings_started ON exec_recordings (started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_exec_recordings_host ON exec_recordings (hostname, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_exec_recordings_container ON exec_recordings (hostname, container_name);
+CREATE INDEX IF NOT EXISTS idx_exec_recordings_user ON exec_recordings (username);
@@ -0,0 +1,57 @@
+-- Swarm inventory collected from hosts flagged as swarm managers (dd_ui_swarm_manager)
+CREATE TABLE IF NOT EXISTS swarm_nodes (
+    id BIGSERIAL PRIMARY KEY,
+    host_id BIGINT NOT NULL REFERENCES hosts(id) ON DELETE CASCADE, -- manager the node was seen from
+    node_id VARCHAR(64) NOT NULL,
+    hostname VARCHAR(255) NOT NULL DEFAULT '',
+    role VARCHAR(32) NOT NULL DEFAULT '',          -- manager|worker
+    availability VARCHAR(32) NOT NULL DEFAULT '',  -- active|pause|drain
+    state VARCHAR(32) NOT NULL DEFAULT '',         -- ready|down|unknown|disconnected
+    addr VARCHAR(255) NOT NULL DEFAULT '',
+    leader BOOLEAN NOT NULL DEFAULT FALSE,
+    engine_version VARCHAR(64) NOT NULL DEFAULT '',
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (host_id, node_id)
+);
+
+CREATE TABLE IF NOT EXISTS swarm_services (
+    id BIGSERIAL PRIMARY KEY,
+    host_id BIGINT NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    service_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    stack_namespace VARCHAR(255) NOT NULL DEFAULT '', -- com.docker.stack.namespace
+    image TEXT NOT NULL DEFAULT '',
+    mode VARCHAR(32) NOT NULL DEFAULT '',             -- replicated|global|...
+    desired_replicas INTEGER NOT NULL DEFAULT 0,
+    running_tasks INTEGER NOT NULL DEFAULT 0,
+    ports JSONB NOT NULL DEFAULT '[]'::jsonb,
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    update_state VARCHAR(32) NOT NULL DEFAULT '',
+    created_ts TIMESTAMPTZ,
+    updated_ts TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (host_id, service_id)
+);
+
+CREATE TABLE IF NOT EXISTS swarm_tasks (
+    id BIGSERIAL PRIMARY KEY,
+    host_id BIGINT NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    task_id VARCHAR(64) NOT NULL,
+    service_id VARCHAR(64) NOT NULL,
+    node_id VARCHAR(64) NOT NULL DEFAULT '',
+    slot INTEGER NOT NULL DEFAULT 0,
+    image TEXT NOT NULL DEFAULT '',
+    desired_state VARCHAR(32) NOT NULL DEFAULT '',
+    state VARCHAR(32) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    container_id VARCHAR(64) NOT NULL DEFAULT '',
+    updated_ts TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (host_id, task_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_swarm_services_namespace ON swarm_services (host_id, stack_namespace);
+CREATE INDEX IF NOT EXISTS idx_swarm_tasks_service ON swarm_tasks (host_id, service_id);
+CREATE INDEX IF NOT EXISTS idx_swarm_tasks_node ON swarm_tasks (host_id, node_id);
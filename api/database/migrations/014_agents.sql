@@ -0,0 +1,15 @@
+-- Enrollment tokens for dd-ui-agent (outbound websocket agent, alternative to SSH)
+CREATE TABLE IF NOT EXISTS agent_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    host_id BIGINT NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL,             -- sha256 hex; the token itself is shown once
+    token_prefix VARCHAR(16) NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ,
+    last_addr VARCHAR(255) NOT NULL DEFAULT '',
+    agent_version VARCHAR(64) NOT NULL DEFAULT '',
+    UNIQUE (host_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_tokens_hash ON agent_tokens (token_hash);
@@ -0,0 +1,17 @@
+-- Managed SSH known_hosts (trust-on-first-use); replaces StrictHostKeyChecking=no
+CREATE TABLE IF NOT EXISTS ssh_known_hosts (
+    id BIGSERIAL PRIMARY KEY,
+    host VARCHAR(255) NOT NULL,               -- host:port as dialled
+    key_type VARCHAR(64) NOT NULL,
+    public_key TEXT NOT NULL,                 -- authorized_keys format
+    fingerprint VARCHAR(128) NOT NULL,        -- SHA256:...
+    status VARCHAR(16) NOT NULL DEFAULT 'pending', -- trusted|pending|mismatch
+    first_seen TIMESTAMPTZ DEFAULT NOW(),
+    last_seen TIMESTAMPTZ DEFAULT NOW(),
+    approved_by VARCHAR(255) NOT NULL DEFAULT '', -- 'tofu' for first-use pins
+    approved_at TIMESTAMPTZ,
+    UNIQUE (host, fingerprint)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ssh_known_hosts_host ON ssh_known_hosts (host);
+CREATE INDEX IF NOT EXISTS idx_ssh_known_hosts_status ON ssh_known_hosts (status);
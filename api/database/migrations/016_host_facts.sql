@@ -0,0 +1,23 @@
+-- Host facts collected periodically over the host's Docker (and SSH) connection; one row per collection
+CREATE TABLE IF NOT EXISTS host_facts (
+    id BIGSERIAL PRIMARY KEY,
+    host_id BIGINT NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    collected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    runtime VARCHAR(16) NOT NULL DEFAULT 'docker',   -- docker|podman
+    engine_version VARCHAR(64) NOT NULL DEFAULT '',
+    api_version VARCHAR(32) NOT NULL DEFAULT '',
+    storage_driver VARCHAR(64) NOT NULL DEFAULT '',
+    os VARCHAR(255) NOT NULL DEFAULT '',
+    kernel VARCHAR(255) NOT NULL DEFAULT '',
+    architecture VARCHAR(32) NOT NULL DEFAULT '',
+    cpus INTEGER NOT NULL DEFAULT 0,
+    mem_total_bytes BIGINT NOT NULL DEFAULT 0,
+    mem_used_bytes BIGINT,                            -- NULL when the host cannot be queried beyond the Docker API
+    docker_root_dir TEXT NOT NULL DEFAULT '',
+    disk_total_bytes BIGINT,
+    disk_used_bytes BIGINT,
+    uptime_seconds BIGINT,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_host_facts_host_time ON host_facts (host_id, collected_at DESC);
@@ -0,0 +1,11 @@
+-- Per-host connection state maintained by the Docker scanner
+CREATE TABLE IF NOT EXISTS host_status (
+    host_id BIGINT PRIMARY KEY REFERENCES hosts(id) ON DELETE CASCADE,
+    state VARCHAR(16) NOT NULL DEFAULT 'unknown',  -- online|degraded|offline|unknown
+    last_seen TIMESTAMPTZ,                          -- last successful connection
+    last_error TEXT NOT NULL DEFAULT '',
+    last_error_at TIMESTAMPTZ,
+    latency_ms INTEGER,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+-- Retention prunes scan_logs by age (and level); filtered queries page by host/level/time
+CREATE INDEX IF NOT EXISTS idx_scan_logs_at ON scan_logs (at);
+CREATE INDEX IF NOT EXISTS idx_scan_logs_level_at ON scan_logs (level, at);
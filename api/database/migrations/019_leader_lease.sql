@@ -0,0 +1,8 @@
+-- Leader lease: one replica holds each named lease and runs the background loops
+CREATE TABLE IF NOT EXISTS leader_lease (
+    name VARCHAR(64) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,         -- replica id
+    acquired_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    renewed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
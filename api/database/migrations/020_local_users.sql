@@ -0,0 +1,17 @@
+-- Built-in accounts (argon2id + TOTP) for logging in without the OIDC provider
+CREATE TABLE IF NOT EXISTS local_users (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    password_hash TEXT NOT NULL,              -- $argon2id$v=19$m=...,t=...,p=...$salt$hash
+    totp_secret TEXT NOT NULL DEFAULT '',     -- base32; SOPS-encrypted when keys are configured
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT NOT NULL DEFAULT 0, -- last accepted time step (replay protection)
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    last_login_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,26 @@
+-- Login sessions (scs store) with the metadata shown in the sessions API
+CREATE TABLE IF NOT EXISTS sessions (
+    token TEXT PRIMARY KEY,                 -- scs session token (cookie value); never returned by the API
+    data BYTEA NOT NULL,
+    expiry TIMESTAMPTZ NOT NULL,
+    sid TEXT NOT NULL DEFAULT '',           -- stable public id (survives token renewal)
+    user_sub TEXT NOT NULL DEFAULT '',
+    user_email TEXT NOT NULL DEFAULT '',
+    user_name TEXT NOT NULL DEFAULT '',
+    provider TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expiry ON sessions (expiry);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_sub ON sessions (user_sub);
+CREATE INDEX IF NOT EXISTS idx_sessions_sid ON sessions (sid);
+
+-- id_tokens kept server-side for RP-initiated logout, keyed by session sid
+CREATE TABLE IF NOT EXISTS session_id_tokens (
+    sid TEXT PRIMARY KEY,
+    id_token TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
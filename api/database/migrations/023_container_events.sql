@@ -0,0 +1,19 @@
+-- Docker lifecycle events per container (die/oom/kill/restart/health_status/...)
+CREATE TABLE IF NOT EXISTS container_events (
+    id BIGSERIAL PRIMARY KEY,
+    host_id BIGINT NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+    container_id VARCHAR(128) NOT NULL,
+    container_name VARCHAR(255) NOT NULL DEFAULT '',
+    stack_name VARCHAR(255) NOT NULL DEFAULT '',  -- compose project
+    action VARCHAR(50) NOT NULL,                  -- die, oom, kill, restart, health_status, start, stop
+    exit_code INTEGER,                            -- die
+    signal VARCHAR(20) NOT NULL DEFAULT '',       -- kill
+    health VARCHAR(20) NOT NULL DEFAULT '',       -- health_status: healthy | unhealthy | starting
+    image TEXT NOT NULL DEFAULT '',
+    time_nano BIGINT NOT NULL,                    -- engine timestamp; dedupes replays after reconnects
+    occurred_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (host_id, container_id, action, time_nano)
+);
+
+CREATE INDEX IF NOT EXISTS idx_container_events_lookup ON container_events (host_id, container_name, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_container_events_occurred_at ON container_events (occurred_at);
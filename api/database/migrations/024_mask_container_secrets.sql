@@ -0,0 +1,11 @@
+-- Container env and labels are now masked before they are stored (see utils/secrets.go).
+-- Drop what earlier scans stored in plaintext; the next scan refills the masked values.
+UPDATE containers SET env = '[]'::jsonb WHERE env IS NOT NULL AND env <> '[]'::jsonb;
+
+UPDATE containers
+SET labels = COALESCE((
+    SELECT jsonb_object_agg(key, value)
+    FROM jsonb_each(labels)
+    WHERE key LIKE 'com.docker.%' OR key LIKE 'io.podman.%'
+), '{}'::jsonb)
+WHERE labels IS NOT NULL AND labels <> '{}'::jsonb;
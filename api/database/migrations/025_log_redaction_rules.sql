@@ -0,0 +1,11 @@
+-- Per-stack regexes applied to container log lines before they are streamed or stored
+CREATE TABLE IF NOT EXISTS log_redaction_rules (
+    id BIGSERIAL PRIMARY KEY,
+    stack_id BIGINT NOT NULL REFERENCES iac_stacks(id) ON DELETE CASCADE,
+    pattern TEXT NOT NULL,                  -- Go regexp; a (?P<secret>...) group limits what is replaced
+    description TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_redaction_rules_stack ON log_redaction_rules (stack_id);
@@ -0,0 +1,4 @@
+-- Compose file order (-f, relative to the stack dir) and active profiles per stack, from the
+-- scanner (x-ddui in the main compose file, else the main file plus override files)
+ALTER TABLE iac_stacks ADD COLUMN IF NOT EXISTS compose_files TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE iac_stacks ADD COLUMN IF NOT EXISTS compose_profiles TEXT[] NOT NULL DEFAULT '{}';
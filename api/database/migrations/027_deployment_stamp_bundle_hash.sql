@@ -0,0 +1,4 @@
+-- Bundle hash (IaC files + rendered templates) a deploy applied. Auto DevOps compares it with
+-- the stack's current bundle, so failed or skipped deploys are retried on the next scan.
+-- Older stamps have none; their stacks are applied once more after the upgrade.
+ALTER TABLE deployment_stamps ADD COLUMN IF NOT EXISTS bundle_hash TEXT;
@@ -0,0 +1,112 @@
+// handlers/adopt.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"dd-ui/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleUnmanagedProjects lists compose projects running on a host that have no IaC stack
+func handleUnmanagedProjects(w http.ResponseWriter, r *http.Request) {
+	hostname := chi.URLParam(r, "hostname")
+	ctrs, err := database.ListContainersByHost(r.Context(), hostname)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type project struct {
+		Project    string   `json:"project"`
+		Services   []string `json:"services"`
+		Containers int      `json:"containers"`
+	}
+	byName := map[string]*project{}
+	for _, c := range ctrs {
+		name := c.Labels["com.docker.compose.project"]
+		if name == "" {
+			continue
+		}
+		if _, err := services.GetStackIDByHostAndName(r.Context(), hostname, name); err == nil {
+			continue
+		}
+		p, ok := byName[name]
+		if !ok {
+			p = &project{Project: name, Services: []string{}}
+			byName[name] = p
+		}
+		p.Containers++
+		if svc := c.Labels["com.docker.compose.service"]; svc != "" && !contains(p.Services, svc) {
+			p.Services = append(p.Services, svc)
+		}
+	}
+
+	out := make([]project, 0, len(byName))
+	for _, p := range byName {
+		sort.Strings(p.Services)
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Project < out[j].Project })
+	writeJSON(w, http.StatusOK, map[string]any{"projects": out})
+}
+
+// handleAdoptProject reconstructs IaC files for an unmanaged compose project
+// POST /api/iac/hosts/{hostname}/adopt/{project}
+// body: { "overwrite": false, "allow_plaintext": false, "keep_auto_devops": false }
+func handleAdoptProject(w http.ResponseWriter, r *http.Request) {
+	hostname := chi.URLParam(r, "hostname")
+	project := chi.URLParam(r, "project")
+
+	var opts services.AdoptOptions
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if id, err := services.GetStackIDByHostAndName(r.Context(), hostname, project); err == nil && !opts.Overwrite {
+		http.Error(w, fmt.Sprintf("project already managed by stack %d", id), http.StatusConflict)
+		return
+	}
+
+	user := middleware.GetUserEmail(r.Context())
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	res, err := services.AdoptComposeProject(ctx, hostname, utils.ComposeProjectLabelFromStack(project), user, opts)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrAdoptNoContainers):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrAdoptExists):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Trigger git push if configured (same as file saves)
+	config, _ := database.GetGitSyncConfig(r.Context())
+	if config != nil && config.SyncEnabled && (config.SyncMode == "push" || config.SyncMode == "sync") {
+		go func() {
+			message := fmt.Sprintf("Adopted %s/%s", res.Host, res.Project)
+			if err := services.GetGitSync().Push(context.Background(), message, user); err != nil {
+				common.ErrorLog("Failed to push changes after adopt: %v", err)
+			}
+		}()
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
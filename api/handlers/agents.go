@@ -0,0 +1,123 @@
+// handlers/agents.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"dd-ui/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// Headers sent by dd-ui-agent when it dials /api/agent/connect
+const (
+	AgentHostHeader    = "X-DD-UI-Agent-Host"
+	AgentVersionHeader = "X-DD-UI-Agent-Version"
+)
+
+// SetupAgentRoutes configures dd-ui-agent enrollment routes:
+// - /api/agents                              enrolled agents with live connection status
+// - /api/agents/hosts/{hostname}/token       POST issues/rotates a token (shown once), DELETE revokes
+//
+// The agent's own websocket (/api/agent/connect) is public and token-authenticated,
+// see HandleAgentConnect.
+func SetupAgentRoutes(router chi.Router) {
+	router.Route("/agents", func(r chi.Router) {
+		r.Get("/", handleAgentsList)
+		r.Post("/hosts/{hostname}/token", handleAgentTokenIssue)
+		r.Delete("/hosts/{hostname}/token", handleAgentTokenRevoke)
+	})
+}
+
+// HandleAgentConnect accepts an agent's outbound websocket and serves Docker streams over it.
+// Only the leader replica holds agent sessions; other replicas answer 503 so the agent retries.
+func HandleAgentConnect(w http.ResponseWriter, r *http.Request) {
+	if !services.IsLeader() {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "not the leader replica; retry", http.StatusServiceUnavailable)
+		return
+	}
+	hostName := strings.TrimSpace(r.Header.Get(AgentHostHeader))
+	tok := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if hostName == "" || tok == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	hostID, err := services.VerifyAgentToken(r.Context(), hostName, tok)
+	if err != nil {
+		common.WarnLog("agent: rejected connection from %s: %v", r.RemoteAddr, err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// answer with our own version so the agent knows whether to use mux flow control
+	hdr := http.Header{}
+	hdr.Set(AgentVersionHeader, strconv.Itoa(utils.AgentMuxFlowVersion))
+	conn, err := utils.WSUpgrader.Upgrade(w, r, hdr)
+	if err != nil {
+		common.ErrorLog("agent: websocket upgrade failed for %s: %v", hostName, err)
+		return
+	}
+	mux := utils.NewAgentMux(conn, true)
+	mux.SetFlowControl(utils.MuxFlowControl(r.Header.Get(AgentVersionHeader)))
+	services.ServeAgent(hostName, hostID, r.RemoteAddr, r.Header.Get(AgentVersionHeader), mux)
+}
+
+// handleAgentsList returns enrolled agents and whether they are connected
+func handleAgentsList(w http.ResponseWriter, r *http.Request) {
+	agents, err := services.ListAgentStatus(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list agents: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"agents": agents})
+}
+
+// handleAgentTokenIssue creates or rotates a host's agent token
+func handleAgentTokenIssue(w http.ResponseWriter, r *http.Request) {
+	hostName := chi.URLParam(r, "hostname")
+	h, err := database.GetHostByName(r.Context(), hostName)
+	if err != nil {
+		http.Error(w, "host not found", http.StatusNotFound)
+		return
+	}
+	user := middleware.GetUserEmail(r.Context())
+	tok, err := services.IssueAgentToken(r.Context(), h, user)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to issue token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	common.InfoLog("agent: token issued for host %s by %s", hostName, user)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"hostname": hostName,
+		"token":    tok,
+		"note":     "store this token now; it cannot be shown again",
+	})
+}
+
+// handleAgentTokenRevoke revokes a host's agent token and disconnects the agent
+func handleAgentTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	hostName := chi.URLParam(r, "hostname")
+	h, err := database.GetHostByName(r.Context(), hostName)
+	if err != nil {
+		http.Error(w, "host not found", http.StatusNotFound)
+		return
+	}
+	ok, err := services.RevokeAgentToken(r.Context(), h)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to revoke token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "no agent token for host", http.StatusNotFound)
+		return
+	}
+	common.InfoLog("agent: token revoked for host %s by %s", hostName, middleware.GetUserEmail(r.Context()))
+	writeJSON(w, http.StatusOK, map[string]any{"hostname": hostName, "revoked": true})
+}
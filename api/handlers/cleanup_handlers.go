@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -23,8 +24,7 @@ import (
 )
 
 func init() {
-	common.DebugLog("Cleanup handlers module initialized - functions available: %v", 
-		handleCleanupBuildCachePrune != nil)
+	common.DebugLog("Cleanup handlers module initialized")
 }
 
 // CleanupOptions holds configuration for cleanup operations
@@ -886,9 +886,32 @@ func updateJobStatus(ctx context.Context, jobID, status string) error {
 	}
 
 	_, err := common.DB.Exec(ctx, query, args...)
+	if err == nil && (status == "completed" || status == "failed") {
+		publishCleanupEvent(ctx, jobID, status)
+	}
 	return err
 }
 
+// publishCleanupEvent emits cleanup.completed / cleanup.failed for a finished job
+func publishCleanupEvent(ctx context.Context, jobID, status string) {
+	job, err := getCleanupJob(ctx, jobID)
+	if err != nil {
+		return
+	}
+	typ := services.EventCleanupCompleted
+	if status == "failed" {
+		typ = services.EventCleanupFailed
+	}
+	host := ""
+	if job.Scope == "single_host" {
+		host = job.Target
+	}
+	services.PublishEvent(ctx, services.Event{Type: typ, Host: host, Subject: job.Operation, Data: map[string]any{
+		"job_id": job.ID, "operation": job.Operation, "scope": job.Scope, "target": job.Target,
+		"dry_run": job.DryRun, "owner": job.Owner, "results": job.Results,
+	}})
+}
+
 // updateJobResults updates the results of a cleanup job
 func updateJobResults(ctx context.Context, jobID string, results map[string]interface{}) error {
 	resultsJSON, _ := json.Marshal(results)
@@ -949,7 +972,7 @@ func performSystemPrune(ctx context.Context, hostName string, options CleanupOpt
 	}
 
 	// Use SSH command to run docker system prune with verbose output
-	cmd := "docker system prune -af --volumes 2>&1"
+	cmd := pruneCommand(host, "system")
 	output, err := runDockerCommand(host, cmd)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("System prune failed: %v", err))
@@ -1010,7 +1033,7 @@ func performImagePrune(ctx context.Context, hostName string, options CleanupOpti
 		return result, nil
 	}
 
-	cmd := "docker image prune -af"
+	cmd := pruneCommand(host, "images")
 	output, err := runDockerCommand(host, cmd)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Image prune failed: %v", err))
@@ -1048,7 +1071,7 @@ func performContainerPrune(ctx context.Context, hostName string, options Cleanup
 		return result, nil
 	}
 
-	cmd := "docker container prune -f"
+	cmd := pruneCommand(host, "containers")
 	output, err := runDockerCommand(host, cmd)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Container prune failed: %v", err))
@@ -1086,7 +1109,7 @@ func performVolumePrune(ctx context.Context, hostName string, options CleanupOpt
 		return result, nil
 	}
 
-	cmd := "docker volume prune -f"
+	cmd := pruneCommand(host, "volumes")
 	output, err := runDockerCommand(host, cmd)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Volume prune failed: %v", err))
@@ -1124,7 +1147,7 @@ func performNetworkPrune(ctx context.Context, hostName string, options CleanupOp
 		return result, nil
 	}
 
-	cmd := "docker network prune -f"
+	cmd := pruneCommand(host, "networks")
 	_, err = runDockerCommand(host, cmd)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Network prune failed: %v", err))
@@ -1164,7 +1187,7 @@ func performBuildCachePrune(ctx context.Context, hostName string, options Cleanu
 	common.InfoLog("Removing build cache on %s (this may take a moment)...", hostName)
 	
 	// Try buildx first, fallback to builder
-	cmd := "docker buildx prune -af 2>/dev/null || docker builder prune -af"
+	cmd := pruneCommand(host, "build-cache")
 	output, err := runDockerCommand(host, cmd)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("Build cache prune failed: %v", err))
@@ -1194,6 +1217,43 @@ func performBuildCachePrune(ctx context.Context, hostName string, options Cleanu
 	return result, nil
 }
 
+// pruneCommand returns the prune command line for the host's container runtime.
+// Podman has no buildx; its build cache is dropped with image prune --build-cache.
+func pruneCommand(host database.HostRow, kind string) string {
+	if services.IsPodmanHost(host) {
+		switch kind {
+		case "system":
+			return "podman system prune -af --volumes 2>&1"
+		case "images":
+			return "podman image prune -af"
+		case "containers":
+			return "podman container prune -f"
+		case "volumes":
+			return "podman volume prune -f"
+		case "networks":
+			return "podman network prune -f"
+		case "build-cache":
+			return "podman image prune -f --build-cache"
+		}
+		return ""
+	}
+	switch kind {
+	case "system":
+		return "docker system prune -af --volumes 2>&1"
+	case "images":
+		return "docker image prune -af"
+	case "containers":
+		return "docker container prune -f"
+	case "volumes":
+		return "docker volume prune -f"
+	case "networks":
+		return "docker network prune -f"
+	case "build-cache":
+		return "docker buildx prune -af 2>/dev/null || docker builder prune -af"
+	}
+	return ""
+}
+
 // runDockerCommand executes a Docker command using the appropriate method (local Docker client or SSH)
 func runDockerCommand(host database.HostRow, command string) (string, error) {
 	url, _ := services.DockerURLFor(host)
@@ -1206,9 +1266,25 @@ func runDockerCommand(host database.HostRow, command string) (string, error) {
 		return string(output), err
 	}
 
+	// Agent hosts: run the CLI here against the agent's local proxy socket
+	if services.IsAgentHost(host) {
+		dockerHost, err := services.CLIDockerHost(host, url)
+		if err != nil {
+			return "", err
+		}
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Env = append(os.Environ(), "DOCKER_HOST="+dockerHost, "CONTAINER_HOST="+dockerHost)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
 	// For remote hosts or non-allowed local access, use SSH
 	if host.Addr != "" && host.Addr != "localhost" && host.Addr != "127.0.0.1" {
-		sshCmd := exec.Command("ssh", "-o", "StrictHostKeyChecking=no", host.Addr, command)
+		sshArgs, sshDest, err := services.OpenSSHArgsForHost(context.Background(), host)
+		if err != nil {
+			return "", err
+		}
+		sshCmd := exec.Command("ssh", append(sshArgs, sshDest, command)...)
 		output, err := sshCmd.CombinedOutput()
 		return string(output), err
 	}
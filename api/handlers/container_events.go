@@ -0,0 +1,72 @@
+// handlers/container_events.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dd-ui/database"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// handleContainerEvents returns a container's lifecycle timeline (die, oom, kill, restart,
+// health_status), newest first, plus its restart-loop / OOM summary.
+// Query: ?since= duration (default 24h), ?action= comma list, ?limit= (default 200)
+func handleContainerEvents(w http.ResponseWriter, r *http.Request) {
+	hostname := chi.URLParam(r, "hostname")
+	ctr := chi.URLParam(r, "ctr")
+	h, err := database.GetHostByName(r.Context(), hostname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "host not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	window := 24 * time.Hour
+	if v := q.Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "bad since duration", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	actions := []string{}
+	for _, a := range strings.Split(q.Get("action"), ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			actions = append(actions, a)
+		}
+	}
+	limit := clamp(parseIntDefault(q.Get("limit"), 200), 1, 2000)
+
+	items, err := database.ListContainerEvents(r.Context(), h.ID, ctr, time.Now().Add(-window), actions, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list container events: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// summaries are keyed by name; resolve an id argument through the timeline
+	name := ctr
+	if len(items) > 0 {
+		name = items[0].ContainerName
+	}
+	summary := services.ContainerEventSummary{}
+	if all, err := services.ContainerEventSummaries(r.Context(), h.ID); err == nil {
+		summary = all[name]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"host":      h.Name,
+		"container": name,
+		"items":     items,
+		"summary":   summary,
+	})
+}
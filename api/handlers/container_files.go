@@ -0,0 +1,459 @@
+// handlers/container_files.go
+package handlers
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/go-chi/chi/v5"
+)
+
+/*
+Container filesystem browser.
+
+Built on the Docker archive API (ContainerStatPath / CopyFromContainer /
+CopyToContainer), so it works the same for local and SSH-tunnelled hosts.
+
+Gates:
+  DD_UI_CONTAINER_FILES=true|false          browse + download (default true)
+  DD_UI_CONTAINER_FILES_UPLOAD=true|false   upload (default false)
+  DD_UI_CONTAINER_FILES_MAX_DOWNLOAD        bytes, default 100 MiB (tar downloads are sized
+                                            with a header pass first and answer 413 over it)
+  DD_UI_CONTAINER_FILES_MAX_UPLOAD          bytes, default 50 MiB
+  DD_UI_CONTAINER_FILES_LIST_SCAN           bytes of tar read to list a directory, default 64 MiB
+Host access is additionally limited by dd_ui_allowed_users (see services.HostAccessAllowed).
+*/
+
+// ContainerFileEntry describes a single path inside a container
+type ContainerFileEntry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Type       string    `json:"type"` // file, dir, symlink, other
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	ModTime    time.Time `json:"mtime"`
+	LinkTarget string    `json:"link_target,omitempty"`
+}
+
+// uploads may never target kernel pseudo-filesystems
+var containerFilesDeniedUploadPrefixes = []string{"/proc", "/sys", "/dev"}
+
+// containerFilesClient resolves host + Docker client for a file request and applies permission gates.
+// On failure it writes the HTTP error and returns a nil client.
+func containerFilesClient(w http.ResponseWriter, r *http.Request, write bool) (*client.Client, database.HostRow, string) {
+	hostname := chi.URLParam(r, "hostname")
+	ctr := chi.URLParam(r, "ctr")
+
+	if !common.EnvBool("DD_UI_CONTAINER_FILES", "true") {
+		http.Error(w, "container file access disabled on server", http.StatusForbidden)
+		return nil, database.HostRow{}, ""
+	}
+	if write && !common.EnvBool("DD_UI_CONTAINER_FILES_UPLOAD", "false") {
+		http.Error(w, "container file upload disabled on server", http.StatusForbidden)
+		return nil, database.HostRow{}, ""
+	}
+
+	h, err := database.GetHostByName(r.Context(), hostname)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, database.HostRow{}, ""
+	}
+	if !services.HostAccessAllowed(h, middleware.GetUserEmail(r.Context())) {
+		http.Error(w, "not allowed on this host", http.StatusForbidden)
+		return nil, database.HostRow{}, ""
+	}
+	cli, err := services.DockerClientForHost(h)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, database.HostRow{}, ""
+	}
+	return cli, h, ctr
+}
+
+// containerFilesPath normalizes the ?path= parameter to a clean absolute path
+func containerFilesPath(r *http.Request) string {
+	p := strings.TrimSpace(r.URL.Query().Get("path"))
+	if p == "" {
+		p = "/"
+	}
+	return path.Clean("/" + p)
+}
+
+func fileEntryFromStat(dir string, st container.PathStat) ContainerFileEntry {
+	e := ContainerFileEntry{
+		Name:       st.Name,
+		Path:       path.Join(dir, st.Name),
+		Size:       st.Size,
+		Mode:       st.Mode.String(),
+		ModTime:    st.Mtime,
+		LinkTarget: st.LinkTarget,
+	}
+	switch {
+	case st.Mode.IsDir():
+		e.Type = "dir"
+	case st.Mode.IsRegular():
+		e.Type = "file"
+	case st.Mode&os.ModeSymlink != 0 || st.LinkTarget != "":
+		e.Type = "symlink"
+	default:
+		e.Type = "other"
+	}
+	return e
+}
+
+// handleContainerFilesList stats a path and, for directories, lists the direct children
+func handleContainerFilesList(w http.ResponseWriter, r *http.Request) {
+	cli, _, ctr := containerFilesClient(w, r, false)
+	if cli == nil {
+		return
+	}
+	defer cli.Close()
+
+	p := containerFilesPath(r)
+	st, err := cli.ContainerStatPath(r.Context(), ctr, p)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("stat %s: %v", p, err), http.StatusNotFound)
+		return
+	}
+	self := fileEntryFromStat(path.Dir(p), st)
+	self.Path = p
+	if !st.Mode.IsDir() {
+		writeJSON(w, http.StatusOK, map[string]any{"path": p, "entry": self})
+		return
+	}
+
+	// Docker has no readdir; read tar headers of the directory archive and keep depth-1 entries.
+	rc, _, err := cli.CopyFromContainer(r.Context(), ctr, p)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read %s: %v", p, err), http.StatusBadRequest)
+		return
+	}
+	defer rc.Close()
+
+	scanLimit := int64(common.EnvInt("DD_UI_CONTAINER_FILES_LIST_SCAN", 64*1024*1024))
+	tr := tar.NewReader(io.LimitReader(rc, scanLimit))
+	entries := []ContainerFileEntry{}
+	truncated := false
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Hitting the scan limit shows up as a short read
+			truncated = true
+			break
+		}
+		// First path component is the directory itself
+		name := strings.TrimSuffix(hdr.Name, "/")
+		i := strings.Index(name, "/")
+		if i < 0 {
+			continue
+		}
+		rel := name[i+1:]
+		if rel == "" || strings.Contains(rel, "/") {
+			continue
+		}
+		fi := hdr.FileInfo()
+		e := ContainerFileEntry{
+			Name:    rel,
+			Path:    path.Join(p, rel),
+			Size:    hdr.Size,
+			Mode:    fi.Mode().String(),
+			ModTime: hdr.ModTime,
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			e.Type = "dir"
+		case tar.TypeReg:
+			e.Type = "file"
+		case tar.TypeSymlink, tar.TypeLink:
+			e.Type = "symlink"
+			e.LinkTarget = hdr.Linkname
+		default:
+			e.Type = "other"
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if (entries[i].Type == "dir") != (entries[j].Type == "dir") {
+			return entries[i].Type == "dir"
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	writeJSON(w, http.StatusOK, map[string]any{
+		"path":      p,
+		"entry":     self,
+		"entries":   entries,
+		"truncated": truncated,
+	})
+}
+
+// limitedWriter fails once more than n bytes have been written
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+var errDownloadTooLarge = errors.New("download exceeds size limit")
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		return 0, errDownloadTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// containerTarSize reads the tar headers of a path's archive and returns the archive size they
+// add up to. Docker has no du, so this costs a pass over the archive; it stops once limit is passed.
+func containerTarSize(ctx context.Context, cli *client.Client, ctr, p string, limit int64) (int64, error) {
+	rc, _, err := cli.CopyFromContainer(ctx, ctr, p)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	total := int64(2 * 512) // end-of-archive blocks
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		total += 512 + (hdr.Size+511)/512*512
+		if total > limit {
+			return total, nil
+		}
+	}
+}
+
+// handleContainerFilesDownload streams a file (raw) or any path as a tar archive (?format=tar)
+func handleContainerFilesDownload(w http.ResponseWriter, r *http.Request) {
+	cli, h, ctr := containerFilesClient(w, r, false)
+	if cli == nil {
+		return
+	}
+	defer cli.Close()
+
+	p := containerFilesPath(r)
+	maxBytes := int64(common.EnvInt("DD_UI_CONTAINER_FILES_MAX_DOWNLOAD", 100*1024*1024))
+
+	st, err := cli.ContainerStatPath(r.Context(), ctr, p)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("stat %s: %v", p, err), http.StatusNotFound)
+		return
+	}
+	asTar := r.URL.Query().Get("format") == "tar" || st.Mode.IsDir()
+	if !st.Mode.IsDir() && st.Size > maxBytes {
+		http.Error(w, fmt.Sprintf("file is %d bytes, limit is %d", st.Size, maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if asTar {
+		size, err := containerTarSize(r.Context(), cli, ctr, p, maxBytes)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("read %s: %v", p, err), http.StatusBadRequest)
+			return
+		}
+		if size > maxBytes {
+			http.Error(w, fmt.Sprintf("archive is over %d bytes, limit is %d", size, maxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+
+	rc, _, err := cli.CopyFromContainer(r.Context(), ctr, p)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read %s: %v", p, err), http.StatusBadRequest)
+		return
+	}
+	defer rc.Close()
+
+	user := middleware.GetUserEmail(r.Context())
+	common.InfoLog("container files: %s downloading %s:%s from host=%s (tar=%v)", user, ctr, p, h.Name, asTar)
+
+	base := st.Name
+	if base == "" || base == "/" {
+		base = "root"
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	if asTar {
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", base+".tar"))
+		// The size was checked above, but the path can grow in between. A stream cut off after
+		// the 200 must not look complete, so the connection is aborted instead.
+		if _, err := io.Copy(&limitedWriter{w: w, n: maxBytes}, rc); err != nil {
+			common.WarnLog("container files: tar download of %s:%s on host=%s aborted: %v", ctr, p, h.Name, err)
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+
+	tr := tar.NewReader(rc)
+	hdr, err := tr.Next()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read %s: %v", p, err), http.StatusBadRequest)
+		return
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		http.Error(w, "not a regular file; use format=tar", http.StatusBadRequest)
+		return
+	}
+	if hdr.Size > maxBytes {
+		http.Error(w, fmt.Sprintf("file is %d bytes, limit is %d", hdr.Size, maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", base))
+	w.Header().Set("Content-Length", strconv.FormatInt(hdr.Size, 10))
+	_, _ = io.Copy(w, tr)
+}
+
+// handleContainerFilesUpload writes one or more multipart files into a container directory
+// POST ?path=/target/dir[&overwrite=true][&mode=0644]  (multipart field: file)
+func handleContainerFilesUpload(w http.ResponseWriter, r *http.Request) {
+	cli, h, ctr := containerFilesClient(w, r, true)
+	if cli == nil {
+		return
+	}
+	defer cli.Close()
+
+	dir := containerFilesPath(r)
+	for _, pre := range containerFilesDeniedUploadPrefixes {
+		if dir == pre || strings.HasPrefix(dir, pre+"/") {
+			http.Error(w, "uploads to "+pre+" are not allowed", http.StatusForbidden)
+			return
+		}
+	}
+
+	st, err := cli.ContainerStatPath(r.Context(), ctr, dir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("stat %s: %v", dir, err), http.StatusNotFound)
+		return
+	}
+	if !st.Mode.IsDir() {
+		http.Error(w, "target path is not a directory", http.StatusBadRequest)
+		return
+	}
+
+	maxBytes := int64(common.EnvInt("DD_UI_CONTAINER_FILES_MAX_UPLOAD", 50*1024*1024))
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1024*1024) // allow for multipart overhead
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, fmt.Sprintf("upload too large or malformed (limit %d bytes): %v", maxBytes, err), http.StatusRequestEntityTooLarge)
+		return
+	}
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		http.Error(w, "no files in field 'file'", http.StatusBadRequest)
+		return
+	}
+
+	mode := int64(0o644)
+	if m := r.URL.Query().Get("mode"); m != "" {
+		v, err := strconv.ParseInt(m, 8, 32)
+		if err != nil || v <= 0 || v > 0o777 {
+			http.Error(w, "invalid mode", http.StatusBadRequest)
+			return
+		}
+		mode = v
+	}
+	overwrite := r.URL.Query().Get("overwrite") == "true"
+
+	// Parts without a usable file name are skipped; only the rest are written and reported
+	type uploadFile struct {
+		name string
+		fh   *multipart.FileHeader
+	}
+	var uploads []uploadFile
+	var total int64
+	for _, fh := range files {
+		name := path.Base(path.Clean("/" + fh.Filename))
+		if name == "/" || name == "." {
+			continue
+		}
+		uploads = append(uploads, uploadFile{name: name, fh: fh})
+		total += fh.Size
+	}
+	if len(uploads) == 0 {
+		http.Error(w, "no file names in field 'file'", http.StatusBadRequest)
+		return
+	}
+	if total > maxBytes {
+		http.Error(w, fmt.Sprintf("upload is %d bytes, limit is %d", total, maxBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	written := make([]string, 0, len(uploads))
+	for _, u := range uploads {
+		target := path.Join(dir, u.name)
+		if !overwrite {
+			if _, err := cli.ContainerStatPath(r.Context(), ctr, target); err == nil {
+				http.Error(w, fmt.Sprintf("%s already exists (pass overwrite=true)", target), http.StatusConflict)
+				return
+			}
+		}
+		written = append(written, target)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		now := time.Now()
+		for _, u := range uploads {
+			name, fh := u.name, u.fh
+			f, err := fh.Open()
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			err = tw.WriteHeader(&tar.Header{
+				Name:     name,
+				Mode:     mode,
+				Size:     fh.Size,
+				ModTime:  now,
+				Typeflag: tar.TypeReg,
+			})
+			if err == nil {
+				_, err = io.Copy(tw, f)
+			}
+			f.Close()
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+
+	if err := cli.CopyToContainer(r.Context(), ctr, dir, pr, container.CopyToContainerOptions{}); err != nil {
+		pr.Close()
+		http.Error(w, fmt.Sprintf("copy to container failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	common.InfoLog("container files: %s uploaded %d file(s) (%d bytes) to %s:%s on host=%s",
+		middleware.GetUserEmail(r.Context()), len(written), total, ctr, dir, h.Name)
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "files": written, "bytes": total})
+}
@@ -14,6 +14,7 @@ import (
 
 	"dd-ui/common"
 	"dd-ui/database"
+	"dd-ui/middleware"
 	"dd-ui/services"
 	"dd-ui/utils"
 	"github.com/docker/docker/api/types"
@@ -30,7 +31,7 @@ import (
 
 // setupDockerRoutes sets up all Docker operations related routes
 // This organizes the Docker management functionality from web.go into logical groups:
-// - Container operations (list, logs, inspect, actions, stats)
+// - Container operations (list, logs, inspect, actions, stats, events, files)
 // - Image operations (list, delete)
 // - Network operations (list, delete) 
 // - Volume operations (list, delete)
@@ -45,9 +46,14 @@ func SetupDockerRoutes(router chi.Router) {
 				r.Get("/logs", handleContainerLogs)
 				r.Get("/logs/stream", handleContainerLogsStream)
 				r.Get("/inspect", handleContainerInspect)
+				r.Get("/secrets", handleContainerSecrets) // unmasked env/labels (handlers/reveal.go)
 				r.Get("/stats", handleContainerStats)
+				r.Get("/events", handleContainerEvents) // lifecycle history (handlers/container_events.go)
 				r.Post("/action", handleContainerAction)
 				r.Post("/enhanced-action", handleContainerEnhancedAction)
+				r.Get("/files", handleContainerFilesList)
+				r.Get("/files/download", handleContainerFilesDownload)
+				r.Post("/files/upload", handleContainerFilesUpload)
 			})
 		})
 	})
@@ -179,6 +185,12 @@ func handleContainerLogsStream(w http.ResponseWriter, r *http.Request) {
 
 	stdout := utils.NewSSELineWriter(w, fl, "stdout")
 	stderr := utils.NewSSELineWriter(w, fl, "stderr")
+	if inspect.Config != nil {
+		if redactor := services.LogRedactorFor(r.Context(), h.Name, inspect.Config.Labels["com.docker.compose.project"]); redactor != nil {
+			stdout.Filter = redactor.Redact
+			stderr.Filter = redactor.Redact
+		}
+	}
 
 	// Keep-alive tick
 	tick := time.NewTicker(15 * time.Second)
@@ -246,9 +258,16 @@ func handleContainerLogs(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rc.Close()
 
+	project := ""
+	if inspect, err := cli.ContainerInspect(r.Context(), ctr); err == nil && inspect.Config != nil {
+		project = inspect.Config.Labels["com.docker.compose.project"]
+	}
+	out := utils.NewLineFilterWriter(w, services.LogRedactorFor(r.Context(), h.Name, project).Redact)
+
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.Header().Set("Cache-Control", "no-store")
-	_, _ = io.Copy(w, rc)
+	_, _ = io.Copy(out, rc)
+	_ = out.Flush()
 }
 
 // handleContainerInspect returns detailed container inspection data
@@ -491,8 +510,22 @@ func handleContainerExec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Hybrid approach: use local Docker client for local host, SSH exec for remote hosts
-	if services.LocalHostAllowed(h) {
+	// Session recording is decided server-side by policy; the client cannot opt out.
+	var rec *services.ExecRecorder
+	if record, src := services.ExecRecordingPolicy(r.Context(), h); record {
+		common.DebugLog("Console: Recording session on host=%s container=%s (policy from %s)", host, ctr, src)
+		rec = services.NewExecRecorder(h, ctr, middleware.GetUserEmail(r.Context()))
+		_ = conn.WriteMessage(websocket.BinaryMessage, []byte("\r\n[this session is being recorded]\r\n"))
+		defer func() {
+			if _, err := rec.Finish(context.Background()); err != nil {
+				common.ErrorLog("Console: Failed to save recording for host=%s container=%s: %v", host, ctr, err)
+			}
+		}()
+	}
+
+	// Hybrid approach: use local Docker client for local host, SSH exec for remote hosts.
+	// Agent hosts have no SSH path; their Docker API (incl. exec hijack) runs through the agent.
+	if services.LocalHostAllowed(h) || services.IsAgentHost(h) {
 		common.DebugLog("Console: Using local Docker client for host %s (local host optimization)", host)
 		cli, err := services.DockerClientForHost(h)
 		if err != nil {
@@ -504,11 +537,11 @@ func handleContainerExec(w http.ResponseWriter, r *http.Request) {
 		common.DebugLog("Console: Docker client created successfully for host %s", host)
 		
 		// Use existing Docker client approach for local host
-		handleLocalConsole(conn, cli, host, ctr, r)
+		handleLocalConsole(conn, cli, host, ctr, r, rec)
 	} else {
 		common.DebugLog("Console: Using SSH exec for remote host %s", host)
 		// Use direct SSH exec approach for remote hosts
-		handleRemoteConsole(conn, h, host, ctr, r)
+		handleRemoteConsole(conn, h, host, ctr, r, rec)
 	}
 }
 
@@ -812,7 +845,7 @@ func handleVolumesDelete(w http.ResponseWriter, r *http.Request) {
 // -------- Console Handling Functions --------
 
 // handleLocalConsole handles console connections for local hosts using Docker client
-func handleLocalConsole(conn *websocket.Conn, cli *client.Client, host, ctr string, r *http.Request) {
+func handleLocalConsole(conn *websocket.Conn, cli *client.Client, host, ctr string, r *http.Request, rec *services.ExecRecorder) {
 	// Choose command: prefer explicit ?cmd, else ?shell, else auto
 	rawCmd := strings.TrimSpace(r.URL.Query().Get("cmd"))
 	shell := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("shell")))
@@ -882,6 +915,7 @@ func handleLocalConsole(conn *websocket.Conn, cli *client.Client, host, ctr stri
 		if ins.Running {
 			common.DebugLog("Console: Successfully started shell %v on host=%s container=%s", cmd, host, ctr)
 			chosen = &runner{id: created.ID, att: att}
+			rec.SetShell(strings.Join(cmd, " "))
 			break
 		}
 		// Not running (probably ENOENT / 127) — close & try next
@@ -923,6 +957,7 @@ func handleLocalConsole(conn *websocket.Conn, cli *client.Client, host, ctr stri
 					Rows int    `json:"rows"`
 				}
 				if err := json.Unmarshal(data, &msg); err == nil && strings.EqualFold(msg.Type, "resize") {
+					rec.Resize(msg.Cols, msg.Rows)
 					_ = cli.ContainerExecResize(context.Background(), execID, container.ResizeOptions{
 						Width:  uint(msg.Cols),
 						Height: uint(msg.Rows),
@@ -931,6 +966,7 @@ func handleLocalConsole(conn *websocket.Conn, cli *client.Client, host, ctr stri
 				}
 			}
 
+			rec.Input(data)
 			_, _ = chosen.att.Conn.Write(data)
 		}
 	}(chosen.id)
@@ -940,6 +976,7 @@ func handleLocalConsole(conn *websocket.Conn, cli *client.Client, host, ctr stri
 	for {
 		n, err := chosen.att.Reader.Read(buf)
 		if n > 0 {
+			rec.Output(buf[:n])
 			_ = conn.WriteMessage(websocket.BinaryMessage, buf[:n])
 		}
 		if err != nil {
@@ -949,7 +986,7 @@ func handleLocalConsole(conn *websocket.Conn, cli *client.Client, host, ctr stri
 }
 
 // handleRemoteConsole handles console connections for remote hosts using direct SSH exec
-func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr string, r *http.Request) {
+func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr string, r *http.Request, rec *services.ExecRecorder) {
 	// Choose command: prefer explicit ?cmd, else ?shell, else auto
 	rawCmd := strings.TrimSpace(r.URL.Query().Get("cmd"))
 	shell := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("shell")))
@@ -977,18 +1014,12 @@ func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr str
 		}
 	}
 
-	// Get SSH configuration
-	user := h.Vars["ansible_user"]
-	if user == "" {
-		user = common.Env("SSH_USER", "root")
-	}
-	addr := h.Addr
-	if addr == "" {
-		addr = h.Name
-	}
-	keyFile := common.Env("SSH_KEY_FILE", "")
-	if keyFile == "" {
-		_ = conn.WriteMessage(websocket.TextMessage, []byte("error: SSH_KEY_FILE not configured"))
+	// Resolve per-host SSH settings (jump hosts included) and verify every hop's host key
+	// against the managed known_hosts before any ssh exec
+	sshArgs, sshDest, err := services.OpenSSHArgsForHost(r.Context(), h)
+	if err != nil {
+		common.ErrorLog("Console: SSH setup failed for host=%s: %v", host, err)
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("error: "+err.Error()))
 		return
 	}
 
@@ -998,11 +1029,9 @@ func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr str
 		common.DebugLog("Console: Testing shell %v on remote host=%s container=%s", cmd, host, ctr)
 		
 		// Test if the shell exists in the container via SSH + docker exec
-		testCmd := fmt.Sprintf("docker exec %s %s -c 'echo shell_test' 2>/dev/null", ctr, strings.Join(cmd, " "))
-		sshCmd := []string{
-			"ssh", "-i", keyFile, "-o", "StrictHostKeyChecking=no", 
-			"-o", "ConnectTimeout=10", fmt.Sprintf("%s@%s", user, addr), testCmd,
-		}
+		testCmd := fmt.Sprintf("%s exec %s %s -c 'echo shell_test' 2>/dev/null", services.RuntimeCLI(h), ctr, strings.Join(cmd, " "))
+		sshCmd := append([]string{"ssh"}, sshArgs...)
+		sshCmd = append(sshCmd, "-o", "ConnectTimeout=10", sshDest, testCmd)
 		
 		testCtx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		testResult := exec.CommandContext(testCtx, sshCmd[0], sshCmd[1:]...)
@@ -1024,12 +1053,13 @@ func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr str
 	}
 
 	// Start the interactive shell via SSH + docker exec
-	dockerExecCmd := fmt.Sprintf("docker exec -it %s %s", ctr, strings.Join(chosenShell, " "))
-	sshCmd := []string{
-		"ssh", "-i", keyFile, "-o", "StrictHostKeyChecking=no",
+	rec.SetShell(strings.Join(chosenShell, " "))
+	dockerExecCmd := fmt.Sprintf("%s exec -it %s %s", services.RuntimeCLI(h), ctr, strings.Join(chosenShell, " "))
+	sshCmd := append([]string{"ssh"}, sshArgs...)
+	sshCmd = append(sshCmd,
 		"-t", "-t", // Force TTY allocation
-		fmt.Sprintf("%s@%s", user, addr), dockerExecCmd,
-	}
+		sshDest, dockerExecCmd,
+	)
 
 	common.DebugLog("Console: Starting remote shell via: %v", sshCmd)
 	
@@ -1092,11 +1122,13 @@ func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr str
 					Rows int    `json:"rows"`
 				}
 				if err := json.Unmarshal(data, &msg); err == nil && strings.EqualFold(msg.Type, "resize") {
+					rec.Resize(msg.Cols, msg.Rows)
 					// For SSH, we can't easily resize the remote TTY, so we'll skip this
 					continue
 				}
 			}
 
+			rec.Input(data)
 			_, err = stdin.Write(data)
 			if err != nil {
 				return
@@ -1110,6 +1142,7 @@ func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr str
 		for {
 			n, err := stdout.Read(buf)
 			if n > 0 {
+				rec.Output(buf[:n])
 				_ = conn.WriteMessage(websocket.BinaryMessage, buf[:n])
 			}
 			if err != nil {
@@ -1124,6 +1157,7 @@ func handleRemoteConsole(conn *websocket.Conn, h database.HostRow, host, ctr str
 		for {
 			n, err := stderr.Read(buf)
 			if n > 0 {
+				rec.Output(buf[:n])
 				_ = conn.WriteMessage(websocket.BinaryMessage, buf[:n])
 			}
 			if err != nil {
@@ -0,0 +1,112 @@
+// handlers/events.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/services"
+	"dd-ui/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupEventRoutes configures the event stream:
+// - /api/events    SSE stream of DD-UI events (?types=deploy.*,container.state_changed &host= &stack=),
+// resuming after Last-Event-ID (or ?since_id=) from the stored history
+func SetupEventRoutes(router chi.Router) {
+	router.Get("/events", handleEventStream)
+}
+
+func handleEventStream(w http.ResponseWriter, r *http.Request) {
+	fl, ok := utils.WriteSSEHeader(w)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	q := r.URL.Query()
+	var types []string
+	for _, t := range strings.Split(q.Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	host, stack := q.Get("host"), q.Get("stack")
+	match := func(e services.Event) bool {
+		return services.EventMatches(types, e.Type) &&
+			(host == "" || strings.EqualFold(e.Host, host)) &&
+			(stack == "" || strings.EqualFold(e.Stack, stack))
+	}
+
+	var lastID int64
+	send := func(e services.Event) bool {
+		if e.ID != 0 && e.ID <= lastID {
+			return true // already sent during replay
+		}
+		if e.ID != 0 {
+			lastID = e.ID
+		}
+		if !match(e) {
+			return true
+		}
+		b, err := json.Marshal(e)
+		if err != nil {
+			return true
+		}
+		if e.ID != 0 {
+			fmt.Fprintf(w, "id: %d\n", e.ID)
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b); err != nil {
+			return false
+		}
+		fl.Flush()
+		return true
+	}
+
+	// subscribe before replaying so nothing falls in between
+	ch, cancel := services.SubscribeEvents()
+	defer cancel()
+
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = q.Get("since_id")
+	}
+	if after, err := strconv.ParseInt(since, 10, 64); err == nil && after > 0 {
+		lastID = after
+		backlog, err := database.ListEventsSince(r.Context(), after, 500)
+		if err != nil {
+			common.DebugLog("events: replay after %d failed: %v", after, err)
+		}
+		for _, e := range backlog {
+			if !send(e) {
+				return
+			}
+		}
+	}
+
+	fmt.Fprint(w, ": connected\n\n")
+	fl.Flush()
+
+	keepalive := time.NewTicker(25 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			fl.Flush()
+		case e := <-ch:
+			if !send(e) {
+				return
+			}
+		}
+	}
+}
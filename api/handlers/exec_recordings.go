@@ -0,0 +1,227 @@
+// handlers/exec_recordings.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupExecRecordingRoutes configures console session recording routes:
+// - /api/exec-recordings                     list (filters: host, container, limit)
+// - /api/exec-recordings/{id}                metadata / delete
+// - /api/exec-recordings/{id}/cast           asciicast v2 for playback
+// - /api/exec-recordings/{id}/download       gzip-compressed .cast download
+// - /api/exec-recordings/policy/...          global, host and group recording policy
+// Recordings hold typed input (passwords typed into shells included), so everything except
+// reading the policy is admin only.
+func SetupExecRecordingRoutes(router chi.Router) {
+	router.Route("/exec-recordings", func(r chi.Router) {
+		r.Route("/policy", func(r chi.Router) {
+			r.Get("/global", handleExecRecordingGlobalGet)
+			r.With(middleware.RequireAdmin).Patch("/global", handleExecRecordingGlobalPatch)
+			r.Get("/{kind}/{name}", handleExecRecordingOverrideGet)
+			r.With(middleware.RequireAdmin).Patch("/{kind}/{name}", handleExecRecordingOverridePatch)
+		})
+
+		r.Group(func(admin chi.Router) {
+			admin.Use(middleware.RequireAdmin)
+			admin.Get("/", handleExecRecordingsList)
+			admin.Route("/{id}", func(r chi.Router) {
+				r.Get("/", handleExecRecordingGet)
+				r.Delete("/", handleExecRecordingDelete)
+				r.Get("/cast", handleExecRecordingCast)
+				r.Get("/download", handleExecRecordingDownload)
+			})
+		})
+	})
+}
+
+func execRecordingID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+}
+
+// handleExecRecordingsList lists recordings, newest first
+func handleExecRecordingsList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	recs, err := database.ListExecRecordings(r.Context(), q.Get("host"), q.Get("container"), limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list recordings: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"recordings": recs})
+}
+
+// handleExecRecordingGet returns metadata for one recording
+func handleExecRecordingGet(w http.ResponseWriter, r *http.Request) {
+	id, err := execRecordingID(r)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	rec, err := database.GetExecRecording(r.Context(), id)
+	if err != nil {
+		http.Error(w, "recording not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"recording": rec})
+}
+
+// handleExecRecordingCast serves the decompressed asciicast for in-browser playback
+func handleExecRecordingCast(w http.ResponseWriter, r *http.Request) {
+	id, err := execRecordingID(r)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	gz, err := database.GetExecRecordingCast(r.Context(), id)
+	if err != nil {
+		http.Error(w, "recording not found", http.StatusNotFound)
+		return
+	}
+	cast, err := services.DecompressCast(gz)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to decompress recording: %v", err), http.StatusInternalServerError)
+		return
+	}
+	common.InfoLog("exec recording: id=%d played by %s", id, middleware.GetUserEmail(r.Context()))
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(cast)
+}
+
+// handleExecRecordingDownload serves the stored gzip as an attachment
+func handleExecRecordingDownload(w http.ResponseWriter, r *http.Request) {
+	id, err := execRecordingID(r)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	rec, err := database.GetExecRecording(r.Context(), id)
+	if err != nil {
+		http.Error(w, "recording not found", http.StatusNotFound)
+		return
+	}
+	gz, err := database.GetExecRecordingCast(r.Context(), id)
+	if err != nil {
+		http.Error(w, "recording not found", http.StatusNotFound)
+		return
+	}
+	common.InfoLog("exec recording: id=%d downloaded by %s", id, middleware.GetUserEmail(r.Context()))
+	filename := fmt.Sprintf("%s-%s-%s.cast.gz", rec.HostName, rec.ContainerName, rec.StartedAt.UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(gz)))
+	_, _ = w.Write(gz)
+}
+
+// handleExecRecordingDelete removes a recording
+func handleExecRecordingDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := execRecordingID(r)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	ok, err := database.DeleteExecRecording(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "recording not found", http.StatusNotFound)
+		return
+	}
+	common.InfoLog("exec recording: id=%d deleted by %s", id, middleware.GetUserEmail(r.Context()))
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// handleExecRecordingGlobalGet returns the global recording policy
+func handleExecRecordingGlobalGet(w http.ResponseWriter, r *http.Request) {
+	val, src := services.GetGlobalExecRecording(r.Context())
+	writeJSON(w, http.StatusOK, map[string]any{"record": val, "source": src})
+}
+
+// handleExecRecordingGlobalPatch sets the global policy: { "record": true|false|null }
+func handleExecRecordingGlobalPatch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Record *bool `json:"record"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	if err := services.SetGlobalExecRecording(r.Context(), body.Record); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	val, src := services.GetGlobalExecRecording(r.Context())
+	writeJSON(w, http.StatusOK, map[string]any{"record": val, "source": src, "status": "ok"})
+}
+
+// handleExecRecordingOverrideGet returns a host/group override and, for hosts, the effective policy
+func handleExecRecordingOverrideGet(w http.ResponseWriter, r *http.Request) {
+	writeExecRecordingOverride(w, r, "")
+}
+
+// handleExecRecordingOverridePatch sets a host/group override: { "record": true|false|null }
+func handleExecRecordingOverridePatch(w http.ResponseWriter, r *http.Request) {
+	kind := chi.URLParam(r, "kind")
+	name := chi.URLParam(r, "name")
+	var body struct {
+		Record *bool `json:"record"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	if err := services.SetExecRecordingOverride(r.Context(), kindSingular(kind), name, body.Record); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	common.InfoLog("exec recording: %s %s policy set to %v by %s", kind, name, body.Record, middleware.GetUserEmail(r.Context()))
+	writeExecRecordingOverride(w, r, "ok")
+}
+
+func writeExecRecordingOverride(w http.ResponseWriter, r *http.Request, status string) {
+	kind := kindSingular(chi.URLParam(r, "kind"))
+	name := chi.URLParam(r, "name")
+	if kind != "host" && kind != "group" {
+		http.Error(w, "kind must be hosts or groups", http.StatusBadRequest)
+		return
+	}
+	override, _ := services.GetExecRecordingOverride(r.Context(), kind, name)
+	resp := map[string]any{"override": override}
+	if kind == "host" {
+		if h, err := database.GetHostByName(r.Context(), name); err == nil {
+			effective, src := services.ExecRecordingPolicy(r.Context(), h)
+			resp["effective"] = effective
+			resp["source"] = src
+		}
+	}
+	if status != "" {
+		resp["status"] = status
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// kindSingular accepts "hosts"/"groups" path segments as well as the singular form
+func kindSingular(kind string) string {
+	switch kind {
+	case "hosts":
+		return "host"
+	case "groups":
+		return "group"
+	}
+	return kind
+}
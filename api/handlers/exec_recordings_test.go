@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dd-ui/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestExecRecordingRoutesAdminOnly(t *testing.T) {
+	t.Setenv("DD_UI_ADMINS", "admin@example.com")
+	router := chi.NewRouter()
+	SetupExecRecordingRoutes(router)
+
+	tests := []struct {
+		method, path string
+	}{
+		{http.MethodGet, "/exec-recordings/"},
+		{http.MethodGet, "/exec-recordings/7"},
+		{http.MethodGet, "/exec-recordings/7/cast"},
+		{http.MethodGet, "/exec-recordings/7/download"},
+		{http.MethodDelete, "/exec-recordings/7"},
+		{http.MethodPatch, "/exec-recordings/policy/global"},
+		{http.MethodPatch, "/exec-recordings/policy/host/web-1"},
+	}
+	user := middleware.User{Sub: "u1", Email: "dev@example.com"}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserKey, user))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403", rec.Code)
+			}
+		})
+	}
+}
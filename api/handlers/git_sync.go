@@ -378,9 +378,11 @@ func (h *GitSyncHandlers) CheckInitialSetupConflict(w http.ResponseWriter, r *ht
 				os.Chmod(tmpFile.Name(), 0600)
 				defer os.Remove(tmpFile.Name())
 				
-				cmd.Env = append(cmd.Env,
-					fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o IdentitiesOnly=yes -o LogLevel=ERROR", tmpFile.Name()),
-				)
+				if sshCmd, err := services.GitSSHCommand(r.Context(), tmpFile.Name(), config.RepoURL); err == nil {
+					cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+sshCmd)
+				} else {
+					common.WarnLog("Git conflict check: %v", err)
+				}
 			}
 		}
 		
@@ -542,9 +544,18 @@ func (h *GitSyncHandlers) TestConnection(w http.ResponseWriter, r *http.Request)
 		}
 		defer os.Remove(tmpFile.Name())
 		
-		cmd.Env = append(cmd.Env,
-			fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o IdentitiesOnly=yes -o LogLevel=ERROR", tmpFile.Name()),
-		)
+		sshCmd, err := services.GitSSHCommand(r.Context(), tmpFile.Name(), req.RepoURL)
+		if err != nil {
+			common.ErrorLog("TestConnection: SSH host key verification failed: %v", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status": "error",
+				"message": "SSH host key verification failed: " + err.Error() + ". Review the key under SSH known hosts.",
+			})
+			return
+		}
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND="+sshCmd)
 	}
 
 	output, err := cmd.CombinedOutput()
@@ -568,10 +579,9 @@ func (h *GitSyncHandlers) TestConnection(w http.ResponseWriter, r *http.Request)
 		} else if strings.Contains(outputStr, "Permission denied") {
 			errorMsg = "Authentication failed. Check your credentials."
 		} else if strings.Contains(outputStr, "Host key verification failed") {
-			// This shouldn't happen since we use StrictHostKeyChecking=no
-			// Log the full error for debugging
-			common.ErrorLog("Unexpected SSH host key verification error with StrictHostKeyChecking=no: %s", outputStr)
-			errorMsg = "SSH host key verification failed unexpectedly. Full error: " + strings.TrimSpace(outputStr)
+			// Host keys are checked against the managed known_hosts store
+			common.ErrorLog("SSH host key verification failed: %s", outputStr)
+			errorMsg = "SSH host key verification failed. Review the key under SSH known hosts. Full error: " + strings.TrimSpace(outputStr)
 		} else if strings.Contains(outputStr, "Could not resolve hostname") || strings.Contains(outputStr, "Name or service not known") {
 			errorMsg = "Cannot reach repository. Check the URL and network connection."
 		} else if strings.Contains(outputStr, "port 22: Connection refused") || strings.Contains(outputStr, "port 2424: Connection refused") {
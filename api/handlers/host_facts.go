@@ -0,0 +1,75 @@
+// handlers/host_facts.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"dd-ui/database"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupHostFactsRoutes configures host facts routes:
+// - /api/hosts/facts                        fleet overview (latest facts per host, outdated/full flags)
+// - /api/hosts/{hostname}/facts             latest facts plus history (?limit=, default 50)
+// - /api/hosts/{hostname}/facts/refresh     collect now
+func SetupHostFactsRoutes(router chi.Router) {
+	router.Get("/hosts/facts", handleHostFactsOverview)
+	router.Get("/hosts/{hostname}/facts", handleHostFactsGet)
+	router.Post("/hosts/{hostname}/facts/refresh", handleHostFactsRefresh)
+}
+
+// handleHostFactsOverview returns every host's latest facts with derived flags
+func handleHostFactsOverview(w http.ResponseWriter, r *http.Request) {
+	items, newest, err := services.ListHostFactsOverview(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list host facts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items, "latest_engine": newest})
+}
+
+// handleHostFactsGet returns a host's facts history, newest first
+func handleHostFactsGet(w http.ResponseWriter, r *http.Request) {
+	h, err := database.GetHostByName(r.Context(), chi.URLParam(r, "hostname"))
+	if err != nil {
+		http.Error(w, "host not found", http.StatusNotFound)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 50
+	}
+	history, err := database.ListHostFactsHistory(r.Context(), h.ID, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load host facts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	var latest *database.HostFactsRow
+	if len(history) > 0 {
+		latest = &history[0]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"host": h.Name, "latest": latest, "history": history})
+}
+
+// handleHostFactsRefresh collects facts from the host immediately
+func handleHostFactsRefresh(w http.ResponseWriter, r *http.Request) {
+	h, err := database.GetHostByName(r.Context(), chi.URLParam(r, "hostname"))
+	if err != nil {
+		http.Error(w, "host not found", http.StatusNotFound)
+		return
+	}
+	f, err := services.CollectHostFacts(r.Context(), h)
+	if errors.Is(err, services.ErrSkipScan) {
+		http.Error(w, "host uses the local Docker socket but is not marked local", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to collect host facts: %v", err), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"facts": f})
+}
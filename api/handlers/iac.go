@@ -25,6 +25,8 @@ import (
 // - Group-scoped IAC endpoints  
 // - Stack-scoped IAC endpoints (CRUD, files, deployment)
 // - Batch operations and scanning
+// - Adopting unmanaged compose projects
+// - Stack template catalog and instantiation
 func SetupIacRoutes(router chi.Router) {
 	router.Route("/iac", func(r chi.Router) {
 		// Scope-based IAC endpoints (works for both hosts and groups)
@@ -374,12 +376,7 @@ func SetupIacRoutes(router chi.Router) {
 					if decrypt {
 						// This check ONLY gates the UI reveal functionality, NOT deployments
 						// Deployments always decrypt SOPS files if keys are available (see deploy_sops.go)
-						if !common.EnvBool("DD_UI_ALLOW_SOPS_DECRYPT", "false") {
-							http.Error(w, "decrypt disabled on server", http.StatusForbidden)
-							return
-						}
-						if strings.ToLower(r.Header.Get("X-Confirm-Reveal")) != "yes" {
-							http.Error(w, "confirmation required", http.StatusForbidden)
+						if !confirmReveal(w, r, scopeName, stackname, "file", rel) {
 							return
 						}
 						
@@ -710,6 +707,18 @@ func SetupIacRoutes(router chi.Router) {
 					writeJSON(w, http.StatusOK, map[string]any{"deleted": true})
 				})
 				
+				// Container log redaction rules (handlers/log_redaction.go)
+				r.Get("/log-redactions", handleLogRedactionsList)
+				r.Post("/log-redactions", handleLogRedactionCreate)
+				r.Delete("/log-redactions/{id}", handleLogRedactionDelete)
+
+				// Rendered *.tmpl preview (handlers/iac_render.go)
+				r.Get("/rendered", handleStackRendered)
+
+				// Whole-stack stop/start/restart/down/pull (handlers/stack_ops.go)
+				r.Post("/ops/{op}", handleStackOperation)
+				r.Get("/ops/{op}/stream", handleStackOperationStream)
+
 				// Deploy endpoint (non-streaming)
 				r.Post("/deploy", func(w http.ResponseWriter, r *http.Request) {
 					scopeName := chi.URLParam(r, "scopename")
@@ -831,6 +840,15 @@ func SetupIacRoutes(router chi.Router) {
 			})
 		})
 		
+		// Adopt running compose projects that have no IaC yet
+		r.Get("/hosts/{hostname}/unmanaged", handleUnmanagedProjects)
+		r.Post("/hosts/{hostname}/adopt/{project}", handleAdoptProject)
+
+		// Stack template catalog (<iac root>/templates/<name>)
+		r.Get("/templates", handleStackTemplatesList)
+		r.Get("/templates/{name}", handleStackTemplateGet)
+		r.Post("/templates/{name}/instantiate", handleStackTemplateInstantiate)
+
 		// Force IaC scan (local)
 		r.Post("/scan", func(w http.ResponseWriter, r *http.Request) {
 			ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
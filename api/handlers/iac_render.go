@@ -0,0 +1,43 @@
+// handlers/iac_render.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dd-ui/database"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleStackRendered previews a stack's *.tmpl files rendered for ?host= (host stacks default
+// to their own host). Secret values are masked unless ?reveal=1 passes confirmReveal.
+func handleStackRendered(w http.ResponseWriter, r *http.Request) {
+	stackID, ok := stackIDFromRoute(w, r)
+	if !ok {
+		return
+	}
+	host := strings.TrimSpace(r.URL.Query().Get("host"))
+	if host != "" {
+		if _, err := database.GetHostByName(r.Context(), host); err != nil {
+			http.Error(w, "unknown host", http.StatusBadRequest)
+			return
+		}
+	}
+	reveal := r.URL.Query().Get("reveal") == "1" || r.URL.Query().Get("reveal") == "true"
+	scope := chi.URLParam(r, "scopename")
+	if host != "" {
+		scope = host
+	}
+	if reveal && !confirmReveal(w, r, scope, chi.URLParam(r, "stackname"), "rendered", chi.URLParam(r, "stackname")) {
+		return
+	}
+	preview, err := services.RenderStackPreview(r.Context(), stackID, host, reveal)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("render failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, preview)
+}
@@ -0,0 +1,109 @@
+// handlers/log_redaction.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"dd-ui/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// stackIDFromRoute resolves {scopename}/{stackname}, writing 404 itself
+func stackIDFromRoute(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	stackID, err := services.GetStackIDByHostAndName(r.Context(), chi.URLParam(r, "scopename"), chi.URLParam(r, "stackname"))
+	if err != nil {
+		http.Error(w, "Stack not found", http.StatusNotFound)
+		return 0, false
+	}
+	return stackID, true
+}
+
+// handleLogRedactionsList returns a stack's log redaction rules and the built-in patterns
+func handleLogRedactionsList(w http.ResponseWriter, r *http.Request) {
+	stackID, ok := stackIDFromRoute(w, r)
+	if !ok {
+		return
+	}
+	rules, err := database.ListLogRedactionRules(r.Context(), stackID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list redaction rules: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"rules":   rules,
+		"builtin": utils.BuiltinLogRedactionPatterns,
+		"enabled": common.EnvBool("DD_UI_LOG_REDACTION", "true"),
+	})
+}
+
+// handleLogRedactionCreate adds a rule: {pattern, description}. Patterns are Go regexps; a
+// (?P<secret>...) group limits the replacement to that group.
+func handleLogRedactionCreate(w http.ResponseWriter, r *http.Request) {
+	stackID, ok := stackIDFromRoute(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Pattern     string `json:"pattern"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	body.Pattern = strings.TrimSpace(body.Pattern)
+	if body.Pattern == "" {
+		http.Error(w, "pattern required", http.StatusBadRequest)
+		return
+	}
+	if _, err := regexp.Compile(body.Pattern); err != nil {
+		http.Error(w, fmt.Sprintf("invalid pattern: %v", err), http.StatusBadRequest)
+		return
+	}
+	rule, err := database.CreateLogRedactionRule(r.Context(), database.LogRedactionRuleRow{
+		StackID:     stackID,
+		Pattern:     body.Pattern,
+		Description: strings.TrimSpace(body.Description),
+		CreatedBy:   middleware.GetUserEmail(r.Context()),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to save redaction rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+	services.InvalidateLogRedactors()
+	common.InfoLog("log redaction: %s added rule %d to stack %d", rule.CreatedBy, rule.ID, stackID)
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+func handleLogRedactionDelete(w http.ResponseWriter, r *http.Request) {
+	stackID, ok := stackIDFromRoute(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad rule id", http.StatusBadRequest)
+		return
+	}
+	deleted, err := database.DeleteLogRedactionRule(r.Context(), stackID, id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete redaction rule: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "rule not found", http.StatusNotFound)
+		return
+	}
+	services.InvalidateLogRedactors()
+	common.InfoLog("log redaction: %s deleted rule %d of stack %d", middleware.GetUserEmail(r.Context()), id, stackID)
+	w.WriteHeader(http.StatusNoContent)
+}
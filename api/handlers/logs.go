@@ -13,6 +13,7 @@ import (
 	"dd-ui/common"
 	"dd-ui/database"
 	"dd-ui/services"
+	"dd-ui/utils"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
@@ -202,6 +203,9 @@ func streamContainerLogs(ctx context.Context, cli *client.Client, hostName strin
 	// Log at startup only, not for every message
 	common.DebugLog("Starting log stream for container %s on host %s (stack: %s)", containerName, hostName, stackName)
 
+	// Secrets are redacted before entries are broadcast (or stored)
+	redactor := services.LogRedactorFor(ctx, hostName, stackName)
+
 	options := container.LogsOptions{
 		ShowStdout: true,
 		ShowStderr: true,
@@ -272,8 +276,8 @@ func streamContainerLogs(ctx context.Context, cli *client.Client, hostName strin
 						ContainerName: containerName,
 						Level:         level,
 						Source:        "stdout",
-						Message:       strings.TrimSpace(logMessage),
-						Labels:        cnt.Labels,
+						Message:       redactor.Redact(strings.TrimSpace(logMessage)),
+						Labels:        utils.MaskLabels(cnt.Labels, nil),
 					}
 
 					// Broadcast to all subscribers
@@ -427,6 +431,7 @@ func sendHistoricalLogs(w http.ResponseWriter, filter LogFilter) {
 		}
 		
 		if matchesFilter(entry, filter) {
+			entry.Message = services.LogRedactorFor(ctx, entry.HostName, entry.StackName).Redact(entry.Message)
 			data, _ := json.Marshal(entry)
 			fmt.Fprintf(w, "data: %s\n\n", string(data))
 			count++
@@ -0,0 +1,58 @@
+// handlers/reveal.go
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// confirmReveal gates every reveal of secret values (decrypted SOPS files, unmasked container
+// env/labels): the server must allow it (DD_UI_ALLOW_SOPS_DECRYPT) and the client must send
+// X-Confirm-Reveal: yes. Allowed reveals are logged and published as secret.revealed.
+func confirmReveal(w http.ResponseWriter, r *http.Request, host, stack, kind, subject string) bool {
+	if !common.EnvBool("DD_UI_ALLOW_SOPS_DECRYPT", "false") {
+		http.Error(w, "decrypt disabled on server", http.StatusForbidden)
+		return false
+	}
+	if strings.ToLower(r.Header.Get("X-Confirm-Reveal")) != "yes" {
+		http.Error(w, "confirmation required", http.StatusForbidden)
+		return false
+	}
+	user := middleware.GetUserEmail(r.Context())
+	common.InfoLog("reveal: %s revealed %s %q (host=%s stack=%s) from %s", user, kind, subject, host, stack, middleware.ClientIP(r))
+	services.PublishEvent(r.Context(), services.Event{Type: services.EventSecretRevealed, Host: host, Stack: stack, Subject: subject,
+		Data: map[string]any{"kind": kind, "user": user, "ip": middleware.ClientIP(r)}})
+	return true
+}
+
+// handleContainerSecrets returns the unmasked values of a container's masked env and labels,
+// read live from Docker (stored and listed container data only carries masked values)
+func handleContainerSecrets(w http.ResponseWriter, r *http.Request) {
+	hostname := chi.URLParam(r, "hostname")
+	ctr := chi.URLParam(r, "ctr")
+	h, err := database.GetHostByName(r.Context(), hostname)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stack := ""
+	if row, err := database.GetContainerByHostAndName(r.Context(), hostname, ctr); err == nil {
+		stack = row.ComposeProj
+	}
+	if !confirmReveal(w, r, h.Name, stack, "container", ctr) {
+		return
+	}
+	secrets, err := services.RevealContainerSecrets(r.Context(), h, ctr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, secrets)
+}
@@ -0,0 +1,81 @@
+// handlers/scan_logs.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"dd-ui/database"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupScanLogRoutes configures scan log query routes:
+// - /api/scan/logs              all hosts (filters: host, level, q, since, until, limit, offset)
+// - /api/scan/logs/{hostname}   one host, same filters
+//
+// level is a comma-separated list (e.g. "warn,error"); since/until accept RFC3339
+// timestamps or a Go duration meaning "that long ago" (e.g. since=24h).
+func SetupScanLogRoutes(router chi.Router) {
+	router.Get("/scan/logs", handleScanLogsList)
+	router.Get("/scan/logs/{hostname}", handleScanLogsList)
+}
+
+func handleScanLogsList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := database.ScanLogFilter{
+		Host:   strings.TrimSpace(q.Get("host")),
+		Query:  strings.TrimSpace(q.Get("q")),
+		Limit:  clamp(parseIntDefault(q.Get("limit"), 100), 1, 1000),
+		Offset: max(parseIntDefault(q.Get("offset"), 0), 0),
+	}
+	if h := chi.URLParam(r, "hostname"); h != "" {
+		f.Host = h
+	}
+	for _, l := range strings.Split(q.Get("level"), ",") {
+		if l = strings.ToLower(strings.TrimSpace(l)); l != "" {
+			f.Levels = append(f.Levels, l)
+		}
+	}
+	var err error
+	if f.Since, err = parseLogTime(q.Get("since")); err != nil {
+		http.Error(w, "bad since: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.Until, err = parseLogTime(q.Get("until")); err != nil {
+		http.Error(w, "bad until: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// fetch one extra row to report whether another page exists
+	want := f.Limit
+	f.Limit++
+	items, err := database.ListScanLogs(r.Context(), f)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list scan logs: %v", err), http.StatusInternalServerError)
+		return
+	}
+	more := len(items) > want
+	if more {
+		items = items[:want]
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":    items,
+		"limit":    want,
+		"offset":   f.Offset,
+		"has_more": more,
+	})
+}
+
+// parseLogTime accepts an RFC3339 timestamp or a duration ago; "" is no bound
+func parseLogTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,139 @@
+// handlers/sessions.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupSessionRoutes configures login session routes:
+// - /api/sessions                        list (GET) or revoke (DELETE) the caller's other sessions (?all=true includes this one)
+// - /api/sessions/{id}                   revoke one of the caller's sessions
+// - /api/admin/sessions                  every user's sessions (?user= sub or email)
+// - /api/admin/sessions/{id}             revoke any session
+// - /api/admin/users/{user}/sessions     force logout of a user (sub or email)
+func SetupSessionRoutes(router chi.Router) {
+	router.Get("/sessions", handleSessionsList)
+	router.Delete("/sessions", handleSessionsRevokeAll)
+	router.Delete("/sessions/{id}", handleSessionRevoke)
+
+	router.Group(func(admin chi.Router) {
+		admin.Use(middleware.RequireAdmin)
+		admin.Get("/admin/sessions", handleAdminSessionsList)
+		admin.Delete("/admin/sessions/{id}", handleAdminSessionRevoke)
+		admin.Delete("/admin/users/{user}/sessions", handleAdminUserLogout)
+	})
+}
+
+// sessionsPersisted: listing and revocation need the Postgres session store
+func sessionsPersisted(w http.ResponseWriter) bool {
+	if common.SessionManager != nil {
+		if _, ok := common.SessionManager.Store.(database.SessionStore); ok {
+			return true
+		}
+	}
+	http.Error(w, "session management requires DD_UI_SESSION_STORE=postgres", http.StatusNotImplemented)
+	return false
+}
+
+func handleSessionsList(w http.ResponseWriter, r *http.Request) {
+	if !sessionsPersisted(w) {
+		return
+	}
+	u := middleware.CurrentUser(r.Context())
+	items, err := database.ListSessions(r.Context(), u.Sub, common.SessionManager.Token(r.Context()))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func handleSessionsRevokeAll(w http.ResponseWriter, r *http.Request) {
+	if !sessionsPersisted(w) {
+		return
+	}
+	u := middleware.CurrentUser(r.Context())
+	keep := common.SessionManager.Token(r.Context())
+	if strings.EqualFold(r.URL.Query().Get("all"), "true") {
+		keep = ""
+	}
+	n, err := database.DeleteUserSessions(r.Context(), u.Sub, keep)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to revoke sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	common.InfoLog("sessions: %s revoked %d of their sessions", middleware.GetUserEmail(r.Context()), n)
+	writeJSON(w, http.StatusOK, map[string]any{"revoked": n})
+}
+
+func handleSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	if !sessionsPersisted(w) {
+		return
+	}
+	u := middleware.CurrentUser(r.Context())
+	revokeSession(w, r, chi.URLParam(r, "id"), u.Sub)
+}
+
+func handleAdminSessionsList(w http.ResponseWriter, r *http.Request) {
+	if !sessionsPersisted(w) {
+		return
+	}
+	items, err := database.ListSessions(r.Context(), "", common.SessionManager.Token(r.Context()))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if user := strings.TrimSpace(r.URL.Query().Get("user")); user != "" {
+		filtered := items[:0]
+		for _, s := range items {
+			if s.UserSub == user || strings.EqualFold(s.UserEmail, user) {
+				filtered = append(filtered, s)
+			}
+		}
+		items = filtered
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func handleAdminSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	if !sessionsPersisted(w) {
+		return
+	}
+	revokeSession(w, r, chi.URLParam(r, "id"), "")
+}
+
+func handleAdminUserLogout(w http.ResponseWriter, r *http.Request) {
+	if !sessionsPersisted(w) {
+		return
+	}
+	user := strings.TrimSpace(chi.URLParam(r, "user"))
+	n, err := database.DeleteUserSessions(r.Context(), user, "")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to revoke sessions: %v", err), http.StatusInternalServerError)
+		return
+	}
+	common.WarnLog("sessions: %s forced logout of %s (%d sessions)", middleware.GetUserEmail(r.Context()), user, n)
+	writeJSON(w, http.StatusOK, map[string]any{"user": user, "revoked": n})
+}
+
+// revokeSession deletes a session by its public id; owner "" allows any user's session
+func revokeSession(w http.ResponseWriter, r *http.Request, sid, owner string) {
+	ok, err := database.DeleteSessionBySID(r.Context(), sid, owner)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to revoke session: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	common.InfoLog("sessions: %s revoked session %s", middleware.GetUserEmail(r.Context()), sid)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,11 +4,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"dd-ui/common"
 	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
 	"dd-ui/utils"
 	"github.com/go-chi/chi/v5"
 )
@@ -28,6 +32,15 @@ func (hp hostProvider) GetHostByName(ctx context.Context, name string) (utils.Ho
 	}, nil
 }
 
+// SSHTarget implements utils.SSHTargetProvider (per-host SSH settings from inventory vars)
+func (hp hostProvider) SSHTarget(ctx context.Context, name string) (utils.SSHTarget, error) {
+	hostRow, err := database.GetHostByName(ctx, name)
+	if err != nil {
+		return utils.SSHTarget{}, err
+	}
+	return services.SSHTargetForHost(ctx, hostRow)
+}
+
 // envProvider implements utils.EnvProvider interface
 type envProvider struct{}
 
@@ -73,4 +86,73 @@ func SetupSshRoutes(router chi.Router) {
 			"output":  output,
 		})
 	})
-}
\ No newline at end of file
+
+	// Managed known_hosts: pinned/pending/mismatched host keys
+	// - GET    /ssh/known-hosts                 list (filters: host, status)
+	// - POST   /ssh/known-hosts/{id}/approve    trust a key (replaces the pinned key of that type)
+	// - DELETE /ssh/known-hosts/{id}            forget a key
+	router.Route("/ssh/known-hosts", func(r chi.Router) {
+		r.Get("/", handleKnownHostsList)
+		r.Post("/{id}/approve", handleKnownHostApprove)
+		r.Delete("/{id}", handleKnownHostDelete)
+	})
+}
+
+// handleKnownHostsList returns host keys; mismatches are reported as alerts
+func handleKnownHostsList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	keys, err := database.ListKnownHostKeys(r.Context(), q.Get("host"), q.Get("status"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list known hosts: %v", err), http.StatusInternalServerError)
+		return
+	}
+	alerts := []database.KnownHostRow{}
+	for _, k := range keys {
+		if k.Status == "mismatch" {
+			alerts = append(alerts, k)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"known_hosts":      keys,
+		"alerts":           alerts,
+		"strict":           !utils.HostKeyCheckingDisabled(),
+		"known_hosts_file": services.KnownHostsPath(),
+	})
+}
+
+// handleKnownHostApprove trusts a pending or mismatched key
+func handleKnownHostApprove(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	user := middleware.GetUserEmail(r.Context())
+	k, err := services.ApproveHostKey(r.Context(), id, user)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to approve key: %v", err), http.StatusNotFound)
+		return
+	}
+	common.InfoLog("SSH: host key %s for %s approved by %s", k.Fingerprint, k.Host, user)
+	writeJSON(w, http.StatusOK, k)
+}
+
+// handleKnownHostDelete forgets a key
+func handleKnownHostDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad id", http.StatusBadRequest)
+		return
+	}
+	ok, err := services.ForgetHostKey(r.Context(), id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete key: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	common.InfoLog("SSH: known host key %d deleted by %s", id, middleware.GetUserEmail(r.Context()))
+	writeJSON(w, http.StatusOK, map[string]any{"deleted": true})
+}
@@ -0,0 +1,105 @@
+// handlers/stack_ops.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// stackOperationFromRoute validates {op} and its options for the stack route. Down with
+// ?volumes=true deletes named volumes, so it is admin only, not offered for swarm stacks and
+// also needs ?confirm=<stack name>.
+func stackOperationFromRoute(w http.ResponseWriter, r *http.Request) (int64, services.StackOperation, services.StackOperationOptions, bool) {
+	var opts services.StackOperationOptions
+	op, err := services.ParseStackOperation(chi.URLParam(r, "op"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, "", opts, false
+	}
+	stackID, ok := stackIDFromRoute(w, r)
+	if !ok {
+		return 0, "", opts, false
+	}
+	if has, err := services.StackHasContent(r.Context(), stackID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return 0, "", opts, false
+	} else if !has {
+		http.Error(w, "stack has no deployable content", http.StatusBadRequest)
+		return 0, "", opts, false
+	}
+	if v := strings.ToLower(r.URL.Query().Get("volumes")); v == "true" || v == "1" {
+		if op != services.StackOpDown {
+			http.Error(w, "volumes is only valid for down", http.StatusBadRequest)
+			return 0, "", opts, false
+		}
+		if !middleware.IsAdmin(middleware.CurrentUser(r.Context())) {
+			http.Error(w, "removing volumes is admin only", http.StatusForbidden)
+			return 0, "", opts, false
+		}
+		if services.IsSwarmStack(r.Context(), stackID) {
+			http.Error(w, "volumes is not supported for swarm stacks", http.StatusBadRequest)
+			return 0, "", opts, false
+		}
+		if r.URL.Query().Get("confirm") != chi.URLParam(r, "stackname") {
+			http.Error(w, "removing volumes requires confirm=<stack name>", http.StatusPreconditionRequired)
+			return 0, "", opts, false
+		}
+		opts.RemoveVolumes = true
+	}
+	opts.User = middleware.GetUserEmail(r.Context())
+	return stackID, op, opts, true
+}
+
+// handleStackOperation runs a whole-stack operation in the background
+func handleStackOperation(w http.ResponseWriter, r *http.Request) {
+	stackID, op, opts, ok := stackOperationFromRoute(w, r)
+	if !ok {
+		return
+	}
+	go func() {
+		if err := services.RunStackOperation(context.Background(), stackID, op, opts); err != nil {
+			common.ErrorLog("stack op: %s of stack %d failed: %v", op, stackID, err)
+		}
+	}()
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":    "accepted",
+		"stackID":   stackID,
+		"operation": op,
+	})
+}
+
+// handleStackOperationStream runs a whole-stack operation and streams its output as SSE
+func handleStackOperationStream(w http.ResponseWriter, r *http.Request) {
+	stackID, op, opts, ok := stackOperationFromRoute(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	eventChan := make(chan map[string]interface{}, 100)
+	go func() {
+		if err := services.RunStackOperationWithStream(r.Context(), stackID, op, opts, eventChan); err != nil {
+			common.ErrorLog("stack op stream: %s of stack %d failed: %v", op, stackID, err)
+		}
+	}()
+	for event := range eventChan {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "data: %s\n\n", string(data))
+		flusher.Flush()
+	}
+}
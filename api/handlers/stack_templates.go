@@ -0,0 +1,101 @@
+// handlers/stack_templates.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// handleStackTemplatesList returns the template catalog
+// GET /api/iac/templates
+func handleStackTemplatesList(w http.ResponseWriter, r *http.Request) {
+	list, err := services.ListStackTemplates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"templates": list})
+}
+
+// handleStackTemplateGet returns one template with its parameters and files
+// GET /api/iac/templates/{name}
+func handleStackTemplateGet(w http.ResponseWriter, r *http.Request) {
+	t, err := services.GetStackTemplate(chi.URLParam(r, "name"))
+	if err != nil {
+		if errors.Is(err, services.ErrTemplateNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"template": t})
+}
+
+// handleStackTemplateInstantiate renders a template into a new stack and optionally deploys it
+// POST /api/iac/templates/{name}/instantiate
+// body: { "scope_name": "host1", "stack_name": "db", "params": {...}, "deploy": false }
+func handleStackTemplateInstantiate(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	var body services.TemplateInstantiateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+
+	user := middleware.GetUserEmail(r.Context())
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+	res, err := services.InstantiateStackTemplate(ctx, name, body)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrTemplateNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrTemplateExists):
+			status = http.StatusConflict
+		case errors.Is(err, services.ErrTemplateInvalid):
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	common.InfoLog("templates: %s instantiated %s as %s/%s", user, res.Template, res.ScopeName, res.StackName)
+
+	// Trigger git push if configured (same as file saves)
+	config, _ := database.GetGitSyncConfig(r.Context())
+	if config != nil && config.SyncEnabled && (config.SyncMode == "push" || config.SyncMode == "sync") {
+		go func() {
+			message := fmt.Sprintf("Created %s/%s from template %s", res.ScopeName, res.StackName, res.Template)
+			if err := services.GetGitSync().Push(context.Background(), message, user); err != nil {
+				common.ErrorLog("Failed to push changes after template instantiate: %v", err)
+			}
+		}()
+	}
+
+	status := http.StatusOK
+	if res.Deploy {
+		// Deploy in background (manual: bypasses Auto DevOps gating)
+		go func(id int64) {
+			ctx := context.WithValue(context.Background(), services.CtxManualKey{}, true)
+			if err := services.DeployStack(ctx, id); err != nil {
+				common.ErrorLog("deploy: stack %d (template %s) failed: %v", id, res.Template, err)
+				return
+			}
+			common.InfoLog("deploy: stack %d (template %s) ok", id, res.Template)
+		}(res.StackID)
+		status = http.StatusAccepted
+	}
+	writeJSON(w, status, res)
+}
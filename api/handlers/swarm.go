@@ -0,0 +1,66 @@
+// handlers/swarm.go
+package handlers
+
+import (
+	"net/http"
+
+	"dd-ui/database"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// SetupSwarmRoutes configures swarm inventory routes for hosts flagged dd_ui_swarm_manager:
+// - /api/swarm/hosts/{hostname}                       nodes + services (from the last scan)
+// - /api/swarm/hosts/{hostname}/tasks                 tasks (filter: service)
+func SetupSwarmRoutes(router chi.Router) {
+	router.Route("/swarm/hosts/{hostname}", func(r chi.Router) {
+		r.Get("/", handleSwarmHost)
+		r.Get("/tasks", handleSwarmTasks)
+	})
+}
+
+func swarmManagerHost(w http.ResponseWriter, r *http.Request) (database.HostRow, bool) {
+	h, err := database.GetHostByName(r.Context(), chi.URLParam(r, "hostname"))
+	if err != nil {
+		http.Error(w, "host not found", http.StatusNotFound)
+		return h, false
+	}
+	if !services.IsSwarmManager(h) {
+		http.Error(w, "host is not flagged as a swarm manager (dd_ui_swarm_manager)", http.StatusBadRequest)
+		return h, false
+	}
+	return h, true
+}
+
+// handleSwarmHost returns the swarm nodes and services seen from a manager host
+func handleSwarmHost(w http.ResponseWriter, r *http.Request) {
+	h, ok := swarmManagerHost(w, r)
+	if !ok {
+		return
+	}
+	nodes, err := database.ListSwarmNodes(r.Context(), h.ID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	svcs, err := database.ListSwarmServices(r.Context(), h.ID, r.URL.Query().Get("namespace"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"host": h.Name, "nodes": nodes, "services": svcs})
+}
+
+// handleSwarmTasks returns tasks seen from a manager host
+func handleSwarmTasks(w http.ResponseWriter, r *http.Request) {
+	h, ok := swarmManagerHost(w, r)
+	if !ok {
+		return
+	}
+	tasks, err := database.ListSwarmTasks(r.Context(), h.ID, r.URL.Query().Get("service"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"host": h.Name, "tasks": tasks})
+}
@@ -160,10 +160,17 @@ func SetupSystemRoutes(router chi.Router) {
 		limit := clamp(parseIntDefault(r.URL.Query().Get("limit"), 200), 1, 1000)
 		offset := parseIntDefault(r.URL.Query().Get("offset"), 0)
 
+		state := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("state")))
+
 		// Convert and filter hosts
 		filtered := make([]map[string]interface{}, 0, len(hosts))
 		for _, h := range hosts {
+			status := services.GetHostStatus(h.Name)
+
 			// Apply filters
+			if state != "" && status.State != state {
+				continue
+			}
 			if owner != "" && !strings.EqualFold(h.Owner, owner) {
 				continue
 			}
@@ -205,6 +212,10 @@ func SetupSystemRoutes(router chi.Router) {
 				"owner":         h.Owner,
 				"env":           h.Env,
 				"labels":        map[string]string{}, // For compatibility
+				"state":         status.State,
+				"last_seen":     status.LastSeen,
+				"last_error":    status.LastError,
+				"latency_ms":    status.LatencyMs,
 			})
 		}
 		
@@ -0,0 +1,244 @@
+// handlers/webhooks.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/middleware"
+	"dd-ui/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// SetupWebhookRoutes configures outbound webhook routes (admins only):
+// - /api/webhooks                                  list, create
+// - /api/webhooks/{id}                             get, update, delete
+// - /api/webhooks/{id}/test                        queue a webhook.ping delivery
+// - /api/webhooks/{id}/deliveries                  delivery log (?limit=, default 50)
+// - /api/webhooks/{id}/deliveries/{delivery}/redeliver   retry a delivery now
+func SetupWebhookRoutes(router chi.Router) {
+	router.Group(func(admin chi.Router) {
+		admin.Use(middleware.RequireAdmin)
+		admin.Get("/webhooks", handleWebhooksList)
+		admin.Post("/webhooks", handleWebhookCreate)
+		admin.Get("/webhooks/{id}", handleWebhookGet)
+		admin.Patch("/webhooks/{id}", handleWebhookUpdate)
+		admin.Delete("/webhooks/{id}", handleWebhookDelete)
+		admin.Post("/webhooks/{id}/test", handleWebhookTest)
+		admin.Get("/webhooks/{id}/deliveries", handleWebhookDeliveries)
+		admin.Post("/webhooks/{id}/deliveries/{delivery}/redeliver", handleWebhookRedeliver)
+	})
+}
+
+// webhookRequest is the create/update body; nil fields are left unchanged on update
+type webhookRequest struct {
+	Name         *string   `json:"name"`
+	URL          *string   `json:"url"`
+	Secret       *string   `json:"secret"`
+	Events       *[]string `json:"events"`
+	Enabled      *bool     `json:"enabled"`
+	RotateSecret bool      `json:"rotate_secret"`
+}
+
+func handleWebhooksList(w http.ResponseWriter, r *http.Request) {
+	items, err := database.ListWebhooks(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list webhooks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// loadWebhook resolves {id}, writing 404/400 itself
+func loadWebhook(w http.ResponseWriter, r *http.Request) (database.WebhookRow, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad webhook id", http.StatusBadRequest)
+		return database.WebhookRow{}, false
+	}
+	hook, err := database.GetWebhook(r.Context(), id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return hook, false
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to load webhook: %v", err), http.StatusInternalServerError)
+		return hook, false
+	}
+	return hook, true
+}
+
+func handleWebhookGet(w http.ResponseWriter, r *http.Request) {
+	if hook, ok := loadWebhook(w, r); ok {
+		writeJSON(w, http.StatusOK, hook)
+	}
+}
+
+func handleWebhookCreate(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	hook := database.WebhookRow{Enabled: true, CreatedBy: middleware.GetUserEmail(r.Context())}
+	if req.URL == nil {
+		http.Error(w, "url required", http.StatusBadRequest)
+		return
+	}
+	if req.Secret == nil || strings.TrimSpace(*req.Secret) == "" {
+		req.RotateSecret = true
+	}
+	saveWebhook(w, r, hook, req)
+}
+
+func handleWebhookUpdate(w http.ResponseWriter, r *http.Request) {
+	hook, ok := loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad json", http.StatusBadRequest)
+		return
+	}
+	saveWebhook(w, r, hook, req)
+}
+
+// saveWebhook applies req to hook and stores it. The secret is only returned when it was
+// generated or rotated here.
+func saveWebhook(w http.ResponseWriter, r *http.Request, hook database.WebhookRow, req webhookRequest) {
+	if req.Name != nil {
+		hook.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		hook.URL = strings.TrimSpace(*req.URL)
+	}
+	if err := services.ValidateWebhookURL(r.Context(), hook.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if hook.Name == "" {
+		hook.Name = hook.URL
+	}
+	if req.Events != nil {
+		hook.EventTypes = []string{}
+		for _, e := range *req.Events {
+			if e = strings.TrimSpace(e); e != "" {
+				hook.EventTypes = append(hook.EventTypes, e)
+			}
+		}
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	var revealed string
+	switch {
+	case req.RotateSecret:
+		secret, err := services.NewWebhookSecret()
+		if err != nil {
+			http.Error(w, "failed to generate secret", http.StatusInternalServerError)
+			return
+		}
+		hook.Secret, revealed = secret, secret
+	case req.Secret != nil && strings.TrimSpace(*req.Secret) != "":
+		hook.Secret = strings.TrimSpace(*req.Secret)
+	}
+
+	created := hook.ID == 0
+	id, err := database.SaveWebhook(r.Context(), hook)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to save webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+	hook.ID = id
+	common.InfoLog("webhooks: %s saved webhook %d (%s) events=%v enabled=%v",
+		middleware.GetUserEmail(r.Context()), hook.ID, hook.URL, hook.EventTypes, hook.Enabled)
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	out := map[string]any{"webhook": hook}
+	if revealed != "" {
+		out["secret"] = revealed // shown once
+	}
+	writeJSON(w, status, out)
+}
+
+func handleWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad webhook id", http.StatusBadRequest)
+		return
+	}
+	ok, err := database.DeleteWebhook(r.Context(), id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	common.InfoLog("webhooks: %s deleted webhook %d", middleware.GetUserEmail(r.Context()), id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleWebhookTest(w http.ResponseWriter, r *http.Request) {
+	hook, ok := loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	did, err := services.PingWebhook(r.Context(), hook, middleware.GetUserEmail(r.Context()))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to queue ping: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"delivery_id": did})
+}
+
+func handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	hook, ok := loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	limit := clamp(parseIntDefault(r.URL.Query().Get("limit"), 50), 1, 500)
+	items, err := database.ListWebhookDeliveries(r.Context(), hook.ID, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list deliveries: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+func handleWebhookRedeliver(w http.ResponseWriter, r *http.Request) {
+	hook, ok := loadWebhook(w, r)
+	if !ok {
+		return
+	}
+	did, err := strconv.ParseInt(chi.URLParam(r, "delivery"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad delivery id", http.StatusBadRequest)
+		return
+	}
+	if err := database.RequeueWebhookDelivery(r.Context(), hook.ID, did); errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "delivery not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, fmt.Sprintf("failed to requeue delivery: %v", err), http.StatusInternalServerError)
+		return
+	}
+	services.WakeWebhookDispatcher()
+	writeJSON(w, http.StatusAccepted, map[string]any{"delivery_id": did})
+}
@@ -88,7 +88,7 @@ func main() {
 	// Initialize auth from environment
 	localSessionManager, err := InitAuthFromEnv()
 	if err != nil {
-		fatalLog("OIDC setup failed: %v", err)
+		fatalLog("auth setup failed: %v", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -97,22 +97,57 @@ func main() {
 	if err := database.InitDBFromEnv(ctx); err != nil {
 		fatalLog("DB init failed: %v", err)
 	}
+	UseSessionStore(ctx)
+	if pw, err := envOrFile("DD_UI_LOCAL_ADMIN_PASSWORD", "DD_UI_LOCAL_ADMIN_PASSWORD_FILE"); err != nil {
+		errorLog("local admin password: %v", err)
+	} else if err := services.BootstrapLocalAdmin(ctx, pw); err != nil {
+		errorLog("local admin bootstrap failed: %v", err)
+	}
+	services.InitKnownHosts()
 	if err := services.InitInventory(); err != nil {
 		fatalLog("inventory init failed: %v", err)
 	}
 
-	// Start inventory file watcher for auto-reload
+	// Start inventory file watcher for auto-reload (every replica serves the API from its
+	// own in-memory inventory, so this one runs regardless of leadership)
 	services.StartInventoryWatcher(ctx)
 
-	// Initialize Git sync service
+	// Relay events published by any replica to this replica's /api/events streams
+	services.StartEventListener(ctx)
+
+	// Background loops run on the elected leader only; every replica serves the API
 	gitSync := services.GetGitSync()
-	if err := gitSync.Initialize(ctx); err != nil {
-		warnLog("Git sync initialization failed (feature disabled): %v", err)
-	}
+	services.StartLeaderElection(ctx, func(lctx context.Context) {
+		// Prune console session recordings past retention
+		services.StartExecRecordingRetention(lctx)
+
+		// Prune scan_logs past retention
+		services.StartScanLogRetention(lctx)
+
+		// Deliver queued webhook events and prune old events
+		services.StartWebhookDispatcher(lctx)
+
+		// Record Docker container lifecycle events and detect restart loops / repeated OOMs
+		services.StartContainerEventWatchers(lctx)
+
+		// Collect host facts (engine, OS, memory, disk, uptime) periodically
+		services.StartHostFactsCollector(lctx)
+
+		// Initialize Git sync service (may clone, so off the election goroutine)
+		go func() {
+			if err := gitSync.Initialize(lctx); err != nil {
+				warnLog("Git sync initialization failed (feature disabled): %v", err)
+			}
+		}()
 
-	// kick off background auto-scanner (Portainer-ish cadence)
-	startAutoScanner(ctx)
-	startIacAutoScanner(ctx)
+		// kick off background auto-scanner (Portainer-ish cadence)
+		startAutoScanner(lctx)
+		startIacAutoScanner(lctx)
+	}, func() {
+		gitSync.Stop()
+		// agent sessions are only served by the leader; make agents reconnect to the new one
+		services.DisconnectAllAgents()
+	})
 
 	r := makeRouter()
 	
@@ -281,8 +316,10 @@ func startSmartScanLoop(ctx context.Context, baseInterval, boostInterval, perHos
 	
 	// Function to scan a specific host
 	scanHost := func(hostName string) {
-		// Scan this specific host
-		if saved, err := services.ScanHostContainers(ctx, hostName); err != nil {
+		// Scan this specific host (bounded, so an unreachable host cannot hold the loop)
+		hctx, cancel := context.WithTimeout(ctx, perHostTO)
+		defer cancel()
+		if saved, err := services.ScanHostContainers(hctx, hostName); err != nil {
 			if !errors.Is(err, services.ErrSkipScan) {
 				debugLog("Smart scan failed for host %s: %v", hostName, err)
 			}
@@ -302,9 +339,9 @@ func startSmartScanLoop(ctx context.Context, baseInterval, boostInterval, perHos
 			timer.Stop()
 		}
 		
-		// Choose interval based on view boost
-		interval := baseInterval
-		if viewBoostTracker.ShouldBoostHost(hostName) {
+		// Choose interval based on view boost; failing hosts back off exponentially instead
+		interval := services.HostScanDelay(hostName, baseInterval)
+		if interval == baseInterval && viewBoostTracker.ShouldBoostHost(hostName) {
 			interval = boostInterval
 		}
 		
@@ -353,9 +390,8 @@ func startIacAutoScanner(ctx context.Context) {
 		return
 	}
 	interval := envDur("DD_UI_SCAN_IAC_INTERVAL", "90s") // default 1m30s
-	infoLog("iac: auto enabled interval=%s", interval)
 
-	// initial scan on boot (non-fatal)
+	// initial scan on boot (non-fatal); converges every auto-applied stack once
 	go func() {
 		if _, _, err := services.ScanIacLocal(ctx); err != nil {
 			errorLog("iac: initial scan failed: %v", err)
@@ -365,18 +401,34 @@ func startIacAutoScanner(ctx context.Context) {
 		}
 	}()
 
+	// stacks deferred while their host was offline are applied as soon as it is back
+	services.OnHostBackOnline(func(host string) {
+		if ctx.Err() == nil {
+			applyPendingForHost(ctx, host)
+		}
+	})
+
+	// inotify rescans only the changed stack directory; polling stays as the fallback
+	if services.StartIacWatcher(ctx, applyAutoDevOpsStacks) {
+		interval = envDur("DD_UI_SCAN_IAC_FALLBACK_INTERVAL", "10m")
+	}
+	infoLog("iac: auto enabled interval=%s", interval)
+
 	t := time.NewTicker(interval)
 	go func() {
 		defer t.Stop()
 		for {
 			select {
 			case <-t.C:
+				unlock := services.LockIacRescan()
 				if _, _, err := services.ScanIacLocal(ctx); err != nil {
 					errorLog("iac: periodic scan failed: %v", err)
 				}
-				if err := applyAutoDevOps(ctx); err != nil {
-					errorLog("iac: apply failed: %v", err)
-				}
+				current, _ := services.SnapshotIacBundleHashes(ctx)
+				unlock()
+				// only stacks whose bundle differs from their last successful deploy are (re)applied;
+				// failed and skipped deploys stay pending and are retried here
+				applyAutoDevOpsStacks(ctx, services.PendingApplyStacks(ctx, current))
 			case <-ctx.Done():
 				infoLog("iac: auto scanner stopping: %v", ctx.Err())
 				return
@@ -385,6 +437,24 @@ func startIacAutoScanner(ctx context.Context) {
 	}()
 }
 
+// applyPendingForHost applies the pending stacks (see services.PendingApplyStacks) that deploy
+// to host
+func applyPendingForHost(ctx context.Context, host string) {
+	unlock := services.LockIacRescan()
+	current, _ := services.SnapshotIacBundleHashes(ctx)
+	unlock()
+	var ids []int64
+	for _, id := range services.PendingApplyStacks(ctx, current) {
+		if name, ok := services.StackHostName(ctx, id); ok && name == host {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		infoLog("devops: host %s is back online, applying %d deferred stack(s)", host, len(ids))
+		applyAutoDevOpsStacks(ctx, ids)
+	}
+}
+
 /* --- Auto DevOps evaluator:
      - Default disabled.
      - If .env DD_UI_DEVOPS_APPLY is present at stack > host > global, it overrides DB flag.
@@ -397,14 +467,23 @@ func applyAutoDevOps(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-
+	var ids []int64
 	for rows.Next() {
 		var id int64
 		if err := rows.Scan(&id); err != nil {
 			continue
 		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	applyAutoDevOpsStacks(ctx, ids)
+	return nil
+}
 
+// applyAutoDevOpsStacks deploys the given stacks where the effective Auto DevOps policy allows it
+func applyAutoDevOpsStacks(ctx context.Context, ids []int64) {
+	for _, id := range ids {
 		// Must have content (compose/services) or there's nothing to deploy
 		has, _ := services.StackHasContent(ctx, id)
 		if !has {
@@ -417,12 +496,18 @@ func applyAutoDevOps(ctx context.Context) error {
 			continue
 		}
 
+		// Don't wait out the deploy timeout against a host the scanner knows is down; the stack
+		// stays pending (no successful stamp) and is applied by a scan after the host is back
+		if host, offline := services.StackHostOffline(ctx, id); offline {
+			infoLog("devops: defer stack %d, host %s is offline", id, host)
+			continue
+		}
+
 		// Kick the deploy (manual=false -> gated in deployStack, which is fine)
 		dctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 		_ = services.DeployStack(dctx, id) // best effort; idempotent for compose
 		cancel()
 	}
-	return nil
 }
 
 /* -------- TLS self-signed helper -------- */
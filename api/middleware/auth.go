@@ -15,8 +15,15 @@ type User struct {
 	Email string `json:"email"`
 	Name  string `json:"name"`
 	Pic   string `json:"picture,omitempty"`
+
+	Provider   string `json:"provider,omitempty"`    // "local" for built-in accounts, empty for OIDC
+	BreakGlass bool   `json:"break_glass,omitempty"` // local login allowed only because OIDC was down
 }
 
+// BreakGlassAllowed, when set, is asked whether break-glass sessions may still be used;
+// false (OIDC is back) rejects them
+var BreakGlassAllowed func(ctx context.Context) bool
+
 // Context key type
 type ctxKey string
 
@@ -56,6 +63,18 @@ func RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 		
+		if u.BreakGlass && BreakGlassAllowed != nil && !BreakGlassAllowed(r.Context()) {
+			common.InfoLog("AUTH MIDDLEWARE: break-glass session of %s ended, OIDC is available", u.Email)
+			_ = common.SessionManager.Destroy(r.Context())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status": "error",
+				"message": "Single sign-on is available again. Please log in with it.",
+			})
+			return
+		}
+
 		common.DebugLog("AUTH MIDDLEWARE: Access granted for user: %s", u.Email)
 		ctx := context.WithValue(r.Context(), UserKey, u)
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+// sessionTouchEvery bounds writes of last_seen/IP/user agent per session
+const sessionTouchEvery = time.Minute
+
+var (
+	sessionTouchMu sync.Mutex
+	sessionTouched = map[string]time.Time{} // token -> last write
+)
+
+// TrackSessionActivity records the owner, client IP, user agent and last use of the
+// caller's session for the sessions API. Runs after RequireAuth; no-op with the memory store.
+func TrackSessionActivity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		TouchSession(r, CurrentUser(r.Context()))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TouchSession writes the session metadata at most once per sessionTouchEvery
+func TouchSession(r *http.Request, u User) {
+	sm := common.SessionManager
+	if sm == nil || u.Sub == "" {
+		return
+	}
+	if _, ok := sm.Store.(database.SessionStore); !ok {
+		return
+	}
+	token := sm.Token(r.Context())
+	if token == "" {
+		return
+	}
+	now := time.Now()
+	sessionTouchMu.Lock()
+	if now.Sub(sessionTouched[token]) < sessionTouchEvery {
+		sessionTouchMu.Unlock()
+		return
+	}
+	if len(sessionTouched) > 10000 {
+		sessionTouched = map[string]time.Time{}
+	}
+	sessionTouched[token] = now
+	sessionTouchMu.Unlock()
+
+	meta := database.SessionMeta{
+		SID:       sm.GetString(r.Context(), "sid"),
+		UserSub:   u.Sub,
+		UserEmail: u.Email,
+		UserName:  u.Name,
+		Provider:  u.Provider,
+		IP:        ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := database.TouchSession(ctx, token, meta); err != nil {
+			common.DebugLog("session: touch failed: %v", err)
+		}
+	}()
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For and X-Real-IP are only believed
+// when the connection comes from a proxy listed in DD_UI_TRUSTED_PROXIES (comma-separated IPs
+// or CIDRs); X-Forwarded-For is then read right to left, skipping further trusted proxies.
+func ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	trusted := trustedProxies()
+	if !trusted.contains(remote) {
+		return remote
+	}
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(hops[i])
+			if net.ParseIP(ip) == nil {
+				break // garbage from the client side of the chain
+			}
+			client = ip
+			if !trusted.contains(ip) {
+				break
+			}
+		}
+		if client != "" {
+			return client
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return remote
+}
+
+type proxyList []*net.IPNet
+
+func (l proxyList) contains(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range l {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+var trustedProxyCache struct {
+	sync.Mutex
+	raw  string
+	list proxyList
+}
+
+// trustedProxies parses DD_UI_TRUSTED_PROXIES, re-parsing only when it changes
+func trustedProxies() proxyList {
+	raw := strings.TrimSpace(common.Env("DD_UI_TRUSTED_PROXIES", ""))
+	trustedProxyCache.Lock()
+	defer trustedProxyCache.Unlock()
+	if raw == trustedProxyCache.raw {
+		return trustedProxyCache.list
+	}
+	var list proxyList
+	for _, e := range strings.Split(raw, ",") {
+		if e = strings.TrimSpace(e); e == "" {
+			continue
+		}
+		if !strings.Contains(e, "/") {
+			if ip := net.ParseIP(e); ip != nil && ip.To4() != nil {
+				e += "/32"
+			} else {
+				e += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			common.WarnLog("session: ignoring invalid DD_UI_TRUSTED_PROXIES entry %q", e)
+			continue
+		}
+		list = append(list, n)
+	}
+	trustedProxyCache.raw, trustedProxyCache.list = raw, list
+	return list
+}
+
+// IsAdmin reports whether u may use admin routes: DD_UI_ADMINS lists admin emails or subjects
+// (comma-separated). Unset, only the bootstrap local account (DD_UI_LOCAL_ADMIN_USER) is one.
+func IsAdmin(u User) bool {
+	if u.Sub == "" {
+		return false
+	}
+	list := strings.TrimSpace(common.Env("DD_UI_ADMINS", ""))
+	if list == "" {
+		return u.Provider == "local" && u.Sub == bootstrapAdminSub()
+	}
+	for _, a := range strings.Split(list, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if a == u.Sub || (u.Email != "" && strings.EqualFold(a, u.Email)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminsConfigProblem describes who is left without admin access when DD_UI_ADMINS is unset
+// and OIDC users log in; "" when DD_UI_ADMINS is set. localAccounts reports whether built-in
+// accounts (and so the bootstrap admin) are enabled.
+func AdminsConfigProblem(localAccounts bool) string {
+	if strings.TrimSpace(common.Env("DD_UI_ADMINS", "")) != "" {
+		return ""
+	}
+	if localAccounts {
+		return "DD_UI_ADMINS is unset: only the bootstrap local account (DD_UI_LOCAL_ADMIN_USER) is an admin, " +
+			"OIDC users cannot use admin routes (webhooks, session management, exec recordings, down with volumes)"
+	}
+	return "DD_UI_ADMINS is unset and local accounts are off: nobody can use admin routes (webhooks, " +
+		"session management, exec recordings, down with volumes); set DD_UI_ADMINS to admin emails or subjects"
+}
+
+var bootstrapAdmin struct {
+	sync.Mutex
+	username, sub string
+}
+
+// bootstrapAdminSub returns "local:<id>" of the DD_UI_LOCAL_ADMIN_USER account, or "" when it
+// does not exist; a found id is cached
+func bootstrapAdminSub() string {
+	username := strings.TrimSpace(common.Env("DD_UI_LOCAL_ADMIN_USER", "admin"))
+	bootstrapAdmin.Lock()
+	defer bootstrapAdmin.Unlock()
+	if bootstrapAdmin.sub != "" && bootstrapAdmin.username == username {
+		return bootstrapAdmin.sub
+	}
+	if common.DB == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lu, err := database.GetLocalUser(ctx, username)
+	if err != nil {
+		return ""
+	}
+	bootstrapAdmin.username, bootstrapAdmin.sub = username, "local:"+strconv.FormatInt(lu.ID, 10)
+	return bootstrapAdmin.sub
+}
+
+// RequireAdmin rejects non-admins with 403; use inside a RequireAuth group
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(CurrentUser(r.Context())) {
+			http.Error(w, "admin only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
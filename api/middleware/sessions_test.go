@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted string
+		remote  string
+		xff     []string
+		realIP  string
+		want    string
+	}{
+		{"no proxies trusted ignores headers", "", "203.0.113.7:5000", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.7"},
+		{"untrusted peer ignores headers", "10.0.0.0/8", "203.0.113.7:5000", []string{"198.51.100.1"}, "", "203.0.113.7"},
+		{"trusted proxy forwards client", "10.0.0.0/8", "10.0.0.5:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"spoofed left entry skipped", "10.0.0.0/8", "10.0.0.5:5000", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"chained trusted proxies", "10.0.0.0/8,192.0.2.10", "10.0.0.5:5000", []string{"198.51.100.1, 192.0.2.10, 10.1.1.1"}, "", "198.51.100.1"},
+		{"repeated header lines", "10.0.0.0/8", "10.0.0.5:5000", []string{"1.2.3.4", "198.51.100.1"}, "", "198.51.100.1"},
+		{"garbage stops the walk", "10.0.0.0/8", "10.0.0.5:5000", []string{"198.51.100.1, junk, 10.2.2.2"}, "", "10.2.2.2"},
+		{"x-real-ip from trusted proxy", "10.0.0.5", "10.0.0.5:5000", nil, "198.51.100.9", "198.51.100.9"},
+		{"invalid x-real-ip ignored", "10.0.0.5", "10.0.0.5:5000", nil, "nope", "10.0.0.5"},
+		{"ipv6 proxy", "fd00::/8", "[fd00::1]:443", []string{"2001:db8::2"}, "", "2001:db8::2"},
+		{"invalid entries ignored", "nonsense,10.0.0.5", "10.0.0.5:5000", []string{"198.51.100.1"}, "", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DD_UI_TRUSTED_PROXIES", tt.trusted)
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Fatalf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	oidcUser := User{Sub: "abc", Email: "Ops@Example.com"}
+	localUser := User{Sub: "local:1", Email: "", Provider: "local"}
+	tests := []struct {
+		name   string
+		admins string
+		user   User
+		want   bool
+	}{
+		{"unset denies oidc users", "", oidcUser, false},
+		{"unset denies anonymous", "", User{}, false},
+		{"email match is case-insensitive", "ops@example.com", oidcUser, true},
+		{"subject match", " x, abc ", oidcUser, true},
+		{"local subject match", "local:1", localUser, true},
+		{"not listed", "someone@example.com", oidcUser, false},
+		{"empty email never matches", ",", localUser, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DD_UI_ADMINS", tt.admins)
+			if got := IsAdmin(tt.user); got != tt.want {
+				t.Fatalf("IsAdmin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminsConfigProblemOIDCOnly(t *testing.T) {
+	t.Setenv("DD_UI_ADMINS", "")
+	// OIDC-only: nobody is an admin, and startup must say so
+	if IsAdmin(User{Sub: "oidc-sub", Email: "ops@example.com"}) {
+		t.Fatal("OIDC user is admin without DD_UI_ADMINS")
+	}
+	if msg := AdminsConfigProblem(false); !strings.Contains(msg, "nobody") {
+		t.Fatalf("OIDC-only problem = %q, want it to say nobody is an admin", msg)
+	}
+	if msg := AdminsConfigProblem(true); !strings.Contains(msg, "bootstrap") {
+		t.Fatalf("with local accounts problem = %q, want it to name the bootstrap account", msg)
+	}
+
+	t.Setenv("DD_UI_ADMINS", "ops@example.com")
+	if msg := AdminsConfigProblem(false); msg != "" {
+		t.Fatalf("problem with DD_UI_ADMINS set = %q", msg)
+	}
+	if !IsAdmin(User{Sub: "oidc-sub", Email: "ops@example.com"}) {
+		t.Fatal("listed OIDC user is not admin")
+	}
+}
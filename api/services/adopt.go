@@ -0,0 +1,528 @@
+// services/adopt.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/goccy/go-yaml"
+)
+
+/*
+Adopt: turn a compose project that is running on a host but has no IaC files
+into a managed stack, without touching the running containers.
+
+  1. inspect every container labelled com.docker.compose.project=<project>
+  2. rebuild a compose file (image, env, ports, volumes, networks, labels,
+     restart policy, healthcheck, command/entrypoint when they differ from the image)
+  3. move secret-looking env values into a SOPS-encrypted .env and reference
+     them as ${VAR} from the compose file
+  4. write docker-compose/<host>/<project>/, register the IaC stack + files,
+     record an "adopt" deployment stamp and baseline the drift cache from the
+     live containers so the stack shows in sync.
+
+Auto DevOps is pinned off for the adopted stack (stack-level override) so the
+next reconcile loop does not recreate containers; review the files and clear
+the override when ready.
+*/
+
+// AdoptOptions tune an adopt operation
+type AdoptOptions struct {
+	Overwrite      bool `json:"overwrite"`        // replace existing files in the stack dir
+	AllowPlaintext bool `json:"allow_plaintext"`  // write .env unencrypted if SOPS is unavailable
+	KeepAutoDevops bool `json:"keep_auto_devops"` // don't pin Auto DevOps off for the new stack
+}
+
+// AdoptResult describes what was written
+type AdoptResult struct {
+	StackID     int64    `json:"stack_id"`
+	Host        string   `json:"host"`
+	Project     string   `json:"project"`
+	RelPath     string   `json:"rel_path"`
+	Services    []string `json:"services"`
+	Files       []string `json:"files"`
+	SecretKeys  []string `json:"secret_keys,omitempty"`
+	EnvSops     bool     `json:"env_sops"`
+	BundleHash  string   `json:"bundle_hash"`
+	StampID     int64    `json:"stamp_id,omitempty"`
+	ComposeYAML string   `json:"compose_yaml,omitempty"`
+}
+
+var (
+	ErrAdoptNoContainers = errors.New("no containers found for compose project")
+	ErrAdoptExists       = errors.New("stack directory already has IaC files")
+
+	secretKeyRe = regexp.MustCompile(`(?i)(pass(word|wd)?|secret|token|api[_-]?key|private[_-]?key|access[_-]?key|credential|auth|salt|(^|_)key$|dsn)`)
+	credURLRe   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)
+)
+
+// looksSecretEnv decides whether an env var should go to the encrypted .env
+func looksSecretEnv(key, val string) bool {
+	if val == "" {
+		return false
+	}
+	return secretKeyRe.MatchString(key) || credURLRe.MatchString(val)
+}
+
+// AdoptComposeProject reconstructs IaC for a running compose project on a host
+func AdoptComposeProject(ctx context.Context, hostName, project, user string, opts AdoptOptions) (*AdoptResult, error) {
+	project = strings.TrimSpace(project)
+	if project == "" || strings.ContainsAny(project, `/\`) || project == "." || project == ".." {
+		return nil, fmt.Errorf("invalid project name %q", project)
+	}
+
+	h, err := database.GetHostByName(ctx, hostName)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := DockerClientForHost(h)
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	ff := filters.NewArgs()
+	ff.Add("label", "com.docker.compose.project="+project)
+	list, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: ff})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrAdoptNoContainers
+	}
+
+	root := strings.TrimSpace(common.Env(IacDefaultRootEnv, IacDefaultRoot))
+	dirname := strings.TrimSpace(common.Env(DockerDirEnv, DefaultDockerDir))
+	relPath := filepath.ToSlash(filepath.Join(dirname, h.Name, project))
+	stackDir, err := joinUnderLocal(root, relPath)
+	if err != nil {
+		return nil, err
+	}
+	composePath := filepath.Join(stackDir, "docker-compose.yml")
+	envPath := filepath.Join(stackDir, ".env")
+	if !opts.Overwrite {
+		for _, p := range []string{composePath, envPath, filepath.Join(stackDir, "compose.yml"), filepath.Join(stackDir, "compose.yaml"), filepath.Join(stackDir, "docker-compose.yaml")} {
+			if _, err := os.Stat(p); err == nil {
+				return nil, ErrAdoptExists
+			}
+		}
+	}
+
+	// ---- Build compose model ----
+	services := yaml.MapSlice{}
+	networks := map[string]yaml.MapSlice{}
+	volumes := map[string]yaml.MapSlice{}
+	secrets := map[string]string{} // VAR -> value
+	var serviceNames, containerIDs []string
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Labels["com.docker.compose.service"] < list[j].Labels["com.docker.compose.service"]
+	})
+	seen := map[string]bool{}
+	for _, c := range list {
+		svcName := c.Labels["com.docker.compose.service"]
+		if svcName == "" || seen[svcName] {
+			// Scaled replicas share one service definition
+			containerIDs = append(containerIDs, c.ID)
+			continue
+		}
+		seen[svcName] = true
+		containerIDs = append(containerIDs, c.ID)
+		serviceNames = append(serviceNames, svcName)
+
+		ins, err := cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("inspect %s: %w", c.ID[:12], err)
+		}
+		imgEnv := map[string]string{}
+		imgLabels := map[string]string{}
+		var imgCmd, imgEntry []string
+		var imgHealth *container.HealthConfig
+		if img, err := cli.ImageInspect(ctx, ins.Image); err == nil && img.Config != nil {
+			for _, kv := range img.Config.Env {
+				k, v, _ := strings.Cut(kv, "=")
+				imgEnv[k] = v
+			}
+			imgLabels = img.Config.Labels
+			imgCmd = img.Config.Cmd
+			imgEntry = img.Config.Entrypoint
+			imgHealth = img.Config.Healthcheck
+		}
+
+		svc := yaml.MapSlice{{Key: "image", Value: ins.Config.Image}}
+
+		name := strings.TrimPrefix(ins.Name, "/")
+		if name != fmt.Sprintf("%s-%s-1", project, svcName) && name != fmt.Sprintf("%s_%s_1", project, svcName) {
+			svc = append(svc, yaml.MapItem{Key: "container_name", Value: name})
+		}
+		if !slices.Equal(ins.Config.Entrypoint, imgEntry) && len(ins.Config.Entrypoint) > 0 {
+			svc = append(svc, yaml.MapItem{Key: "entrypoint", Value: []string(ins.Config.Entrypoint)})
+		}
+		if !slices.Equal(ins.Config.Cmd, imgCmd) && len(ins.Config.Cmd) > 0 {
+			svc = append(svc, yaml.MapItem{Key: "command", Value: []string(ins.Config.Cmd)})
+		}
+		if rp := ins.HostConfig.RestartPolicy; rp.Name != "" && rp.Name != "no" {
+			v := string(rp.Name)
+			if rp.Name == "on-failure" && rp.MaximumRetryCount > 0 {
+				v = fmt.Sprintf("on-failure:%d", rp.MaximumRetryCount)
+			}
+			svc = append(svc, yaml.MapItem{Key: "restart", Value: v})
+		}
+
+		// environment (only what differs from the image)
+		env := yaml.MapSlice{}
+		for _, kv := range ins.Config.Env {
+			k, v, _ := strings.Cut(kv, "=")
+			if iv, ok := imgEnv[k]; ok && iv == v {
+				continue
+			}
+			if looksSecretEnv(k, v) {
+				ref := k
+				if prev, ok := secrets[ref]; ok && prev != v {
+					ref = strings.ToUpper(strings.ReplaceAll(utils.SanitizeProject(svcName), "-", "_")) + "_" + k
+				}
+				secrets[ref] = v
+				env = append(env, yaml.MapItem{Key: k, Value: "${" + ref + "}"})
+				continue
+			}
+			// escape compose interpolation in literal values
+			env = append(env, yaml.MapItem{Key: k, Value: strings.ReplaceAll(v, "$", "$$")})
+		}
+		if len(env) > 0 {
+			svc = append(svc, yaml.MapItem{Key: "environment", Value: env})
+		}
+
+		// ports
+		var ports []string
+		for cport, binds := range ins.HostConfig.PortBindings {
+			target := cport.Port()
+			if cport.Proto() != "" && cport.Proto() != "tcp" {
+				target += "/" + cport.Proto()
+			}
+			for _, b := range binds {
+				spec := target
+				if b.HostPort != "" {
+					spec = b.HostPort + ":" + spec
+				}
+				if b.HostIP != "" && b.HostIP != "0.0.0.0" && b.HostIP != "::" {
+					spec = b.HostIP + ":" + spec
+				}
+				ports = append(ports, spec)
+			}
+		}
+		sort.Strings(ports)
+		ports = slices.Compact(ports)
+		if len(ports) > 0 {
+			svc = append(svc, yaml.MapItem{Key: "ports", Value: ports})
+		}
+
+		// volumes
+		var vols []string
+		for _, m := range ins.Mounts {
+			ro := ""
+			if !m.RW {
+				ro = ":ro"
+			}
+			switch m.Type {
+			case mount.TypeBind:
+				vols = append(vols, m.Source+":"+m.Destination+ro)
+			case mount.TypeVolume:
+				if m.Name == "" || (len(m.Name) == 64 && !strings.ContainsAny(m.Name, "_-")) {
+					// anonymous volume – compose recreates these from the image
+					continue
+				}
+				key := strings.TrimPrefix(m.Name, project+"_")
+				if key == m.Name {
+					volumes[key] = yaml.MapSlice{{Key: "external", Value: true}, {Key: "name", Value: m.Name}}
+				} else if _, ok := volumes[key]; !ok {
+					volumes[key] = nil
+				}
+				vols = append(vols, key+":"+m.Destination+ro)
+			case mount.TypeTmpfs:
+				vols = append(vols, "tmpfs:"+m.Destination) // rewritten below
+			}
+		}
+		var tmpfs []string
+		vols = slices.DeleteFunc(vols, func(v string) bool {
+			if strings.HasPrefix(v, "tmpfs:") {
+				tmpfs = append(tmpfs, strings.TrimPrefix(v, "tmpfs:"))
+				return true
+			}
+			return false
+		})
+		if len(vols) > 0 {
+			svc = append(svc, yaml.MapItem{Key: "volumes", Value: vols})
+		}
+		if len(tmpfs) > 0 {
+			svc = append(svc, yaml.MapItem{Key: "tmpfs", Value: tmpfs})
+		}
+
+		// networks
+		var netNames []string
+		if ins.NetworkSettings != nil {
+			for n := range ins.NetworkSettings.Networks {
+				netNames = append(netNames, n)
+			}
+		}
+		sort.Strings(netNames)
+		mode := string(ins.HostConfig.NetworkMode)
+		switch {
+		case mode == "host" || mode == "none" || strings.HasPrefix(mode, "container:") || strings.HasPrefix(mode, "service:"):
+			svc = append(svc, yaml.MapItem{Key: "network_mode", Value: mode})
+		case len(netNames) == 1 && netNames[0] == project+"_default":
+			// implicit default network
+		default:
+			var svcNets []string
+			for _, n := range netNames {
+				key := strings.TrimPrefix(n, project+"_")
+				if key == n {
+					networks[key] = yaml.MapSlice{{Key: "external", Value: true}, {Key: "name", Value: n}}
+				} else if key != "default" {
+					if _, ok := networks[key]; !ok {
+						networks[key] = nil
+					}
+				}
+				svcNets = append(svcNets, key)
+			}
+			if len(svcNets) > 0 {
+				svc = append(svc, yaml.MapItem{Key: "networks", Value: svcNets})
+			}
+		}
+
+		// labels (minus compose bookkeeping and image-provided labels)
+		labels := yaml.MapSlice{}
+		var lkeys []string
+		for k := range ins.Config.Labels {
+			lkeys = append(lkeys, k)
+		}
+		sort.Strings(lkeys)
+		for _, k := range lkeys {
+			v := ins.Config.Labels[k]
+			if strings.HasPrefix(k, "com.docker.compose.") {
+				continue
+			}
+			if iv, ok := imgLabels[k]; ok && iv == v {
+				continue
+			}
+			labels = append(labels, yaml.MapItem{Key: k, Value: v})
+		}
+		if len(labels) > 0 {
+			svc = append(svc, yaml.MapItem{Key: "labels", Value: labels})
+		}
+
+		// healthcheck
+		if hc := ins.Config.Healthcheck; hc != nil && len(hc.Test) > 0 && !healthEqual(hc, imgHealth) {
+			hcm := yaml.MapSlice{}
+			if hc.Test[0] == "NONE" {
+				hcm = append(hcm, yaml.MapItem{Key: "disable", Value: true})
+			} else {
+				hcm = append(hcm, yaml.MapItem{Key: "test", Value: []string(hc.Test)})
+				if hc.Interval > 0 {
+					hcm = append(hcm, yaml.MapItem{Key: "interval", Value: hc.Interval.String()})
+				}
+				if hc.Timeout > 0 {
+					hcm = append(hcm, yaml.MapItem{Key: "timeout", Value: hc.Timeout.String()})
+				}
+				if hc.Retries > 0 {
+					hcm = append(hcm, yaml.MapItem{Key: "retries", Value: hc.Retries})
+				}
+				if hc.StartPeriod > 0 {
+					hcm = append(hcm, yaml.MapItem{Key: "start_period", Value: hc.StartPeriod.String()})
+				}
+			}
+			svc = append(svc, yaml.MapItem{Key: "healthcheck", Value: hcm})
+		}
+
+		services = append(services, yaml.MapItem{Key: svcName, Value: svc})
+	}
+
+	doc := yaml.MapSlice{{Key: "services", Value: services}}
+	if len(networks) > 0 {
+		doc = append(doc, yaml.MapItem{Key: "networks", Value: sortedMapSlice(networks)})
+	}
+	if len(volumes) > 0 {
+		doc = append(doc, yaml.MapItem{Key: "volumes", Value: sortedMapSlice(volumes)})
+	}
+	composeYAML, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal compose: %w", err)
+	}
+	header := fmt.Sprintf("# Adopted from running compose project %q on %s (%s)\n# Review before enabling Auto DevOps.\n",
+		project, h.Name, time.Now().UTC().Format(time.RFC3339))
+	composeYAML = append([]byte(header), composeYAML...)
+
+	// ---- Write files ----
+	if err := ensureDir(stackDir, 0o755); err != nil {
+		return nil, err
+	}
+	res := &AdoptResult{Host: h.Name, Project: project, RelPath: relPath, Services: serviceNames, ComposeYAML: string(composeYAML)}
+	if err := os.WriteFile(composePath, composeYAML, 0o644); err != nil {
+		return nil, err
+	}
+	res.Files = append(res.Files, "docker-compose.yml")
+
+	if len(secrets) > 0 {
+		var keys []string
+		for k := range secrets {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s=%s\n", k, dotenvQuote(secrets[k]))
+		}
+		encrypted, err := writeSopsDotenv(ctx, stackDir, envPath, []byte(b.String()))
+		if err != nil {
+			if !opts.AllowPlaintext {
+				_ = os.Remove(composePath)
+				return nil, fmt.Errorf("encrypt .env: %w", err)
+			}
+			common.WarnLog("adopt: SOPS encryption failed for %s/%s, writing plaintext .env: %v", h.Name, project, err)
+			if err := writeFileSecure(envPath, []byte(b.String()), 0o600); err != nil {
+				return nil, err
+			}
+		}
+		res.EnvSops = encrypted
+		res.SecretKeys = keys
+		res.Files = append(res.Files, ".env")
+	}
+
+	// ---- Register IaC stack + files ----
+	repoID, err := UpsertIacRepoLocal(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	sopsStatus := "none"
+	if res.EnvSops {
+		sopsStatus = "all"
+	}
+	stackID, err := UpsertIacStack(ctx, repoID, "host", h.Name, project, relPath, "docker-compose.yml", "compose", "missing", sopsStatus, true)
+	if err != nil {
+		return nil, err
+	}
+	res.StackID = stackID
+	for _, f := range res.Files {
+		role := "compose"
+		sops := false
+		if f == ".env" {
+			role = "env"
+			sops = res.EnvSops
+		}
+		sum, size := sha256File(filepath.Join(stackDir, f))
+		if err := UpsertIacFile(ctx, stackID, role, relPath+"/"+f, sops, sum, size); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.KeepAutoDevops {
+		off := false
+		if err := SetStackDevopsOverride(ctx, "host", h.Name, project, &off); err != nil {
+			common.WarnLog("adopt: failed to pin Auto DevOps off for %s/%s: %v", h.Name, project, err)
+		}
+	}
+
+	// ---- Link to the runtime: stamp + drift baseline (no compose up) ----
+	bundleHash, _ := ComputeCurrentBundleHash(ctx, stackID)
+	res.BundleHash = bundleHash
+	if stamp, err := database.CreateDeploymentStampWithHash(ctx, stackID, "adopt", user, "adopt:"+bundleHash,
+		map[string]string{"bundle_hash": bundleHash}); err == nil {
+		_ = database.UpdateDeploymentStampStatus(ctx, stamp.ID, "success")
+		if _, err := database.AssociateContainersWithStampIDs(ctx, containerIDs, stamp.ID, stamp.DeploymentHash); err != nil {
+			common.WarnLog("adopt: failed to associate containers with stamp %d: %v", stamp.ID, err)
+		}
+		res.StampID = stamp.ID
+	} else {
+		common.WarnLog("adopt: failed to create deployment stamp for %s/%s: %v", h.Name, project, err)
+	}
+
+	stageFunc := func(ctx context.Context, stackID int64) (string, []string, func(), error) {
+		return StageStackForCompose(ctx, stackID)
+	}
+	if err := utils.OnSuccessfulDeploymentWithDeps(ctx, common.DB, &funcStackStager{stageFunc: stageFunc}, stackID, project, cli); err != nil {
+		common.WarnLog("adopt: failed to baseline drift cache for %s/%s: %v", h.Name, project, err)
+	}
+
+	common.InfoLog("adopt: %s adopted compose project %s on %s (stack=%d services=%d secrets=%d sops=%v)",
+		user, project, h.Name, stackID, len(serviceNames), len(res.SecretKeys), res.EnvSops)
+	return res, nil
+}
+
+func healthEqual(a, b *container.HealthConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return slices.Equal(a.Test, b.Test) && a.Interval == b.Interval && a.Timeout == b.Timeout &&
+		a.Retries == b.Retries && a.StartPeriod == b.StartPeriod
+}
+
+func sortedMapSlice(m map[string]yaml.MapSlice) yaml.MapSlice {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := yaml.MapSlice{}
+	for _, k := range keys {
+		if m[k] == nil {
+			out = append(out, yaml.MapItem{Key: k, Value: map[string]any{}})
+			continue
+		}
+		out = append(out, yaml.MapItem{Key: k, Value: m[k]})
+	}
+	return out
+}
+
+// dotenvEscaper escapes a value for a double-quoted dotenv string; compose decodes these
+// escapes and reads \$ as a literal $
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "\n", `\n`, "\r", `\r`)
+
+// dotenvQuote quotes a value when dotenv parsing would otherwise alter it. Single quotes are
+// literal in compose (no escapes, no interpolation) and are used when the value allows it;
+// values with a ' or a newline, or ending in a backslash, are double-quoted and escaped.
+func dotenvQuote(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t#\"'$\\\n\r") {
+		return v
+	}
+	if !strings.ContainsAny(v, "'\n\r") && !strings.HasSuffix(v, `\`) {
+		return "'" + v + "'"
+	}
+	return `"` + dotenvEscaper.Replace(v) + `"`
+}
+
+// writeSopsDotenv encrypts dotenv content with the repo's SOPS rules and writes it to dest.
+// Returns false with an error if SOPS is not usable.
+func writeSopsDotenv(ctx context.Context, dir, dest string, content []byte) (bool, error) {
+	// Keep a .env suffix on the temp file so path_regex creation rules still match
+	tmp := filepath.Join(dir, ".dd-ui-tmp.env")
+	if err := writeFileSecure(tmp, content, 0o600); err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+
+	cctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(cctx, "sops", "-e", "-i", "--input-type", "dotenv", "--output-type", "dotenv", tmp)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("sops: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/dotenv"
+)
+
+func TestDotenvQuoteRoundTrip(t *testing.T) {
+	values := []string{
+		"",
+		"plain",
+		"with space",
+		" leading and trailing ",
+		"tab\there",
+		"hash # comment",
+		"#start",
+		"dollar$HOME",
+		"${NOT_A_VAR}",
+		"$$double",
+		`back\slash`,
+		`trailing\`,
+		`\$escaped`,
+		`\n literal`,
+		`\0123`,
+		"it's",
+		`say "hi"`,
+		`mixed '"$\`,
+		"multi\nline",
+		"crlf\r\n",
+		"ünïcödé ✓",
+		"\x01control",
+	}
+	lookup := func(string) (string, bool) { return "", false }
+	for _, v := range values {
+		quoted := dotenvQuote(v)
+		got, err := dotenv.UnmarshalWithLookup("KEY="+quoted+"\n", lookup)
+		if err != nil {
+			t.Errorf("%q: quoted as %s: parse error: %v", v, quoted, err)
+			continue
+		}
+		if got["KEY"] != v {
+			t.Errorf("%q: quoted as %s: compose read %q", v, quoted, got["KEY"])
+		}
+	}
+}
+
+func TestDotenvQuoteStyle(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"p$ss", "'p$ss'"},
+		{`c:\dir`, `'c:\dir'`},
+		{"it's", `"it's"`},
+		{`end\`, `"end\\"`},
+		{"a\nb", `"a\nb"`},
+	}
+	for _, tt := range tests {
+		if got := dotenvQuote(tt.in); got != tt.want {
+			t.Errorf("dotenvQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	if strings.Contains(dotenvQuote("x'y"), `\u`) {
+		t.Error("dotenvQuote must not emit Go escapes")
+	}
+}
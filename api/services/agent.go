@@ -0,0 +1,355 @@
+// services/agent.go
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+/*
+dd-ui-agent is an optional per-host agent (cmd/dd-ui-agent) that dials OUT to DD-UI
+over an authenticated websocket, so hosts behind NAT work without inbound SSH.
+DD-UI multiplexes Docker API connections over that socket (utils.AgentMux).
+
+Inventory vars:
+
+  dd_ui_connection: agent    # route this host through its agent (agent://<host>)
+
+or docker_host: agent://<host> as a per-host override. The docker CLI (deploys,
+cleanup) reaches the agent through a per-host unix socket proxy.
+
+Sessions live in the memory of the replica the agent is connected to, so only the
+leader (which runs scans, deploys and event watchers) accepts agents; followers
+answer 503 and the agent retries until it reaches the leader. A demoted leader
+drops its agents so they reconnect to the new one.
+*/
+
+const (
+	agentScheme      = "agent://"
+	agentTokenPrefix = "ddui_agent_"
+	// AgentDockerTarget is the stream target the agent maps to its Docker socket
+	AgentDockerTarget = "docker"
+)
+
+var ErrAgentNotConnected = errors.New("agent not connected")
+
+// AgentSession is a live agent connection
+type AgentSession struct {
+	Host        string
+	HostID      int64
+	RemoteAddr  string
+	Version     string
+	ConnectedAt time.Time
+	mux         *utils.AgentMux
+	proxy       net.Listener // guarded by agentHub
+	proxyPath   string       // guarded by agentHub
+	closed      bool         // guarded by agentHub
+}
+
+// AgentStatus is the API view of an enrolled agent
+type AgentStatus struct {
+	database.AgentTokenRow
+	Connected   bool       `json:"connected"`
+	ConnectedAt *time.Time `json:"connected_at,omitempty"`
+	RemoteAddr  string     `json:"remote_addr,omitempty"`
+}
+
+var agentHub = struct {
+	sync.RWMutex
+	sessions map[string]*AgentSession
+	sockDir  string // per-process 0700 directory of the local proxy sockets
+}{sessions: map[string]*AgentSession{}}
+
+// IsAgentHost reports whether a host is reached through dd-ui-agent
+func IsAgentHost(h database.HostRow) bool {
+	if strings.HasPrefix(h.Vars["docker_host"], agentScheme) {
+		return true
+	}
+	return strings.EqualFold(strings.TrimSpace(h.Vars["dd_ui_connection"]), "agent")
+}
+
+func hashAgentToken(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAgentToken creates (or rotates) a host's enrollment token; the plain token is returned once
+func IssueAgentToken(ctx context.Context, h database.HostRow, createdBy string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	tok := agentTokenPrefix + hex.EncodeToString(b)
+	if err := database.SetAgentToken(ctx, h.ID, hashAgentToken(tok), tok[:len(agentTokenPrefix)+6], createdBy); err != nil {
+		return "", err
+	}
+	// a rotated token invalidates the running agent
+	DisconnectAgent(h.Name)
+	return tok, nil
+}
+
+// RevokeAgentToken deletes a host's token and drops its connection
+func RevokeAgentToken(ctx context.Context, h database.HostRow) (bool, error) {
+	ok, err := database.DeleteAgentToken(ctx, h.ID)
+	if err != nil {
+		return false, err
+	}
+	DisconnectAgent(h.Name)
+	return ok, nil
+}
+
+// VerifyAgentToken checks a presented token for a host; returns the host id
+func VerifyAgentToken(ctx context.Context, hostName, tok string) (int64, error) {
+	hostID, hash, err := database.GetAgentTokenHash(ctx, hostName)
+	if err != nil {
+		return 0, fmt.Errorf("no agent token for host %s", hostName)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAgentToken(tok)), []byte(hash)) != 1 {
+		return 0, fmt.Errorf("invalid agent token for host %s", hostName)
+	}
+	return hostID, nil
+}
+
+// ServeAgent registers an authenticated agent connection and blocks until it drops
+func ServeAgent(hostName string, hostID int64, remoteAddr, version string, mux *utils.AgentMux) {
+	s := &AgentSession{
+		Host: hostName, HostID: hostID, RemoteAddr: remoteAddr, Version: version,
+		ConnectedAt: time.Now(), mux: mux,
+	}
+
+	agentHub.Lock()
+	old := agentHub.sessions[hostName]
+	agentHub.sessions[hostName] = s
+	agentHub.Unlock()
+	if old != nil {
+		common.InfoLog("agent: %s reconnected from %s, replacing previous session", hostName, remoteAddr)
+		old.close()
+	}
+
+	_ = database.TouchAgentToken(context.Background(), hostID, remoteAddr, version)
+	common.InfoLog("agent: %s connected from %s (version %s)", hostName, remoteAddr, version)
+
+	err := mux.Run()
+
+	agentHub.Lock()
+	if agentHub.sessions[hostName] == s {
+		delete(agentHub.sessions, hostName)
+	}
+	agentHub.Unlock()
+	s.close()
+	_ = database.TouchAgentToken(context.Background(), hostID, remoteAddr, version)
+	common.InfoLog("agent: %s disconnected: %v", hostName, err)
+}
+
+func (s *AgentSession) close() {
+	_ = s.mux.Close()
+	agentHub.Lock()
+	proxy, path := s.proxy, s.proxyPath
+	s.proxy, s.proxyPath, s.closed = nil, "", true
+	agentHub.Unlock()
+	if proxy != nil {
+		_ = proxy.Close()
+		_ = os.Remove(path)
+	}
+}
+
+// validAgentSocketName rejects host names that cannot be used as a socket file name
+func validAgentSocketName(name string) bool {
+	return name != "" && name != "." && !strings.Contains(name, "..") && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// DisconnectAgent drops a host's live agent connection, if any
+func DisconnectAgent(hostName string) {
+	agentHub.Lock()
+	s := agentHub.sessions[hostName]
+	delete(agentHub.sessions, hostName)
+	agentHub.Unlock()
+	if s != nil {
+		s.close()
+	}
+}
+
+// DisconnectAllAgents drops every agent connection (on demotion; agents reconnect to the leader)
+func DisconnectAllAgents() {
+	agentHub.Lock()
+	sessions := agentHub.sessions
+	agentHub.sessions = map[string]*AgentSession{}
+	agentHub.Unlock()
+	for _, s := range sessions {
+		s.close()
+	}
+	if len(sessions) > 0 {
+		common.InfoLog("agent: dropped %d agent connection(s) after stepping down", len(sessions))
+	}
+}
+
+func agentSession(hostName string) (*AgentSession, error) {
+	agentHub.RLock()
+	s := agentHub.sessions[hostName]
+	agentHub.RUnlock()
+	if s == nil {
+		if !IsLeader() {
+			return nil, fmt.Errorf("%w: %s (agents connect to the leader replica %s)",
+				ErrAgentNotConnected, hostName, GetLeaderStatus().Leader)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotConnected, hostName)
+	}
+	return s, nil
+}
+
+// AgentDialer returns a DialContext that opens a Docker stream through the host's agent
+func AgentDialer(hostName string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		s, err := agentSession(hostName)
+		if err != nil {
+			return nil, err
+		}
+		return s.mux.Open(ctx, AgentDockerTarget)
+	}
+}
+
+// agentDockerClient builds a Docker SDK client that talks through the agent
+func agentDockerClient(ctx context.Context, url string) (*client.Client, func(), error) {
+	hostName := strings.TrimPrefix(url, agentScheme)
+	if _, err := agentSession(hostName); err != nil {
+		return nil, nil, err
+	}
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://dd-ui-agent:2375"),
+		client.WithDialContext(AgentDialer(hostName)),
+		client.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	pctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := cli.Ping(pctx); err != nil {
+		_ = cli.Close()
+		return nil, nil, fmt.Errorf("agent Docker connection test failed: %v", err)
+	}
+	return cli, func() { _ = cli.Close() }, nil
+}
+
+// AgentLocalSocket returns a local unix socket that proxies to the host's agent,
+// for tools that need DOCKER_HOST (the docker CLI). Started lazily per session.
+func AgentLocalSocket(hostName string) (string, error) {
+	s, err := agentSession(hostName)
+	if err != nil {
+		return "", err
+	}
+
+	if !validAgentSocketName(hostName) {
+		return "", fmt.Errorf("agent proxy socket: invalid host name %q", hostName)
+	}
+
+	agentHub.Lock()
+	defer agentHub.Unlock()
+	if s.closed {
+		return "", fmt.Errorf("%w: %s", ErrAgentNotConnected, hostName)
+	}
+	if s.proxy != nil {
+		return s.proxyPath, nil
+	}
+
+	if agentHub.sockDir == "" {
+		dir, err := os.MkdirTemp("", "dd-ui-agent-")
+		if err != nil {
+			return "", fmt.Errorf("agent proxy socket: %w", err)
+		}
+		agentHub.sockDir = dir // MkdirTemp creates it 0700
+	}
+	path := filepath.Join(agentHub.sockDir, hostName+".sock")
+	_ = os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return "", fmt.Errorf("agent proxy socket: %w", err)
+	}
+	_ = os.Chmod(path, 0o600)
+	s.proxy, s.proxyPath = ln, path
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				st, err := s.mux.Open(context.Background(), AgentDockerTarget)
+				if err != nil {
+					common.WarnLog("agent: proxy stream to %s failed: %v", hostName, err)
+					return
+				}
+				defer st.Close()
+				done := make(chan struct{})
+				go func() {
+					_, _ = io.Copy(st, c)
+					_ = st.CloseWrite()
+					close(done)
+				}()
+				_, _ = io.Copy(c, st)
+				if uc, ok := c.(*net.UnixConn); ok {
+					_ = uc.CloseWrite()
+				}
+				<-done
+			}(c)
+		}
+	}()
+	common.DebugLog("agent: local proxy for %s at %s", hostName, path)
+	return path, nil
+}
+
+// ListAgentStatus merges enrolled agents with live connections
+func ListAgentStatus(ctx context.Context) ([]AgentStatus, error) {
+	toks, err := database.ListAgentTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	agentHub.RLock()
+	defer agentHub.RUnlock()
+	out := make([]AgentStatus, 0, len(toks))
+	for _, t := range toks {
+		st := AgentStatus{AgentTokenRow: t}
+		if s := agentHub.sessions[t.HostName]; s != nil {
+			at := s.ConnectedAt
+			st.Connected, st.ConnectedAt, st.RemoteAddr = true, &at, s.RemoteAddr
+			st.AgentVersion = s.Version
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].HostName < out[j].HostName })
+	return out, nil
+}
+
+// CLIDockerHost maps an agent:// URL to its local proxy socket for the docker CLI;
+// other URLs are returned unchanged
+func CLIDockerHost(h database.HostRow, url string) (string, error) {
+	if !strings.HasPrefix(url, agentScheme) {
+		return url, nil
+	}
+	sock, err := AgentLocalSocket(h.Name)
+	if err != nil {
+		return "", fmt.Errorf("agent host %s: %w", h.Name, err)
+	}
+	return "unix://" + sock, nil
+}
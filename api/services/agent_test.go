@@ -0,0 +1,22 @@
+package services
+
+import "testing"
+
+func TestValidAgentSocketName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"web-1":          true,
+		"db.example.com": true,
+		"":               false,
+		".":              false,
+		"..":             false,
+		"../etc/passwd":  false,
+		"a/b":            false,
+		`a\b`:            false,
+		"a\x00b":         false,
+		"host..internal": false,
+	} {
+		if got := validAgentSocketName(name); got != want {
+			t.Errorf("validAgentSocketName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
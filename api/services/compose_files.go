@@ -0,0 +1,389 @@
+// services/compose_files.go
+package services
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+
+	"dd-ui/common"
+)
+
+// composeMainNames are the main compose file names, in lookup order
+var composeMainNames = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml",
+	"docker-compose.yml.tmpl", "docker-compose.yaml.tmpl", "compose.yml.tmpl", "compose.yaml.tmpl"}
+
+// composeOverrideRe matches compose.override.yml (every host) and compose.<host>.override.yml
+// (one host), with or without the docker- prefix and .tmpl suffix
+var composeOverrideRe = regexp.MustCompile(`^(?:docker-)?compose\.(?:([^/]+)\.)?override\.ya?ml(?:\.tmpl)?$`)
+
+// composeXDdui is the x-ddui block of a stack's main compose file:
+//
+//	x-ddui:
+//	  files: [compose.yml, compose.prod.yml] # -f order, relative to the stack dir
+//	  profiles: [monitoring]                 # compose profiles to activate
+type composeXDdui struct {
+	Files    []string `yaml:"files"`
+	Profiles []string `yaml:"profiles"`
+}
+
+// composeRefsDoc is the part of a compose file that pulls in other compose files
+type composeRefsDoc struct {
+	Include  []any `yaml:"include"`
+	Services map[string]*struct {
+		Extends any `yaml:"extends"`
+	} `yaml:"services"`
+}
+
+// composeOverrideHost returns the host an override file is limited to ("" for every host);
+// ok is false when name is not an override file
+func composeOverrideHost(name string) (host string, ok bool) {
+	m := composeOverrideRe.FindStringSubmatch(name)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// composeFileApplies reports whether a file of a stack's -f list is used for host; per-host
+// override files are skipped for other hosts (and when no host applies)
+func composeFileApplies(rel, host string) bool {
+	h, ok := composeOverrideHost(path.Base(filepath.ToSlash(rel)))
+	return !ok || h == "" || h == host
+}
+
+// discoverComposeFiles works out a stack directory's compose set from its main file:
+//   - order: the -f list relative to dir; x-ddui.files when declared, else the main file and
+//     compose.override.yml; per-host override files always follow
+//   - profiles: x-ddui.profiles
+//   - all: absolute paths of every compose file the set loads, including include: and
+//     extends: targets (limited to root)
+func discoverComposeFiles(root, dir, main string, x composeXDdui) (order, profiles, all []string) {
+	entries, _ := os.ReadDir(dir)
+	var shared, perHost []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if h, ok := composeOverrideHost(e.Name()); ok {
+			if h == "" {
+				shared = append(shared, e.Name())
+			} else {
+				perHost = append(perHost, e.Name())
+			}
+		}
+	}
+	sort.Strings(shared)
+	sort.Strings(perHost)
+
+	seen := map[string]bool{}
+	add := func(rel string) {
+		rel = filepath.ToSlash(filepath.Clean(rel))
+		if seen[rel] {
+			return
+		}
+		full, err := joinUnderLocal(dir, rel)
+		if err != nil {
+			common.WarnLog("iac: compose file %q in %s escapes the stack directory; ignored", rel, dir)
+			return
+		}
+		if fi, err := os.Stat(full); err != nil || fi.IsDir() {
+			common.WarnLog("iac: compose file %q listed in %s does not exist; ignored", rel, dir)
+			return
+		}
+		seen[rel] = true
+		order = append(order, rel)
+	}
+	if len(x.Files) > 0 {
+		for _, f := range x.Files {
+			if strings.TrimSpace(f) != "" {
+				add(strings.TrimSpace(f))
+			}
+		}
+	} else if main != "" {
+		add(main)
+		for _, f := range shared {
+			add(f)
+		}
+	}
+	for _, f := range perHost {
+		add(f)
+	}
+	for _, p := range x.Profiles {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+
+	// follow include: and extends: references
+	loaded := map[string]bool{}
+	var walk func(full string)
+	walk = func(full string) {
+		if loaded[full] {
+			return
+		}
+		loaded[full] = true
+		all = append(all, full)
+		b, err := os.ReadFile(full)
+		if err != nil {
+			return
+		}
+		var doc composeRefsDoc
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return
+		}
+		base := filepath.Dir(full)
+		for _, ref := range composeFileRefs(doc) {
+			if filepath.IsAbs(ref) {
+				common.DebugLog("iac: absolute compose reference %q in %s is not tracked", ref, full)
+				continue
+			}
+			target := filepath.Clean(filepath.Join(base, ref))
+			if rel, err := filepath.Rel(root, target); err != nil || strings.HasPrefix(rel, "..") {
+				common.WarnLog("iac: compose reference %q in %s is outside the repository; not tracked", ref, full)
+				continue
+			}
+			if fi, err := os.Stat(target); err == nil && !fi.IsDir() {
+				walk(target)
+			}
+		}
+	}
+	for _, rel := range order {
+		walk(filepath.Join(dir, filepath.FromSlash(rel)))
+	}
+	return order, profiles, all
+}
+
+// composeFileRefs lists the files a compose document includes or extends services from
+func composeFileRefs(doc composeRefsDoc) []string {
+	var out []string
+	addPath := func(v any) {
+		switch p := v.(type) {
+		case string:
+			out = append(out, p)
+		case []any:
+			for _, it := range p {
+				if s, ok := it.(string); ok {
+					out = append(out, s)
+				}
+			}
+		}
+	}
+	for _, inc := range doc.Include {
+		switch v := inc.(type) {
+		case string:
+			out = append(out, v)
+		case map[string]any:
+			addPath(v["path"])
+		}
+	}
+	names := make([]string, 0, len(doc.Services))
+	for name := range doc.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		svc := doc.Services[name]
+		if svc == nil {
+			continue
+		}
+		if ext, ok := svc.Extends.(map[string]any); ok {
+			if f, ok := ext["file"].(string); ok && f != "" {
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
+
+// mergeComposeDocs parses the stack's compose files in -f order and merges their services
+// the way compose overrides do for the fields the scanner records (x- keys are not merged):
+// labels and environment per key, env_file appended, ports appended unless identical,
+// volumes replaced by target, everything else overridden
+func mergeComposeDocs(dir string, order []string) *composeDoc {
+	merged := &composeDoc{Services: map[string]*composeSvc{}}
+	for _, rel := range order {
+		if h, ok := composeOverrideHost(path.Base(rel)); ok && h != "" {
+			continue // host-specific; not part of the shared view
+		}
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			continue
+		}
+		doc := &composeDoc{}
+		_ = yaml.Unmarshal(b, doc)
+		for name, svc := range doc.Services {
+			if svc == nil {
+				continue
+			}
+			prev, ok := merged.Services[name]
+			if !ok || prev == nil {
+				merged.Services[name] = svc
+				continue
+			}
+			if svc.Image != "" {
+				prev.Image = svc.Image
+			}
+			if svc.ContainerName != "" {
+				prev.ContainerName = svc.ContainerName
+			}
+			if svc.Labels != nil {
+				prev.Labels = mergeComposeKeyValues(prev.Labels, svc.Labels)
+			}
+			if svc.Environment != nil {
+				prev.Environment = mergeComposeKeyValues(prev.Environment, svc.Environment)
+			}
+			if svc.EnvFile != nil {
+				prev.EnvFile = mergeComposeSequence(prev.EnvFile, svc.EnvFile, composeEnvFileKey)
+			}
+			if svc.Ports != nil {
+				prev.Ports = mergeComposeSequence(prev.Ports, svc.Ports, composePortKey)
+			}
+			if svc.Volumes != nil {
+				prev.Volumes = mergeComposeSequence(prev.Volumes, svc.Volumes, composeVolumeKey)
+			}
+			if svc.Deploy != nil {
+				prev.Deploy = svc.Deploy
+			}
+		}
+	}
+	return merged
+}
+
+// composeKeyValues reads a labels/environment block in map or "KEY=value" list form; a bare
+// "KEY" has an empty value
+func composeKeyValues(v any) map[string]any {
+	out := map[string]any{}
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			out[k] = val
+		}
+	case []any:
+		for _, it := range t {
+			s, ok := it.(string)
+			if !ok {
+				continue
+			}
+			if k, val, found := strings.Cut(s, "="); found {
+				out[strings.TrimSpace(k)] = val
+			} else if k = strings.TrimSpace(s); k != "" {
+				out[k] = ""
+			}
+		}
+	}
+	return out
+}
+
+// mergeComposeKeyValues overlays next onto prev key by key
+func mergeComposeKeyValues(prev, next any) any {
+	out := composeKeyValues(prev)
+	for k, v := range composeKeyValues(next) {
+		out[k] = v
+	}
+	return out
+}
+
+// mergeComposeSequence appends next to prev; an entry whose key matches an earlier one
+// replaces it in place
+func mergeComposeSequence(prev, next any, key func(any) string) any {
+	asList := func(v any) []any {
+		switch t := v.(type) {
+		case []any:
+			return t
+		case nil:
+			return nil
+		default:
+			return []any{t} // env_file: a single path
+		}
+	}
+	out := append([]any(nil), asList(prev)...)
+	at := map[string]int{}
+	for i, it := range out {
+		if k := key(it); k != "" {
+			at[k] = i
+		}
+	}
+	for _, it := range asList(next) {
+		k := key(it)
+		if i, ok := at[k]; ok && k != "" {
+			out[i] = it
+			continue
+		}
+		if k != "" {
+			at[k] = len(out)
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// composeEnvFileKey: env_file entries are unique by path
+func composeEnvFileKey(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case map[string]any:
+		p, _ := t["path"].(string)
+		return p
+	}
+	return ""
+}
+
+// composeVolumeKey: service volumes are unique by container target
+func composeVolumeKey(v any) string {
+	switch t := v.(type) {
+	case string:
+		_, dst, _ := splitVolString(t)
+		return dst
+	case map[string]any:
+		dst, _ := t["target"].(string)
+		return dst
+	}
+	return ""
+}
+
+// composePortKey: ports are unique by host IP, published port, target and protocol
+func composePortKey(v any) string {
+	ip, published, target, proto := "0.0.0.0", "", "", "tcp"
+	switch t := v.(type) {
+	case int, uint64, int64:
+		target = fmt.Sprint(t)
+	case string:
+		s := strings.TrimSpace(t)
+		if i := strings.LastIndexByte(s, '/'); i >= 0 {
+			s, proto = s[:i], strings.ToLower(s[i+1:])
+		}
+		// [ip:][published:]target; IPv6 addresses are bracketed
+		if i := strings.LastIndexByte(s, ':'); i >= 0 {
+			target, s = s[i+1:], s[:i]
+			if j := strings.LastIndexByte(s, ':'); j >= 0 && !strings.HasSuffix(s, "]") {
+				ip, published = s[:j], s[j+1:]
+			} else {
+				published = s
+			}
+		} else {
+			target = s
+		}
+	case map[string]any:
+		if s, ok := t["host_ip"].(string); ok && s != "" {
+			ip = s
+		}
+		if p, ok := t["protocol"].(string); ok && p != "" {
+			proto = strings.ToLower(p)
+		}
+		if t["published"] != nil {
+			published = fmt.Sprint(t["published"])
+		}
+		target = fmt.Sprint(t["target"])
+	default:
+		return ""
+	}
+	return ip + ":" + published + ":" + target + "/" + proto
+}
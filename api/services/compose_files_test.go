@@ -0,0 +1,219 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestComposeOverrideHost(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		ok   bool
+	}{
+		{"compose.override.yml", "", true},
+		{"docker-compose.override.yaml", "", true},
+		{"compose.override.yml.tmpl", "", true},
+		{"compose.web-1.override.yml", "web-1", true},
+		{"docker-compose.db.example.com.override.yaml.tmpl", "db.example.com", true},
+		{"compose.yml", "", false},
+		{"compose.prod.yml", "", false},
+		{"my-compose.override.yml", "", false},
+		{"compose.override.yml.bak", "", false},
+		{"compose.override.json", "", false},
+	}
+	for _, tt := range tests {
+		host, ok := composeOverrideHost(tt.name)
+		if host != tt.host || ok != tt.ok {
+			t.Errorf("composeOverrideHost(%q) = %q, %v; want %q, %v", tt.name, host, ok, tt.host, tt.ok)
+		}
+	}
+
+	applies := []struct {
+		rel, host string
+		want      bool
+	}{
+		{"stack/compose.yml", "web-1", true},
+		{"stack/compose.override.yml", "", true},
+		{"stack/compose.web-1.override.yml", "web-1", true},
+		{"stack/compose.web-1.override.yml", "web-2", false},
+		{"stack/compose.web-1.override.yml", "", false},
+	}
+	for _, tt := range applies {
+		if got := composeFileApplies(tt.rel, tt.host); got != tt.want {
+			t.Errorf("composeFileApplies(%q, %q) = %v, want %v", tt.rel, tt.host, got, tt.want)
+		}
+	}
+}
+
+func writeComposeFixture(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiscoverComposeFilesOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		main  string
+		x     composeXDdui
+		order []string
+	}{
+		{
+			name: "main then shared then per-host overrides",
+			files: map[string]string{
+				"compose.yml": "services: {}\n", "compose.override.yml": "{}\n",
+				"compose.b.override.yml": "{}\n", "compose.a.override.yml": "{}\n",
+				"notes.txt": "",
+			},
+			main:  "compose.yml",
+			order: []string{"compose.yml", "compose.override.yml", "compose.a.override.yml", "compose.b.override.yml"},
+		},
+		{
+			name: "x-ddui files replace the default order, per-host overrides still follow",
+			files: map[string]string{
+				"compose.yml": "{}\n", "compose.prod.yml": "{}\n", "compose.override.yml": "{}\n",
+				"compose.web.override.yml": "{}\n",
+			},
+			main:  "compose.yml",
+			x:     composeXDdui{Files: []string{"compose.prod.yml", " compose.yml ", "compose.prod.yml", "missing.yml", "../escape.yml"}},
+			order: []string{"compose.prod.yml", "compose.yml", "compose.web.override.yml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "stack")
+			writeComposeFixture(t, dir, tt.files)
+			order, _, _ := discoverComposeFiles(root, dir, tt.main, tt.x)
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Fatalf("order = %v, want %v", order, tt.order)
+			}
+		})
+	}
+}
+
+func TestDiscoverComposeFilesFollowsReferences(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "stack")
+	writeComposeFixture(t, root, map[string]string{
+		"stack/compose.yml":   "include:\n  - shared/db.yml\n  - path: [../common/extra.yml]\nservices:\n  app:\n    extends:\n      file: base.yml\n      service: app\n",
+		"stack/shared/db.yml": "services: {}\n",
+		"stack/base.yml":      "services: {}\n",
+		"common/extra.yml":    "services: {}\n",
+	})
+	order, profiles, all := discoverComposeFiles(root, dir, "compose.yml", composeXDdui{Profiles: []string{" ops ", ""}})
+	if !reflect.DeepEqual(order, []string{"compose.yml"}) || !reflect.DeepEqual(profiles, []string{"ops"}) {
+		t.Fatalf("order = %v, profiles = %v", order, profiles)
+	}
+	want := []string{
+		filepath.Join(dir, "compose.yml"),
+		filepath.Join(dir, "shared", "db.yml"),
+		filepath.Join(root, "common", "extra.yml"),
+		filepath.Join(dir, "base.yml"),
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Fatalf("all = %v, want %v", all, want)
+	}
+}
+
+func TestMergeComposeDocs(t *testing.T) {
+	dir := t.TempDir()
+	writeComposeFixture(t, dir, map[string]string{
+		"compose.yml": `services:
+  app:
+    image: app:1
+    labels:
+      a: "1"
+      b: "2"
+    environment:
+      - LOG=info
+      - TZ=UTC
+    env_file: app.env
+    ports:
+      - "8080:80"
+      - target: 443
+        published: 8443
+    volumes:
+      - ./data:/data
+      - logs:/var/log
+`,
+		"compose.override.yml": `services:
+  app:
+    image: app:2
+    labels: ["b=3", "c=4"]
+    environment:
+      LOG: debug
+    env_file: [app.env, extra.env]
+    ports:
+      - "8080:80"
+      - "9090:90/udp"
+    volumes:
+      - type: bind
+        source: /srv/data
+        target: /data
+`,
+		"compose.web.override.yml": `services:
+  app:
+    image: ignored
+`,
+	})
+	merged := mergeComposeDocs(dir, []string{"compose.yml", "compose.override.yml", "compose.web.override.yml"})
+	app := merged.Services["app"]
+	if app == nil {
+		t.Fatal("service app missing")
+	}
+	if app.Image != "app:2" {
+		t.Errorf("image = %q", app.Image)
+	}
+	if got, want := normLabels(app.Labels), map[string]string{"a": "1", "b": "3", "c": "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+	if got, want := composeKeyValues(app.Environment), map[string]any{"LOG": "debug", "TZ": "UTC"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("environment = %v, want %v", got, want)
+	}
+	if got, want := app.EnvFile, []any{"app.env", "extra.env"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("env_file = %v, want %v", got, want)
+	}
+	ports := app.Ports.([]any)
+	if len(ports) != 3 || ports[2] != "9090:90/udp" {
+		t.Errorf("ports = %v, want the override's new port appended and the duplicate dropped", ports)
+	}
+	vols := app.Volumes.([]any)
+	if len(vols) != 2 || vols[1] != "logs:/var/log" {
+		t.Fatalf("volumes = %v", vols)
+	}
+	if m, ok := vols[0].(map[string]any); !ok || m["source"] != "/srv/data" {
+		t.Errorf("volume /data = %v, want the override's bind", vols[0])
+	}
+}
+
+func TestComposePortKey(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{"80", "0.0.0.0::80/tcp"},
+		{"8080:80", "0.0.0.0:8080:80/tcp"},
+		{"127.0.0.1:8080:80/UDP", "127.0.0.1:8080:80/udp"},
+		{"[::1]:8080:80", "[::1]:8080:80/tcp"},
+		{uint64(80), "0.0.0.0::80/tcp"},
+		{map[string]any{"target": uint64(80), "published": "8080"}, "0.0.0.0:8080:80/tcp"},
+		{map[string]any{"target": uint64(80), "published": uint64(8080), "host_ip": "10.0.0.1", "protocol": "udp"}, "10.0.0.1:8080:80/udp"},
+	}
+	for _, tt := range tests {
+		if got := composePortKey(tt.in); got != tt.want {
+			t.Errorf("composePortKey(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
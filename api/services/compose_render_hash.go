@@ -18,14 +18,23 @@ import (
 	"github.com/goccy/go-yaml"
 )
 
-// computeRenderedConfigHash runs `docker compose config --hash` against the staged
-// compose set and produces a stable hash by sorting and hashing all lines.
-// On failure, returns empty string.
-func computeRenderedConfigHash(ctx context.Context, stageDir string, projectName string, files []string) string {
+// composeProjectArgs builds `compose -p <project> [--profile <p>]... -f <file>...`
+func composeProjectArgs(projectName string, files, profiles []string) []string {
 	args := []string{"compose", "-p", projectName}
+	for _, p := range profiles {
+		args = append(args, "--profile", p)
+	}
 	for _, f := range files {
 		args = append(args, "-f", f)
 	}
+	return args
+}
+
+// computeRenderedConfigHash runs `docker compose config --hash` against the staged
+// compose set and produces a stable hash by sorting and hashing all lines.
+// On failure, returns empty string.
+func computeRenderedConfigHash(ctx context.Context, stageDir string, projectName string, files, profiles []string) string {
+	args := composeProjectArgs(projectName, files, profiles)
 	args = append(args, "config", "--hash")
 
 	cmd := exec.CommandContext(ctx, "docker", args...)
@@ -52,11 +61,8 @@ func computeRenderedConfigHash(ctx context.Context, stageDir string, projectName
 }
 
 // parseServiceConfigHashes extracts service-specific config hashes from `docker compose config --hash` output
-func parseServiceConfigHashes(ctx context.Context, stageDir string, projectName string, files []string) (map[string]string, error) {
-	args := []string{"compose", "-p", projectName}
-	for _, f := range files {
-		args = append(args, "-f", f)
-	}
+func parseServiceConfigHashes(ctx context.Context, stageDir string, projectName string, files, profiles []string) (map[string]string, error) {
+	args := composeProjectArgs(projectName, files, profiles)
 	args = append(args, "config", "--hash")
 
 	cmd := exec.CommandContext(ctx, "docker", args...)
@@ -111,7 +117,7 @@ func computeComposeFilesHash(stageDir string, files []string) (string, error) {
 // 2. Service-level environment: variables
 // 3. Service-specific env_file: files (in order they appear)
 // 4. Default values from ${VAR:-default} syntax
-func renderComposeServices(ctx context.Context, stageDir, projectName string, files []string) ([]common.RenderedService, error) {
+func renderComposeServices(ctx context.Context, stageDir, projectName string, files, profiles []string) ([]common.RenderedService, error) {
 	// Create temporary directory for decrypted compose files
 	tempDir, err := os.MkdirTemp("", "ddui-render-*")
 	if err != nil {
@@ -190,20 +196,23 @@ func renderComposeServices(ctx context.Context, stageDir, projectName string, fi
 		}
 	}
 
+	// The staged -f list (declared order, overrides, nested paths) wins over the top-level scan
+	configDir := tempDir
+	if len(files) > 0 {
+		composeFiles = files
+		configDir = stageDir
+	}
 	if len(composeFiles) == 0 {
 		return nil, fmt.Errorf("no compose files found in %s", stageDir)
 	}
 
 	// Step 2: Try docker compose config first (fastest path when it works)
-	args := []string{"compose", "-p", projectName}
-	for _, f := range composeFiles {
-		args = append(args, "-f", f)
-	}
+	args := composeProjectArgs(projectName, composeFiles, profiles)
 	args = append(args, "config", "--format", "json")
 
-	common.DebugLog("Running docker compose config in %s with files: %v", tempDir, composeFiles)
+	common.DebugLog("Running docker compose config in %s with files: %v profiles: %v", configDir, composeFiles, profiles)
 	cmd := exec.CommandContext(ctx, "docker", args...)
-	cmd.Dir = tempDir
+	cmd.Dir = configDir
 	out, err := cmd.CombinedOutput()
 	
 	if err == nil {
@@ -243,7 +252,10 @@ func parseComposeWithVariableResolution(ctx context.Context, tempDir string, com
 	var rs []common.RenderedService
 	
 	for _, filename := range composeFiles {
-		content, err := os.ReadFile(filepath.Join(tempDir, filename))
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(tempDir, filename)
+		}
+		content, err := os.ReadFile(filename)
 		if err != nil {
 			common.DebugLog("Failed to read compose file %s: %v", filename, err)
 			continue
@@ -0,0 +1,273 @@
+// services/container_events.go
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// containerEventActions are the Docker lifecycle actions kept in container_events
+var containerEventActions = []string{"die", "oom", "kill", "restart", "health_status"}
+
+// ContainerEventThresholds control restart-loop and repeated-OOM detection
+type ContainerEventThresholds struct {
+	RestartLoopCount  int
+	RestartLoopWindow time.Duration
+	OOMCount          int
+	OOMWindow         time.Duration
+}
+
+// LoadContainerEventThresholds reads DD_UI_RESTART_LOOP_THRESHOLD (default 5 dies) within
+// DD_UI_RESTART_LOOP_WINDOW (default 10m), and DD_UI_OOM_THRESHOLD (default 2) within
+// DD_UI_OOM_WINDOW (default 1h)
+func LoadContainerEventThresholds() ContainerEventThresholds {
+	return ContainerEventThresholds{
+		RestartLoopCount:  max(1, common.EnvInt("DD_UI_RESTART_LOOP_THRESHOLD", 5)),
+		RestartLoopWindow: envDuration("DD_UI_RESTART_LOOP_WINDOW", 10*time.Minute),
+		OOMCount:          max(1, common.EnvInt("DD_UI_OOM_THRESHOLD", 2)),
+		OOMWindow:         envDuration("DD_UI_OOM_WINDOW", time.Hour),
+	}
+}
+
+// ContainerEventSummary is a container's recent die/oom history with detected patterns
+type ContainerEventSummary struct {
+	database.ContainerEventCounts
+	RestartLoop bool `json:"restart_loop"`
+	OOMRepeated bool `json:"oom_repeated"`
+}
+
+// ContainerEventSummaries returns per-container-name summaries for a host
+func ContainerEventSummaries(ctx context.Context, hostID int64) (map[string]ContainerEventSummary, error) {
+	t := LoadContainerEventThresholds()
+	now := time.Now()
+	counts, err := database.CountContainerEvents(ctx, hostID, now.Add(-t.RestartLoopWindow), now.Add(-t.OOMWindow))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]ContainerEventSummary, len(counts))
+	for name, c := range counts {
+		out[name] = ContainerEventSummary{
+			ContainerEventCounts: c,
+			RestartLoop:          c.Dies >= t.RestartLoopCount,
+			OOMRepeated:          c.OOMs >= t.OOMCount,
+		}
+	}
+	return out, nil
+}
+
+// StartContainerEventWatchers streams Docker container events from every host into
+// container_events (leader only; DD_UI_CONTAINER_EVENTS=false disables). Hosts are re-listed
+// every minute; each host reconnects with backoff and resumes from its last stored event
+// (at most 1h back). Events older than DD_UI_CONTAINER_EVENTS_RETENTION (default 720h) are
+// pruned hourly.
+func StartContainerEventWatchers(ctx context.Context) {
+	if !common.EnvBool("DD_UI_CONTAINER_EVENTS", "true") {
+		common.InfoLog("container events: capture disabled")
+		return
+	}
+	retention := envDuration("DD_UI_CONTAINER_EVENTS_RETENTION", 720*time.Hour)
+	t := LoadContainerEventThresholds()
+	common.InfoLog("container events: capture started (retention=%s restart_loop=%d/%s oom=%d/%s)",
+		retention, t.RestartLoopCount, t.RestartLoopWindow, t.OOMCount, t.OOMWindow)
+
+	go func() {
+		watchers := map[string]context.CancelFunc{} // host name -> cancel
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		var lastPrune time.Time
+		for {
+			hosts, err := database.ListHosts(ctx)
+			if err != nil {
+				common.DebugLog("container events: list hosts failed: %v", err)
+			} else {
+				seen := map[string]bool{}
+				for _, h := range hosts {
+					seen[h.Name] = true
+					if _, ok := watchers[h.Name]; ok {
+						continue
+					}
+					hctx, cancel := context.WithCancel(ctx)
+					watchers[h.Name] = cancel
+					go watchHostContainerEvents(hctx, h)
+				}
+				for name, cancel := range watchers {
+					if !seen[name] {
+						cancel()
+						delete(watchers, name)
+					}
+				}
+			}
+
+			if retention > 0 && time.Since(lastPrune) > time.Hour {
+				lastPrune = time.Now()
+				if n, err := database.PruneContainerEvents(ctx, time.Now().Add(-retention)); err != nil {
+					common.ErrorLog("container events: prune failed: %v", err)
+				} else if n > 0 {
+					common.InfoLog("container events: pruned %d events older than %s", n, retention)
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				for _, cancel := range watchers {
+					cancel()
+				}
+				return
+			}
+		}
+	}()
+}
+
+// watchHostContainerEvents keeps an event stream open to one host until ctx ends
+func watchHostContainerEvents(ctx context.Context, h database.HostRow) {
+	backoff := 10 * time.Second
+	for ctx.Err() == nil {
+		wait := backoff
+		if HostOffline(h.Name) {
+			wait = time.Minute
+		} else {
+			start := time.Now()
+			err := streamHostContainerEvents(ctx, h)
+			if errors.Is(err, ErrSkipScan) {
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				common.DebugLog("container events: %s: stream ended: %v", h.Name, err)
+			}
+			if time.Since(start) > 5*time.Minute {
+				backoff = 10 * time.Second // it was healthy for a while; start over
+			} else {
+				backoff *= 2
+				if backoff > 5*time.Minute {
+					backoff = 5 * time.Minute
+				}
+			}
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+		}
+	}
+}
+
+func streamHostContainerEvents(ctx context.Context, h database.HostRow) error {
+	url, sshCmd := DockerURLFor(h)
+	if IsUnixSock(url) && !LocalHostAllowed(h) {
+		return ErrSkipScan
+	}
+	cli, done, err := DockerClientForURL(ctx, url, sshCmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	since := time.Now().Add(-time.Hour)
+	if last, err := database.LastContainerEventTime(ctx, h.ID); err == nil && last.After(since) {
+		since = last
+	}
+	args := filters.NewArgs(filters.Arg("type", string(events.ContainerEventType)))
+	for _, a := range containerEventActions {
+		args.Add("event", a)
+	}
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	msgs, errs := cli.Events(sctx, events.ListOptions{Since: strconv.FormatInt(since.Unix(), 10), Filters: args})
+	common.DebugLog("container events: %s: streaming since %s", h.Name, since.Format(time.RFC3339))
+	for {
+		select {
+		case m := <-msgs:
+			recordContainerEvent(ctx, h, m)
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// recordContainerEvent normalizes and stores a Docker event, then checks for patterns
+func recordContainerEvent(ctx context.Context, h database.HostRow, m events.Message) {
+	attrs := m.Actor.Attributes
+	e := database.ContainerEventRow{
+		HostID:        h.ID,
+		ContainerID:   m.Actor.ID,
+		ContainerName: strings.TrimPrefix(attrs["name"], "/"),
+		StackName:     attrs["com.docker.compose.project"],
+		Action:        string(m.Action),
+		Signal:        attrs["signal"],
+		Image:         attrs["image"],
+		TimeNano:      m.TimeNano,
+		OccurredAt:    time.Unix(0, m.TimeNano).UTC(),
+	}
+	// health events arrive as "health_status: healthy"
+	if action, health, ok := strings.Cut(e.Action, ":"); ok {
+		e.Action, e.Health = strings.TrimSpace(action), strings.TrimSpace(health)
+	}
+	if v, ok := attrs["exitCode"]; ok {
+		if code, err := strconv.Atoi(v); err == nil {
+			e.ExitCode = &code
+		}
+	}
+	if e.ContainerName == "" {
+		e.ContainerName = e.ContainerID
+	}
+
+	inserted, err := database.InsertContainerEvent(ctx, e)
+	if err != nil {
+		common.DebugLog("container events: %s: store %s/%s failed: %v", h.Name, e.ContainerName, e.Action, err)
+		return
+	}
+	if inserted && (e.Action == "die" || e.Action == "oom") {
+		detectContainerPatterns(ctx, h, e)
+	}
+}
+
+var (
+	containerPatternMu   sync.Mutex
+	containerPatternSeen = map[string]bool{} // host/container/pattern -> currently flagged
+)
+
+// detectContainerPatterns publishes restart-loop / repeated-OOM events when a container
+// crosses a threshold; it publishes again only after the pattern has cleared
+func detectContainerPatterns(ctx context.Context, h database.HostRow, e database.ContainerEventRow) {
+	summaries, err := ContainerEventSummaries(ctx, h.ID)
+	if err != nil {
+		return
+	}
+	s := summaries[e.ContainerName]
+	t := LoadContainerEventThresholds()
+	check := func(pattern string, active bool, data map[string]any) {
+		key := h.Name + "/" + e.ContainerName + "/" + pattern
+		containerPatternMu.Lock()
+		prev := containerPatternSeen[key]
+		if active {
+			containerPatternSeen[key] = true
+		} else {
+			delete(containerPatternSeen, key)
+		}
+		containerPatternMu.Unlock()
+		if !active || prev {
+			return
+		}
+		common.WarnLog("container events: %s/%s: %s detected", h.Name, e.ContainerName, pattern)
+		data["container"] = e.ContainerName
+		data["container_id"] = e.ContainerID
+		data["image"] = e.Image
+		PublishEvent(ctx, Event{Type: pattern, Host: h.Name, Stack: e.StackName, Subject: e.ContainerName, Data: data})
+	}
+	check(EventContainerRestartLoop, s.RestartLoop, map[string]any{
+		"dies": s.Dies, "window": t.RestartLoopWindow.String(), "last_exit_code": s.LastExitCode})
+	check(EventContainerOOMRepeated, s.OOMRepeated, map[string]any{
+		"ooms": s.OOMs, "window": t.OOMWindow.String()})
+}
@@ -0,0 +1,113 @@
+// services/container_secrets.go
+package services
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+// sopsEnvKeysForProject returns the keys defined in the SOPS-encrypted env files of the IaC
+// stack deployed as a compose project on a host. Keys are readable without decrypting.
+func sopsEnvKeysForProject(ctx context.Context, hostName, project string) map[string]bool {
+	keys := map[string]bool{}
+	s, ok := iacStackForProject(ctx, hostName, project)
+	if !ok {
+		return keys
+	}
+	for _, full := range stackEnvFiles(ctx, s.ID, true) {
+		data, err := os.ReadFile(full)
+		if err != nil {
+			common.DebugLog("secrets: read %s failed: %v", full, err)
+			continue
+		}
+		for k := range utils.ParseDotenv(string(data)) {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+// iacStackForProject finds the IaC stack of a host whose compose project label is project
+func iacStackForProject(ctx context.Context, hostName, project string) (IacStackOut, bool) {
+	if project == "" {
+		return IacStackOut{}, false
+	}
+	stacks, err := listIacStacksForHost(ctx, hostName)
+	if err != nil {
+		return IacStackOut{}, false
+	}
+	for _, s := range stacks {
+		if utils.ComposeProjectLabelFromStack(s.Name) == utils.SanitizeProject(project) {
+			return s, true
+		}
+	}
+	return IacStackOut{}, false
+}
+
+// stackEnvFiles returns the absolute paths of a stack's env files (only SOPS ones if sopsOnly)
+func stackEnvFiles(ctx context.Context, stackID int64, sopsOnly bool) []string {
+	files, err := ListFilesForStack(ctx, stackID)
+	if err != nil {
+		return nil
+	}
+	root, err := GetRepoRootForStack(ctx, stackID)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, f := range files {
+		if !strings.EqualFold(f.Role, "env") || (sopsOnly && !f.Sops) {
+			continue
+		}
+		if full, err := JoinUnder(root, f.RelPath); err == nil {
+			out = append(out, full)
+		}
+	}
+	return out
+}
+
+// ContainerSecrets are a container's unmasked env and labels, read live from Docker
+type ContainerSecrets struct {
+	Env    map[string]string `json:"env"`
+	Labels map[string]string `json:"labels"`
+}
+
+// RevealContainerSecrets inspects a container and returns the env and label values that are
+// masked in stored data. Callers must gate and audit this (see handlers confirmReveal).
+func RevealContainerSecrets(ctx context.Context, h database.HostRow, ctr string) (ContainerSecrets, error) {
+	out := ContainerSecrets{Env: map[string]string{}, Labels: map[string]string{}}
+	cli, err := DockerClientForHost(h)
+	if err != nil {
+		return out, err
+	}
+	defer cli.Close()
+	info, err := cli.ContainerInspect(ctx, ctr)
+	if err != nil {
+		return out, err
+	}
+	if info.Config == nil {
+		return out, nil
+	}
+	project := info.Config.Labels["com.docker.compose.project"]
+	sopsKeys := sopsEnvKeysForProject(ctx, h.Name, project)
+	all := utils.MaskAllEnv()
+
+	masked := utils.ToEnvMap(utils.MaskEnv(info.Config.Env, sopsKeys, all))
+	for k, v := range utils.ToEnvMap(info.Config.Env) {
+		if masked[k] != v {
+			out.Env[k] = v
+		}
+	}
+	maskedLabels := utils.MaskLabels(info.Config.Labels, sopsKeys)
+	for k, v := range info.Config.Labels {
+		if maskedLabels[k] != v {
+			out.Labels[k] = v
+		}
+	}
+	return out, nil
+}
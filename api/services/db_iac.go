@@ -90,6 +90,26 @@ func UpsertIacStack(ctx context.Context, repoID int64, scopeKind, scopeName, sta
 	return id, err
 }
 
+// SetIacStackComposeConfig records a stack's compose file order (-f, relative to the stack
+// dir) and active profiles
+func SetIacStackComposeConfig(ctx context.Context, stackID int64, files, profiles []string) error {
+	if files == nil {
+		files = []string{}
+	}
+	if profiles == nil {
+		profiles = []string{}
+	}
+	_, err := common.DB.Exec(ctx, `UPDATE iac_stacks SET compose_files=$1, compose_profiles=$2 WHERE id=$3`, files, profiles, stackID)
+	return err
+}
+
+// StackComposeProfiles returns the compose profiles a stack deploys with
+func StackComposeProfiles(ctx context.Context, stackID int64) []string {
+	var profiles []string
+	_ = common.DB.QueryRow(ctx, `SELECT compose_profiles FROM iac_stacks WHERE id=$1`, stackID).Scan(&profiles)
+	return profiles
+}
+
 func upsertIacService(ctx context.Context, s IacServiceRow) error {
 	lb, _ := json.Marshal(s.Labels)
 	ek, _ := json.Marshal(s.EnvKeys)
@@ -158,20 +178,63 @@ func pruneIacStacksNotIn(ctx context.Context, repoID int64, keepIDs []int64) (in
 	return cmd.RowsAffected(), nil
 }
 
+func stackIDByScopeAndName(ctx context.Context, scopeName, stackName string) (int64, error) {
+	var id int64
+	err := common.DB.QueryRow(ctx,
+		`SELECT id FROM iac_stacks WHERE scope_name=$1 AND stack_name=$2 ORDER BY id LIMIT 1`,
+		scopeName, stackName).Scan(&id)
+	return id, err
+}
+
+func deleteIacStackByName(ctx context.Context, repoID int64, scopeName, stackName string) (int64, error) {
+	cmd, err := common.DB.Exec(ctx, `DELETE FROM iac_stacks WHERE repo_id=$1 AND scope_name=$2 AND stack_name=$3`,
+		repoID, scopeName, stackName)
+	if err != nil {
+		return 0, err
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// SnapshotIacBundleHashes returns the current bundle hash of every IaC stack
+func SnapshotIacBundleHashes(ctx context.Context) (map[int64]string, error) {
+	rows, err := common.DB.Query(ctx, `SELECT id FROM iac_stacks`)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	out := make(map[int64]string, len(ids))
+	for _, id := range ids {
+		if h, err := ComputeCurrentBundleHash(ctx, id); err == nil {
+			out[id] = h
+		}
+	}
+	return out, nil
+}
+
 /* ---------- Read for API ---------- */
 
 type IacStackOut struct {
-	ID         int64           `json:"id"`
-	Name       string          `json:"name"` // stack_name
-	ScopeKind  string          `json:"scope_kind"`
-	ScopeName  string          `json:"scope_name"`
-	DeployKind string          `json:"deploy_kind"`
-	PullPolicy string          `json:"pull_policy"`
-	SopsStatus string          `json:"sops_status"`
-	IacEnabled bool            `json:"iac_enabled"`
-	RelPath    string          `json:"rel_path"`
-	Compose    string          `json:"compose_file,omitempty"`
-	Services   []IacServiceRow `json:"services"`
+	ID              int64           `json:"id"`
+	Name            string          `json:"name"` // stack_name
+	ScopeKind       string          `json:"scope_kind"`
+	ScopeName       string          `json:"scope_name"`
+	DeployKind      string          `json:"deploy_kind"`
+	PullPolicy      string          `json:"pull_policy"`
+	SopsStatus      string          `json:"sops_status"`
+	IacEnabled      bool            `json:"iac_enabled"`
+	RelPath         string          `json:"rel_path"`
+	Compose         string          `json:"compose_file,omitempty"`
+	ComposeFiles    []string        `json:"compose_files,omitempty"`    // -f order, relative to the stack dir
+	ComposeProfiles []string        `json:"compose_profiles,omitempty"` // active compose profiles
+	Services        []IacServiceRow `json:"services"`
 }
 
 // Helper function for hierarchical API name-to-ID resolution
@@ -198,7 +261,8 @@ func listIacStacksForHost(ctx context.Context, hostName string) ([]IacStackOut,
 	common.DebugLog("Host %s belongs to groups: %v", hostName, groups)
 
 	rows, err := common.DB.Query(ctx, `
-	  SELECT id, repo_id, scope_kind, scope_name, stack_name, rel_path, compose_file, deploy_kind, pull_policy, sops_status, iac_enabled
+	  SELECT id, repo_id, scope_kind, scope_name, stack_name, rel_path, compose_file, deploy_kind, pull_policy, sops_status, iac_enabled,
+	         compose_files, compose_profiles
 	  FROM iac_stacks
 	  WHERE (scope_kind='host' AND scope_name=$1)
 	     OR (scope_kind='group' AND scope_name = ANY($2))
@@ -213,7 +277,8 @@ func listIacStacksForHost(ctx context.Context, hostName string) ([]IacStackOut,
 	for rows.Next() {
 		var s IacStackOut
 		var repoID int64
-		if err := rows.Scan(&s.ID, &repoID, &s.ScopeKind, &s.ScopeName, &s.Name, &s.RelPath, &s.Compose, &s.DeployKind, &s.PullPolicy, &s.SopsStatus, &s.IacEnabled); err != nil {
+		if err := rows.Scan(&s.ID, &repoID, &s.ScopeKind, &s.ScopeName, &s.Name, &s.RelPath, &s.Compose, &s.DeployKind, &s.PullPolicy, &s.SopsStatus, &s.IacEnabled,
+			&s.ComposeFiles, &s.ComposeProfiles); err != nil {
 			return nil, err
 		}
 		stacks = append(stacks, s)
@@ -397,16 +462,25 @@ func StackHasContent(ctx context.Context, stackID int64) (bool, error) {
 	return strings.TrimSpace(compose) != "", nil
 }
 
-// ComputeCurrentBundleHash returns a stable roll-up hash of all tracked IaC files (post-decrypt).
+// ComputeCurrentBundleHash returns a stable roll-up hash of all tracked IaC files (post-decrypt),
+// plus the rendered output of *.tmpl files.
 func ComputeCurrentBundleHash(ctx context.Context, stackID int64) (string, error) {
 	files, err := ListFilesForStack(ctx, stackID)
 	if err != nil {
 		return "", err
 	}
-	lines := make([]string, 0, len(files))
+	lines := make([]string, 0, len(files)+1)
+	hasTemplates := false
 	for _, f := range files {
 		lines = append(lines, fmt.Sprintf("%s|%s|%s|%d",
 			strings.ToLower(f.Role), f.RelPath, strings.ToLower(f.Sha256Hex), f.SizeBytes))
+		if IsTemplateFile(f.RelPath) {
+			hasTemplates = true
+		}
+	}
+	// templates also depend on inventory vars and secrets: hash what they render to
+	if hasTemplates {
+		lines = append(lines, "rendered|"+renderedTemplatesDigest(ctx, stackID, files))
 	}
 	sort.Strings(lines)
 	h := sha256.New()
@@ -427,6 +501,12 @@ type ContainerBrief struct {
 	Image      string `json:"image"`
 	State      string `json:"state"`
 	ConfigHash string `json:"config_hash,omitempty"` // com.docker.compose.config-hash
+	// recent lifecycle history from container_events
+	RecentDies   int  `json:"recent_dies,omitempty"`
+	RecentOOMs   int  `json:"recent_ooms,omitempty"`
+	LastExitCode *int `json:"last_exit_code,omitempty"`
+	RestartLoop  bool `json:"restart_loop,omitempty"`
+	OOMRepeated  bool `json:"oom_repeated,omitempty"`
 }
 
 type EnhancedIacStackOut struct {
@@ -438,6 +518,8 @@ type EnhancedIacStackOut struct {
 	RenderedServices  []common.RenderedService `json:"rendered_services,omitempty"`
 	RenderedConfigSha string            `json:"rendered_config_hash,omitempty"`
 	EffectiveAutoDevops bool            `json:"effective_auto_devops"`
+	RestartLoop       bool              `json:"restart_loop"`  // any container in a restart loop
+	OOMRepeated       bool              `json:"oom_repeated"`  // any container repeatedly OOM-killed
 }
 
 func ListEnhancedIacStacksForHost(ctx context.Context, hostName string) ([]EnhancedIacStackOut, error) {
@@ -461,6 +543,17 @@ func ListEnhancedIacStacksForHost(ctx context.Context, hostName string) ([]Enhan
 	defer cli.Close()
 	common.DebugLog("Docker client created successfully for host %s", hostName)
 
+	swarmMode := IsSwarmManager(h)
+
+	// Templates of group stacks render with this host's vars
+	ctx = context.WithValue(ctx, CtxRenderHostKey{}, hostName)
+
+	// Restart-loop / OOM history keyed by container name (best effort)
+	ctrEvents, cerr := ContainerEventSummaries(ctx, h.ID)
+	if cerr != nil {
+		common.DebugLog("Container event summaries failed for host %s: %v", hostName, cerr)
+	}
+
 	out := make([]EnhancedIacStackOut, 0, len(base))
 	for _, s := range base {
 		// Skip empty stacks to prevent 500 errors during processing
@@ -480,7 +573,11 @@ func ListEnhancedIacStacksForHost(ctx context.Context, hostName string) ([]Enhan
 		projectLabel := utils.ComposeProjectLabelFromStack(s.Name)
 
 		ff := filters.NewArgs()
-		ff.Add("label", "com.docker.compose.project="+projectLabel)
+		if swarmMode {
+			ff.Add("label", swarmNamespaceLabel+"="+projectLabel)
+		} else {
+			ff.Add("label", "com.docker.compose.project="+projectLabel)
+		}
 		common.DebugLog("Stack %s looking for containers with project label: %s", s.Name, projectLabel)
 		ctrs, lerr := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: ff})
 		if lerr == nil {
@@ -496,14 +593,28 @@ func ListEnhancedIacStacksForHost(ctx context.Context, hostName string) ([]Enhan
 				if len(c.Names) > 0 {
 					name = strings.TrimPrefix(c.Names[0], "/")
 				}
-				e.Containers = append(e.Containers, ContainerBrief{
+				service := lbl("com.docker.compose.service")
+				if service == "" && swarmMode {
+					service = strings.TrimPrefix(lbl("com.docker.swarm.service.name"), projectLabel+"_")
+				}
+				brief := ContainerBrief{
 					ID:         c.ID,
 					Name:       name,
-					Service:    lbl("com.docker.compose.service"),
+					Service:    service,
 					Image:      c.Image,
 					State:      c.State,
 					ConfigHash: lbl("com.docker.compose.config-hash"),
-				})
+				}
+				if ev, ok := ctrEvents[name]; ok {
+					brief.RecentDies = ev.Dies
+					brief.RecentOOMs = ev.OOMs
+					brief.LastExitCode = ev.LastExitCode
+					brief.RestartLoop = ev.RestartLoop
+					brief.OOMRepeated = ev.OOMRepeated
+					e.RestartLoop = e.RestartLoop || ev.RestartLoop
+					e.OOMRepeated = e.OOMRepeated || ev.OOMRepeated
+				}
+				e.Containers = append(e.Containers, brief)
 			}
 		}
 
@@ -533,10 +644,20 @@ func ListEnhancedIacStacksForHost(ctx context.Context, hostName string) ([]Enhan
 				defer cleanup()
 			}
 			// Rendered config hash (best effort)
-			e.RenderedConfigSha = computeRenderedConfigHash(ctx, stageDir, s.Name, stagedComposes)
+			e.RenderedConfigSha = computeRenderedConfigHash(ctx, stageDir, s.Name, stagedComposes, s.ComposeProfiles)
+
+			// Swarm: compose config-hash labels don't exist; compare replicas/images with the swarm instead
+			if swarmMode && err == nil && !e.DriftDetected {
+				if drift, reason, serr := DetectSwarmDrift(ctx, cli, projectLabel, stageDir, stagedComposes); serr == nil {
+					e.DriftDetected = drift
+					e.DriftReason = reason
+				} else {
+					common.DebugLog("Stack %s swarm drift check failed: %v", s.Name, serr)
+				}
+			}
 
 			// Fully rendered services (post-decrypt, post-interpolation)
-			if rs, rerr := renderComposeServices(ctx, stageDir, s.Name, stagedComposes); rerr == nil {
+			if rs, rerr := renderComposeServices(ctx, stageDir, s.Name, stagedComposes, s.ComposeProfiles); rerr == nil {
 				e.RenderedServices = rs
 				common.DebugLog("Stack %s rendered %d services", s.Name, len(rs))
 				for _, r := range rs {
@@ -550,6 +671,10 @@ func ListEnhancedIacStacksForHost(ctx context.Context, hostName string) ([]Enhan
 			common.DebugLog("Stack %s staging failed: %v", s.Name, derr)
 		}
 
+		if err == nil {
+			noteStackDrift(ctx, hostName, s.ID, s.Name, e.DriftDetected, e.DriftReason)
+		}
+
 		// Calculate effective auto devops for this stack
 		e.EffectiveAutoDevops, _ = ShouldAutoApply(ctx, s.ID)
 
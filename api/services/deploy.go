@@ -97,8 +97,15 @@ func DeployStack(ctx context.Context, stackID int64) error {
 		return nil
 	}
 
+	// Copy stack-relative bind sources and config files to the target host
+	if err := SyncStackAssets(ctx, stackID, stageDir, stagedComposes); err != nil {
+		common.ErrorLog("deploy: stack %d: %v", stackID, err)
+		return err
+	}
+
 	// Precompute rendered config-hash + bundle hash (best effort; for stamping/drift).
-	renderedCfgHash := computeRenderedConfigHash(ctx, stageDir, rawProjectName, stagedComposes)
+	profiles := StackComposeProfiles(ctx, stackID)
+	renderedCfgHash := computeRenderedConfigHash(ctx, stageDir, rawProjectName, stagedComposes, profiles)
 	bundleHash, _ := ComputeCurrentBundleHash(ctx, stackID)
 
 	// Build a deployment stamp (content bytes = concatenated staged compose files).
@@ -106,6 +113,7 @@ func DeployStack(ctx context.Context, stackID int64) error {
 	meta := map[string]string{
 		"rendered_config_hash": renderedCfgHash,
 		"bundle_hash":          bundleHash,
+		"compose_profiles":     strings.Join(profiles, ","),
 	}
 	var allComposeContent []byte
 	for _, f := range stagedComposes {
@@ -116,13 +124,24 @@ func DeployStack(ctx context.Context, stackID int64) error {
 		allComposeContent = append(allComposeContent, b...)
 		allComposeContent = append(allComposeContent, '\n')
 	}
-	stamp, serr := database.CreateDeploymentStamp(ctx, stackID, "compose", "", allComposeContent, meta)
+	// Swarm managers deploy with `docker stack deploy` (namespace = sanitized stack name)
+	swarmMode := false
+	if host, herr := getHostForStack(ctx, stackID); herr == nil && IsSwarmManager(host) {
+		swarmMode = true
+	}
+	method := "compose"
+	if swarmMode {
+		method = "swarm"
+	}
+
+	stamp, serr := database.CreateDeploymentStamp(ctx, stackID, method, "", allComposeContent, meta)
 	if serr != nil {
 		common.InfoLog("deploy: failed to create deployment stamp: %v", serr)
 		// If stamp creation fails due to unique constraint, try to find the existing one
 		if existingStamp, findErr := database.CheckDeploymentStampExists(ctx, stackID, allComposeContent); findErr == nil && existingStamp != nil {
 			common.InfoLog("deploy: reusing existing deployment stamp %d", existingStamp.ID)
 			stamp = existingStamp
+			_ = database.SetDeploymentStampBundleHash(ctx, stamp.ID, bundleHash)
 		} else {
 			common.ErrorLog("deploy: could not find existing stamp either: %v", findErr)
 			return serr
@@ -136,11 +155,19 @@ func DeployStack(ctx context.Context, stackID int64) error {
 	common.DebugLog("deploy: created/found stamp with ID %d for stack %d", stamp.ID, stackID)
 
 	// docker compose -p <RAW stack name> -f ... up -d --remove-orphans
-	args := []string{"compose", "-p", rawProjectName}
-	for _, f := range stagedComposes {
-		args = append(args, "-f", f)
+	// (swarm: docker stack deploy --prune -c ... <label project>)
+	var args []string
+	if swarmMode {
+		if len(profiles) > 0 {
+			common.WarnLog("deploy: stack %d: docker stack deploy ignores compose profiles %v", stackID, profiles)
+		}
+		args = swarmDeployArgs(labelProject, stagedComposes)
+	} else {
+		args = composeProjectArgs(rawProjectName, stagedComposes, profiles)
+		args = append(args, "up", "-d", "--remove-orphans")
 	}
-	args = append(args, "up", "-d", "--remove-orphans")
+
+	bin := "docker"
 
 	// Create a minimal environment to prevent host environment leakage
 	// Docker Compose should only use env vars from .env files in the staged directory
@@ -166,8 +193,18 @@ func DeployStack(ctx context.Context, stackID int64) error {
 			common.ErrorLog("deploy: failed to setup SSH config for Docker CLI: %v", err)
 			return err
 		}
+		if dockerURL, err = CLIDockerHost(host, dockerURL); err != nil {
+			common.ErrorLog("deploy: %v", err)
+			return err
+		}
 		
-		dockerEnv = append(dockerEnv, "DOCKER_HOST="+dockerURL)
+		if IsPodmanHost(host) && !swarmMode {
+			var podmanEnv []string
+			bin, args, podmanEnv = podmanComposeInvocation(host, dockerURL, args)
+			dockerEnv = append(dockerEnv, podmanEnv...)
+		} else {
+			dockerEnv = append(dockerEnv, "DOCKER_HOST="+dockerURL)
+		}
 		common.DebugLog("deploy: using Docker host %s for stack %d with minimal env (no host leakage)", dockerURL, stackID)
 	} else {
 		common.ErrorLog("deploy: failed to get host for stack %d, using default Docker connection: %v", stackID, herr)
@@ -175,7 +212,12 @@ func DeployStack(ctx context.Context, stackID int64) error {
 		common.DebugLog("deploy: using default Docker connection with minimal env (no host leakage)")
 	}
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	if swarmMode {
+		// stack deploy doesn't read .env; export the decrypted values for interpolation
+		dockerEnv = append(dockerEnv, swarmInterpolationEnv(stageDir)...)
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args...)
 	cmd.Dir = stageDir
 	cmd.Env = dockerEnv
 
@@ -184,6 +226,11 @@ func DeployStack(ctx context.Context, stackID int64) error {
 		if stamp != nil {
 			_ = database.UpdateDeploymentStampStatus(ctx, stamp.ID, "failed")
 		}
+		publishDeployEvent(ctx, stackID, rawProjectName, err)
+		if swarmMode {
+			common.LogCommandError("deploy: docker stack deploy", err, out)
+			return fmt.Errorf("docker stack deploy failed: %v", err)
+		}
 		common.LogCommandError("deploy: docker compose", err, out)
 		return fmt.Errorf("docker compose up failed: %v", err)
 	}
@@ -226,6 +273,7 @@ func DeployStack(ctx context.Context, stackID int64) error {
 		}(labelProject, stamp.ID, stamp.DeploymentHash)
 	}
 
+	publishDeployEvent(ctx, stackID, rawProjectName, nil)
 	common.InfoLog("deploy: stack %d deployed (compose=%d, stage=%s, repoRoot=%s, stamp=%v)",
 		stackID, len(stagedComposes), stageDir, root, stamp != nil)
 
@@ -307,14 +355,22 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 		return nil
 	}
 
+	// Copy stack-relative bind sources and config files to the target host
+	if err := SyncStackAssets(ctx, stackID, stageDir, stagedComposes); err != nil {
+		sendEvent("error", fmt.Sprintf("Failed to sync stack files: %v", err), nil)
+		return err
+	}
+
 	// Precompute rendered config-hash + bundle hash (best effort; for stamping/drift).
-	renderedCfgHash := computeRenderedConfigHash(ctx, stageDir, rawProjectName, stagedComposes)
+	profiles := StackComposeProfiles(ctx, stackID)
+	renderedCfgHash := computeRenderedConfigHash(ctx, stageDir, rawProjectName, stagedComposes, profiles)
 	bundleHash, _ := ComputeCurrentBundleHash(ctx, stackID)
 
 	// Build a deployment stamp - first check if configuration has changed
 	meta := map[string]string{
 		"rendered_config_hash": renderedCfgHash,
 		"bundle_hash":          bundleHash,
+		"compose_profiles":     strings.Join(profiles, ","),
 	}
 	var allComposeContent []byte
 	for _, f := range stagedComposes {
@@ -345,13 +401,24 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 		}
 	}
 
-	stamp, serr := database.CreateDeploymentStamp(ctx, stackID, "compose", "", allComposeContent, meta)
+	// Swarm managers deploy with `docker stack deploy` (namespace = sanitized stack name)
+	swarmMode := false
+	if host, herr := getHostForStack(ctx, stackID); herr == nil && IsSwarmManager(host) {
+		swarmMode = true
+	}
+	method := "compose"
+	if swarmMode {
+		method = "swarm"
+	}
+
+	stamp, serr := database.CreateDeploymentStamp(ctx, stackID, method, "", allComposeContent, meta)
 	if serr != nil {
 		common.InfoLog("deploy: failed to create deployment stamp: %v", serr)
 		// If stamp creation fails due to unique constraint, try to find the existing one
 		if existingStamp, findErr := database.CheckDeploymentStampExists(ctx, stackID, allComposeContent); findErr == nil && existingStamp != nil {
 			common.InfoLog("deploy: reusing existing deployment stamp %d", existingStamp.ID)
 			stamp = existingStamp
+			_ = database.SetDeploymentStampBundleHash(ctx, stamp.ID, bundleHash)
 			sendEvent("info", "Reusing existing deployment stamp", nil)
 		} else {
 			common.ErrorLog("deploy: could not find existing stamp either: %v", findErr)
@@ -367,12 +434,19 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 	}
 	common.DebugLog("deploy: created/found stamp with ID %d for stack %d", stamp.ID, stackID)
 
-	// docker compose command
-	args := []string{"compose", "-p", rawProjectName}
-	for _, f := range stagedComposes {
-		args = append(args, "-f", f)
+	// docker compose command (swarm managers: docker stack deploy)
+	var args []string
+	if swarmMode {
+		if len(profiles) > 0 {
+			common.WarnLog("deploy: stack %d: docker stack deploy ignores compose profiles %v", stackID, profiles)
+		}
+		args = swarmDeployArgs(labelProject, stagedComposes)
+	} else {
+		args = composeProjectArgs(rawProjectName, stagedComposes, profiles)
+		args = append(args, "up", "-d", "--remove-orphans")
 	}
-	args = append(args, "up", "-d", "--remove-orphans")
+
+	bin := "docker"
 
 	// Create a minimal environment to prevent host environment leakage
 	// Docker Compose should only use env vars from .env files in the staged directory
@@ -399,8 +473,19 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 			sendEvent("error", fmt.Sprintf("Failed to setup SSH config: %v", err), nil)
 			return err
 		}
+		if dockerURL, err = CLIDockerHost(host, dockerURL); err != nil {
+			common.ErrorLog("deploy: %v", err)
+			sendEvent("error", err.Error(), nil)
+			return err
+		}
 		
-		dockerEnv = append(dockerEnv, "DOCKER_HOST="+dockerURL)
+		if IsPodmanHost(host) && !swarmMode {
+			var podmanEnv []string
+			bin, args, podmanEnv = podmanComposeInvocation(host, dockerURL, args)
+			dockerEnv = append(dockerEnv, podmanEnv...)
+		} else {
+			dockerEnv = append(dockerEnv, "DOCKER_HOST="+dockerURL)
+		}
 		common.DebugLog("deploy: using Docker host %s for stack %d with minimal env (no host leakage)", dockerURL, stackID)
 		sendEvent("info", fmt.Sprintf("Using Docker host: %s (isolated environment)", dockerURL), nil)
 	} else {
@@ -409,9 +494,14 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 		sendEvent("info", "Using default Docker connection (isolated environment)", nil)
 	}
 
-	sendEvent("info", fmt.Sprintf("Running: docker %s", strings.Join(args, " ")), nil)
+	sendEvent("info", fmt.Sprintf("Running: %s %s", bin, strings.Join(args, " ")), nil)
 
-	cmd := exec.CommandContext(ctx, "docker", args...)
+	if swarmMode {
+		// stack deploy doesn't read .env; export the decrypted values for interpolation
+		dockerEnv = append(dockerEnv, swarmInterpolationEnv(stageDir)...)
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args...)
 	cmd.Dir = stageDir
 	cmd.Env = dockerEnv
 
@@ -433,6 +523,7 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 		if stamp != nil {
 			_ = database.UpdateDeploymentStampStatus(ctx, stamp.ID, "failed")
 		}
+		publishDeployEvent(ctx, stackID, rawProjectName, err)
 		return err
 	}
 
@@ -470,6 +561,7 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 		if stamp != nil {
 			_ = database.UpdateDeploymentStampStatus(ctx, stamp.ID, "failed")
 		}
+		publishDeployEvent(ctx, stackID, rawProjectName, cmdErr)
 		sendEvent("error", fmt.Sprintf("Docker compose failed: %v", cmdErr), nil)
 		return cmdErr
 	}
@@ -512,6 +604,7 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 		}(labelProject, stamp.ID, stamp.DeploymentHash)
 	}
 
+	publishDeployEvent(ctx, stackID, rawProjectName, nil)
 	sendEvent("complete", fmt.Sprintf("Deployment of stack %s completed successfully", rawProjectName), map[string]interface{}{
 		"success": true,
 		"stackID": stackID,
@@ -520,11 +613,30 @@ func DeployStackWithStream(ctx context.Context, stackID int64, eventChannel chan
 	return nil
 }
 
+// publishDeployEvent emits deploy.succeeded or deploy.failed for a stack
+func publishDeployEvent(ctx context.Context, stackID int64, stackName string, err error) {
+	e := Event{Type: EventDeploySucceeded, Stack: stackName, Subject: stackName, Data: map[string]any{"stack_id": stackID}}
+	if h, herr := getHostForStack(ctx, stackID); herr == nil {
+		e.Host = h.Name
+	}
+	if man, _ := ctx.Value(CtxManualKey{}).(bool); man {
+		e.Data["trigger"] = "manual"
+	} else {
+		e.Data["trigger"] = "auto"
+	}
+	if err != nil {
+		e.Type = EventDeployFailed
+		e.Data["error"] = err.Error()
+	}
+	PublishEvent(ctx, e)
+}
+
 // associateByProjectInspect stamps all containers with the given Compose project label value.
 func associateByProjectInspect(ctx context.Context, projectLabel string, stampID int64, deploymentHash string, stackID int64) error {
 	var cli *client.Client
 	var done func()
-	if host, herr := getHostForStack(ctx, stackID); herr == nil {
+	host, herr := getHostForStack(ctx, stackID)
+	if herr == nil {
 		dockerURL, sshCmd := DockerURLFor(host)
 		if c, d, derr := DockerClientForURL(ctx, dockerURL, sshCmd); derr == nil {
 			cli = c
@@ -542,7 +654,12 @@ func associateByProjectInspect(ctx context.Context, projectLabel string, stampID
 	}()
 
 	flt := filters.NewArgs()
-	flt.Add("label", "com.docker.compose.project="+projectLabel)
+	if IsSwarmManager(host) {
+		// only tasks scheduled on the manager itself are visible here
+		flt.Add("label", swarmNamespaceLabel+"="+projectLabel)
+	} else {
+		flt.Add("label", "com.docker.compose.project="+projectLabel)
+	}
 
 	list, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: flt})
 	if err != nil {
@@ -567,17 +684,17 @@ func associateByProjectInspect(ctx context.Context, projectLabel string, stampID
 
 // setupSSHConfigForDocker creates SSH config for Docker CLI to use proper authentication
 func setupSSHConfigForDocker(host database.HostRow) error {
-	// Get SSH configuration
-	user := host.Vars["ansible_user"]
-	if user == "" {
-		user = common.Env("SSH_USER", "root")
+	// Agent hosts are reached through a local proxy socket, no SSH involved
+	if IsAgentHost(host) {
+		return nil
 	}
-	addr := host.Addr
-	if addr == "" {
-		addr = host.Name
+	
+	// Get SSH configuration (per-host vars, jump hosts; see ssh_target.go)
+	t, err := SSHTargetForHost(context.Background(), host)
+	if err != nil {
+		return err
 	}
-	keyFile := common.Env("SSH_KEY_FILE", "")
-	if keyFile == "" {
+	if t.KeyFile == "" {
 		return fmt.Errorf("SSH_KEY_FILE not configured")
 	}
 	
@@ -594,15 +711,17 @@ func setupSSHConfigForDocker(host database.HostRow) error {
 	// Create SSH config file
 	configPath := sshDir + "/config"
 	
-	// Check if config already has this host
-	configContent := fmt.Sprintf(`Host %s
-    User %s
-    IdentityFile %s
-    StrictHostKeyChecking no
-    ConnectTimeout 30
-    UserKnownHostsFile /dev/null
-
-`, addr, user, keyFile)
+	// Verify (or pin on first use) every hop's host key before the docker CLI connects
+	if err := utils.PinSSHTargetHostKeys(context.Background(), t); err != nil {
+		return err
+	}
+	hostKeyOpts, err := utils.HostKeyConfigOptions()
+	if err != nil {
+		return err
+	}
+	
+	// One block per jump hop (chained with ProxyJump) plus the target's own block
+	configContent := utils.OpenSSHConfigBlocks(t, t.Addr, hostKeyOpts)
 	
 	// Read existing config
 	existingConfig := ""
@@ -610,23 +729,40 @@ func setupSSHConfigForDocker(host database.HostRow) error {
 		existingConfig = string(data)
 	}
 	
-	// Check if this host config already exists
-	if strings.Contains(existingConfig, fmt.Sprintf("Host %s", addr)) {
-		common.DebugLog("SSH config for host %s already exists", addr)
+	// Check if this host config already exists unchanged
+	if strings.Contains(existingConfig, configContent) {
+		common.DebugLog("SSH config for host %s already exists", t.Addr)
 		return nil
 	}
 	
-	// Append new host config
-	file, err := os.OpenFile(configPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
-	if err != nil {
-		return fmt.Errorf("failed to open SSH config file: %v", err)
+	// Replace stale blocks for this host and its hops (e.g. ones written with StrictHostKeyChecking no)
+	newConfig := existingConfig
+	for _, line := range strings.Split(configContent, "\n") {
+		if alias, ok := strings.CutPrefix(line, "Host "); ok {
+			newConfig = removeSSHConfigHostBlock(newConfig, alias)
+		}
 	}
-	defer file.Close()
-	
-	if _, err := file.WriteString(configContent); err != nil {
+	newConfig += configContent
+	if err := os.WriteFile(configPath, []byte(newConfig), 0600); err != nil {
 		return fmt.Errorf("failed to write SSH config: %v", err)
 	}
 	
-	common.DebugLog("SSH config added for host %s (%s@%s)", host.Name, user, addr)
+	common.DebugLog("SSH config added for host %s (%s)", host.Name, t)
 	return nil
 }
+
+// removeSSHConfigHostBlock drops the "Host <addr>" block from an ssh config
+func removeSSHConfigHostBlock(config, addr string) string {
+	var out []string
+	skipping := false
+	for _, line := range strings.Split(config, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Host ") {
+			skipping = trimmed == "Host "+addr
+		}
+		if !skipping {
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -396,8 +396,16 @@ func parseEnvFileRefs(yml []byte) (map[string][]string, error) {
 			}
 		case []any:
 			for _, it := range v {
-				if s, ok := it.(string); ok && strings.TrimSpace(s) != "" {
-					refs = append(refs, s)
+				switch e := it.(type) {
+				case string:
+					if strings.TrimSpace(e) != "" {
+						refs = append(refs, e)
+					}
+				case map[string]any:
+					// long syntax: {path: ..., required: ...}
+					if s, _ := e["path"].(string); strings.TrimSpace(s) != "" {
+						refs = append(refs, s)
+					}
 				}
 			}
 		}
@@ -412,7 +420,7 @@ func parseEnvFileRefs(yml []byte) (map[string][]string, error) {
 
 // stageStackForCompose prepares a scope-aware staging directory that mirrors the IaC layout,
 // copying compose/scripts/other files verbatim and materializing any env files decrypted with
-// their original names/paths. It returns:
+// their original names/paths. *.tmpl files are rendered (see iac_render.go). It returns:
 //   - stageStackDir: the directory compose should run in (mirrors the stack's rel_path)
 //   - stagedComposes: absolute paths to compose files within the stage tree (pass with -f ...)
 //   - cleanup: removes the staging directory
@@ -429,8 +437,9 @@ func StageStackForCompose(ctx context.Context, stackID int64) (stageStackDir str
 		scopeName  string
 		stackName  string
 	)
-	_ = common.DB.QueryRow(ctx, `SELECT rel_path, scope_kind::text, scope_name, stack_name FROM iac_stacks WHERE id=$1`, stackID).
-		Scan(&rel, &scopeKind, &scopeName, &stackName)
+	var composeOrder []string
+	_ = common.DB.QueryRow(ctx, `SELECT rel_path, scope_kind::text, scope_name, stack_name, compose_files FROM iac_stacks WHERE id=$1`, stackID).
+		Scan(&rel, &scopeKind, &scopeName, &stackName, &composeOrder)
 	if strings.TrimSpace(rel) == "" {
 		return "", nil, func() {}, fmt.Errorf("deploy: stack has no rel_path")
 	}
@@ -472,20 +481,24 @@ func StageStackForCompose(ctx context.Context, stackID int64) (stageStackDir str
 	cleanup = func() { _ = os.RemoveAll(leaf) }
 
 	// Gather tracked files
-	rows, err := common.DB.Query(ctx, `SELECT role, rel_path FROM iac_stack_files WHERE stack_id=$1`, stackID)
+	rows, err := common.DB.Query(ctx, `SELECT role, rel_path, sops FROM iac_stack_files WHERE stack_id=$1`, stackID)
 	if err != nil {
 		return "", nil, cleanup, err
 	}
 	defer rows.Close()
 
-	type rec struct{ role, relPath, srcAbs, dstAbs string }
+	type rec struct {
+		role, relPath, srcAbs, dstAbs string
+		sops                          bool
+	}
 	var (
 		files          []rec
 		composePairs   = map[string]string{} // staged compose -> source compose (for ref resolution)
 	)
 	for rows.Next() {
 		var role, rp string
-		if err := rows.Scan(&role, &rp); err != nil {
+		var sops bool
+		if err := rows.Scan(&role, &rp, &sops); err != nil {
 			return "", nil, cleanup, err
 		}
 		srcAbs, jerr := joinUnderLocal(root, rp)
@@ -496,13 +509,39 @@ func StageStackForCompose(ctx context.Context, stackID int64) (stageStackDir str
 		if sj != nil {
 			return "", nil, cleanup, sj
 		}
-		files = append(files, rec{role: strings.ToLower(role), relPath: rp, srcAbs: srcAbs, dstAbs: dstAbs})
+		files = append(files, rec{role: strings.ToLower(role), relPath: rp, srcAbs: srcAbs, dstAbs: dstAbs, sops: sops})
 	}
 
 	// Copy files into stage:
 	//  - compose/scripts/other: copy plaintext (if compose is SOPS-encrypted, decrypt to plaintext)
 	//  - env: decrypt to plaintext and filter sops_* keys
+	//  - *.tmpl (compose/env/other): decrypt, render with inventory vars, stage without the suffix
+	var renderer *stackRenderer
 	for _, f := range files {
+		if IsTemplateFile(f.relPath) && f.role != "script" {
+			if renderer == nil {
+				if renderer, err = newStackRenderer(ctx, stackID); err != nil {
+					return "", nil, cleanup, err
+				}
+			}
+			out, rerr := renderer.renderFile(ctx, f.role, f.relPath, f.srcAbs)
+			if rerr != nil {
+				return "", nil, cleanup, rerr
+			}
+			dst := strings.TrimSuffix(f.dstAbs, TemplateSuffix)
+			mode := os.FileMode(0o644)
+			if f.role == "env" || f.sops || len(renderer.revealed) > 0 {
+				mode = 0o600
+			}
+			if err := writeFileSecure(dst, out, mode); err != nil {
+				return "", nil, cleanup, err
+			}
+			if f.role == "compose" {
+				stagedComposes = append(stagedComposes, dst)
+				composePairs[dst] = strings.TrimSuffix(f.srcAbs, TemplateSuffix)
+			}
+			continue
+		}
 		switch f.role {
 		case "env":
 			content, wasDecrypted, derr := readDecryptedOrPlain(ctx, f.srcAbs, "dotenv")
@@ -533,14 +572,31 @@ func StageStackForCompose(ctx context.Context, stackID int64) (stageStackDir str
 			}
 			stagedComposes = append(stagedComposes, f.dstAbs)
 			composePairs[f.dstAbs] = f.srcAbs
+		case "other":
+			// auxiliary files (bind mount sources, configs): decrypt SOPS-encrypted ones
+			if !f.sops {
+				if err := copyRegularFile(f.srcAbs, f.dstAbs, 0o644); err != nil {
+					return "", nil, cleanup, err
+				}
+				continue
+			}
+			plain, _, derr := readDecryptedOrPlain(ctx, f.srcAbs, sopsInputType(f.relPath))
+			if derr != nil {
+				return "", nil, cleanup, derr
+			}
+			if err := writeFileSecure(f.dstAbs, plain, 0o600); err != nil {
+				return "", nil, cleanup, err
+			}
 		default:
-			// scripts/other auxiliary files
+			// scripts
 			if err := copyRegularFile(f.srcAbs, f.dstAbs, 0o644); err != nil {
 				return "", nil, cleanup, err
 			}
 		}
 	}
 
+	stagedComposes = orderStagedComposes(stageStackDir, stagedComposes, composeOrder, renderHostFor(ctx, scopeKind, scopeName))
+
 	// Ensure project .env (default interpolation) is staged & decrypted if present
 	origStackDir, err := joinUnderLocal(root, rel)
 	if err != nil {
@@ -600,5 +656,55 @@ func StageStackForCompose(ctx context.Context, stackID int64) (stageStackDir str
 		}
 	}
 
+	// Remote hosts: point stack-relative bind sources at the managed copy SyncStackAssets uploads
+	if _, remoteDir, ok := stackAssetsTarget(ctx, stackID); ok && len(stagedComposes) > 0 {
+		override, oerr := writeStackAssetsOverride(stageStackDir, remoteDir, stagedComposes)
+		if oerr != nil {
+			return "", nil, cleanup, oerr
+		}
+		if override != "" {
+			stagedComposes = append(stagedComposes, override)
+		}
+	}
+
 	return stageStackDir, stagedComposes, cleanup, nil
 }
+
+// orderStagedComposes returns the staged compose files to pass with -f, in the stack's
+// declared order. Per-host overrides of other hosts and include:/extends: targets (staged,
+// but loaded by compose itself) are left out. Stacks scanned before compose_files existed
+// keep every staged compose file, sorted.
+func orderStagedComposes(stageStackDir string, staged, order []string, host string) []string {
+	if len(order) == 0 {
+		sort.Strings(staged)
+		return staged
+	}
+	have := make(map[string]bool, len(staged))
+	for _, f := range staged {
+		have[f] = true
+	}
+	out := make([]string, 0, len(order))
+	for _, rel := range order {
+		if !composeFileApplies(rel, host) {
+			continue
+		}
+		full, err := joinUnderLocal(stageStackDir, strings.TrimSuffix(rel, TemplateSuffix))
+		if err == nil && have[full] {
+			out = append(out, full)
+		}
+	}
+	return out
+}
+
+// sopsInputType picks the sops --input-type for a file by extension ("" lets sops detect)
+func sopsInputType(p string) string {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".yml", ".yaml":
+		return "yaml"
+	case ".json":
+		return "json"
+	case ".env":
+		return "dotenv"
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+// services/events.go
+package services
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+// Event is a typed DD-UI state change, streamed on /api/events and delivered to webhooks
+type Event = database.EventRow
+
+// Event types
+const (
+	EventContainerCreated     = "container.created"
+	EventContainerState       = "container.state_changed"
+	EventContainerRemoved     = "container.removed"
+	EventContainerRestartLoop = "container.restart_loop"
+	EventContainerOOMRepeated = "container.oom_repeated"
+	EventDeploySucceeded      = "deploy.succeeded"
+	EventDeployFailed         = "deploy.failed"
+	EventStackOpSucceeded     = "stack.operation.succeeded"
+	EventStackOpFailed        = "stack.operation.failed"
+	EventStackDrift           = "stack.drift_changed"
+	EventGitSync              = "git.sync"
+	EventCleanupCompleted     = "cleanup.completed"
+	EventCleanupFailed        = "cleanup.failed"
+	EventWebhookPing          = "webhook.ping"
+	EventSecretRevealed       = "secret.revealed"
+)
+
+var (
+	eventSubsMu    sync.RWMutex
+	eventSubs      = map[int64]chan Event{}
+	eventSubSeq    int64
+	eventListening atomic.Bool // this replica receives its own events via LISTEN
+	eventWake      = make(chan struct{}, 1)
+)
+
+// PublishEvent stores an event, announces it to every replica's subscribers and queues it
+// for matching webhooks. Never blocks callers on failures: events are best effort.
+func PublishEvent(ctx context.Context, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	id, err := database.InsertEvent(ctx, e)
+	if err != nil {
+		common.DebugLog("events: store %s failed: %v", e.Type, err)
+		fanoutEvent(e)
+		return
+	}
+	e.ID = id
+	if err := database.NotifyEvent(ctx, id); err != nil || !eventListening.Load() {
+		fanoutEvent(e)
+	}
+	if n, err := database.EnqueueWebhookDeliveries(ctx, id, e.Type); err != nil {
+		common.WarnLog("events: queue webhooks for %s failed: %v", e.Type, err)
+	} else if n > 0 {
+		select {
+		case eventWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+var (
+	stackDriftMu   sync.Mutex
+	stackDriftSeen = map[int64]bool{} // stack id -> last observed drift
+)
+
+// noteStackDrift emits stack.drift_changed when a stack's drift flips. The first
+// observation after start only records the state, so restarts don't replay every drifted stack.
+func noteStackDrift(ctx context.Context, hostName string, stackID int64, stackName string, drift bool, reason string) {
+	stackDriftMu.Lock()
+	prev, known := stackDriftSeen[stackID]
+	stackDriftSeen[stackID] = drift
+	stackDriftMu.Unlock()
+	if !known || prev == drift {
+		return
+	}
+	PublishEvent(ctx, Event{Type: EventStackDrift, Host: hostName, Stack: stackName, Subject: stackName,
+		Data: map[string]any{"stack_id": stackID, "drift": drift, "reason": reason}})
+}
+
+// SubscribeEvents returns a channel of live events and a cancel func. Slow subscribers
+// miss events rather than stall publishers.
+func SubscribeEvents() (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+	eventSubsMu.Lock()
+	eventSubSeq++
+	id := eventSubSeq
+	eventSubs[id] = ch
+	eventSubsMu.Unlock()
+	return ch, func() {
+		eventSubsMu.Lock()
+		delete(eventSubs, id)
+		eventSubsMu.Unlock()
+	}
+}
+
+func fanoutEvent(e Event) {
+	eventSubsMu.RLock()
+	defer eventSubsMu.RUnlock()
+	for _, ch := range eventSubs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// EventMatches reports whether an event type matches any pattern ("deploy.*", "*");
+// no patterns matches everything
+func EventMatches(patterns []string, eventType string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.TrimSpace(p), eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// StartEventListener LISTENs for events published by any replica and fans them out to
+// this replica's subscribers. Runs on every replica; reconnects after errors.
+func StartEventListener(ctx context.Context) {
+	go func() {
+		for ctx.Err() == nil {
+			if err := listenEvents(ctx); err != nil && ctx.Err() == nil {
+				common.DebugLog("events: listener stopped: %v", err)
+			}
+			eventListening.Store(false)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+			}
+		}
+	}()
+}
+
+func listenEvents(ctx context.Context) error {
+	pooled, err := common.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// LISTEN state stays on the connection; take it out of the pool and close it when done
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+database.EventsChannel); err != nil {
+		return err
+	}
+	eventListening.Store(true)
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.ParseInt(n.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+		e, err := database.GetEvent(ctx, id)
+		if err != nil {
+			common.DebugLog("events: load event %d failed: %v", id, err)
+			continue
+		}
+		fanoutEvent(e)
+	}
+}
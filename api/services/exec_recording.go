@@ -0,0 +1,328 @@
+// services/exec_recording.go
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+/*
+Exec session recording (asciinema v2).
+
+A recording is an asciicast v2 document: one JSON header line followed by one
+JSON array per event, [seconds, code, data]. Codes used here:
+  "o" - output written to the terminal
+  "i" - input typed by the user
+  "r" - terminal resize ("COLSxROWS")
+
+Policy (most specific wins):
+  host override (app_settings host:<name>:exec_recording)
+  inventory var dd_ui_exec_recording on the host
+  group overrides (app_settings group:<name>:exec_recording)
+  global (app_settings exec_recording, else DD_UI_EXEC_RECORDING env, default false)
+*/
+
+const execRecordingKey = "exec_recording"
+
+// ExecRecorder buffers a console session in asciicast v2 form. All methods are
+// safe on a nil receiver so callers can record unconditionally.
+type ExecRecorder struct {
+	mu        sync.Mutex
+	hostID    int64
+	hostName  string
+	container string
+	user      string
+	shell     string
+	started   time.Time
+	width     int
+	height    int
+	sized     bool
+	events    bytes.Buffer
+	count     int
+	maxBytes  int
+	truncated bool
+	pending   map[string][]byte // incomplete UTF-8 tails per event code
+}
+
+// NewExecRecorder starts a recording for a console session.
+func NewExecRecorder(h database.HostRow, container, user string) *ExecRecorder {
+	return &ExecRecorder{
+		hostID:    h.ID,
+		hostName:  h.Name,
+		container: container,
+		user:      user,
+		started:   time.Now(),
+		width:     80,
+		height:    24,
+		maxBytes:  common.EnvInt("DD_UI_EXEC_RECORDING_MAX_BYTES", 50*1024*1024),
+		pending:   map[string][]byte{},
+	}
+}
+
+// SetShell records the command that was started in the container.
+func (r *ExecRecorder) SetShell(shell string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.shell = shell
+	r.mu.Unlock()
+}
+
+// Output records bytes written to the terminal.
+func (r *ExecRecorder) Output(p []byte) { r.record("o", p) }
+
+// Input records bytes typed by the user.
+func (r *ExecRecorder) Input(p []byte) { r.record("i", p) }
+
+// Resize records a terminal resize. A resize before any output sets the header size.
+func (r *ExecRecorder) Resize(cols, rows int) {
+	if r == nil || cols <= 0 || rows <= 0 {
+		return
+	}
+	r.mu.Lock()
+	if !r.sized && r.count == 0 {
+		r.width, r.height, r.sized = cols, rows, true
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+	r.appendEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+func (r *ExecRecorder) record(code string, p []byte) {
+	if r == nil || len(p) == 0 {
+		return
+	}
+	r.mu.Lock()
+	// Reads can split multi-byte runes; hold back an incomplete tail so the
+	// JSON string stays valid UTF-8.
+	buf := append(r.pending[code], p...)
+	cut := len(buf)
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	r.pending[code] = append([]byte(nil), buf[cut:]...)
+	r.mu.Unlock()
+
+	if cut > 0 {
+		r.appendEvent(code, string(buf[:cut]))
+	}
+}
+
+func (r *ExecRecorder) appendEvent(code, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncated {
+		return
+	}
+	elapsed := time.Since(r.started).Seconds()
+	line, err := json.Marshal([]any{json.Number(strconv.FormatFloat(elapsed, 'f', 6, 64)), code, data})
+	if err != nil {
+		return
+	}
+	if r.maxBytes > 0 && r.events.Len()+len(line)+1 > r.maxBytes {
+		r.truncated = true
+		common.WarnLog("exec recording: size limit reached for host=%s container=%s, truncating", r.hostName, r.container)
+		return
+	}
+	r.events.Write(line)
+	r.events.WriteByte('\n')
+	r.count++
+}
+
+// Cast renders the full asciicast v2 document.
+func (r *ExecRecorder) Cast() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	header := map[string]any{
+		"version":   2,
+		"width":     r.width,
+		"height":    r.height,
+		"timestamp": r.started.Unix(),
+		"title":     fmt.Sprintf("%s/%s", r.hostName, r.container),
+		"env": map[string]string{
+			"TERM":  "xterm-256color",
+			"SHELL": r.shell,
+		},
+	}
+	hb, _ := json.Marshal(header)
+
+	var out bytes.Buffer
+	out.Grow(len(hb) + 1 + r.events.Len())
+	out.Write(hb)
+	out.WriteByte('\n')
+	out.Write(r.events.Bytes())
+	return out.Bytes()
+}
+
+// Finish compresses the recording and stores it. Returns the recording id.
+func (r *ExecRecorder) Finish(ctx context.Context) (int64, error) {
+	if r == nil {
+		return 0, nil
+	}
+	ended := time.Now()
+	cast := r.Cast()
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(cast); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	rec := database.ExecRecording{
+		HostName:      r.hostName,
+		ContainerName: r.container,
+		Username:      r.user,
+		Shell:         r.shell,
+		StartedAt:     r.started,
+		EndedAt:       ended,
+		DurationMs:    ended.Sub(r.started).Milliseconds(),
+		Width:         r.width,
+		Height:        r.height,
+		EventCount:    r.count,
+		RawBytes:      int64(len(cast)),
+		Truncated:     r.truncated,
+	}
+	if r.hostID > 0 {
+		id := r.hostID
+		rec.HostID = &id
+	}
+	r.mu.Unlock()
+
+	id, err := database.InsertExecRecording(ctx, rec, gz.Bytes())
+	if err != nil {
+		return 0, err
+	}
+	common.InfoLog("exec recording: saved id=%d host=%s container=%s user=%s duration=%dms events=%d",
+		id, rec.HostName, rec.ContainerName, rec.Username, rec.DurationMs, rec.EventCount)
+	return id, nil
+}
+
+// DecompressCast expands a stored recording back to asciicast text.
+func DecompressCast(gz []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	var out bytes.Buffer
+	if _, err := out.ReadFrom(zr); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ---- Policy ----
+
+// GetGlobalExecRecording returns the global recording setting with source.
+func GetGlobalExecRecording(ctx context.Context) (bool, string) {
+	if b, ok := GetAppSettingBool(ctx, execRecordingKey); ok && b != nil {
+		return *b, "db"
+	}
+	return common.EnvBool("DD_UI_EXEC_RECORDING", "false"), "env"
+}
+
+// SetGlobalExecRecording sets (or clears, with nil) the global recording setting.
+func SetGlobalExecRecording(ctx context.Context, v *bool) error {
+	return setBoolSetting(ctx, execRecordingKey, v)
+}
+
+// GetExecRecordingOverride gets a host or group recording override.
+func GetExecRecordingOverride(ctx context.Context, kind, name string) (*bool, error) {
+	b, _ := GetAppSettingBool(ctx, fmt.Sprintf("%s:%s:%s", kind, name, execRecordingKey))
+	return b, nil
+}
+
+// SetExecRecordingOverride sets (or clears, with nil) a host or group recording override.
+func SetExecRecordingOverride(ctx context.Context, kind, name string, v *bool) error {
+	if kind != "host" && kind != "group" {
+		return fmt.Errorf("invalid scope kind %q", kind)
+	}
+	return setBoolSetting(ctx, fmt.Sprintf("%s:%s:%s", kind, name, execRecordingKey), v)
+}
+
+func setBoolSetting(ctx context.Context, key string, v *bool) error {
+	if v == nil {
+		return DelAppSetting(ctx, key)
+	}
+	if *v {
+		return SetAppSetting(ctx, key, "true")
+	}
+	return SetAppSetting(ctx, key, "false")
+}
+
+// ExecRecordingPolicy resolves whether console sessions on a host must be
+// recorded, returning the effective value and where it came from.
+func ExecRecordingPolicy(ctx context.Context, h database.HostRow) (bool, string) {
+	if o, _ := GetExecRecordingOverride(ctx, "host", h.Name); o != nil {
+		return *o, "host"
+	}
+	if v, ok := h.Vars["dd_ui_exec_recording"]; ok && v != "" {
+		return IsTrueish(v), "inventory"
+	}
+	for _, g := range h.Groups {
+		if o, _ := GetExecRecordingOverride(ctx, "group", g); o != nil {
+			return *o, "group:" + g
+		}
+	}
+	return GetGlobalExecRecording(ctx)
+}
+
+// ---- Retention ----
+
+// StartExecRecordingRetention prunes recordings older than
+// DD_UI_EXEC_RECORDING_RETENTION (default 720h; 0 keeps forever).
+func StartExecRecordingRetention(ctx context.Context) {
+	retention := 720 * time.Hour
+	if v := common.Env("DD_UI_EXEC_RECORDING_RETENTION", ""); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			retention = d
+		} else {
+			common.WarnLog("exec recording: invalid DD_UI_EXEC_RECORDING_RETENTION %q, using %s", v, retention)
+		}
+	}
+	if retention <= 0 {
+		common.InfoLog("exec recording: retention disabled, recordings are kept forever")
+		return
+	}
+	common.InfoLog("exec recording: retention=%s", retention)
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+		for {
+			n, err := database.PruneExecRecordings(ctx, time.Now().Add(-retention))
+			if err != nil {
+				common.ErrorLog("exec recording: prune failed: %v", err)
+			} else if n > 0 {
+				common.InfoLog("exec recording: pruned %d recordings older than %s", n, retention)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,126 @@
+// services/fs_watch.go
+package services
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"dd-ui/common"
+)
+
+// FS watching (inotify on Linux) for the inventory and IaC tree. DD_UI_WATCH_FS=false
+// disables it and leaves only the polling fallbacks; DD_UI_WATCH_DEBOUNCE sets the quiet
+// period before a burst of events (git pull, editor saves) is acted upon.
+
+func fsWatchEnabled() bool { return common.EnvBool("DD_UI_WATCH_FS", "true") }
+
+func fsWatchDebounce() time.Duration {
+	if d, err := time.ParseDuration(common.Env("DD_UI_WATCH_DEBOUNCE", "2s")); err == nil && d > 0 {
+		return d
+	}
+	return 2 * time.Second
+}
+
+// debouncedWatcher watches directory trees and fires once per key after events settle
+type debouncedWatcher struct {
+	w        *fsnotify.Watcher
+	debounce time.Duration
+	mu       sync.Mutex
+	timers   map[string]*time.Timer
+}
+
+func newDebouncedWatcher() (*debouncedWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &debouncedWatcher{w: w, debounce: fsWatchDebounce(), timers: map[string]*time.Timer{}}, nil
+}
+
+// addTree watches dir and every directory below it (inotify is not recursive)
+func (dw *debouncedWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != dir && ignoredWatchName(d.Name()) {
+			return fs.SkipDir
+		}
+		if err := dw.w.Add(p); err != nil {
+			common.WarnLog("watch: cannot watch %s: %v", p, err)
+		}
+		return nil
+	})
+}
+
+// ignoredWatchName skips VCS internals and editor temp/swap files
+func ignoredWatchName(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") ||
+		strings.HasSuffix(name, ".swp") || strings.HasSuffix(name, ".tmp")
+}
+
+// run dispatches events until ctx ends. keyFor maps a path to a debounce key ("" ignores
+// the event); fire runs once per key when no event for it arrived within the debounce.
+func (dw *debouncedWatcher) run(ctx context.Context, recursive bool, keyFor func(string) string, fire func(string)) {
+	defer dw.w.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			dw.mu.Lock()
+			for _, t := range dw.timers {
+				t.Stop()
+			}
+			dw.mu.Unlock()
+			return
+		case ev, ok := <-dw.w.Events:
+			if !ok {
+				return
+			}
+			if ignoredWatchName(filepath.Base(ev.Name)) {
+				continue
+			}
+			if recursive && ev.Has(fsnotify.Create) {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					_ = dw.addTree(ev.Name)
+				}
+			}
+			if key := keyFor(ev.Name); key != "" {
+				dw.schedule(key, fire)
+			}
+		case err, ok := <-dw.w.Errors:
+			if !ok {
+				return
+			}
+			common.WarnLog("watch: %v", err)
+		}
+	}
+}
+
+func (dw *debouncedWatcher) schedule(key string, fire func(string)) {
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+	if t, ok := dw.timers[key]; ok && t.Stop() {
+		t.Reset(dw.debounce)
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(dw.debounce, func() {
+		dw.mu.Lock()
+		if dw.timers[key] == t {
+			delete(dw.timers, key)
+		}
+		dw.mu.Unlock()
+		fire(key)
+	})
+	dw.timers[key] = t
+}
@@ -214,7 +214,10 @@ func (g *GitSyncService) initInExistingDirectory(ctx context.Context) error {
 	var cleanup func()
 
 	if g.config.SSHKey != "" {
-		sshCmd, cleanupFunc := g.setupSSHCommand(ctx)
+		sshCmd, cleanupFunc, err := g.setupSSHCommand(ctx)
+		if err != nil {
+			return err
+		}
 		if sshCmd != "" {
 			cleanup = cleanupFunc
 			fetchCmd = exec.CommandContext(ctx, "git", "fetch", "origin", g.config.Branch)
@@ -361,7 +364,10 @@ func (g *GitSyncService) mergeRemoteChanges(ctx context.Context) error {
 	var cleanup func()
 
 	if g.config.SSHKey != "" {
-		sshCmd, cleanupFunc := g.setupSSHCommand(ctx)
+		sshCmd, cleanupFunc, err := g.setupSSHCommand(ctx)
+		if err != nil {
+			return err
+		}
 		if sshCmd != "" {
 			cleanup = cleanupFunc
 			mergeCmd = exec.CommandContext(ctx, "git", "pull", "--no-rebase", "origin", g.config.Branch)
@@ -457,7 +463,10 @@ func (g *GitSyncService) cloneRepository(ctx context.Context) error {
 
 	// Handle SSH key authentication specially for clone
 	if g.config.SSHKey != "" {
-		sshCmd, cleanupFunc := g.setupSSHCommand(ctx)
+		sshCmd, cleanupFunc, err := g.setupSSHCommand(ctx)
+		if err != nil {
+			return err
+		}
 		if sshCmd != "" {
 			cleanup = cleanupFunc
 			cloneCmd = exec.CommandContext(ctx, "git", "clone", 
@@ -553,32 +562,27 @@ func (g *GitSyncService) buildGitCommand(ctx context.Context, args ...string) *e
 		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_ASKPASS=/bin/echo"))
 		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_USERNAME=token"))
 		cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_PASSWORD=%s", g.config.AuthToken))
-	} else if g.config.SSHKey != "" {
-		// For SSH with key - create a unique temp file for this command
-		if sshCmd, cleanup := g.setupSSHCommand(ctx); sshCmd != "" {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_SSH_COMMAND=%s", sshCmd))
-			// Register cleanup to run after command completes
-			if cleanup != nil {
-				defer cleanup()
-			}
-		}
 	}
+	// SSH keys are set up by callers with setupSSHCommand: the key file has to
+	// outlive the command, so it cannot be created (and cleaned up) here
+
 
 	return cmd
 }
 
 // setupSSHCommand prepares SSH authentication for Git operations
-// Returns the GIT_SSH_COMMAND value and a cleanup function
-func (g *GitSyncService) setupSSHCommand(ctx context.Context) (string, func()) {
+// Returns the GIT_SSH_COMMAND value and a cleanup function; with no SSH key
+// configured both are empty. An error (including a host key that cannot be
+// verified) must abort the operation rather than fall back to plain git.
+func (g *GitSyncService) setupSSHCommand(ctx context.Context) (string, func(), error) {
 	if g.config.SSHKey == "" {
-		return "", nil
+		return "", nil, nil
 	}
 
 	// Create a unique temporary file for the SSH key
 	tmpFile, err := os.CreateTemp("/tmp", "ddui_git_ssh_key_*.pem")
 	if err != nil {
-		common.ErrorLog("Failed to create temp SSH key file: %v", err)
-		return "", nil
+		return "", nil, fmt.Errorf("failed to create temp SSH key file: %w", err)
 	}
 
 	// Write the SSH key to the file
@@ -589,18 +593,16 @@ func (g *GitSyncService) setupSSHCommand(ctx context.Context) (string, func()) {
 	}
 
 	if _, err := tmpFile.WriteString(sshKeyContent); err != nil {
-		common.ErrorLog("Failed to write SSH key: %v", err)
 		tmpFile.Close()
 		os.Remove(tmpFile.Name())
-		return "", nil
+		return "", nil, fmt.Errorf("failed to write SSH key: %w", err)
 	}
 	tmpFile.Close()
 
 	// Set proper permissions
 	if err := os.Chmod(tmpFile.Name(), 0600); err != nil {
-		common.ErrorLog("Failed to set SSH key permissions: %v", err)
 		os.Remove(tmpFile.Name())
-		return "", nil
+		return "", nil, fmt.Errorf("failed to set SSH key permissions: %w", err)
 	}
 
 	// Create cleanup function
@@ -610,16 +612,15 @@ func (g *GitSyncService) setupSSHCommand(ctx context.Context) (string, func()) {
 		}
 	}
 
-	// Build SSH command with proper options
-	// -o IdentitiesOnly=yes ensures only the specified key is used
-	// -o StrictHostKeyChecking=no bypasses host key verification (needed for automated systems)
-	// -o UserKnownHostsFile=/dev/null prevents host key storage
-	// -o LogLevel=ERROR reduces verbosity
-	sshCmd := fmt.Sprintf("ssh -i %s -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o IdentitiesOnly=yes -o LogLevel=ERROR", tmpFile.Name())
+	sshCmd, err := GitSSHCommand(ctx, tmpFile.Name(), g.config.RepoURL)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("git SSH host key verification failed: %w", err)
+	}
 	
 	common.InfoLog("Git SSH authentication configured using provided SSH key")
 	common.DebugLog("SSH key file created at: %s (will be cleaned up after operation)", tmpFile.Name())
-	return sshCmd, cleanup
+	return sshCmd, cleanup, nil
 }
 
 // buildAuthenticatedURL creates a Git URL with embedded authentication
@@ -657,7 +658,10 @@ func (g *GitSyncService) atomicPull(ctx context.Context, force bool) error {
 	var cleanup func()
 	var gitEnv []string
 	if g.config.SSHKey != "" {
-		sshCmd, cleanupFunc := g.setupSSHCommand(ctx)
+		sshCmd, cleanupFunc, err := g.setupSSHCommand(ctx)
+		if err != nil {
+			return err
+		}
 		if sshCmd != "" {
 			cleanup = cleanupFunc
 			// Use command-specific environment instead of global
@@ -814,7 +818,10 @@ func (g *GitSyncService) atomicPush(ctx context.Context, force bool) error {
 	var cleanup func()
 	var gitEnv []string
 	if g.config.SSHKey != "" {
-		sshCmd, cleanupFunc := g.setupSSHCommand(ctx)
+		sshCmd, cleanupFunc, err := g.setupSSHCommand(ctx)
+		if err != nil {
+			return err
+		}
 		if sshCmd != "" {
 			cleanup = cleanupFunc
 			gitEnv = append(os.Environ(), fmt.Sprintf("GIT_SSH_COMMAND=%s", sshCmd))
@@ -1007,7 +1014,11 @@ func (g *GitSyncService) detectConflict(ctx context.Context) (bool, string) {
 	
 	// Setup SSH if needed for ls-remote
 	if g.config.SSHKey != "" {
-		sshCmd, cleanup := g.setupSSHCommand(ctx)
+		sshCmd, cleanup, err := g.setupSSHCommand(ctx)
+		if err != nil {
+			common.WarnLog("Conflict check skipped: %v", err)
+			return false, ""
+		}
 		if cleanup != nil {
 			defer cleanup()
 		}
@@ -1052,10 +1063,16 @@ func (g *GitSyncService) ensureWorkingDirectory(ctx context.Context) error {
 		// Setup authentication for clone
 		var cloneCmd *exec.Cmd
 		var cleanup func()
+		var sshCmd string
 		
 		if g.config.SSHKey != "" {
 			// SSH authentication
-			sshCmd, cleanupFunc := g.setupSSHCommand(ctx)
+			var cleanupFunc func()
+			var err error
+			sshCmd, cleanupFunc, err = g.setupSSHCommand(ctx)
+			if err != nil {
+				return err
+			}
 			if sshCmd != "" {
 				cleanup = cleanupFunc
 				// Try with specified branch first, fallback to default if it fails
@@ -1086,7 +1103,7 @@ func (g *GitSyncService) ensureWorkingDirectory(ctx context.Context) error {
 				// Clone without specifying branch (gets default)
 				var fallbackCmd *exec.Cmd
 				if g.config.SSHKey != "" && cleanup != nil {
-					sshCmd, _ := g.setupSSHCommand(ctx)
+					// reuse the key set up for the first attempt (cleaned up on return)
 					fallbackCmd = exec.CommandContext(ctx, "git", "clone", g.config.RepoURL, g.workPath)
 					fallbackCmd.Env = append(os.Environ(), fmt.Sprintf("GIT_SSH_COMMAND=%s", sshCmd))
 				} else if g.config.AuthToken != "" {
@@ -1182,10 +1199,7 @@ func (g *GitSyncService) ensureWorkingDirectory(ctx context.Context) error {
 			// Fetch
 			var gitEnv []string
 			if g.config.SSHKey != "" {
-				sshCmd, cleanup := g.setupSSHCommand(ctx)
-				if cleanup != nil {
-					defer cleanup()
-				}
+				// the key from the clone attempt is still in place (cleaned up on return)
 				if sshCmd != "" {
 					gitEnv = append(os.Environ(), fmt.Sprintf("GIT_SSH_COMMAND=%s", sshCmd))
 				}
@@ -1712,6 +1726,15 @@ func (g *GitSyncService) logOperationWithDuration(ctx context.Context, operation
 	}); err != nil {
 		common.ErrorLog("Failed to log git operation: %v", err)
 	}
+
+	data := map[string]any{"operation": operation, "status": status, "message": message, "initiated_by": initiatedBy}
+	if beforeHash != "" || afterHash != "" {
+		data["commit_before"], data["commit_after"] = beforeHash, afterHash
+	}
+	if len(files) > 0 {
+		data["files_changed"] = files
+	}
+	PublishEvent(ctx, Event{Type: EventGitSync, Subject: operation, Data: data})
 }
 
 
@@ -1784,8 +1807,8 @@ func (g *GitSyncService) UpdateConfig(ctx context.Context, config *database.GitS
 	g.config = config
 	g.mu.Unlock()
 
-	// Start sync outside of lock to prevent blocking
-	if config.SyncEnabled && config.RepoURL != "" {
+	// Start sync outside of lock to prevent blocking; only the leader runs the sync timer
+	if config.SyncEnabled && config.RepoURL != "" && IsLeader() {
 		common.InfoLog("UpdateConfig: Starting Git sync for %s", config.RepoURL)
 		// Start in background to prevent blocking the HTTP response
 		// Use background context so it doesn't get canceled when HTTP request completes
@@ -1804,4 +1827,15 @@ func (g *GitSyncService) UpdateConfig(ctx context.Context, config *database.GitS
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// GitSSHCommand builds GIT_SSH_COMMAND for a key file, verifying the remote's host key
+// against the managed known_hosts first.
+// -o IdentitiesOnly=yes ensures only the specified key is used
+// -o LogLevel=ERROR reduces verbosity
+func GitSSHCommand(ctx context.Context, keyFile, repoURL string) (string, error) {
+	hostKeyArgs, err := GitSSHHostKeyArgs(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ssh -i %s %s -o IdentitiesOnly=yes -o LogLevel=ERROR", keyFile, strings.Join(hostKeyArgs, " ")), nil
+}
@@ -0,0 +1,54 @@
+// services/host_access.go
+package services
+
+import (
+	"slices"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+// HostAccessAllowed reports whether a user may perform sensitive operations
+// (file transfer, etc.) on a host. Access is open when neither the host nor any
+// of its groups declare dd_ui_allowed_users; otherwise the user must be listed
+// there or be the owner of the host.
+func HostAccessAllowed(h database.HostRow, user string) bool {
+	user = strings.ToLower(strings.TrimSpace(user))
+
+	var allowed []string
+	owner := h.Owner
+	if im := GetInventoryManager(); im != nil {
+		if ih, err := im.GetHost(h.Name); err == nil {
+			allowed = append(allowed, ih.AllowedUsers...)
+			if ih.Owner != "" {
+				owner = ih.Owner
+			}
+		}
+		if groups, err := im.GetGroups(); err == nil {
+			for _, g := range groups {
+				if len(g.AllowedUsers) == 0 || !slices.Contains(g.Hosts, h.Name) {
+					continue
+				}
+				allowed = append(allowed, g.AllowedUsers...)
+			}
+		}
+	}
+
+	if len(allowed) == 0 {
+		return true
+	}
+	if user == "" {
+		return false
+	}
+	if strings.EqualFold(strings.TrimSpace(owner), user) {
+		return true
+	}
+	for _, u := range allowed {
+		if strings.EqualFold(strings.TrimSpace(u), user) {
+			return true
+		}
+	}
+	common.DebugLog("host access: %s denied on host %s", user, h.Name)
+	return false
+}
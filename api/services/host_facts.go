@@ -0,0 +1,303 @@
+// services/host_facts.go
+package services
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+// CollectHostFacts reads engine and OS facts over the host's Docker connection, plus memory,
+// disk and uptime through a shell (SSH for ssh:// hosts, locally for the local socket), and
+// appends them to the host's history. Shell failures are recorded in the row's error field.
+// Disk is not reported for the local socket: df there would see DD-UI's own container.
+func CollectHostFacts(ctx context.Context, h database.HostRow) (database.HostFactsRow, error) {
+	f := database.HostFactsRow{HostID: h.ID, HostName: h.Name, Runtime: HostRuntime(h)}
+
+	url, sshCmd := DockerURLFor(h)
+	if IsUnixSock(url) && !LocalHostAllowed(h) {
+		return f, ErrSkipScan
+	}
+	cli, done, err := DockerClientForURL(ctx, url, sshCmd)
+	if err != nil {
+		return f, err
+	}
+	defer done()
+
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return f, err
+	}
+	f.EngineVersion = info.ServerVersion
+	f.StorageDriver = info.Driver
+	f.OS = info.OperatingSystem
+	f.Kernel = info.KernelVersion
+	f.Architecture = info.Architecture
+	f.CPUs = info.NCPU
+	f.MemTotalBytes = info.MemTotal
+	f.DockerRootDir = info.DockerRootDir
+	if v, err := cli.ServerVersion(ctx); err == nil {
+		f.APIVersion = v.APIVersion
+	}
+
+	out, err := readHostShellFacts(ctx, h, url, f.DockerRootDir)
+	switch {
+	case errors.Is(err, errNoShellFacts):
+	case err != nil:
+		f.Error = err.Error()
+	default:
+		parseHostShellFacts(out, &f)
+	}
+
+	if f.ID, err = database.InsertHostFacts(ctx, f); err != nil {
+		return f, err
+	}
+	f.CollectedAt = time.Now()
+	return f, nil
+}
+
+var errNoShellFacts = errors.New("no shell access")
+
+// hostFactsScript prints uptime, MemTotal/MemAvailable and, with disk, the df line of the
+// Docker root dir
+func hostFactsScript(rootDir string, disk bool) string {
+	script := "cat /proc/uptime; grep -E '^(MemTotal|MemAvailable):' /proc/meminfo"
+	if !disk {
+		return script
+	}
+	if rootDir == "" {
+		rootDir = "/"
+	}
+	return script + "; df -P -B1 '" + strings.ReplaceAll(rootDir, "'", `'\''`) + "' 2>/dev/null | tail -n 1"
+}
+
+func readHostShellFacts(ctx context.Context, h database.HostRow, url, rootDir string) (string, error) {
+	switch {
+	case IsUnixSock(url):
+		// /proc is the host kernel's, but the filesystem is DD-UI's container: no disk
+		out, err := exec.CommandContext(ctx, "sh", "-c", hostFactsScript(rootDir, false)).Output()
+		return string(out), err
+	case strings.HasPrefix(url, "ssh://"):
+		t, err := SSHTargetForHost(ctx, h)
+		if err != nil {
+			return "", err
+		}
+		sc, err := utils.SSHPool.GetSSHConnectionForTarget(t)
+		if err != nil {
+			return "", err
+		}
+		sess, err := sc.NewSession()
+		if err != nil {
+			return "", err
+		}
+		defer sess.Close()
+		out, err := sess.Output(hostFactsScript(rootDir, true))
+		return string(out), err
+	}
+	// agent:// and tcp:// hosts only expose the Docker API
+	return "", errNoShellFacts
+}
+
+func parseHostShellFacts(out string, f *database.HostFactsRow) {
+	var memTotal, memAvail int64 = -1, -1
+	for i, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case i == 0 && !strings.HasSuffix(fields[0], ":"):
+			if up, err := strconv.ParseFloat(fields[0], 64); err == nil {
+				v := int64(up)
+				f.UptimeSeconds = &v
+			}
+		case fields[0] == "MemTotal:" && len(fields) >= 2:
+			if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				memTotal = kb * 1024
+			}
+		case fields[0] == "MemAvailable:" && len(fields) >= 2:
+			if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				memAvail = kb * 1024
+			}
+		case len(fields) >= 6:
+			// df -P: Filesystem 1B-blocks Used Available Capacity Mounted-on
+			total, err1 := strconv.ParseInt(fields[1], 10, 64)
+			used, err2 := strconv.ParseInt(fields[2], 10, 64)
+			if err1 == nil && err2 == nil {
+				f.DiskTotalBytes, f.DiskUsedBytes = &total, &used
+			}
+		}
+	}
+	if memTotal > 0 && memAvail >= 0 {
+		used := memTotal - memAvail
+		f.MemUsedBytes = &used
+		if f.MemTotalBytes == 0 {
+			f.MemTotalBytes = memTotal
+		}
+	}
+}
+
+// ---- Fleet overview ----
+
+// HostFactsOverview is a host's latest facts with the derived flags the fleet view highlights
+type HostFactsOverview struct {
+	database.HostFactsRow
+	DiskUsedPercent *float64 `json:"disk_used_percent"`
+	MemUsedPercent  *float64 `json:"mem_used_percent"`
+	DiskWarning     bool     `json:"disk_warning"`
+	EngineOutdated  bool     `json:"engine_outdated"`
+	Stale           bool     `json:"stale"`
+}
+
+// ListHostFactsOverview flags engines older than DD_UI_MIN_ENGINE_VERSION (or, when unset, than
+// the newest engine of the same runtime in the fleet), disks above DD_UI_HOST_DISK_WARN_PERCENT
+// (default 85) and collections older than three collection intervals.
+func ListHostFactsOverview(ctx context.Context) ([]HostFactsOverview, map[string]string, error) {
+	rows, err := database.ListLatestHostFacts(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newest := map[string]string{}
+	for _, f := range rows {
+		if f.EngineVersion != "" && compareVersions(f.EngineVersion, newest[f.Runtime]) > 0 {
+			newest[f.Runtime] = f.EngineVersion
+		}
+	}
+	minVersion := strings.TrimSpace(common.Env("DD_UI_MIN_ENGINE_VERSION", ""))
+	warnPct := float64(common.EnvInt("DD_UI_HOST_DISK_WARN_PERCENT", 85))
+	staleAfter := 3 * hostFactsInterval()
+
+	out := make([]HostFactsOverview, 0, len(rows))
+	for _, f := range rows {
+		o := HostFactsOverview{HostFactsRow: f}
+		if f.DiskTotalBytes != nil && f.DiskUsedBytes != nil && *f.DiskTotalBytes > 0 {
+			p := float64(*f.DiskUsedBytes) * 100 / float64(*f.DiskTotalBytes)
+			o.DiskUsedPercent = &p
+			o.DiskWarning = p >= warnPct
+		}
+		if f.MemUsedBytes != nil && f.MemTotalBytes > 0 {
+			p := float64(*f.MemUsedBytes) * 100 / float64(f.MemTotalBytes)
+			o.MemUsedPercent = &p
+		}
+		if f.EngineVersion != "" {
+			if minVersion != "" {
+				o.EngineOutdated = compareVersions(f.EngineVersion, minVersion) < 0
+			} else {
+				o.EngineOutdated = compareVersions(f.EngineVersion, newest[f.Runtime]) < 0
+			}
+		}
+		o.Stale = staleAfter > 0 && time.Since(f.CollectedAt) > staleAfter
+		out = append(out, o)
+	}
+	return out, newest, nil
+}
+
+// compareVersions compares dotted numeric versions ("28.0.1", "5.2.2-dev"); "" sorts first
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	var out []int
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			break
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// ---- Collector ----
+
+func hostFactsInterval() time.Duration {
+	return envDuration("DD_UI_HOST_FACTS_INTERVAL", 15*time.Minute)
+}
+
+// CollectAllHostFacts collects facts from every host, a few at a time
+func CollectAllHostFacts(ctx context.Context) {
+	hosts, err := database.ListHosts(ctx)
+	if err != nil {
+		common.ErrorLog("host facts: list hosts failed: %v", err)
+		return
+	}
+	sem := make(chan struct{}, max(1, common.EnvInt("DD_UI_HOST_FACTS_CONCURRENCY", 3)))
+	var wg sync.WaitGroup
+	for _, h := range hosts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(h database.HostRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			hctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+			defer cancel()
+			if _, err := CollectHostFacts(hctx, h); err != nil && !errors.Is(err, ErrSkipScan) {
+				common.DebugLog("host facts: %s: %v", h.Name, err)
+				database.ScanLog(ctx, h.ID, "warn", "host facts collection failed", map[string]any{"error": err.Error()})
+			}
+		}(h)
+	}
+	wg.Wait()
+}
+
+// StartHostFactsCollector collects facts every DD_UI_HOST_FACTS_INTERVAL (default 15m; 0
+// disables) and prunes history older than DD_UI_HOST_FACTS_RETENTION (default 720h).
+func StartHostFactsCollector(ctx context.Context) {
+	interval := hostFactsInterval()
+	if interval <= 0 {
+		common.InfoLog("host facts: collection disabled")
+		return
+	}
+	retention := envDuration("DD_UI_HOST_FACTS_RETENTION", 720*time.Hour)
+	common.InfoLog("host facts: interval=%s retention=%s", interval, retention)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			CollectAllHostFacts(ctx)
+			if retention > 0 {
+				if n, err := database.PruneHostFacts(ctx, time.Now().Add(-retention)); err != nil {
+					common.ErrorLog("host facts: prune failed: %v", err)
+				} else if n > 0 {
+					common.InfoLog("host facts: pruned %d collections older than %s", n, retention)
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
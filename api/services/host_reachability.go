@@ -0,0 +1,250 @@
+// services/host_reachability.go
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+// Host connection states reported by the scanner
+const (
+	HostStateUnknown  = "unknown"
+	HostStateOnline   = "online"
+	HostStateDegraded = "degraded" // reachable but slow, or failing fewer than DD_UI_HOST_OFFLINE_AFTER times
+	HostStateOffline  = "offline"
+)
+
+// hostStatusPersistEvery bounds DB writes for hosts whose state is unchanged
+const hostStatusPersistEvery = time.Minute
+
+var (
+	hostStatusMu       sync.Mutex
+	hostStatusByName   = map[string]*hostStatusEntry{}
+	hostStatusLoaded   sync.Once
+	hostStatusReloaded time.Time // last DB refresh on a follower replica
+)
+
+// hostOnlineHook is called (in its own goroutine) when an offline host is reachable again
+var (
+	hostOnlineHookMu sync.Mutex
+	hostOnlineHook   func(hostName string)
+)
+
+// OnHostBackOnline registers fn to run when an offline host becomes reachable again (Auto
+// DevOps applies the stacks it deferred while the host was down); nil unregisters
+func OnHostBackOnline(fn func(hostName string)) {
+	hostOnlineHookMu.Lock()
+	hostOnlineHook = fn
+	hostOnlineHookMu.Unlock()
+}
+
+type hostStatusEntry struct {
+	row       database.HostStatusRow
+	persisted time.Time
+}
+
+// loadHostStatus seeds the in-memory state from the DB so restarts keep backoff and last_seen
+func loadHostStatus(ctx context.Context) {
+	hostStatusLoaded.Do(func() { refreshHostStatus(ctx, false) })
+}
+
+// refreshHostStatus reads host_status; overwrite replaces in-memory rows (followers only
+// observe what the leader's scanner wrote)
+func refreshHostStatus(ctx context.Context, overwrite bool) {
+	rows, err := database.ListHostStatus(ctx)
+	if err != nil {
+		common.DebugLog("host status: load failed: %v", err)
+		return
+	}
+	hostStatusMu.Lock()
+	defer hostStatusMu.Unlock()
+	for _, r := range rows {
+		if _, ok := hostStatusByName[r.HostName]; !ok || overwrite {
+			hostStatusByName[r.HostName] = &hostStatusEntry{row: r, persisted: r.UpdatedAt}
+		}
+	}
+}
+
+// RecordHostReachable marks a successful connection; latency above
+// DD_UI_HOST_DEGRADED_LATENCY (default 2s) reports the host as degraded.
+func RecordHostReachable(ctx context.Context, h database.HostRow, latency time.Duration) {
+	loadHostStatus(ctx)
+	now := time.Now()
+	ms := int(latency.Milliseconds())
+
+	hostStatusMu.Lock()
+	e := hostStatusEntryFor(h)
+	prev := e.row.State
+	e.row.LastSeen = &now
+	e.row.LatencyMs = &ms
+	e.row.ConsecutiveFailures = 0
+	e.row.State = HostStateOnline
+	if latency > hostDegradedLatency() {
+		e.row.State = HostStateDegraded
+	}
+	row, persist := e.row, e.row.State != prev || now.Sub(e.persisted) >= hostStatusPersistEvery
+	if persist {
+		e.persisted = now
+	}
+	hostStatusMu.Unlock()
+
+	if prev == HostStateOffline {
+		common.InfoLog("host %s is back online (latency %dms)", h.Name, ms)
+		database.ScanLog(ctx, h.ID, "info", "host back online", map[string]any{"latency_ms": ms})
+		hostOnlineHookMu.Lock()
+		fn := hostOnlineHook
+		hostOnlineHookMu.Unlock()
+		if fn != nil {
+			go fn(h.Name)
+		}
+	}
+	if persist {
+		saveHostStatus(ctx, row)
+	}
+}
+
+// RecordHostUnreachable counts a failed connection and returns the new state. first is
+// true on the first failure of a streak or on the transition to offline, which is when
+// callers should log; repeated failures of an offline host stay quiet.
+func RecordHostUnreachable(ctx context.Context, h database.HostRow, err error) (state string, first bool) {
+	loadHostStatus(ctx)
+	now := time.Now()
+
+	hostStatusMu.Lock()
+	e := hostStatusEntryFor(h)
+	prev := e.row.State
+	e.row.ConsecutiveFailures++
+	e.row.LastError = err.Error()
+	e.row.LastErrorAt = &now
+	e.row.LatencyMs = nil
+	e.row.State = HostStateDegraded
+	if e.row.ConsecutiveFailures >= hostOfflineAfter() {
+		e.row.State = HostStateOffline
+	}
+	row := e.row
+	first = row.ConsecutiveFailures == 1 || (row.State == HostStateOffline && prev != HostStateOffline)
+	persist := row.State != prev || now.Sub(e.persisted) >= hostStatusPersistEvery
+	if persist {
+		e.persisted = now
+	}
+	hostStatusMu.Unlock()
+
+	if row.State == HostStateOffline && prev != HostStateOffline {
+		common.WarnLog("host %s is offline after %d failed connections: %v", h.Name, row.ConsecutiveFailures, err)
+	}
+	if persist {
+		saveHostStatus(ctx, row)
+	}
+	return row.State, first
+}
+
+func hostStatusEntryFor(h database.HostRow) *hostStatusEntry {
+	e, ok := hostStatusByName[h.Name]
+	if !ok {
+		e = &hostStatusEntry{row: database.HostStatusRow{HostID: h.ID, HostName: h.Name, State: HostStateUnknown}}
+		hostStatusByName[h.Name] = e
+	}
+	e.row.HostID = h.ID
+	return e
+}
+
+func saveHostStatus(ctx context.Context, row database.HostStatusRow) {
+	if err := database.UpsertHostStatus(ctx, row); err != nil {
+		common.DebugLog("host status: save %s failed: %v", row.HostName, err)
+	}
+}
+
+// GetHostStatus returns the last observed state of a host ("unknown" before its first scan)
+func GetHostStatus(name string) database.HostStatusRow {
+	loadHostStatus(context.Background())
+	if !IsLeader() {
+		hostStatusMu.Lock()
+		stale := time.Since(hostStatusReloaded) > 15*time.Second
+		if stale {
+			hostStatusReloaded = time.Now()
+		}
+		hostStatusMu.Unlock()
+		if stale {
+			refreshHostStatus(context.Background(), true)
+		}
+	}
+	hostStatusMu.Lock()
+	defer hostStatusMu.Unlock()
+	if e, ok := hostStatusByName[name]; ok {
+		return e.row
+	}
+	return database.HostStatusRow{HostName: name, State: HostStateUnknown}
+}
+
+// HostOffline reports whether the scanner currently considers the host offline
+func HostOffline(name string) bool {
+	return GetHostStatus(name).State == HostStateOffline
+}
+
+// HostScanDelay returns the wait before the next scan of a host: base while it is healthy,
+// doubling per consecutive failure up to DD_UI_SCAN_DOCKER_MAX_BACKOFF (default 5m).
+func HostScanDelay(name string, base time.Duration) time.Duration {
+	return scanBackoff(GetHostStatus(name).ConsecutiveFailures, base, hostMaxBackoff())
+}
+
+// scanBackoff doubles base (at least 1s) per failure, capped at maxDelay
+func scanBackoff(failures int, base, maxDelay time.Duration) time.Duration {
+	if failures == 0 {
+		return base
+	}
+	d := max(base, time.Second)
+	for i := 0; i < failures && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		return maxDelay
+	}
+	return d
+}
+
+func hostDegradedLatency() time.Duration {
+	return envDuration("DD_UI_HOST_DEGRADED_LATENCY", 2*time.Second)
+}
+
+func hostMaxBackoff() time.Duration {
+	return envDuration("DD_UI_SCAN_DOCKER_MAX_BACKOFF", 5*time.Minute)
+}
+
+func hostOfflineAfter() int {
+	return max(1, common.EnvInt("DD_UI_HOST_OFFLINE_AFTER", 3))
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := common.Env(key, "")
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		common.WarnLog("invalid %s %q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+// StackHostName returns the host a stack deploys to
+func StackHostName(ctx context.Context, stackID int64) (string, bool) {
+	h, err := getHostForStack(ctx, stackID)
+	if err != nil {
+		return "", false
+	}
+	return h.Name, true
+}
+
+// StackHostOffline reports whether a stack's target host is currently offline
+func StackHostOffline(ctx context.Context, stackID int64) (string, bool) {
+	h, err := getHostForStack(ctx, stackID)
+	if err != nil {
+		return "", false
+	}
+	return h.Name, HostOffline(h.Name)
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanBackoff(t *testing.T) {
+	tests := []struct {
+		failures  int
+		base, max time.Duration
+		want      time.Duration
+	}{
+		{0, 30 * time.Second, 5 * time.Minute, 30 * time.Second},
+		{1, 30 * time.Second, 5 * time.Minute, time.Minute},
+		{2, 30 * time.Second, 5 * time.Minute, 2 * time.Minute},
+		{3, 30 * time.Second, 5 * time.Minute, 4 * time.Minute},
+		{4, 30 * time.Second, 5 * time.Minute, 5 * time.Minute},
+		{100, 30 * time.Second, 5 * time.Minute, 5 * time.Minute},
+		{1, 0, time.Minute, 2 * time.Second},                 // base below 1s starts at 1s
+		{0, 10 * time.Minute, time.Minute, 10 * time.Minute}, // healthy hosts keep the base
+		{1, 10 * time.Minute, time.Minute, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := scanBackoff(tt.failures, tt.base, tt.max); got != tt.want {
+			t.Errorf("scanBackoff(%d, %s, %s) = %s, want %s", tt.failures, tt.base, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// services/host_vars.go
+package services
+
+import (
+	"sort"
+
+	"github.com/goccy/go-yaml"
+
+	"dd-ui/database"
+)
+
+// EffectiveHostVars merges inventory vars for a host with Ansible precedence:
+// inventory group vars (all < parent groups < child groups, by depth then name)
+// < group_vars/ files (same order) < inventory host vars < host_vars/ files.
+func EffectiveHostVars(h database.HostRow) map[string]string {
+	out := map[string]string{}
+	chain := hostGroupVarsChain(h.Name)
+	for _, g := range chain {
+		for k, v := range g.vars {
+			out[k] = stringify(v)
+		}
+	}
+	im := GetInventoryManager()
+	if im != nil {
+		for _, g := range chain {
+			for k, v := range im.GroupVarsFromFiles(g.name) {
+				out[k] = stringify(v)
+			}
+		}
+	}
+	for k, v := range h.Vars {
+		out[k] = v
+	}
+	if im != nil {
+		for k, v := range im.HostVarsFromFiles(h.Name) {
+			out[k] = stringify(v)
+		}
+	}
+	return out
+}
+
+type groupVarsAtDepth struct {
+	name  string
+	depth int
+	vars  map[string]any
+}
+
+// hostGroupVarsChain returns every group containing the host with its inline vars,
+// lowest precedence first
+func hostGroupVarsChain(hostName string) []groupVarsAtDepth {
+	return groupVarsChain(func(name string, g *ansibleGroup) bool {
+		_, ok := g.Hosts[hostName]
+		return ok
+	})
+}
+
+// groupAncestorsVarsChain returns a group and every group containing it (all included) with
+// their inline vars, lowest precedence first
+func groupAncestorsVarsChain(groupName string) []groupVarsAtDepth {
+	return groupVarsChain(func(name string, _ *ansibleGroup) bool { return name == groupName })
+}
+
+// groupVarsChain returns every group for which member holds for the group itself or one of
+// its descendants, with inline vars, lowest precedence first
+func groupVarsChain(member func(name string, g *ansibleGroup) bool) []groupVarsAtDepth {
+	all := []groupVarsAtDepth{{name: "all"}} // every host is in all
+	im := GetInventoryManager()
+	if im == nil {
+		return all
+	}
+	im.mu.RLock()
+	data := im.data
+	im.mu.RUnlock()
+	if len(data) == 0 {
+		return all
+	}
+
+	var inv ansibleInventory
+	if err := yaml.Unmarshal(data, &inv); err != nil {
+		return all
+	}
+
+	found := map[string]groupVarsAtDepth{}
+	add := func(name string, g *ansibleGroup, depth int) {
+		prev, seen := found[name]
+		if seen && prev.depth >= depth {
+			if len(prev.vars) == 0 {
+				prev.vars = g.Vars
+				found[name] = prev
+			}
+			return
+		}
+		vars := g.Vars
+		if seen && len(vars) == 0 {
+			vars = prev.vars
+		}
+		found[name] = groupVarsAtDepth{name: name, depth: depth, vars: vars}
+	}
+
+	// walk returns whether member holds for g or any of its descendants; children listed as
+	// empty references ({}) resolve to their top-level definition
+	var walk func(name string, g *ansibleGroup, depth int, stack map[string]bool) bool
+	walk = func(name string, g *ansibleGroup, depth int, stack map[string]bool) bool {
+		if g == nil || stack[name] {
+			return false
+		}
+		if len(g.Hosts) == 0 && len(g.Children) == 0 && len(g.Vars) == 0 {
+			if def := inv.Groups[name]; def != nil && def != g {
+				g = def
+			}
+		}
+		stack[name] = true
+		defer delete(stack, name)
+
+		in := name == "all" || member(name, g)
+		for cname, child := range g.Children {
+			if walk(cname, child, depth+1, stack) {
+				in = true
+			}
+		}
+		if in {
+			add(name, g, depth)
+		}
+		return in
+	}
+
+	if inv.All != nil {
+		walk("all", inv.All, 0, map[string]bool{})
+	} else {
+		found["all"] = all[0]
+	}
+	for name, g := range inv.Groups {
+		if name != "all" {
+			walk(name, g, 1, map[string]bool{})
+		}
+	}
+
+	out := make([]groupVarsAtDepth, 0, len(found))
+	for _, f := range found {
+		out = append(out, f)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].depth != out[j].depth {
+			return out[i].depth < out[j].depth
+		}
+		return out[i].name < out[j].name
+	})
+	return out
+}
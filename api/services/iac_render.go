@@ -0,0 +1,518 @@
+// services/iac_render.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/goccy/go-yaml"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+// TemplateSuffix marks IaC files rendered with Go templates during staging; the staged
+// file drops the suffix (docker-compose.yml.tmpl -> docker-compose.yml)
+const TemplateSuffix = ".tmpl"
+
+// stackVarsFiles hold stack-level template vars (highest precedence), next to the compose file
+var stackVarsFiles = []string{"ddui.vars.yml", "ddui.vars.yaml"}
+
+// CtxRenderHostKey selects the host whose inventory vars render a stack's templates. Host
+// stacks default to their scope host; group stacks without it render with group vars only.
+type CtxRenderHostKey struct{}
+
+// renderHostFor is the host a stack is rendered/staged for: CtxRenderHostKey, else the scope
+// host of a host stack ("" for group stacks)
+func renderHostFor(ctx context.Context, scopeKind, scopeName string) string {
+	if host, _ := ctx.Value(CtxRenderHostKey{}).(string); host != "" {
+		return host
+	}
+	if scopeKind == "host" {
+		return scopeName
+	}
+	return ""
+}
+
+// IsTemplateFile reports whether an IaC file is a template
+func IsTemplateFile(relPath string) bool { return strings.HasSuffix(relPath, TemplateSuffix) }
+
+// stackRenderer renders one stack's templates over its merged variable context
+type stackRenderer struct {
+	stackID   int64
+	host      string
+	vars      map[string]any
+	data      map[string]any
+	secrets   map[string]string // stack env file values, loaded on first secret lookup
+	revealed  map[string]string // values returned by the secret helper
+	loadedEnv bool
+	mask      bool // preview without reveal: secret helper values and secret-looking vars are placeholders
+	masked    int  // placeholders handed out, to flag the files that used them
+}
+
+// newStackRenderer builds the variable context of a stack:
+// all -> group chain (parents before children) -> host -> stack (ddui.vars.yml).
+// Each inventory layer's dd_ui_env entries override its plain vars.
+func newStackRenderer(ctx context.Context, stackID int64) (*stackRenderer, error) {
+	var rel, scopeKind, scopeName, stackName string
+	if err := common.DB.QueryRow(ctx, `SELECT rel_path, scope_kind::text, scope_name, stack_name FROM iac_stacks WHERE id=$1`, stackID).
+		Scan(&rel, &scopeKind, &scopeName, &stackName); err != nil {
+		return nil, err
+	}
+	host := renderHostFor(ctx, scopeKind, scopeName)
+
+	var chain []groupVarsAtDepth
+	switch {
+	case host != "":
+		chain = hostGroupVarsChain(host)
+	case scopeKind == "group":
+		chain = groupAncestorsVarsChain(scopeName)
+	default:
+		chain = []groupVarsAtDepth{{name: "all"}}
+	}
+
+	vars := map[string]any{}
+	groups := make([]string, 0, len(chain))
+	im := GetInventoryManager()
+	for _, g := range chain {
+		groups = append(groups, g.name)
+		applyTemplateVarsLayer(vars, g.vars)
+		if im != nil {
+			applyTemplateVarsLayer(vars, im.GroupVarsFromFiles(g.name))
+		}
+	}
+	if host != "" {
+		var ih *InventoryHost
+		if im != nil {
+			ih, _ = im.GetHost(host)
+		}
+		if ih != nil {
+			applyTemplateVarsLayer(vars, ih.Vars)
+			for k, v := range ih.Env {
+				vars[k] = v
+			}
+		} else if h, err := database.GetHostByName(ctx, host); err == nil {
+			for k, v := range h.Vars {
+				vars[k] = v
+			}
+		}
+	}
+	if root, err := GetRepoRootForStack(ctx, stackID); err == nil {
+		if dir, err := joinUnderLocal(root, rel); err == nil {
+			for _, n := range stackVarsFiles {
+				full := filepath.Join(dir, n)
+				if _, err := os.Stat(full); err != nil {
+					continue
+				}
+				b, _, err := readDecryptedOrPlain(ctx, full, "yaml")
+				if err != nil {
+					return nil, fmt.Errorf("render: %s: %w", n, err)
+				}
+				var m map[string]any
+				if err := yaml.Unmarshal(b, &m); err != nil {
+					return nil, fmt.Errorf("render: %s: %w", n, err)
+				}
+				applyTemplateVarsLayer(vars, m)
+				break
+			}
+		}
+	}
+
+	data := make(map[string]any, len(vars)+1)
+	for k, v := range vars {
+		data[k] = v
+	}
+	data["ddui"] = map[string]any{
+		"host":       host,
+		"stack":      stackName,
+		"scope_kind": scopeKind,
+		"scope_name": scopeName,
+		"groups":     groups,
+	}
+	return &stackRenderer{stackID: stackID, host: host, vars: vars, data: data, revealed: map[string]string{}}, nil
+}
+
+// applyTemplateVarsLayer merges one layer of inventory vars: dd_ui_* metadata is dropped and
+// dd_ui_env entries are applied last
+func applyTemplateVarsLayer(dst, layer map[string]any) {
+	for k, v := range layer {
+		if !strings.HasPrefix(k, "dd_ui_") {
+			dst[k] = v
+		}
+	}
+	if env, ok := layer["dd_ui_env"].(map[string]any); ok {
+		for k, v := range env {
+			dst[k] = stringify(v)
+		}
+	}
+}
+
+// loadSecrets reads the values of the stack's (non-template) env files, decrypting SOPS ones
+func (sr *stackRenderer) loadSecrets(ctx context.Context) {
+	if sr.loadedEnv {
+		return
+	}
+	sr.loadedEnv = true
+	sr.secrets = map[string]string{}
+	for _, full := range stackEnvFiles(ctx, sr.stackID, false) {
+		if IsTemplateFile(full) {
+			continue
+		}
+		b, _, err := readDecryptedOrPlain(ctx, full, "dotenv")
+		if err != nil {
+			common.DebugLog("render: read %s failed: %v", full, err)
+			continue
+		}
+		for k, v := range utils.ParseDotenv(string(filterDotenvSopsKeys(b))) {
+			sr.secrets[k] = v
+		}
+	}
+}
+
+// maskSecretVars switches the renderer to preview masking: secret-looking vars are replaced
+// before rendering, so no helper (quote, upper, ...) can turn a value into something a
+// search for the raw text would miss
+func (sr *stackRenderer) maskSecretVars() {
+	sr.mask = true
+	for k := range sr.vars {
+		if utils.IsSecretKey(k) {
+			sr.vars[k] = utils.MaskedValue
+			sr.data[k] = utils.MaskedValue
+		}
+	}
+}
+
+// funcs returns the template helpers; envFile selects dotenv quoting for quote
+func (sr *stackRenderer) funcs(ctx context.Context, envFile bool) template.FuncMap {
+	return template.FuncMap{
+		// var "name" ["default"]: optional lookup (plain .name fails when the var is missing)
+		"var": func(name string, def ...any) (any, error) {
+			if v, ok := sr.vars[name]; ok {
+				return v, nil
+			}
+			if len(def) > 0 {
+				return def[0], nil
+			}
+			return nil, fmt.Errorf("var %q is not defined", name)
+		},
+		// secret "KEY": a value from the stack's env files (SOPS files are decrypted)
+		"secret": func(name string) (string, error) {
+			sr.loadSecrets(ctx)
+			v, ok := sr.secrets[name]
+			if !ok {
+				return "", fmt.Errorf("secret %q not found in the stack's env files", name)
+			}
+			sr.revealed[name] = v
+			if sr.mask {
+				sr.masked++
+				return utils.MaskedValue, nil
+			}
+			return v, nil
+		},
+		"default": func(def, v any) any {
+			if v == nil || stringify(v) == "" {
+				return def
+			}
+			return v
+		},
+		"required": func(msg string, v any) (any, error) {
+			if v == nil || stringify(v) == "" {
+				return nil, errors.New(msg)
+			}
+			return v, nil
+		},
+		// quote: a YAML double-quoted scalar, or dotenv quoting in env files
+		"quote": func(v any) string {
+			if envFile {
+				return dotenvQuote(stringify(v))
+			}
+			return yamlQuote(stringify(v))
+		},
+		"yamlquote": func(v any) string { return yamlQuote(stringify(v)) },
+		"envquote":  func(v any) string { return dotenvQuote(stringify(v)) },
+		"lower":     func(v any) string { return strings.ToLower(stringify(v)) },
+		"upper":     func(v any) string { return strings.ToUpper(stringify(v)) },
+		"trim":      func(v any) string { return strings.TrimSpace(stringify(v)) },
+		"replace": func(old, repl string, v any) string {
+			return strings.ReplaceAll(stringify(v), old, repl)
+		},
+		"join": func(sep string, v any) string {
+			items, ok := v.([]any)
+			if !ok {
+				return stringify(v)
+			}
+			parts := make([]string, 0, len(items))
+			for _, it := range items {
+				parts = append(parts, stringify(it))
+			}
+			return strings.Join(parts, sep)
+		},
+	}
+}
+
+// yamlQuote returns s as a YAML double-quoted scalar: backslash, double quote and control
+// characters are escaped
+func yamlQuote(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// render executes one template (name is used in error messages)
+func (sr *stackRenderer) render(ctx context.Context, name string, content []byte, envFile bool) ([]byte, error) {
+	t, err := template.New(name).Option("missingkey=error").Funcs(sr.funcs(ctx, envFile)).Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, sr.data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderFile decrypts (if SOPS) and renders a tracked template file
+func (sr *stackRenderer) renderFile(ctx context.Context, role, relPath, full string) ([]byte, error) {
+	inputType := sopsInputType(strings.TrimSuffix(relPath, TemplateSuffix))
+	switch role {
+	case "env":
+		inputType = "dotenv"
+	case "compose":
+		inputType = "yaml"
+	}
+	src, _, err := readDecryptedOrPlain(ctx, full, inputType)
+	if err != nil {
+		return nil, err
+	}
+	out, err := sr.render(ctx, relPath, src, role == "env")
+	if err != nil {
+		return nil, fmt.Errorf("render %s: %w", relPath, err)
+	}
+	if role == "env" {
+		out = filterDotenvSopsKeys(out)
+	}
+	return out, nil
+}
+
+// renderDigestTTL bounds how long a cached template digest is reused; it covers inputs the
+// cache key cannot see (host vars from the database)
+const renderDigestTTL = 10 * time.Minute
+
+type renderDigestEntry struct {
+	digest string
+	at     time.Time
+}
+
+var renderDigestCache = struct {
+	sync.Mutex
+	m map[string]renderDigestEntry
+}{m: map[string]renderDigestEntry{}}
+
+// renderDigestKey identifies the inputs of a stack's rendered templates: the stack, the render
+// host, every tracked file's hash (templates, env files for secret, ddui.vars.yml) and the
+// inventory version
+func renderDigestKey(ctx context.Context, stackID int64, files []IacFileMetaRow) string {
+	h := sha256.New()
+	host, _ := ctx.Value(CtxRenderHostKey{}).(string)
+	var inv uint64
+	if im := GetInventoryManager(); im != nil {
+		inv = im.Version()
+	}
+	fmt.Fprintf(h, "%d|%s|%d\n", stackID, host, inv)
+	for _, f := range files {
+		fmt.Fprintf(h, "%s|%s|%s|%d\n", f.Role, f.RelPath, f.Sha256Hex, f.SizeBytes)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// renderedTemplatesDigest hashes the rendered output of a stack's template files; render
+// errors are hashed too so fixing them changes the bundle hash. Results are cached by
+// renderDigestKey so snapshots do not re-render and decrypt unchanged stacks.
+func renderedTemplatesDigest(ctx context.Context, stackID int64, files []IacFileMetaRow) string {
+	key := renderDigestKey(ctx, stackID, files)
+	renderDigestCache.Lock()
+	e, ok := renderDigestCache.m[key]
+	renderDigestCache.Unlock()
+	if ok && time.Since(e.at) < renderDigestTTL {
+		return e.digest
+	}
+
+	digest := computeRenderedTemplatesDigest(ctx, stackID, files)
+	if digest == "" {
+		return ""
+	}
+	now := time.Now()
+	renderDigestCache.Lock()
+	if len(renderDigestCache.m) >= 1024 {
+		for k, old := range renderDigestCache.m {
+			if now.Sub(old.at) >= renderDigestTTL {
+				delete(renderDigestCache.m, k)
+			}
+		}
+	}
+	renderDigestCache.m[key] = renderDigestEntry{digest: digest, at: now}
+	renderDigestCache.Unlock()
+	return digest
+}
+
+func computeRenderedTemplatesDigest(ctx context.Context, stackID int64, files []IacFileMetaRow) string {
+	h := sha256.New()
+	sr, err := newStackRenderer(ctx, stackID)
+	if err != nil {
+		_, _ = h.Write([]byte("error|" + err.Error()))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	root, err := GetRepoRootForStack(ctx, stackID)
+	if err != nil {
+		return ""
+	}
+	for _, f := range files {
+		if !IsTemplateFile(f.RelPath) {
+			continue
+		}
+		full, err := joinUnderLocal(root, f.RelPath)
+		if err != nil {
+			continue
+		}
+		out, err := sr.renderFile(ctx, strings.ToLower(f.Role), f.RelPath, full)
+		_, _ = h.Write([]byte(f.RelPath + "\n"))
+		if err != nil {
+			_, _ = h.Write([]byte("error|" + err.Error()))
+		} else {
+			_, _ = h.Write(out)
+		}
+		_, _ = h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// RenderedTemplateFile is one rendered template in a preview
+type RenderedTemplateFile struct {
+	Path    string `json:"path"`
+	Output  string `json:"output"` // staged path (suffix removed)
+	Role    string `json:"role"`
+	Content string `json:"content,omitempty"`
+	Masked  bool   `json:"masked,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// StackRenderPreview is the rendered view of a stack's templates for one host
+type StackRenderPreview struct {
+	Host    string                 `json:"host,omitempty"`
+	Vars    map[string]any         `json:"vars"`
+	Files   []RenderedTemplateFile `json:"files"`
+	Secrets []string               `json:"secrets,omitempty"` // keys looked up with the secret helper
+}
+
+// RenderStackPreview renders a stack's templates as they would be staged. Unless reveal is
+// set, secret-looking vars, secret helper values, env values and SOPS files are masked.
+func RenderStackPreview(ctx context.Context, stackID int64, host string, reveal bool) (StackRenderPreview, error) {
+	if host != "" {
+		ctx = context.WithValue(ctx, CtxRenderHostKey{}, host)
+	}
+	sr, err := newStackRenderer(ctx, stackID)
+	if err != nil {
+		return StackRenderPreview{}, err
+	}
+	files, err := ListFilesForStack(ctx, stackID)
+	if err != nil {
+		return StackRenderPreview{}, err
+	}
+	root, err := GetRepoRootForStack(ctx, stackID)
+	if err != nil {
+		return StackRenderPreview{}, err
+	}
+
+	if !reveal {
+		sr.maskSecretVars()
+	}
+	out := StackRenderPreview{Host: sr.host, Vars: make(map[string]any, len(sr.vars)), Files: []RenderedTemplateFile{}}
+	for k, v := range sr.vars {
+		out.Vars[k] = v
+	}
+	for _, f := range files {
+		if !IsTemplateFile(f.RelPath) {
+			continue
+		}
+		role := strings.ToLower(f.Role)
+		rf := RenderedTemplateFile{Path: f.RelPath, Output: strings.TrimSuffix(f.RelPath, TemplateSuffix), Role: role}
+		full, err := joinUnderLocal(root, f.RelPath)
+		if err != nil {
+			rf.Error = err.Error()
+			out.Files = append(out.Files, rf)
+			continue
+		}
+		before := sr.masked
+		b, err := sr.renderFile(ctx, role, f.RelPath, full)
+		rf.Masked = sr.masked > before
+		switch {
+		case err != nil:
+			rf.Error = err.Error()
+		case reveal:
+			rf.Content = string(b)
+		case f.Sops:
+			rf.Masked = true
+		case role == "env":
+			rf.Content = maskDotenvValues(string(b))
+			rf.Masked = true
+		default:
+			rf.Content = string(b)
+		}
+		out.Files = append(out.Files, rf)
+	}
+
+	for k := range sr.revealed {
+		out.Secrets = append(out.Secrets, k)
+	}
+	sort.Strings(out.Secrets)
+	return out, nil
+}
+
+// maskDotenvValues replaces every non-empty value of dotenv content
+func maskDotenvValues(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if k, v, ok := strings.Cut(line, "="); ok && strings.TrimSpace(v) != "" {
+			lines[i] = k + "=" + utils.MaskedValue
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"dd-ui/utils"
+
+	"github.com/compose-spec/compose-go/v2/dotenv"
+	"github.com/goccy/go-yaml"
+)
+
+var quoteInputs = []string{
+	"plain",
+	"",
+	`say "hi"`,
+	`C:\path\to`,
+	`ends with \`,
+	"multi\nline\r\n",
+	"tab\there",
+	"bell\x07 and del\x7f",
+	"colon: hash # dollar $HOME",
+	"it's",
+	"unicode ✓",
+}
+
+func TestYAMLQuoteRoundTrip(t *testing.T) {
+	for _, in := range quoteInputs {
+		doc := "v: " + yamlQuote(in) + "\n"
+		var out struct {
+			V string `yaml:"v"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &out); err != nil {
+			t.Fatalf("%q: %v (doc %q)", in, err, doc)
+		}
+		if out.V != in {
+			t.Errorf("yamlQuote(%q) parsed back as %q (doc %q)", in, out.V, doc)
+		}
+	}
+}
+
+func TestTemplateQuoteByFormat(t *testing.T) {
+	sr := &stackRenderer{revealed: map[string]string{}}
+	ctx := context.Background()
+	for _, in := range quoteInputs {
+		sr.vars = map[string]any{"v": in}
+		sr.data = map[string]any{"v": in}
+
+		out, err := sr.render(ctx, "compose.yml.tmpl", []byte("v: {{ quote .v }}\nw: {{ yamlquote .v }}\n"), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var y struct {
+			V string `yaml:"v"`
+			W string `yaml:"w"`
+		}
+		if err := yaml.Unmarshal(out, &y); err != nil {
+			t.Fatalf("%q: %v (out %q)", in, err, out)
+		}
+		if y.V != in || y.W != in {
+			t.Errorf("yaml quote(%q) parsed back as %q / %q", in, y.V, y.W)
+		}
+
+		out, err = sr.render(ctx, ".env.tmpl", []byte("V={{ quote .v }}\nW={{ envquote .v }}\n"), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := dotenv.UnmarshalWithLookup(string(out), func(string) (string, bool) { return "", false })
+		if err != nil {
+			t.Fatalf("%q: %v (out %q)", in, err, out)
+		}
+		if env["V"] != in || env["W"] != in {
+			t.Errorf("env quote(%q) parsed back as %q / %q (out %q)", in, env["V"], env["W"], out)
+		}
+	}
+}
+
+func TestPreviewMasksSecretsAtSource(t *testing.T) {
+	const pw = `p"w\d`
+	tmpl := []byte("a: {{ quote (secret \"DB_PASSWORD\") }}\nb: {{ secret \"DB_PASSWORD\" | upper }}\nc: {{ quote .api_token }}\n")
+	newRenderer := func() *stackRenderer {
+		vars := map[string]any{"api_token": "tok-123"}
+		return &stackRenderer{vars: vars, data: map[string]any{"api_token": "tok-123"}, revealed: map[string]string{},
+			loadedEnv: true, secrets: map[string]string{"DB_PASSWORD": pw}}
+	}
+	ctx := context.Background()
+
+	sr := newRenderer()
+	sr.maskSecretVars()
+	out, err := sr.render(ctx, "compose.yml.tmpl", tmpl, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{pw, yamlQuote(pw), strings.ToUpper(pw), "tok-123"} {
+		if strings.Contains(string(out), leak) {
+			t.Errorf("masked preview contains %q:\n%s", leak, out)
+		}
+	}
+	if strings.Count(string(out), utils.MaskedValue) != 3 || sr.masked != 2 {
+		t.Errorf("masked preview = %q (%d secret lookups masked)", out, sr.masked)
+	}
+	if sr.revealed["DB_PASSWORD"] != pw {
+		t.Error("masked lookups should still be listed as used secrets")
+	}
+
+	sr = newRenderer()
+	out, err = sr.render(ctx, "compose.yml.tmpl", tmpl, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "a: "+yamlQuote(pw)+"\n") || strings.Contains(string(out), utils.MaskedValue) {
+		t.Errorf("revealed render = %q", out)
+	}
+}
+
+func TestRenderDigestKey(t *testing.T) {
+	ctx := context.Background()
+	files := []IacFileMetaRow{
+		{Role: "compose", RelPath: "app/compose.yml.tmpl", Sha256Hex: "aa", SizeBytes: 10},
+		{Role: "env", RelPath: "app/.env", Sha256Hex: "bb", SizeBytes: 5},
+	}
+	base := renderDigestKey(ctx, 1, files)
+	if base != renderDigestKey(ctx, 1, files) {
+		t.Fatal("key is not stable")
+	}
+
+	changedEnv := append([]IacFileMetaRow(nil), files...)
+	changedEnv[1].Sha256Hex = "cc"
+	hostCtx := context.WithValue(ctx, CtxRenderHostKey{}, "web-1")
+
+	for name, key := range map[string]string{
+		"other stack":      renderDigestKey(ctx, 2, files),
+		"secret file edit": renderDigestKey(ctx, 1, changedEnv),
+		"render host":      renderDigestKey(hostCtx, 1, files),
+		"file removed":     renderDigestKey(ctx, 1, files[:1]),
+	} {
+		if key == base || strings.TrimSpace(key) == "" {
+			t.Errorf("%s: key did not change", name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// services/iac_watcher.go
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+// iacRescanMu serializes watcher rescans with each other and with the polling fallback
+var iacRescanMu sync.Mutex
+
+// StartIacWatcher watches the docker-compose/ tree and rescans only the stack directory an
+// event belongs to. onChanged receives the rescanned stacks whose bundle differs from their
+// last successful deploy (see PendingApplyStacks). Returns false when watching is disabled or
+// unavailable, in which case polling is the only mechanism.
+func StartIacWatcher(ctx context.Context, onChanged func(ctx context.Context, stackIDs []int64)) bool {
+	if !fsWatchEnabled() {
+		return false
+	}
+	root := strings.TrimSpace(common.Env(IacDefaultRootEnv, IacDefaultRoot))
+	dirname := strings.TrimSpace(common.Env(DockerDirEnv, DefaultDockerDir))
+	base := filepath.Join(root, dirname)
+	if fi, err := os.Stat(base); err != nil || !fi.IsDir() {
+		common.InfoLog("iac: watch disabled, %s is not a directory", base)
+		return false
+	}
+
+	dw, err := newDebouncedWatcher()
+	if err != nil {
+		common.WarnLog("iac: file watching unavailable, polling only: %v", err)
+		return false
+	}
+	if err := dw.addTree(base); err != nil {
+		common.WarnLog("iac: file watching unavailable, polling only: %v", err)
+		dw.w.Close()
+		return false
+	}
+
+	// key: "<scope>/<stack>" for a stack, "*" for changes at scope level (full rescan)
+	keyFor := func(p string) string {
+		rel, err := filepath.Rel(base, p)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return ""
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) < 2 {
+			return "*"
+		}
+		return parts[0] + "/" + parts[1]
+	}
+
+	fire := func(key string) {
+		if ctx.Err() != nil {
+			return
+		}
+		iacRescanMu.Lock()
+		defer iacRescanMu.Unlock()
+
+		if key == "*" {
+			if _, _, err := ScanIacLocal(ctx); err != nil {
+				common.ErrorLog("iac: watch rescan failed: %v", err)
+				return
+			}
+			current, _ := SnapshotIacBundleHashes(ctx)
+			if pending := PendingApplyStacks(ctx, current); len(pending) > 0 {
+				onChanged(ctx, pending)
+			}
+			return
+		}
+
+		scope, stack, _ := strings.Cut(key, "/")
+		id, err := ScanIacStack(ctx, scope, stack)
+		if err != nil {
+			common.ErrorLog("iac: rescan of %s failed: %v", key, err)
+			return
+		}
+		if id == 0 {
+			return
+		}
+		after, _ := ComputeCurrentBundleHash(ctx, id)
+		if pending := PendingApplyStacks(ctx, map[int64]string{id: after}); len(pending) > 0 {
+			common.InfoLog("iac: stack %s differs from its last deploy (bundle %s)", key, shortBundle(after))
+			onChanged(ctx, pending)
+		}
+	}
+
+	go dw.run(ctx, true, keyFor, fire)
+	common.InfoLog("iac: watching %s for changes (debounce %s)", base, dw.debounce)
+	return true
+}
+
+// PendingApplyStacks lists the stacks of current (id -> bundle hash) whose bundle differs from
+// the one their last successful deploy applied: never deployed, changed since, or the last
+// attempt failed or was skipped (e.g. host offline), so Auto DevOps retries until it succeeds
+func PendingApplyStacks(ctx context.Context, current map[int64]string) []int64 {
+	deployed, err := database.LastDeployedBundleHashes(ctx)
+	if err != nil {
+		common.WarnLog("iac: reading deployed bundles failed: %v", err)
+		return nil
+	}
+	var out []int64
+	for id, h := range current {
+		if d, ok := deployed[id]; !ok || d != h {
+			out = append(out, id)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// LockIacRescan serializes a full IaC scan with the watcher's incremental rescans
+func LockIacRescan() func() {
+	iacRescanMu.Lock()
+	return iacRescanMu.Unlock
+}
+
+func shortBundle(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
@@ -2,12 +2,11 @@
 package services
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"os"
-	"strings"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -114,6 +113,7 @@ func loadInventory(p string) error {
 	if derr != nil {
 		return derr
 	}
+	applyHostVarsDir(p, parsed)
 
 	// Persist to DB (implemented in db_hosts.go)
 	if err := database.ImportInventoryToDB(context.Background(), parsed); err != nil {
@@ -195,46 +195,44 @@ func mapYamlToHosts(y yamlInventory) []common.Host {
 	return out
 }
 
-// Minimal INI-ish fallback: "name ansible_host=1.2.3.4 foo=bar"
+// INI: sections, :vars and :children are handled by parseINIToInventory (inventory_ini.go)
 func parseINIInventory(b []byte) ([]common.Host, error) {
-	sc := bufio.NewScanner(strings.NewReader(string(b)))
-	var out []common.Host
-	for sc.Scan() {
-		line := strings.TrimSpace(sc.Text())
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "[") {
-			continue
-		}
-		fs := strings.Fields(line)
-		if len(fs) == 0 {
-			continue
-		}
-		h := common.Host{Name: fs[0], Vars: map[string]string{}}
-		for _, f := range fs[1:] {
-			kv := strings.SplitN(f, "=", 2)
-			if len(kv) != 2 {
-				continue
-			}
-			k, v := kv[0], kv[1]
-			if k == "ansible_host" {
-				h.Addr = v
-			} else {
-				h.Vars[k] = v
+	inv, err := parseINIToInventory(b)
+	if err != nil {
+		return nil, err
+	}
+	var y yamlInventory
+	y.All.Hosts = map[string]map[string]any{}
+	if all, ok := inv["all"].(map[string]any); ok {
+		if hs, ok := all["hosts"].(map[string]any); ok {
+			for name, v := range hs {
+				vars, _ := v.(map[string]any)
+				if vars == nil {
+					vars = map[string]any{}
+				}
+				y.All.Hosts[name] = vars
 			}
 		}
+	}
+	return mapYamlToHosts(y), nil
+}
 
-		// --- NEW: owner from vars["owner"] or DD_UI_DEFAULT_OWNER
-		if o, ok := h.Vars["owner"]; ok && o != "" {
-			h.Owner = o
-		} else if def := common.Env("DD_UI_DEFAULT_OWNER", ""); def != "" {
-			h.Owner = def
+// applyHostVarsDir overlays host_vars/<name> files next to the inventory onto parsed hosts
+func applyHostVarsDir(invFile string, hs []common.Host) {
+	files := loadVarsDir(filepath.Join(filepath.Dir(invFile), "host_vars"))
+	for i := range hs {
+		for k, v := range mergeVarsFiles(files[hs[i].Name]) {
+			switch k {
+			case "ansible_host":
+				hs[i].Addr = stringify(v)
+			case "owner":
+				hs[i].Owner = stringify(v)
+				hs[i].Vars[k] = stringify(v)
+			default:
+				hs[i].Vars[k] = stringify(v)
+			}
 		}
-
-		out = append(out, h)
-	}
-	if len(out) == 0 {
-		return nil, errors.New("ini: no hosts found")
 	}
-	return out, nil
 }
 
 func stringify(v any) string { return fmt.Sprintf("%v", v) }
@@ -0,0 +1,345 @@
+// services/inventory_ini.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+/*
+INI inventories are converted to the YAML layout InventoryManager edits
+(all.hosts holds every host with its vars, groups live at top level with
+hosts/vars/children) and written back as INI on save:
+
+  web1 ansible_host=10.0.0.1 dd_ui_tags='["prod"]'
+  [web]
+  web1
+  [web:vars]
+  http_port=80
+  [prod:children]
+  web
+
+Host ranges (web[01:03]) are expanded; comments are not preserved on write.
+*/
+
+// isINIInventory reports whether b is not a YAML mapping (and so must be INI)
+func isINIInventory(b []byte) bool {
+	if strings.TrimSpace(string(b)) == "" {
+		return false
+	}
+	var m map[string]any
+	return yaml.Unmarshal(b, &m) != nil || len(m) == 0
+}
+
+// parseINIToInventory converts an INI inventory into the YAML inventory layout
+func parseINIToInventory(b []byte) (map[string]any, error) {
+	allHosts := map[string]any{}
+	all := map[string]any{"hosts": allHosts}
+	inv := map[string]any{"all": all}
+
+	group := func(name string) map[string]any {
+		if name == "all" {
+			return all
+		}
+		g, ok := inv[name].(map[string]any)
+		if !ok {
+			g = map[string]any{}
+			inv[name] = g
+		}
+		return g
+	}
+	sub := func(g map[string]any, key string) map[string]any {
+		m, ok := g[key].(map[string]any)
+		if !ok {
+			m = map[string]any{}
+			g[key] = m
+		}
+		return m
+	}
+
+	section, kind := "ungrouped", "hosts"
+	for n, raw := range strings.Split(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind = strings.TrimSpace(line[1:len(line)-1]), "hosts"
+			if i := strings.LastIndex(section, ":"); i > 0 {
+				section, kind = section[:i], section[i+1:]
+			}
+			if kind != "hosts" && kind != "vars" && kind != "children" {
+				return nil, fmt.Errorf("ini line %d: unknown section type %q", n+1, kind)
+			}
+			group(section)
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("ini line %d: expected key=value in [%s:vars]", n+1, section)
+			}
+			sub(group(section), "vars")[strings.TrimSpace(kv[0])] = iniValue(unquoteINI(strings.TrimSpace(kv[1])))
+		case "children":
+			child := strings.Fields(line)[0]
+			sub(group(section), "children")[child] = map[string]any{}
+			group(child)
+		default:
+			fields := splitINIFields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			vars := map[string]any{}
+			for _, f := range fields[1:] {
+				kv := strings.SplitN(f, "=", 2)
+				if len(kv) != 2 {
+					return nil, fmt.Errorf("ini line %d: expected key=value, got %q", n+1, f)
+				}
+				vars[kv[0]] = iniValue(kv[1])
+			}
+			for _, name := range expandHostPattern(fields[0]) {
+				hv, ok := allHosts[name].(map[string]any)
+				if !ok {
+					hv = map[string]any{}
+					allHosts[name] = hv
+				}
+				for k, v := range vars {
+					hv[k] = v
+				}
+				if section != "ungrouped" && section != "all" {
+					sub(group(section), "hosts")[name] = map[string]any{}
+				}
+			}
+		}
+	}
+	delete(inv, "ungrouped")
+	if len(allHosts) == 0 {
+		return nil, fmt.Errorf("ini: no hosts found")
+	}
+	return inv, nil
+}
+
+// splitINIFields splits a host line on whitespace, honouring quotes and trailing comments
+func splitINIFields(line string) []string {
+	var out []string
+	var cur strings.Builder
+	var quote rune
+	inField := false
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inField = r, true
+		case r == '#' && !inField:
+			return out
+		case r == ' ' || r == '\t':
+			if inField {
+				out = append(out, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+func unquoteINI(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// iniValue keeps scalars as strings and decodes list/map literals (JSON or YAML flow)
+func iniValue(s string) any {
+	if strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+		var v any
+		if err := yaml.Unmarshal([]byte(s), &v); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+// expandHostPattern expands one numeric or alphabetic range: web[01:03] -> web01 web02 web03
+func expandHostPattern(p string) []string {
+	open, close := strings.Index(p, "["), strings.Index(p, "]")
+	if open < 0 || close < open {
+		return []string{p}
+	}
+	bounds := strings.SplitN(p[open+1:close], ":", 2)
+	if len(bounds) != 2 {
+		return []string{p}
+	}
+	prefix, suffix := p[:open], p[close+1:]
+	var out []string
+	if lo, err1 := strconv.Atoi(bounds[0]); err1 == nil {
+		hi, err2 := strconv.Atoi(bounds[1])
+		if err2 != nil || hi < lo {
+			return []string{p}
+		}
+		width := 0
+		if strings.HasPrefix(bounds[0], "0") {
+			width = len(bounds[0])
+		}
+		for i := lo; i <= hi; i++ {
+			out = append(out, fmt.Sprintf("%s%0*d%s", prefix, width, i, suffix))
+		}
+		return out
+	}
+	if len(bounds[0]) == 1 && len(bounds[1]) == 1 && bounds[0] <= bounds[1] {
+		for c := bounds[0][0]; c <= bounds[1][0]; c++ {
+			out = append(out, prefix+string(c)+suffix)
+		}
+		return out
+	}
+	return []string{p}
+}
+
+// iniGroup is a flattened group for INI output
+type iniGroup struct {
+	hosts    map[string]bool
+	vars     map[string]any
+	children map[string]bool
+}
+
+// renderINIInventory writes the YAML inventory layout back as INI
+func renderINIInventory(inv map[string]any) []byte {
+	groups := map[string]*iniGroup{}
+	var collect func(name string, g map[string]any)
+	collect = func(name string, g map[string]any) {
+		ig, ok := groups[name]
+		if !ok {
+			ig = &iniGroup{hosts: map[string]bool{}, vars: map[string]any{}, children: map[string]bool{}}
+			groups[name] = ig
+		}
+		if hs, ok := g["hosts"].(map[string]any); ok {
+			for h := range hs {
+				ig.hosts[h] = true
+			}
+		}
+		if vs, ok := g["vars"].(map[string]any); ok {
+			for k, v := range vs {
+				ig.vars[k] = v
+			}
+		}
+		if cs, ok := g["children"].(map[string]any); ok {
+			for c, cg := range cs {
+				ig.children[c] = true
+				if m, ok := cg.(map[string]any); ok {
+					collect(c, m)
+				}
+			}
+		}
+	}
+	for name, g := range inv {
+		if m, ok := g.(map[string]any); ok {
+			collect(name, m)
+		}
+	}
+
+	var b strings.Builder
+	// every host with its vars first (ungrouped form; Ansible drops grouped hosts from ungrouped)
+	if all, ok := inv["all"].(map[string]any); ok {
+		if hs, ok := all["hosts"].(map[string]any); ok {
+			for _, h := range sortedKeys(hs) {
+				b.WriteString(h)
+				vars, _ := hs[h].(map[string]any)
+				for _, k := range sortedKeys(vars) {
+					b.WriteString(" " + k + "=" + iniQuote(iniFormat(vars[k])))
+				}
+				b.WriteByte('\n')
+			}
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g := groups[name]
+		if name != "all" && len(g.hosts) > 0 {
+			fmt.Fprintf(&b, "\n[%s]\n", name)
+			for _, h := range sortedSet(g.hosts) {
+				b.WriteString(h + "\n")
+			}
+		}
+		if len(g.vars) > 0 {
+			fmt.Fprintf(&b, "\n[%s:vars]\n", name)
+			for _, k := range sortedKeys(g.vars) {
+				b.WriteString(k + "=" + iniQuote(iniFormat(g.vars[k])) + "\n")
+			}
+		}
+		if name != "all" && len(g.children) > 0 {
+			fmt.Fprintf(&b, "\n[%s:children]\n", name)
+			for _, c := range sortedSet(g.children) {
+				b.WriteString(c + "\n")
+			}
+		}
+		if name != "all" && len(g.hosts) == 0 && len(g.vars) == 0 && len(g.children) == 0 {
+			fmt.Fprintf(&b, "\n[%s]\n", name) // keep empty groups
+		}
+	}
+	return []byte(b.String())
+}
+
+// iniFormat renders a value; lists and maps become JSON (valid for Ansible's literal parsing)
+func iniFormat(v any) string {
+	switch v.(type) {
+	case []any, map[string]any, []string, map[string]string:
+		if j, err := json.Marshal(v); err == nil {
+			return string(j)
+		}
+	case nil:
+		return ""
+	}
+	return stringify(v)
+}
+
+func iniQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t'\"#") {
+		return s
+	}
+	if strings.Contains(s, "'") {
+		return `"` + s + `"`
+	}
+	return "'" + s + "'"
+}
+
+func sortedKeys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedSet(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
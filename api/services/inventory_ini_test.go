@@ -0,0 +1,123 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testINIInventory = `# comment
+web[01:02] ansible_host=10.0.0.1 dd_ui_tags='["prod", "edge"]'
+db1 ansible_host=db.example.com dd_ui_description="main database" # trailing comment
+lone
+
+[web]
+web[01:02]
+
+[web:vars]
+http_port=80
+motd='hello world'
+quoted="it's"
+dns=["1.1.1.1", "8.8.8.8"]
+
+[db]
+db1
+
+[prod:children]
+web
+db
+
+[all:vars]
+ansible_user=deploy
+`
+
+func TestINIInventoryRoundTrip(t *testing.T) {
+	inv, err := parseINIToInventory([]byte(testINIInventory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := inv["all"].(map[string]any)["hosts"].(map[string]any)
+	for _, h := range []string{"web01", "web02", "db1", "lone"} {
+		if _, ok := hosts[h]; !ok {
+			t.Errorf("host %s missing from all.hosts", h)
+		}
+	}
+	web01 := hosts["web01"].(map[string]any)
+	if !reflect.DeepEqual(web01["dd_ui_tags"], []any{"prod", "edge"}) {
+		t.Errorf("web01 dd_ui_tags = %#v", web01["dd_ui_tags"])
+	}
+	if d := hosts["db1"].(map[string]any)["dd_ui_description"]; d != "main database" {
+		t.Errorf("db1 description = %q", d)
+	}
+	webVars := inv["web"].(map[string]any)["vars"].(map[string]any)
+	if webVars["motd"] != "hello world" || webVars["quoted"] != "it's" {
+		t.Errorf("web vars = %#v", webVars)
+	}
+
+	out := renderINIInventory(inv)
+	again, err := parseINIToInventory(out)
+	if err != nil {
+		t.Fatalf("re-parse: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(inv, again) {
+		t.Errorf("round trip changed the inventory\nfirst:  %#v\nsecond: %#v\nini:\n%s", inv, again, out)
+	}
+	if !strings.Contains(string(out), "motd='hello world'") {
+		t.Errorf("group vars are not quoted:\n%s", out)
+	}
+}
+
+func TestIsINIInventory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"", false},
+		{"all:\n  hosts:\n    web1: {}\n", false},
+		{"web1 ansible_host=10.0.0.1\n[web]\nweb1\n", true},
+		{"[web]\nweb1\n", true},
+	}
+	for _, tt := range tests {
+		if got := isINIInventory([]byte(tt.in)); got != tt.want {
+			t.Errorf("isINIInventory(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandHostPattern(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"web1", []string{"web1"}},
+		{"web[1:3]", []string{"web1", "web2", "web3"}},
+		{"web[01:03].lan", []string{"web01.lan", "web02.lan", "web03.lan"}},
+		{"db-[a:c]", []string{"db-a", "db-b", "db-c"}},
+		{"bad[3:1]", []string{"bad[3:1]"}},
+		{"odd[x]", []string{"odd[x]"}},
+	}
+	for _, tt := range tests {
+		if got := expandHostPattern(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandHostPattern(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestINIQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain", "plain"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"it's", `"it's"`},
+		{`["a b"]`, `'["a b"]'`},
+	}
+	for _, tt := range tests {
+		if got := iniQuote(tt.in); got != tt.want {
+			t.Errorf("iniQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+		if tt.in != "" && unquoteINI(iniQuote(tt.in)) != tt.in {
+			t.Errorf("unquoteINI(iniQuote(%q)) did not round trip", tt.in)
+		}
+	}
+}
@@ -15,9 +15,13 @@ import (
 
 // InventoryManager manages the Ansible inventory file as the single source of truth
 type InventoryManager struct {
-	path string
-	data []byte // Raw YAML content to preserve formatting
-	mu   sync.RWMutex
+	path      string
+	data      []byte // Raw YAML content to preserve formatting
+	format    string // "yaml" or "ini"; INI files are edited as YAML and written back as INI
+	groupVars map[string][]*varsFile // group_vars/ next to the inventory
+	hostVars  map[string][]*varsFile // host_vars/ next to the inventory
+	version   uint64                 // bumped on every load and save
+	mu        sync.RWMutex
 }
 
 // Metadata structures for DD-UI specific fields
@@ -136,7 +140,23 @@ func (im *InventoryManager) Load() error {
 		return fmt.Errorf("failed to read inventory: %w", err)
 	}
 
+	im.format = "yaml"
+	if isINIInventory(data) {
+		inv, err := parseINIToInventory(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse INI inventory: %w", err)
+		}
+		if data, err = yaml.Marshal(inv); err != nil {
+			return fmt.Errorf("failed to convert INI inventory: %w", err)
+		}
+		im.format = "ini"
+	}
 	im.data = data
+
+	dir := filepath.Dir(im.path)
+	im.groupVars = loadVarsDir(filepath.Join(dir, "group_vars"))
+	im.hostVars = loadVarsDir(filepath.Join(dir, "host_vars"))
+	im.version++
 	return nil
 }
 
@@ -145,6 +165,7 @@ func (im *InventoryManager) saveInternal() error {
 	if im.path == "" {
 		return errors.New("no inventory path configured")
 	}
+	im.version++
 
 	// Ensure directory exists
 	dir := filepath.Dir(im.path)
@@ -160,7 +181,15 @@ func (im *InventoryManager) saveInternal() error {
 		}
 	}
 
-	return os.WriteFile(im.path, im.data, 0644)
+	out := im.data
+	if im.format == "ini" {
+		var inv map[string]any
+		if err := yaml.Unmarshal(im.data, &inv); err != nil {
+			return fmt.Errorf("failed to parse inventory: %w", err)
+		}
+		out = renderINIInventory(inv)
+	}
+	return os.WriteFile(im.path, out, 0644)
 }
 
 // Save writes the current inventory data to file
@@ -170,6 +199,14 @@ func (im *InventoryManager) Save() error {
 	return im.saveInternal()
 }
 
+// Version changes whenever the inventory or its vars files are loaded or saved, so callers
+// can cache what they derive from them
+func (im *InventoryManager) Version() uint64 {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return im.version
+}
+
 // Reload re-reads the inventory file
 func (im *InventoryManager) Reload() error {
 	return im.Load()
@@ -200,6 +237,17 @@ func (im *InventoryManager) GetHosts() ([]InventoryHost, error) {
 	// Process hosts from 'all' group
 	if inv.All != nil && inv.All.Hosts != nil {
 		for name, vars := range inv.All.Hosts {
+			// host_vars/<name> files override inline host vars
+			if fileVars := mergeVarsFiles(im.hostVars[name]); len(fileVars) > 0 {
+				merged := make(map[string]any, len(vars)+len(fileVars))
+				for k, v := range vars {
+					merged[k] = v
+				}
+				for k, v := range fileVars {
+					merged[k] = v
+				}
+				vars = merged
+			}
 			host := im.parseHost(name, vars)
 			host.Groups = hostGroups[name]
 			hosts = append(hosts, host)
@@ -280,6 +328,26 @@ func (im *InventoryManager) GetGroup(name string) (*InventoryGroup, error) {
 	return nil, ErrNotFound
 }
 
+// metadataKeys are the vars HostMetadata and GroupMetadata manage
+var metadataKeys = []string{"dd_ui_tags", "dd_ui_description", "dd_ui_alt_name", "dd_ui_tenant",
+	"dd_ui_allowed_users", "dd_ui_owner", "dd_ui_env"}
+
+func isMetadataKey(k string) bool {
+	for _, m := range metadataKeys {
+		if k == m {
+			return true
+		}
+	}
+	return false
+}
+
+// clearMetadataKeys removes the metadata vars before they are set from a full metadata update
+func clearMetadataKeys(vars map[string]any) {
+	for _, k := range metadataKeys {
+		delete(vars, k)
+	}
+}
+
 // UpdateHostMetadata updates DD-UI metadata for a host
 func (im *InventoryManager) UpdateHostMetadata(name string, metadata HostMetadata) error {
 	im.mu.Lock()
@@ -293,35 +361,40 @@ func (im *InventoryManager) UpdateHostMetadata(name string, metadata HostMetadat
 	// Find the host in the inventory structure
 	if all, ok := inv["all"].(map[string]any); ok {
 		if hosts, ok := all["hosts"].(map[string]any); ok {
-			if host, ok := hosts[name].(map[string]any); ok {
-				// Update DD-UI fields
-				if len(metadata.Tags) > 0 {
-					host["dd_ui_tags"] = metadata.Tags
-				}
-				if metadata.Description != "" {
-					host["dd_ui_description"] = metadata.Description
-				}
-				if metadata.AltName != "" {
-					host["dd_ui_alt_name"] = metadata.AltName
-				}
-				if metadata.Tenant != "" {
-					host["dd_ui_tenant"] = metadata.Tenant
-				}
-				if len(metadata.AllowedUsers) > 0 {
-					host["dd_ui_allowed_users"] = metadata.AllowedUsers
-				}
-				if metadata.Owner != "" {
-					host["dd_ui_owner"] = metadata.Owner
-				}
-				if len(metadata.Env) > 0 {
-					host["dd_ui_env"] = metadata.Env
-				}
-			} else {
+			host, ok := hosts[name].(map[string]any)
+			if !ok {
 				// Host exists but has no vars yet
-				hosts[name] = map[string]any{
+				host = map[string]any{
 					"ansible_host": name, // Default to hostname if no ansible_host
 				}
-				return im.UpdateHostMetadata(name, metadata) // Retry
+				hosts[name] = host
+			}
+			// Update DD-UI fields (cleared fields are removed)
+			clearMetadataKeys(host)
+			if len(metadata.Tags) > 0 {
+				host["dd_ui_tags"] = metadata.Tags
+			}
+			if metadata.Description != "" {
+				host["dd_ui_description"] = metadata.Description
+			}
+			if metadata.AltName != "" {
+				host["dd_ui_alt_name"] = metadata.AltName
+			}
+			if metadata.Tenant != "" {
+				host["dd_ui_tenant"] = metadata.Tenant
+			}
+			if len(metadata.AllowedUsers) > 0 {
+				host["dd_ui_allowed_users"] = metadata.AllowedUsers
+			}
+			if metadata.Owner != "" {
+				host["dd_ui_owner"] = metadata.Owner
+			}
+			if len(metadata.Env) > 0 {
+				host["dd_ui_env"] = metadata.Env
+			}
+			// Keys defined in host_vars/<name> are written back there
+			if err := routeVarsToFiles(im.hostVars[name], host, isMetadataKey); err != nil {
+				return err
 			}
 		}
 	}
@@ -348,33 +421,38 @@ func (im *InventoryManager) UpdateGroupMetadata(name string, metadata GroupMetad
 
 	// Find the group in the inventory structure
 	if group, ok := inv[name].(map[string]any); ok {
-		if vars, ok := group["vars"].(map[string]any); ok {
-			// Update DD-UI fields in vars
-			if len(metadata.Tags) > 0 {
-				vars["dd_ui_tags"] = metadata.Tags
-			}
-			if metadata.Description != "" {
-				vars["dd_ui_description"] = metadata.Description
-			}
-			if metadata.AltName != "" {
-				vars["dd_ui_alt_name"] = metadata.AltName
-			}
-			if metadata.Tenant != "" {
-				vars["dd_ui_tenant"] = metadata.Tenant
-			}
-			if len(metadata.AllowedUsers) > 0 {
-				vars["dd_ui_allowed_users"] = metadata.AllowedUsers
-			}
-			if metadata.Owner != "" {
-				vars["dd_ui_owner"] = metadata.Owner
-			}
-			if len(metadata.Env) > 0 {
-				vars["dd_ui_env"] = metadata.Env
-			}
-		} else {
+		vars, ok := group["vars"].(map[string]any)
+		if !ok {
 			// Group has no vars yet, create them
-			group["vars"] = map[string]any{}
-			return im.UpdateGroupMetadata(name, metadata) // Retry
+			vars = map[string]any{}
+			group["vars"] = vars
+		}
+		// Update DD-UI fields in vars (cleared fields are removed)
+		clearMetadataKeys(vars)
+		if len(metadata.Tags) > 0 {
+			vars["dd_ui_tags"] = metadata.Tags
+		}
+		if metadata.Description != "" {
+			vars["dd_ui_description"] = metadata.Description
+		}
+		if metadata.AltName != "" {
+			vars["dd_ui_alt_name"] = metadata.AltName
+		}
+		if metadata.Tenant != "" {
+			vars["dd_ui_tenant"] = metadata.Tenant
+		}
+		if len(metadata.AllowedUsers) > 0 {
+			vars["dd_ui_allowed_users"] = metadata.AllowedUsers
+		}
+		if metadata.Owner != "" {
+			vars["dd_ui_owner"] = metadata.Owner
+		}
+		if len(metadata.Env) > 0 {
+			vars["dd_ui_env"] = metadata.Env
+		}
+		// Keys defined in group_vars/<name> are written back there
+		if err := routeVarsToFiles(im.groupVars[name], vars, isMetadataKey); err != nil {
+			return err
 		}
 	} else {
 		return fmt.Errorf("group %s not found", name)
@@ -722,6 +800,13 @@ func (im *InventoryManager) UpdateHost(name, ansibleHost string, metadata HostMe
 		host["dd_ui_env"] = metadata.Env
 	}
 
+	// Keys defined in host_vars/<name> are written back there (cleared DD-UI fields removed)
+	if err := routeVarsToFiles(im.hostVars[name], host, func(k string) bool {
+		return strings.HasPrefix(k, "dd_ui_")
+	}); err != nil {
+		return err
+	}
+
 	// Marshal back to YAML
 	data, err := yaml.Marshal(inv)
 	if err != nil {
@@ -902,9 +987,19 @@ func (im *InventoryManager) parseGroup(name string, group *ansibleGroup) Invento
 		}
 	}
 
-	// Process vars
-	if group.Vars != nil {
+	// Process vars (group_vars/<name> files override inline group vars)
+	vars := group.Vars
+	if fileVars := mergeVarsFiles(im.groupVars[name]); len(fileVars) > 0 {
+		vars = make(map[string]any, len(group.Vars)+len(fileVars))
 		for k, v := range group.Vars {
+			vars[k] = v
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	if vars != nil {
+		for k, v := range vars {
 			switch k {
 			case "dd_ui_tags":
 				if tags, ok := v.([]any); ok {
@@ -1012,7 +1107,7 @@ func (im *InventoryManager) SetRawYAML(content string) error {
 	}
 
 	im.data = []byte(content)
-	return im.Save()
+	return im.saveInternal()
 }
 
 // ExportForAnsible exports inventory without DD-UI fields for pure Ansible use
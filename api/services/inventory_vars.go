@@ -0,0 +1,364 @@
+// services/inventory_vars.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+
+	"dd-ui/common"
+)
+
+/*
+group_vars/ and host_vars/ next to the inventory file, as Ansible loads them:
+
+  group_vars/<group>.yml | .yaml | .json | (no extension)
+  group_vars/<group>/*.yml                 (merged in lexical order)
+  host_vars/<host>.yml ...
+
+Precedence (see EffectiveHostVars): inventory group vars < group_vars files
+< inventory host vars < host_vars files. Edits to a key that lives in one of
+these files are written back to that file; SOPS-encrypted files are read-only.
+Only files whose values changed are rewritten, and YAML files are edited key by
+key so comments, anchors and key order survive.
+*/
+
+// varsFile is one file under group_vars/ or host_vars/
+type varsFile struct {
+	path string
+	vars map[string]any
+	raw  []byte // content as read (decrypted for SOPS), edited in place by save
+	sops bool
+}
+
+// loadVarsDir reads <dir>/<name>[.ext] and <dir>/<name>/* keyed by name
+func loadVarsDir(dir string) map[string][]*varsFile {
+	out := map[string][]*varsFile{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return out
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		full := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			var paths []string
+			_ = filepath.WalkDir(full, func(p string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() && isVarsFileName(d.Name()) {
+					paths = append(paths, p)
+				}
+				return nil
+			})
+			sort.Strings(paths)
+			for _, p := range paths {
+				if f := readVarsFile(p); f != nil {
+					out[e.Name()] = append(out[e.Name()], f)
+				}
+			}
+			continue
+		}
+		if !isVarsFileName(e.Name()) {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if f := readVarsFile(full); f != nil {
+			out[name] = append(out[name], f)
+		}
+	}
+	return out
+}
+
+func isVarsFileName(n string) bool {
+	if strings.HasPrefix(n, ".") || strings.HasSuffix(n, "~") || strings.HasSuffix(n, ".bak") {
+		return false
+	}
+	switch filepath.Ext(n) {
+	case ".yml", ".yaml", ".json", "":
+		return true
+	}
+	return false
+}
+
+func readVarsFile(p string) *varsFile {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		common.WarnLog("inventory: failed to read vars file %s: %v", p, err)
+		return nil
+	}
+	if strings.HasPrefix(string(b), "$ANSIBLE_VAULT") {
+		common.DebugLog("inventory: skipping ansible-vault file %s", p)
+		return nil
+	}
+	f := &varsFile{path: p, vars: map[string]any{}}
+	if looksSops(b) {
+		f.sops = true
+		if b, _, err = readDecryptedOrPlain(context.Background(), p, ""); err != nil {
+			common.WarnLog("inventory: failed to decrypt vars file %s: %v", p, err)
+			return nil
+		}
+	}
+	if err := yaml.Unmarshal(b, &f.vars); err != nil {
+		common.WarnLog("inventory: failed to parse vars file %s: %v", p, err)
+		return nil
+	}
+	if f.vars == nil {
+		f.vars = map[string]any{}
+	}
+	f.raw = b
+	delete(f.vars, "sops")
+	return f
+}
+
+// mergeVarsFiles merges a name's files, later files winning
+func mergeVarsFiles(files []*varsFile) map[string]any {
+	out := map[string]any{}
+	for _, f := range files {
+		for k, v := range f.vars {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// varsFileOwning returns the file whose value of key is effective (the last one defining it)
+func varsFileOwning(files []*varsFile, key string) *varsFile {
+	for i := len(files) - 1; i >= 0; i-- {
+		if _, ok := files[i].vars[key]; ok {
+			return files[i]
+		}
+	}
+	return nil
+}
+
+// save writes the given keys (deleted when missing from f.vars) back to the file
+func (f *varsFile) save(keys []string) error {
+	if f.sops {
+		return fmt.Errorf("%s is SOPS-encrypted; edit it in the repository", f.path)
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if filepath.Ext(f.path) == ".json" {
+		data, err = editJSONVars(f.raw, f.vars)
+	} else {
+		data, err = editYAMLVars(f.raw, f.vars, keys)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", f.path, err)
+	}
+	if err := os.WriteFile(f.path, data, 0644); err != nil {
+		return err
+	}
+	f.raw = data
+	return nil
+}
+
+// editYAMLVars replaces, appends or removes keys of the document's top-level mapping through
+// the AST, leaving every other node (comments, anchors, order) as it was
+func editYAMLVars(raw []byte, vars map[string]any, keys []string) ([]byte, error) {
+	file, err := parser.ParseBytes(raw, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var m *ast.MappingNode
+	if len(file.Docs) > 0 {
+		m, _ = file.Docs[0].Body.(*ast.MappingNode)
+	}
+	if m == nil {
+		// no mapping to edit (empty or comment-only file)
+		return yaml.Marshal(vars)
+	}
+	for _, k := range keys {
+		idx := -1
+		for i, mv := range m.Values {
+			if mv.Key.GetToken().Value == k {
+				idx = i // the last definition is the effective one
+			}
+		}
+		v, ok := vars[k]
+		if !ok {
+			if idx >= 0 {
+				m.Values = append(m.Values[:idx], m.Values[idx+1:]...)
+			}
+			continue
+		}
+		mv, err := yamlMappingValue(k, v)
+		if err != nil {
+			return nil, err
+		}
+		if idx < 0 {
+			m.Values = append(m.Values, mv)
+			continue
+		}
+		keepYAMLDecorations(m.Values[idx], mv)
+		m.Values[idx] = mv
+	}
+	out := file.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return []byte(out), nil
+}
+
+// yamlMappingValue builds the "key: value" node the encoder would write at the top level
+func yamlMappingValue(key string, v any) (*ast.MappingValueNode, error) {
+	b, err := yaml.Marshal(map[string]any{key: v})
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseBytes(b, 0)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := f.Docs[0].Body.(*ast.MappingNode)
+	if !ok || len(m.Values) != 1 {
+		return nil, fmt.Errorf("unexpected encoding of %s", key)
+	}
+	return m.Values[0], nil
+}
+
+// keepYAMLDecorations carries the comments and anchor of a replaced entry over to its new node
+func keepYAMLDecorations(old, mv *ast.MappingValueNode) {
+	if c := old.GetComment(); c != nil {
+		_ = mv.SetComment(c)
+	}
+	if c := old.Key.GetComment(); c != nil {
+		_ = mv.Key.SetComment(c)
+	}
+	if anchor, ok := old.Value.(*ast.AnchorNode); ok {
+		anchor.Value = mv.Value
+		mv.Value = anchor
+		return
+	}
+	if _, scalar := mv.Value.(ast.ScalarNode); scalar {
+		if c := old.Value.GetComment(); c != nil {
+			_ = mv.Value.SetComment(c)
+		}
+	}
+}
+
+// editJSONVars re-encodes a JSON vars file, keeping the original key order and appending new keys
+func editJSONVars(raw []byte, vars map[string]any) ([]byte, error) {
+	var order []string
+	seen := map[string]bool{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err == nil && tok == json.Delim('{') {
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				break
+			}
+			k, _ := tok.(string)
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				break
+			}
+			if _, ok := vars[k]; ok && !seen[k] {
+				order = append(order, k)
+				seen[k] = true
+			}
+		}
+	}
+	var added []string
+	for k := range vars {
+		if !seen[k] {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	order = append(order, added...)
+
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, k := range order {
+		kb, _ := json.Marshal(k)
+		vb, err := json.MarshalIndent(vars[k], "  ", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  ")
+		b.Write(kb)
+		b.WriteString(": ")
+		b.Write(vb)
+	}
+	b.WriteString("\n}\n")
+	return b.Bytes(), nil
+}
+
+// sameVarValue compares an inventory value with a file value; both may come from different
+// decoders ([]string vs []any, int vs uint64), so they are compared by their JSON encoding
+func sameVarValue(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// routeVarsToFiles moves keys owned by a group_vars/host_vars file out of vars (an inventory
+// host or group vars map) and into that file. Owned keys missing from vars are deleted from
+// their file when managed reports them as edited by the caller. Only files whose values
+// changed are written. Caller holds im.mu.
+func routeVarsToFiles(files []*varsFile, vars map[string]any, managed func(string) bool) error {
+	changed := map[*varsFile][]string{}
+	for _, f := range files {
+		for k, cur := range f.vars {
+			if varsFileOwning(files, k) != f {
+				continue
+			}
+			if v, ok := vars[k]; ok {
+				delete(vars, k)
+				if !sameVarValue(cur, v) {
+					f.vars[k] = v
+					changed[f] = append(changed[f], k)
+				}
+			} else if managed != nil && managed(k) {
+				delete(f.vars, k)
+				changed[f] = append(changed[f], k)
+			}
+		}
+	}
+	for _, f := range files {
+		keys := changed[f]
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+		if err := f.save(keys); err != nil {
+			return err
+		}
+		common.InfoLog("InventoryManager: Updated vars file %s", f.path)
+	}
+	return nil
+}
+
+// GroupVarsFromFiles returns the merged group_vars/<name> vars
+func (im *InventoryManager) GroupVarsFromFiles(name string) map[string]any {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return mergeVarsFiles(im.groupVars[name])
+}
+
+// HostVarsFromFiles returns the merged host_vars/<name> vars
+func (im *InventoryManager) HostVarsFromFiles(name string) map[string]any {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return mergeVarsFiles(im.hostVars[name])
+}
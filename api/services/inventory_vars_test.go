@@ -0,0 +1,113 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testHostVarsFile = `# managed by hand
+base: &base
+  region: eu   # primary
+copy: *base
+dd_ui_description: old # set by DD-UI
+ntp: pool.ntp.org
+`
+
+func writeVarsFile(t *testing.T, dir, name, content string) *varsFile {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := readVarsFile(p)
+	if f == nil {
+		t.Fatalf("readVarsFile(%s) failed", p)
+	}
+	return f
+}
+
+func TestRouteVarsToFilesKeepsComments(t *testing.T) {
+	f := writeVarsFile(t, t.TempDir(), "web.yml", testHostVarsFile)
+	vars := map[string]any{"dd_ui_description": "new", "ntp": "pool.ntp.org", "ansible_host": "10.0.0.1"}
+	if err := routeVarsToFiles([]*varsFile{f}, vars, isMetadataKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := vars["dd_ui_description"]; ok || vars["ansible_host"] != "10.0.0.1" {
+		t.Fatalf("owned keys should move to the file, others stay: %v", vars)
+	}
+	b, _ := os.ReadFile(f.path)
+	got := string(b)
+	for _, want := range []string{"# managed by hand\n", "base: &base\n", "region: eu # primary", "copy: *base\n", "dd_ui_description: new # set by DD-UI\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("saved file lost %q:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "dd_ui_description") > strings.Index(got, "ntp:") {
+		t.Errorf("key order changed:\n%s", got)
+	}
+
+	// a cleared metadata key is removed, the rest stays
+	if err := routeVarsToFiles([]*varsFile{f}, map[string]any{}, isMetadataKey); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = os.ReadFile(f.path)
+	if strings.Contains(string(b), "dd_ui_description") || !strings.Contains(string(b), "ntp: pool.ntp.org") {
+		t.Errorf("after clearing dd_ui_description:\n%s", b)
+	}
+}
+
+func TestRouteVarsToFilesSkipsUnchanged(t *testing.T) {
+	f := writeVarsFile(t, t.TempDir(), "web.yml", "dd_ui_tags: [a, b]\nport: 8080\n")
+	// a write to this path fails, so any save shows up as an error
+	f.path = filepath.Join(t.TempDir(), "missing", "web.yml")
+	vars := map[string]any{"dd_ui_tags": []string{"a", "b"}, "port": 8080}
+	if err := routeVarsToFiles([]*varsFile{f}, vars, isMetadataKey); err != nil {
+		t.Fatalf("unchanged values were written: %v", err)
+	}
+
+	// unchanged values owned by a SOPS file are not an error either
+	f.sops = true
+	if err := routeVarsToFiles([]*varsFile{f}, map[string]any{"port": 8080}, nil); err != nil {
+		t.Fatalf("unchanged SOPS-owned value: %v", err)
+	}
+	if err := routeVarsToFiles([]*varsFile{f}, map[string]any{"port": 9090}, nil); err == nil {
+		t.Fatal("changing a SOPS-owned value should fail")
+	}
+}
+
+func TestEditJSONVarsKeepsOrder(t *testing.T) {
+	raw := []byte(`{"z": 1, "a": {"x": true}, "m": "keep"}`)
+	out, err := editJSONVars(raw, map[string]any{"z": 2, "a": map[string]any{"x": true}, "m": "keep", "b": "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(out)
+	if !(strings.Index(got, `"z"`) < strings.Index(got, `"a"`) && strings.Index(got, `"a"`) < strings.Index(got, `"m"`) &&
+		strings.Index(got, `"m"`) < strings.Index(got, `"b"`)) || !strings.Contains(got, `"z": 2`) {
+		t.Errorf("editJSONVars = %s", got)
+	}
+}
+
+func TestUpdateHostMetadataRoutesHostWithoutVars(t *testing.T) {
+	dir := t.TempDir()
+	f := writeVarsFile(t, dir, "web.yml", testHostVarsFile)
+	im := &InventoryManager{
+		path:     filepath.Join(dir, "inventory.yml"),
+		format:   "yaml",
+		data:     []byte("all:\n  hosts:\n    web:\n"),
+		hostVars: map[string][]*varsFile{"web": {f}},
+	}
+	if err := im.UpdateHostMetadata("web", HostMetadata{Description: "edge node", Owner: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	inv, _ := os.ReadFile(im.path)
+	if strings.Contains(string(inv), "dd_ui_description") || !strings.Contains(string(inv), "dd_ui_owner: ops") {
+		t.Errorf("inventory:\n%s", inv)
+	}
+	b, _ := os.ReadFile(f.path)
+	if !strings.Contains(string(b), "dd_ui_description: edge node # set by DD-UI") {
+		t.Errorf("host_vars file:\n%s", b)
+	}
+}
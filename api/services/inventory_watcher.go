@@ -4,33 +4,39 @@ import (
 	"context"
 	"time"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"dd-ui/common"
 )
 
 var (
 	lastModTime time.Time
 	watcherRunning bool
+	invReloadMu sync.Mutex // guards lastModTime and serializes reloads
 )
 
-// StartInventoryWatcher monitors the inventory file for changes and reloads automatically
+// StartInventoryWatcher monitors the inventory file (and group_vars/host_vars) for changes
+// and reloads automatically: inotify with debouncing, mtime polling as a fallback
 func StartInventoryWatcher(ctx context.Context) {
 	if watcherRunning {
 		return
 	}
 	watcherRunning = true
-	
+
 	// Get initial mod time from InventoryManager
 	invMgr := GetInventoryManager()
 	if invMgr.path != "" {
 		if stat, err := os.Stat(invMgr.path); err == nil {
 			lastModTime = stat.ModTime()
 		}
+		startInventoryFSWatch(ctx, invMgr.path)
 	}
-	
+
 	go func() {
 		ticker := time.NewTicker(10 * time.Second) // Check every 10 seconds
 		defer ticker.Stop()
-		
+
 		for {
 			select {
 			case <-ctx.Done():
@@ -42,39 +48,95 @@ func StartInventoryWatcher(ctx context.Context) {
 			}
 		}
 	}()
-	
+
 	common.InfoLog("Inventory watcher started (checking every 10s)")
 }
 
+// startInventoryFSWatch watches the inventory's directory (editors and git replace files
+// by rename, so the file itself is not watched) plus group_vars/ and host_vars/
+func startInventoryFSWatch(ctx context.Context, invPath string) {
+	if !fsWatchEnabled() {
+		return
+	}
+	dw, err := newDebouncedWatcher()
+	if err != nil {
+		common.WarnLog("Inventory watcher: inotify unavailable, polling only: %v", err)
+		return
+	}
+	dir := filepath.Dir(invPath)
+	if err := dw.w.Add(dir); err != nil {
+		common.WarnLog("Inventory watcher: cannot watch %s, polling only: %v", dir, err)
+		dw.w.Close()
+		return
+	}
+	for _, sub := range []string{"group_vars", "host_vars"} {
+		if fi, err := os.Stat(filepath.Join(dir, sub)); err == nil && fi.IsDir() {
+			_ = dw.addTree(filepath.Join(dir, sub))
+		}
+	}
+
+	keyFor := func(p string) string {
+		if p == invPath {
+			return "inventory"
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return ""
+		}
+		top := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+		if top == "group_vars" || top == "host_vars" {
+			return "inventory"
+		}
+		return ""
+	}
+	go dw.run(ctx, true, keyFor, func(string) {
+		common.InfoLog("Inventory change detected, reloading...")
+		reloadInventoryNow()
+	})
+	common.InfoLog("Inventory watcher: watching %s (debounce %s)", dir, dw.debounce)
+}
+
 func checkAndReloadInventory() {
 	// Reload both the old inventory system and the new InventoryManager
 	invMgr := GetInventoryManager()
 	if invMgr.path == "" {
 		return
 	}
-	
+
 	stat, err := os.Stat(invMgr.path)
 	if err != nil {
 		common.DebugLog("Inventory watcher: failed to stat %s: %v", invMgr.path, err)
 		return
 	}
-	
-	modTime := stat.ModTime()
-	if modTime.After(lastModTime) {
+
+	invReloadMu.Lock()
+	changed := stat.ModTime().After(lastModTime)
+	invReloadMu.Unlock()
+	if changed {
 		common.InfoLog("Inventory file changed, reloading...")
-		
-		// Reload the new InventoryManager
-		if err := invMgr.Reload(); err != nil {
-			common.ErrorLog("Failed to reload InventoryManager: %v", err)
-		} else {
-			common.InfoLog("InventoryManager reloaded successfully")
-		}
-		
-		// Also reload the old inventory system for backward compatibility
-		if err := ReloadInventory(); err != nil {
-			common.ErrorLog("Failed to reload old inventory: %v", err)
-		}
-		
-		lastModTime = modTime
+		reloadInventoryNow()
 	}
-}
\ No newline at end of file
+}
+
+// reloadInventoryNow reloads the InventoryManager and the DB host list
+func reloadInventoryNow() {
+	invReloadMu.Lock()
+	defer invReloadMu.Unlock()
+
+	invMgr := GetInventoryManager()
+	if stat, err := os.Stat(invMgr.path); err == nil {
+		lastModTime = stat.ModTime()
+	}
+
+	// Reload the new InventoryManager
+	if err := invMgr.Reload(); err != nil {
+		common.ErrorLog("Failed to reload InventoryManager: %v", err)
+	} else {
+		common.InfoLog("InventoryManager reloaded successfully")
+	}
+
+	// Also reload the old inventory system for backward compatibility
+	if err := ReloadInventory(); err != nil {
+		common.ErrorLog("Failed to reload old inventory: %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+// services/known_hosts.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+/*
+Managed SSH known_hosts. Host keys live in ssh_known_hosts and are pinned on first
+use (SSH_HOST_KEY_TOFU=false requires approval of every new host instead). A key
+that differs from the pinned one is recorded as "mismatch", logged as a security
+alert and the connection is refused until an operator approves it via
+/api/ssh/known-hosts. OpenSSH callers (docker CLI, git, remote console) get a
+generated known_hosts file with StrictHostKeyChecking=yes.
+*/
+
+var (
+	ErrHostKeyMismatch  = errors.New("ssh host key mismatch")
+	ErrHostKeyUntrusted = errors.New("ssh host key not approved")
+)
+
+type dbHostKeyStore struct {
+	mu sync.Mutex // serializes first-use pins and file writes
+}
+
+var knownHostsStore = &dbHostKeyStore{}
+
+// InitKnownHosts installs the DB-backed host key store for all SSH paths
+func InitKnownHosts() {
+	utils.SetHostKeyStore(knownHostsStore)
+	if utils.HostKeyCheckingDisabled() {
+		common.WarnLog("SSH host key checking disabled (SSH_STRICT_HOST_KEY=false)")
+	}
+}
+
+// KnownHostsPath is where the OpenSSH known_hosts file is generated
+func KnownHostsPath() string {
+	return common.Env("SSH_KNOWN_HOSTS_FILE", filepath.Join(os.TempDir(), "dd-ui", "known_hosts"))
+}
+
+func (s *dbHostKeyStore) CheckHostKey(hostport string, remote net.Addr, key ssh.PublicKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fp := ssh.FingerprintSHA256(key)
+	offered := database.KnownHostRow{
+		Host:        hostport,
+		KeyType:     key.Type(),
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+		Fingerprint: fp,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := database.ListKnownHostKeys(ctx, hostport, "")
+	if err != nil {
+		return fmt.Errorf("known_hosts lookup for %s: %v", hostport, err)
+	}
+
+	pinnedSameType := false
+	for _, k := range keys {
+		if k.Fingerprint == fp {
+			_ = database.TouchKnownHostKey(ctx, k.ID)
+			if k.Status == "trusted" {
+				return nil
+			}
+			return fmt.Errorf("%w: %s %s (%s) awaiting approval", ErrHostKeyUntrusted, hostport, fp, k.Status)
+		}
+		if k.Status == "trusted" && k.KeyType == offered.KeyType {
+			pinnedSameType = true
+		}
+	}
+
+	switch {
+	case len(keys) == 0 && common.EnvBool("SSH_HOST_KEY_TOFU", "true"):
+		offered.Status, offered.ApprovedBy = "trusted", "tofu"
+		if _, err := database.InsertKnownHostKey(ctx, offered); err != nil {
+			return fmt.Errorf("known_hosts pin for %s: %v", hostport, err)
+		}
+		common.InfoLog("SSH: pinned host key for %s on first use: %s %s", hostport, offered.KeyType, fp)
+		if err := s.writeKnownHostsFile(ctx); err != nil {
+			common.WarnLog("SSH: failed to update known_hosts file: %v", err)
+		}
+		return nil
+	case pinnedSameType:
+		offered.Status = "mismatch"
+		_, _ = database.InsertKnownHostKey(ctx, offered)
+		common.ErrorLog("SECURITY: SSH host key mismatch for %s (offered %s %s); connection blocked until approved", hostport, offered.KeyType, fp)
+		return fmt.Errorf("%w: %s offered %s", ErrHostKeyMismatch, hostport, fp)
+	default:
+		offered.Status = "pending"
+		_, _ = database.InsertKnownHostKey(ctx, offered)
+		common.WarnLog("SSH: new host key for %s awaiting approval: %s %s", hostport, offered.KeyType, fp)
+		return fmt.Errorf("%w: %s %s awaiting approval", ErrHostKeyUntrusted, hostport, fp)
+	}
+}
+
+func (s *dbHostKeyStore) KnownHostsFile() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writeKnownHostsFile(ctx); err != nil {
+		return "", err
+	}
+	return KnownHostsPath(), nil
+}
+
+// writeKnownHostsFile regenerates the OpenSSH file from trusted keys (caller holds mu)
+func (s *dbHostKeyStore) writeKnownHostsFile(ctx context.Context) error {
+	keys, err := database.ListKnownHostKeys(ctx, "", "trusted")
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, k := range keys {
+		pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(k.PublicKey))
+		if err != nil {
+			common.WarnLog("SSH: skipping unparsable known host key %d for %s: %v", k.ID, k.Host, err)
+			continue
+		}
+		b.WriteString(knownhosts.Line([]string{knownhosts.Normalize(k.Host)}, pk))
+		b.WriteByte('\n')
+	}
+
+	path := KnownHostsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ApproveHostKey trusts a pending/mismatched key (replacing the old key of that type)
+func ApproveHostKey(ctx context.Context, id int64, by string) (database.KnownHostRow, error) {
+	k, err := database.ApproveKnownHostKey(ctx, id, by)
+	if err != nil {
+		return k, err
+	}
+	if _, err := knownHostsStore.KnownHostsFile(); err != nil {
+		common.WarnLog("SSH: failed to update known_hosts file: %v", err)
+	}
+	return k, nil
+}
+
+// ForgetHostKey deletes a key; the next connection pins (or proposes) again
+func ForgetHostKey(ctx context.Context, id int64) (bool, error) {
+	ok, err := database.DeleteKnownHostKey(ctx, id)
+	if err != nil || !ok {
+		return ok, err
+	}
+	if _, err := knownHostsStore.KnownHostsFile(); err != nil {
+		common.WarnLog("SSH: failed to update known_hosts file: %v", err)
+	}
+	return true, nil
+}
+
+// GitSSHHostKeyArgs verifies the SSH host of a git remote (scp-like or ssh:// URL);
+// non-SSH remotes need no options
+func GitSSHHostKeyArgs(ctx context.Context, repoURL string) ([]string, error) {
+	hostport := gitSSHHostPort(repoURL)
+	if hostport == "" {
+		return nil, nil
+	}
+	return utils.OpenSSHHostKeyArgs(ctx, hostport)
+}
+
+// gitSSHHostPort extracts host:port from git@host:org/repo.git or ssh://git@host:2222/repo
+func gitSSHHostPort(repoURL string) string {
+	u := strings.TrimSpace(repoURL)
+	if strings.HasPrefix(u, "ssh://") {
+		rest := strings.TrimPrefix(u, "ssh://")
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i]
+		}
+		if i := strings.LastIndex(rest, "@"); i >= 0 {
+			rest = rest[i+1:]
+		}
+		if _, _, err := net.SplitHostPort(rest); err == nil {
+			return rest
+		}
+		return net.JoinHostPort(rest, "22")
+	}
+	if strings.Contains(u, "://") {
+		return "" // https, file, ...
+	}
+	// scp-like: [user@]host:path
+	i := strings.Index(u, ":")
+	if i <= 0 {
+		return ""
+	}
+	host := u[:i]
+	if j := strings.LastIndex(host, "@"); j >= 0 {
+		host = host[j+1:]
+	}
+	return net.JoinHostPort(host, "22")
+}
+
+// knownHostsSSHOpts is the ssh command suffix (DOCKER_SSH_CMD) enforcing the managed file
+func knownHostsSSHOpts() string {
+	if utils.HostKeyCheckingDisabled() {
+		return " -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
+	}
+	return " -o StrictHostKeyChecking=yes -o UserKnownHostsFile=" + KnownHostsPath() + " -o CheckHostIP=no"
+}
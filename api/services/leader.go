@@ -0,0 +1,177 @@
+// services/leader.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"github.com/google/uuid"
+)
+
+// leaderLeaseName is the lease guarding scanners, Auto DevOps, git sync and retention jobs
+const leaderLeaseName = "background"
+
+// LeaderStatus is what /healthz reports about leader election
+type LeaderStatus struct {
+	Enabled   bool       `json:"enabled"`
+	ReplicaID string     `json:"replica_id"`
+	IsLeader  bool       `json:"is_leader"`
+	Leader    string     `json:"leader,omitempty"`
+	Since     *time.Time `json:"since,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+var (
+	leaderMu     sync.RWMutex
+	leaderStatus = LeaderStatus{IsLeader: true}
+	replicaOnce  sync.Once
+	replicaID    string
+)
+
+// ReplicaID identifies this process in the lease table: DD_UI_REPLICA_ID, or hostname plus a
+// random suffix so a restarted container never mistakes the previous process's lease for its own.
+func ReplicaID() string {
+	replicaOnce.Do(func() {
+		replicaID = common.Env("DD_UI_REPLICA_ID", "")
+		if replicaID == "" {
+			host, _ := os.Hostname()
+			replicaID = fmt.Sprintf("%s-%s", host, uuid.NewString()[:8])
+		}
+	})
+	return replicaID
+}
+
+// IsLeader reports whether this replica currently runs the background loops
+// (always true when leader election is disabled)
+func IsLeader() bool {
+	leaderMu.RLock()
+	defer leaderMu.RUnlock()
+	return leaderStatus.IsLeader
+}
+
+// GetLeaderStatus returns a snapshot for health output
+func GetLeaderStatus() LeaderStatus {
+	leaderMu.RLock()
+	defer leaderMu.RUnlock()
+	return leaderStatus
+}
+
+// StartLeaderElection runs onElected with a context that lives as long as this replica holds
+// the lease, and onDemoted after that context is cancelled. The lease (DD_UI_LEADER_LEASE,
+// default 30s) is renewed every DD_UI_LEADER_RENEW (default 10s); a leader that cannot renew
+// steps down before its lease expires, so another replica never overlaps with it. With
+// DD_UI_LEADER_ELECTION=false, onElected runs once with ctx.
+func StartLeaderElection(ctx context.Context, onElected func(context.Context), onDemoted func()) {
+	id := ReplicaID()
+	if !common.EnvBool("DD_UI_LEADER_ELECTION", "true") {
+		leaderMu.Lock()
+		leaderStatus = LeaderStatus{Enabled: false, ReplicaID: id, IsLeader: true, Leader: id}
+		leaderMu.Unlock()
+		common.InfoLog("leader: election disabled, %s runs background loops", id)
+		onElected(ctx)
+		return
+	}
+
+	ttl := envDuration("DD_UI_LEADER_LEASE", 30*time.Second)
+	renew := envDuration("DD_UI_LEADER_RENEW", 10*time.Second)
+	if renew <= 0 || renew >= ttl {
+		renew = ttl / 3
+		common.WarnLog("leader: DD_UI_LEADER_RENEW must be below the lease, using %s", renew)
+	}
+	leaderMu.Lock()
+	leaderStatus = LeaderStatus{Enabled: true, ReplicaID: id}
+	leaderMu.Unlock()
+	common.InfoLog("leader: replica %s joining election (lease=%s renew=%s)", id, ttl, renew)
+
+	go func() {
+		var (
+			leading   bool
+			cancel    context.CancelFunc
+			lastRenew time.Time
+		)
+		stepDown := func(reason string) {
+			if !leading {
+				return
+			}
+			common.WarnLog("leader: %s stepping down: %s", id, reason)
+			cancel()
+			leading = false
+			leaderMu.Lock()
+			leaderStatus.IsLeader = false
+			leaderStatus.Since = nil
+			leaderMu.Unlock()
+			if onDemoted != nil {
+				onDemoted()
+			}
+		}
+
+		ticker := time.NewTicker(renew)
+		defer ticker.Stop()
+		for {
+			actx, acancel := context.WithTimeout(ctx, renew)
+			lease, ok, err := database.AcquireLease(actx, leaderLeaseName, id, ttl)
+			acancel()
+
+			switch {
+			case ctx.Err() != nil:
+			case err != nil:
+				common.DebugLog("leader: lease query failed: %v", err)
+				leaderMu.Lock()
+				leaderStatus.LastError = err.Error()
+				leaderMu.Unlock()
+				// keep leading only while our last renewal is certainly still valid
+				if leading && time.Since(lastRenew) >= ttl-renew {
+					stepDown("cannot renew lease: " + err.Error())
+				}
+			case ok:
+				lastRenew = time.Now()
+				if !leading {
+					leading = true
+					common.InfoLog("leader: %s elected, starting background loops", id)
+					leaderMu.Lock()
+					leaderStatus.IsLeader = true
+					leaderMu.Unlock()
+					cancel = startLeading(ctx, onElected)
+				}
+				leaderMu.Lock()
+				leaderStatus.Leader = lease.Holder
+				leaderStatus.Since = &lease.AcquiredAt
+				leaderStatus.ExpiresAt = &lease.ExpiresAt
+				leaderStatus.LastError = ""
+				leaderMu.Unlock()
+			default:
+				stepDown("lease taken by " + lease.Holder)
+				leaderMu.Lock()
+				leaderStatus.Leader = lease.Holder
+				leaderStatus.ExpiresAt = &lease.ExpiresAt
+				leaderStatus.LastError = ""
+				leaderMu.Unlock()
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				if leading {
+					stepDown("shutting down")
+					rctx, rcancel := context.WithTimeout(context.Background(), 5*time.Second)
+					_ = database.ReleaseLease(rctx, leaderLeaseName, id)
+					rcancel()
+				}
+				return
+			}
+		}
+	}()
+}
+
+// startLeading runs onElected with a context cancelled when leadership is lost
+func startLeading(ctx context.Context, onElected func(context.Context)) context.CancelFunc {
+	lctx, cancel := context.WithCancel(ctx)
+	onElected(lctx)
+	return cancel
+}
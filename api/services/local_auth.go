@@ -0,0 +1,315 @@
+// services/local_auth.go
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/argon2"
+)
+
+// Local account modes (DD_UI_LOCAL_AUTH)
+const (
+	LocalAuthOff        = "off"
+	LocalAuthOn         = "on"         // local login always offered alongside OIDC
+	LocalAuthBreakGlass = "breakglass" // local login only while OIDC is unconfigured or unreachable
+)
+
+// LocalAuthMode returns DD_UI_LOCAL_AUTH (off, on, breakglass; default off)
+func LocalAuthMode() string {
+	switch m := strings.ToLower(strings.TrimSpace(common.Env("DD_UI_LOCAL_AUTH", LocalAuthOff))); m {
+	case LocalAuthOn, "true", "1", "yes":
+		return LocalAuthOn
+	case LocalAuthBreakGlass, "break-glass":
+		return LocalAuthBreakGlass
+	case LocalAuthOff, "false", "0", "no", "":
+		return LocalAuthOff
+	default:
+		common.WarnLog("auth: unknown DD_UI_LOCAL_AUTH %q, local accounts disabled", m)
+		return LocalAuthOff
+	}
+}
+
+// LocalTOTPRequired: DD_UI_LOCAL_REQUIRE_TOTP (default true) makes a confirmed second factor
+// mandatory; accounts without one must enroll on their first password login
+func LocalTOTPRequired() bool {
+	return common.EnvBool("DD_UI_LOCAL_REQUIRE_TOTP", "true")
+}
+
+// Lockout after repeated failures
+const (
+	localMaxAttempts = 5
+	localLockFor     = 15 * time.Minute
+)
+
+var (
+	ErrLocalBadCredentials = errors.New("invalid username or password")
+	ErrLocalLocked         = errors.New("account temporarily locked")
+	ErrLocalTOTPRequired   = errors.New("totp code required")
+	ErrLocalBadTOTP        = errors.New("invalid totp code")
+)
+
+// LocalLoginResult is the outcome of a password check. When Enroll is set the password was
+// correct but the account has no second factor yet: the caller shows Secret/URI and the user
+// logs in again with a code from their authenticator.
+type LocalLoginResult struct {
+	User   database.LocalUserRow
+	Enroll bool
+	Secret string
+	URI    string
+}
+
+// AuthenticateLocal verifies username, password and (when enrolled or required) a TOTP code.
+// Failures are counted per account; after localMaxAttempts the account locks for localLockFor.
+func AuthenticateLocal(ctx context.Context, username, password, code string) (LocalLoginResult, error) {
+	var res LocalLoginResult
+	u, err := database.GetLocalUser(ctx, strings.TrimSpace(username))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// spend the same time as a real check so usernames cannot be probed by timing
+		_, _ = VerifyPassword(password, dummyPasswordHash())
+		return res, ErrLocalBadCredentials
+	}
+	if err != nil {
+		return res, err
+	}
+	if u.Disabled {
+		return res, ErrLocalBadCredentials
+	}
+	if u.LockedUntil != nil && time.Now().Before(*u.LockedUntil) {
+		return res, ErrLocalLocked
+	}
+
+	fail := func(e error) (LocalLoginResult, error) {
+		if err := database.RecordLocalLoginFailure(ctx, u.ID, localMaxAttempts, localLockFor); err != nil {
+			common.DebugLog("auth: record local login failure for %s: %v", u.Username, err)
+		}
+		common.WarnLog("auth: local login failed user=%s: %v", u.Username, e)
+		return res, e
+	}
+
+	ok, err := VerifyPassword(password, u.PasswordHash)
+	if err != nil {
+		common.ErrorLog("auth: local user %s has an unreadable password hash: %v", u.Username, err)
+	}
+	if !ok {
+		return fail(ErrLocalBadCredentials)
+	}
+
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	var step int64
+	switch {
+	case u.TOTPEnabled:
+		if code == "" {
+			return res, ErrLocalTOTPRequired
+		}
+		if step = VerifyTOTP(u.TOTPSecret, code, time.Now()); step == 0 || step <= u.TOTPLastStep {
+			return fail(ErrLocalBadTOTP)
+		}
+	case u.TOTPSecret != "" && code != "":
+		// confirming a pending enrollment
+		if step = VerifyTOTP(u.TOTPSecret, code, time.Now()); step == 0 {
+			return fail(ErrLocalBadTOTP)
+		}
+		if err := database.SetLocalUserTOTP(ctx, u.ID, u.TOTPSecret, true); err != nil {
+			return res, err
+		}
+		u.TOTPEnabled = true
+		common.InfoLog("auth: local user %s enrolled TOTP", u.Username)
+	case LocalTOTPRequired():
+		secret := u.TOTPSecret
+		if secret == "" {
+			if secret, err = NewTOTPSecret(); err != nil {
+				return res, err
+			}
+			if err := database.SetLocalUserTOTP(ctx, u.ID, secret, false); err != nil {
+				return res, err
+			}
+		}
+		res.User, res.Enroll, res.Secret, res.URI = u, true, secret, TOTPURI(u.Username, secret)
+		return res, nil
+	}
+
+	if err := database.RecordLocalLoginSuccess(ctx, u.ID, step); err != nil {
+		common.DebugLog("auth: record local login for %s: %v", u.Username, err)
+	}
+	res.User = u
+	return res, nil
+}
+
+// BootstrapLocalAdmin creates the DD_UI_LOCAL_ADMIN_USER account (default "admin") with
+// password (DD_UI_LOCAL_ADMIN_PASSWORD{/_FILE}) when local accounts are enabled and none exist yet.
+// DD_UI_LOCAL_ADMIN_RESET=true resets that account's password and TOTP on every start,
+// which is how a lost password or authenticator is recovered.
+func BootstrapLocalAdmin(ctx context.Context, password string) error {
+	if LocalAuthMode() == LocalAuthOff {
+		return nil
+	}
+	username := strings.TrimSpace(common.Env("DD_UI_LOCAL_ADMIN_USER", "admin"))
+	reset := common.EnvBool("DD_UI_LOCAL_ADMIN_RESET", "false")
+
+	n, err := database.CountLocalUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 && !reset {
+		return nil
+	}
+	if password == "" {
+		if n == 0 {
+			common.WarnLog("auth: local accounts enabled but no users exist; set DD_UI_LOCAL_ADMIN_PASSWORD to create %q", username)
+		}
+		return nil
+	}
+	if len(password) < 12 {
+		return errors.New("DD_UI_LOCAL_ADMIN_PASSWORD must be at least 12 characters")
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := database.UpsertLocalUser(ctx, username, common.Env("DD_UI_LOCAL_ADMIN_EMAIL", ""), "Local administrator", hash, "", false); err != nil {
+		return err
+	}
+	if n == 0 {
+		common.InfoLog("auth: created local admin %q", username)
+	} else {
+		common.WarnLog("auth: DD_UI_LOCAL_ADMIN_RESET: reset password and TOTP of local user %q", username)
+	}
+	return nil
+}
+
+// ---- argon2id ----
+
+// argon2id parameters (RFC 9106 second recommended option)
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// dummyPasswordHash is verified against when the user does not exist
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() { dummyHash, _ = HashPassword("dd-ui-no-such-user") })
+	return dummyHash
+}
+
+// HashPassword returns a PHC-formatted argon2id hash:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword checks password against a hash from HashPassword, honouring the
+// parameters stored in the hash so they can be raised later
+func VerifyPassword(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, errors.New("not an argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	var m, t uint32
+	var p uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &m, &t, &p); err != nil {
+		return false, fmt.Errorf("bad argon2 parameters %q", parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, err
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, err
+	}
+	got := argon2.IDKey([]byte(password), salt, t, m, p, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
+
+// ---- TOTP (RFC 6238: HMAC-SHA1, 30s steps, 6 digits) ----
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1 // accept one step either side for clock drift
+)
+
+// NewTOTPSecret returns a random 160-bit base32 secret
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// TOTPURI is the otpauth:// URI authenticator apps import (usually shown as a QR code)
+func TOTPURI(username, secret string) string {
+	issuer := common.Env("DD_UI_TOTP_ISSUER", "DD-UI")
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(totpDigits))
+	q.Set("period", strconv.Itoa(totpPeriod))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+username) + "?" + q.Encode()
+}
+
+// VerifyTOTP returns the matched time step, or 0 when code is invalid at now
+func VerifyTOTP(secret, code string, now time.Time) int64 {
+	if len(code) != totpDigits {
+		return 0
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return 0
+	}
+	cur := now.Unix() / totpPeriod
+	for s := cur - totpSkew; s <= cur+totpSkew; s++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, s)), []byte(code)) == 1 {
+			return s
+		}
+	}
+	return 0
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%1000000)
+}
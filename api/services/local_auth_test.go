@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B (SHA1), truncated to six digits
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+func TestVerifyTOTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		unix   int64
+		step   int64
+	}{
+		{"rfc 59", rfc6238Secret, "287082", 59, 1},
+		{"rfc 1111111109", rfc6238Secret, "081804", 1111111109, 37037036},
+		{"rfc 1111111111", rfc6238Secret, "050471", 1111111111, 37037037},
+		{"rfc 1234567890", rfc6238Secret, "005924", 1234567890, 41152263},
+		{"rfc 2000000000", rfc6238Secret, "279037", 2000000000, 66666666},
+		{"lowercase padded secret", strings.ToLower(rfc6238Secret) + "==", "279037", 2000000000, 66666666},
+		{"previous step accepted", rfc6238Secret, "279037", 2000000000 + totpPeriod, 66666666},
+		{"next step accepted", rfc6238Secret, "279037", 2000000000 - totpPeriod, 66666666},
+		{"two steps late", rfc6238Secret, "279037", 2000000000 + 2*totpPeriod, 0},
+		{"wrong code", rfc6238Secret, "279038", 2000000000, 0},
+		{"short code", rfc6238Secret, "27903", 2000000000, 0},
+		{"bad secret", "not base32!", "279037", 2000000000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyTOTP(tt.secret, tt.code, time.Unix(tt.unix, 0)); got != tt.step {
+				t.Fatalf("VerifyTOTP = %d, want %d", got, tt.step)
+			}
+		})
+	}
+}
+
+func TestNewTOTPSecretRoundTrip(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Fatalf("secret %q: want 32 base32 chars", secret)
+	}
+	now := time.Unix(1700000000, 0)
+	key := mustDecodeTOTPSecret(t, secret)
+	if got := VerifyTOTP(secret, totpCode(key, now.Unix()/totpPeriod), now); got != now.Unix()/totpPeriod {
+		t.Fatalf("own code rejected: step %d", got)
+	}
+}
+
+func mustDecodeTOTPSecret(t *testing.T, secret string) []byte {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Fatalf("unexpected hash format %q", hash)
+	}
+	parts := strings.Split(hash, "$")
+	// same password hashed with cheaper parameters must still verify: they come from the hash
+	cheap := "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$" +
+		"TJqEe8os/EHZfL3Vapc59ET5wfcEzIeGVsEYtgkuoKw"
+
+	tests := []struct {
+		name     string
+		password string
+		encoded  string
+		ok       bool
+		wantErr  bool
+	}{
+		{"match", "correct horse", hash, true, false},
+		{"mismatch", "correct horse ", hash, false, false},
+		{"empty password", "", hash, false, false},
+		{"stored parameters honoured", "password", cheap, true, false},
+		{"stored parameters mismatch", "Password", cheap, false, false},
+		{"not argon2id", "x", "$2a$10$abcdefghijklmnopqrstuv", false, true},
+		{"wrong version", "x", strings.Join([]string{"", "argon2id", "v=16", parts[3], parts[4], parts[5]}, "$"), false, true},
+		{"bad parameters", "x", strings.Join([]string{"", "argon2id", parts[2], "m=x", parts[4], parts[5]}, "$"), false, true},
+		{"bad salt", "x", strings.Join([]string{"", "argon2id", parts[2], parts[3], "!!", parts[5]}, "$"), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyPassword(tt.password, tt.encoded)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
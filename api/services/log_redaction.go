@@ -0,0 +1,95 @@
+// services/log_redaction.go
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+// logRedactorTTL bounds how long a stack's redactor (decrypted env values, rules) is reused
+const logRedactorTTL = 2 * time.Minute
+
+type cachedLogRedactor struct {
+	r     *utils.LogRedactor
+	built time.Time
+}
+
+var (
+	logRedactorsMu sync.Mutex
+	logRedactors   = map[string]cachedLogRedactor{} // host/project -> redactor
+)
+
+// LogRedactorFor returns the redactor for container logs of a compose project on a host:
+// built-in patterns (DD_UI_LOG_REDACT_BUILTIN, default true), the stack's redaction rules,
+// and every value of at least DD_UI_LOG_REDACT_MIN_LENGTH (default 6) characters from the
+// stack's SOPS env files (plain env files contribute only secret-looking keys).
+// Returns nil when DD_UI_LOG_REDACTION=false.
+func LogRedactorFor(ctx context.Context, hostName, project string) *utils.LogRedactor {
+	if !common.EnvBool("DD_UI_LOG_REDACTION", "true") {
+		return nil
+	}
+	key := hostName + "/" + project
+	logRedactorsMu.Lock()
+	c, ok := logRedactors[key]
+	logRedactorsMu.Unlock()
+	if ok && time.Since(c.built) < logRedactorTTL {
+		return c.r
+	}
+
+	var patterns, values []string
+	if common.EnvBool("DD_UI_LOG_REDACT_BUILTIN", "true") {
+		patterns = append(patterns, utils.BuiltinLogRedactionPatterns...)
+	}
+	if s, ok := iacStackForProject(ctx, hostName, project); ok {
+		if rules, err := database.ListLogRedactionRules(ctx, s.ID); err == nil {
+			for _, rule := range rules {
+				patterns = append(patterns, rule.Pattern)
+			}
+		}
+		values = stackSecretEnvValues(ctx, s.ID)
+	}
+	r, errs := utils.NewLogRedactor(patterns, values, max(1, common.EnvInt("DD_UI_LOG_REDACT_MIN_LENGTH", 6)))
+	for _, err := range errs {
+		common.WarnLog("log redaction: %s: skipping invalid pattern: %v", key, err)
+	}
+
+	logRedactorsMu.Lock()
+	logRedactors[key] = cachedLogRedactor{r: r, built: time.Now()}
+	logRedactorsMu.Unlock()
+	return r
+}
+
+// InvalidateLogRedactors drops cached redactors so rule changes apply to new streams at once
+func InvalidateLogRedactors() {
+	logRedactorsMu.Lock()
+	logRedactors = map[string]cachedLogRedactor{}
+	logRedactorsMu.Unlock()
+}
+
+// stackSecretEnvValues returns the values to mask from a stack's env files: all values of
+// SOPS-encrypted files (decrypted here) and secret-looking keys of plain ones
+func stackSecretEnvValues(ctx context.Context, stackID int64) []string {
+	var values []string
+	for _, full := range stackEnvFiles(ctx, stackID, false) {
+		data, decrypted, err := readDecryptedOrPlain(ctx, full, "dotenv")
+		if err != nil {
+			common.DebugLog("log redaction: read %s failed: %v", full, err)
+			continue
+		}
+		for k, v := range utils.ParseDotenv(string(data)) {
+			if v == "" || strings.HasPrefix(v, "ENC[") {
+				continue
+			}
+			if decrypted || utils.IsSecretKey(k) {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,197 @@
+// services/podman.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+/*
+Podman hosts talk to Podman's Docker-compatible API. Inventory vars:
+
+  dd_ui_runtime: podman          # docker (default) | podman
+  podman_rootless: true          # use the user socket /run/user/<uid>/podman/podman.sock
+  podman_uid: 1000               # optional; resolved over SSH (id -u) when omitted
+  podman_socket: /path/to.sock   # optional explicit socket (wins over the above)
+  podman_compose: podman-compose # optional; "podman compose" (default when podman is installed) or "podman-compose"
+
+Sockets are reached locally (unix://), over SSH (tunnelled to the socket path) or
+via tcp:// (podman system service tcp:...), mirroring DOCKER_CONNECTION_METHOD.
+*/
+
+const (
+	podmanRootfulSocket = "/run/podman/podman.sock"
+)
+
+var podmanUIDCache sync.Map // "user@addr" -> uid string
+
+// HostRuntime returns the container runtime configured for a host ("docker" or "podman")
+func HostRuntime(h database.HostRow) string {
+	if strings.EqualFold(strings.TrimSpace(h.Vars["dd_ui_runtime"]), "podman") {
+		return "podman"
+	}
+	return "docker"
+}
+
+// IsPodmanHost reports whether a host runs Podman instead of Docker
+func IsPodmanHost(h database.HostRow) bool { return HostRuntime(h) == "podman" }
+
+func podmanRootless(h database.HostRow) bool {
+	v := strings.ToLower(strings.TrimSpace(h.Vars["podman_rootless"]))
+	return v == "true" || v == "yes" || v == "1"
+}
+
+// podmanSocketPath resolves the API socket path for a host; remote=false means the
+// socket lives on this machine.
+func podmanSocketPath(h database.HostRow, remote bool, t utils.SSHTarget) string {
+	if s := strings.TrimSpace(h.Vars["podman_socket"]); s != "" {
+		return s
+	}
+	if !podmanRootless(h) {
+		return podmanRootfulSocket
+	}
+	uid := strings.TrimSpace(h.Vars["podman_uid"])
+	if uid == "" && !remote {
+		if xdg := os.Getenv("XDG_RUNTIME_DIR"); xdg != "" {
+			return xdg + "/podman/podman.sock"
+		}
+		uid = strconv.Itoa(os.Getuid())
+	}
+	if uid == "" {
+		uid = resolveRemoteUID(t)
+	}
+	if uid == "" {
+		common.WarnLog("podman: could not resolve uid for rootless socket on %s; set podman_uid", h.Name)
+		return podmanRootfulSocket
+	}
+	return "/run/user/" + uid + "/podman/podman.sock"
+}
+
+// resolveRemoteUID runs `id -u` over SSH once per target
+func resolveRemoteUID(t utils.SSHTarget) string {
+	key := t.String()
+	if v, ok := podmanUIDCache.Load(key); ok {
+		return v.(string)
+	}
+	if t.KeyFile == "" {
+		return ""
+	}
+	sc, err := utils.SSHPool.GetSSHConnectionForTarget(t)
+	if err != nil {
+		common.DebugLog("podman: uid lookup ssh %s failed: %v", key, err)
+		return ""
+	}
+	sess, err := sc.NewSession()
+	if err != nil {
+		return ""
+	}
+	defer sess.Close()
+	out, err := sess.Output("id -u")
+	if err != nil {
+		return ""
+	}
+	uid := strings.TrimSpace(string(out))
+	if _, err := strconv.Atoi(uid); err != nil {
+		return ""
+	}
+	podmanUIDCache.Store(key, uid)
+	return uid
+}
+
+// podmanURLFor mirrors DockerURLFor for Podman hosts
+func podmanURLFor(h database.HostRow) (string, string) {
+	t, err := SSHTargetForHost(context.Background(), h)
+	if err != nil {
+		common.WarnLog("SSH settings for host %s: %v", h.Name, err)
+	}
+	addr := t.Addr
+
+	local := LocalHostAllowed(h)
+	if lh := strings.TrimSpace(common.Env("DD_UI_LOCAL_HOST", "")); lh != "" && strings.EqualFold(lh, h.Name) {
+		local = true
+	}
+	kind := common.Env("DOCKER_CONNECTION_METHOD", "ssh")
+	if local || kind == "local" {
+		return "unix://" + podmanSocketPath(h, false, t), ""
+	}
+	if kind == "tcp" {
+		port := h.Vars["podman_tcp_port"]
+		if port == "" {
+			port = common.Env("DOCKER_TCP_PORT", "2375")
+		}
+		return fmt.Sprintf("tcp://%s:%s", addr, port), ""
+	}
+
+	return sshURLForTarget(t, podmanSocketPath(h, true, t)), sshCommandForTarget(t)
+}
+
+// podmanComposeInvocation rewrites a `docker compose ...` invocation for a Podman host.
+// Returns the binary, its args and extra environment (CONTAINER_HOST etc.).
+func podmanComposeInvocation(h database.HostRow, url string, dockerArgs []string) (string, []string, []string) {
+	rest := dockerArgs
+	if len(rest) > 0 && rest[0] == "compose" {
+		rest = rest[1:]
+	}
+
+	env := []string{"CONTAINER_HOST=" + url}
+	if strings.HasPrefix(url, "unix://") {
+		// `podman compose` with the docker-compose provider talks to DOCKER_HOST
+		env = append(env, "DOCKER_HOST="+url)
+	}
+	if strings.HasPrefix(url, "ssh://") {
+		if t, _, err := sshTargetForURL(url); err == nil && t.KeyFile != "" {
+			env = append(env, "CONTAINER_SSHKEY="+t.KeyFile)
+		}
+	}
+
+	// podman_compose picks the provider; by default podman, then podman-compose, then the
+	// docker compose plugin against the Podman API (needs a unix:// or tcp:// URL)
+	provider := strings.TrimSpace(h.Vars["podman_compose"])
+	if provider == "" {
+		if _, err := exec.LookPath("podman"); err == nil {
+			provider = "podman compose"
+		} else if _, err := exec.LookPath("podman-compose"); err == nil {
+			provider = "podman-compose"
+		} else {
+			provider = "docker compose"
+		}
+	}
+	switch provider {
+	case "podman-compose":
+		return "podman-compose", rest, env
+	case "docker compose":
+		return "docker", append([]string{"compose"}, rest...), []string{"DOCKER_HOST=" + url}
+	}
+	return "podman", append([]string{"compose"}, rest...), env
+}
+
+// isPodmanInfraContainer detects pod infra (pause) containers in a container list
+func isPodmanInfraContainer(image string, names []string) bool {
+	img := strings.ToLower(image)
+	if strings.Contains(img, "podman-pause") || strings.HasSuffix(strings.SplitN(img, ":", 2)[0], "/pause") {
+		return true
+	}
+	for _, n := range names {
+		if strings.HasSuffix(n, "-infra") {
+			return true
+		}
+	}
+	return false
+}
+
+// RuntimeCLI returns the CLI binary used for ad-hoc commands on a host (prune etc.)
+func RuntimeCLI(h database.HostRow) string {
+	if IsPodmanHost(h) {
+		return "podman"
+	}
+	return "docker"
+}
@@ -52,6 +52,16 @@ func DockerURLFor(h database.HostRow) (string, string) {
 	if v := h.Vars["docker_host"]; v != "" {
 		return v, h.Vars["docker_ssh_cmd"]
 	}
+
+	// Hosts enrolled with dd-ui-agent are reached over the agent's websocket
+	if IsAgentHost(h) {
+		return agentScheme + h.Name, ""
+	}
+
+	// Podman hosts (dd_ui_runtime: podman) talk to the Podman API socket instead
+	if IsPodmanHost(h) {
+		return podmanURLFor(h)
+	}
 	
 	// Check if this host matches DD_UI_LOCAL_HOST - if so, use local socket for performance
 	if lh := strings.TrimSpace(common.Env("DD_UI_LOCAL_HOST", "")); lh != "" && strings.EqualFold(lh, h.Name) {
@@ -72,29 +82,14 @@ func DockerURLFor(h database.HostRow) (string, string) {
 		port := common.Env("DOCKER_TCP_PORT", "2375")
 		return fmt.Sprintf("tcp://%s:%s", host, port), ""
 	default: // ssh
-		user := h.Vars["ansible_user"]
-		if user == "" {
-			user = common.Env("SSH_USER", "root")
-		}
-		addr := h.Addr
-		if addr == "" {
-			addr = h.Name
-		}
-		
-		// Build SSH command for Docker CLI SSH support
-		sshCmd := "ssh"
-		if keyFile := common.Env("SSH_KEY_FILE", ""); keyFile != "" {
-			sshCmd += " -i " + keyFile
-		}
-		if common.Env("SSH_STRICT_HOST_KEY", "true") == "false" {
-			sshCmd += " -o StrictHostKeyChecking=no"
-		}
-		if port := common.Env("SSH_PORT", ""); port != "" && port != "22" {
-			sshCmd += " -p " + port
+		// Per-host user/port/key/jump hosts come from inventory vars (see ssh_target.go)
+		t, err := SSHTargetForHost(context.Background(), h)
+		if err != nil {
+			common.WarnLog("SSH settings for host %s: %v", h.Name, err)
 		}
 		
-		// Return SSH URL format that Docker CLI expects
-		return fmt.Sprintf("ssh://%s@%s", user, addr), sshCmd
+		// Return SSH URL format that Docker CLI expects, plus the matching ssh command
+		return sshURLForTarget(t, ""), sshCommandForTarget(t)
 	}
 }
 
@@ -118,24 +113,30 @@ func withSSHEnv(cmd string, fn func() error) error {
 func DockerClientForURL(ctx context.Context, url, sshCmd string) (*client.Client, func(), error) {
 	var cli *client.Client
 	
+	// dd-ui-agent connections are multiplexed over the agent's websocket
+	if strings.HasPrefix(url, agentScheme) {
+		return agentDockerClient(ctx, url)
+	}
+	
 	// Handle SSH connections with proper SSH transport
 	if strings.HasPrefix(url, "ssh://") {
 		common.DebugLog("SSH connection detected, using SSH transport")
 		
-		// Parse SSH URL to extract user and host
-		user, host, err := utils.ParseSSHURL(url)
+		// Resolve the host's SSH target (registered by DockerURLFor; env defaults otherwise)
+		t, sock, err := sshTargetForURL(url)
 		if err != nil {
 			return nil, nil, fmt.Errorf("invalid SSH URL: %v", err)
 		}
-		
-		// Get SSH key file from environment
-		keyFile := common.Env("SSH_KEY_FILE", "")
-		if keyFile == "" {
+		if t.KeyFile == "" {
 			return nil, nil, fmt.Errorf("SSH_KEY_FILE not configured")
 		}
 		
-		// Create Docker client with SSH transport
-		cli, cleanup, err := utils.CreateSSHDockerClient(user, host, keyFile)
+		// Create Docker client with SSH transport (ssh://user@host/path selects a non-default
+		// socket, e.g. Podman's)
+		if sock == "" {
+			sock = utils.DefaultDockerSocket
+		}
+		cli, cleanup, err := utils.CreateSSHDockerClientForTarget(t, sock)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to create SSH Docker client: %v", err)
 		}
@@ -149,7 +150,7 @@ func DockerClientForURL(ctx context.Context, url, sshCmd string) (*client.Client
 			return nil, nil, fmt.Errorf("SSH Docker connection test failed: %v", err)
 		}
 		
-		common.DebugLog("SSH Docker client created successfully for %s@%s", user, host)
+		common.DebugLog("SSH Docker client created successfully for %s", t)
 		return cli, cleanup, nil
 	}
 	
@@ -230,12 +231,20 @@ func ScanHostContainers(ctx context.Context, hostName string) (int, error) {
 		common.InfoLog("scan: host=%s docker_url=%s", h.Name, url)
 	}
 
+	start := time.Now()
 	cli, done, err := DockerClientForURL(ctx, url, sshCmd)
+	if err == nil {
+		defer done()
+		_, err = cli.Ping(ctx)
+	}
 	if err != nil {
-		database.ScanLog(ctx, h.ID, "error", "docker connect failed", map[string]any{"error": err.Error(), "url": url})
+		// log the first failure of a streak and the transition to offline, not every retry
+		if state, first := RecordHostUnreachable(ctx, h, err); first {
+			database.ScanLog(ctx, h.ID, "error", "docker connect failed", map[string]any{"error": err.Error(), "url": url, "state": state})
+		}
 		return 0, err
 	}
-	defer done()
+	RecordHostReachable(ctx, h, time.Since(start))
 
 	list, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: filters.NewArgs()})
 	if err != nil {
@@ -243,10 +252,25 @@ func ScanHostContainers(ctx context.Context, hostName string) (int, error) {
 		return 0, err
 	}
 
+	// previous states, for container.* events (nothing is emitted on a host's first scan)
+	prev := map[string]database.ContainerRow{}
+	if rows, err := database.ListContainersByHost(ctx, h.Name); err == nil {
+		for _, r := range rows {
+			prev[r.ContainerID] = r
+		}
+	}
+
 	seen := make([]string, 0, len(list))
 	saved := 0
 
+	podman := IsPodmanHost(h)
+	maskAll := utils.MaskAllEnv()
+	sopsKeys := map[string]map[string]bool{} // compose project -> SOPS env keys
 	for _, c := range list {
+		// Podman pod infra (pause) containers are plumbing, not workloads
+		if podman && isPodmanInfraContainer(c.Image, c.Names) {
+			continue
+		}
 		seen = append(seen, c.ID)
 
 		ci, err := cli.ContainerInspect(ctx, c.ID)
@@ -264,6 +288,9 @@ func ScanHostContainers(ctx context.Context, hostName string) (int, error) {
 		if project == "" {
 			project = labels["com.docker.stack.namespace"]
 		}
+		if project == "" {
+			project = labels["io.podman.compose.project"]
+		}
 		var stackIDPtr *int64
 		if project != "" {
 			if sid, err := database.EnsureStack(ctx, h.ID, project, h.Owner); err == nil {
@@ -292,9 +319,16 @@ func ScanHostContainers(ctx context.Context, hostName string) (int, error) {
 				}
 			}
 		}
+		// env values and secret-looking labels are masked before they are stored;
+		// the real values are only read live through the reveal endpoint
+		keys, ok := sopsKeys[project]
+		if !ok {
+			keys = sopsEnvKeysForProject(ctx, h.Name, project)
+			sopsKeys[project] = keys
+		}
 		var envOut []string
 		if ci.Config != nil && ci.Config.Env != nil {
-			envOut = ci.Config.Env
+			envOut = utils.MaskEnv(ci.Config.Env, keys, maskAll)
 		}
 		var networksOut any = map[string]any{}
 		if ci.NetworkSettings != nil && ci.NetworkSettings.Networks != nil {
@@ -320,7 +354,7 @@ func ScanHostContainers(ctx context.Context, hostName string) (int, error) {
 
 		if err := database.UpsertContainer(
 			ctx, h.ID, stackIDPtr, c.ID, name, c.Image, c.State, c.Status, h.Owner,
-			createdPtr, ip, portsOut, labels, envOut, networksOut, mountsOut,
+			createdPtr, ip, portsOut, utils.MaskLabels(labels, keys), envOut, networksOut, mountsOut,
 		); err != nil {
 			database.ScanLog(ctx, h.ID, "error", "upsert container failed", map[string]any{"name": name, "id": c.ID, "error": err.Error()})
 			continue
@@ -329,6 +363,29 @@ func ScanHostContainers(ctx context.Context, hostName string) (int, error) {
 		saved++
 		database.ScanLog(ctx, h.ID, "info", "container discovered",
 			map[string]any{"name": name, "image": c.Image, "state": c.State, "status": c.Status, "project": project})
+
+		if len(prev) > 0 {
+			data := map[string]any{"id": c.ID, "image": c.Image, "state": c.State, "status": c.Status}
+			if old, ok := prev[c.ID]; !ok {
+				PublishEvent(ctx, Event{Type: EventContainerCreated, Host: h.Name, Stack: project, Subject: name, Data: data})
+			} else if old.State != c.State {
+				data["previous_state"] = old.State
+				PublishEvent(ctx, Event{Type: EventContainerState, Host: h.Name, Stack: project, Subject: name, Data: data})
+			}
+		}
+	}
+
+	if len(prev) > 0 {
+		kept := make(map[string]bool, len(seen))
+		for _, id := range seen {
+			kept[id] = true
+		}
+		for id, old := range prev {
+			if !kept[id] {
+				PublishEvent(ctx, Event{Type: EventContainerRemoved, Host: h.Name, Stack: old.ComposeProj, Subject: old.Name,
+					Data: map[string]any{"id": id, "image": old.Image, "previous_state": old.State}})
+			}
+		}
 	}
 
 	// prune gone containers
@@ -336,6 +393,11 @@ func ScanHostContainers(ctx context.Context, hostName string) (int, error) {
 		database.ScanLog(ctx, h.ID, "info", "pruned missing containers", map[string]any{"count": pruned})
 	}
 
+	// swarm managers also inventory nodes/services/tasks (best effort)
+	if IsSwarmManager(h) {
+		_ = ScanSwarm(ctx, h, cli)
+	}
+
 	database.ScanLog(ctx, h.ID, "info", "scan complete", map[string]any{"containers": saved})
 	return saved, nil
 }
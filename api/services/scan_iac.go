@@ -2,6 +2,7 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -28,6 +29,7 @@ var (
 type composeDoc struct {
 	Services map[string]*composeSvc `yaml:"services"`
 	XPull    string                 `yaml:"x-pull-policy"`
+	XDdui    composeXDdui           `yaml:"x-ddui"`
 }
 
 type composeSvc struct {
@@ -94,114 +96,11 @@ func ScanIacLocal(ctx context.Context) (int, int, error) {
 			return nil
 		}
 
-		// Determine scope_kind by checking if scopeName is a known host
-		scopeKind := "group"
-		if _, err := database.GetHostByName(ctx, scopeName); err == nil {
-			scopeKind = "host"
-		}
-
-		composeFile := findOne(p, []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"})
-		deployKind := "unmanaged"
-		if composeFile != "" {
-			deployKind = "compose"
-		}
-		if existsAny(p, []string{"deploy.sh", "pre.sh", "post.sh"}) {
-			deployKind = "script" // if both exist we keep compose
-		}
-
-		// env files (record + sops detection)
-		envFiles := listEnvFiles(p)
-		sopsStatus := summarizeSops(envFiles)
-
-		// Check if this directory actually has any IaC content before creating a stack record
-		if !directoryHasIacContent(p, composeFile, envFiles) {
-			common.DebugLog("iac: skipping empty directory %s/%s (no IaC content found)", scopeName, stackName)
-			return fs.SkipDir
-		}
-
-		stackID, err := UpsertIacStack(ctx, repoID, scopeKind, scopeName, stackName,
-			filepath.ToSlash(filepath.Join(dirname, scopeName, stackName)),
-			composeFile, deployKind, "", sopsStatus, true)
-		if err != nil {
-			common.ErrorLog("iac: stack upsert failed scope=%s stack=%s path=%s err=%v", scopeName, stackName, p, err)
-			return fs.SkipDir
-		}
-		common.InfoLog("iac: stack %s/%s id=%d deploy=%s compose=%q sops=%s", scopeName, stackName, stackID, deployKind, composeFile, sopsStatus)
-
-		keepStackIDs = append(keepStackIDs, stackID)
-		stacksFound++
-
-		// Track files
-		for _, ef := range envFiles {
-			sum, sz := sha256File(ef.fullPath)
-			if err := UpsertIacFile(ctx, stackID, "env", relFrom(root, ef.fullPath), ef.sops, sum, sz); err != nil {
-				common.ErrorLog("iac: upsert file(env) failed stack_id=%d file=%s err=%v", stackID, ef.fullPath, err)
-			}
-		}
-		if composeFile != "" {
-			full := filepath.Join(p, composeFile)
-			sum, sz := sha256File(full)
-			if err := UpsertIacFile(ctx, stackID, "compose", filepath.ToSlash(filepath.Join(dirname, scopeName, stackName, composeFile)), false, sum, sz); err != nil {
-				common.ErrorLog("iac: upsert file(compose) failed stack_id=%d file=%s err=%v", stackID, full, err)
-			}
-		}
-		for _, s := range []string{"deploy.sh", "pre.sh", "post.sh"} {
-			full := filepath.Join(p, s)
-			if fi, err := os.Stat(full); err == nil && !fi.IsDir() {
-				sum, sz := sha256File(full)
-				if err := UpsertIacFile(ctx, stackID, "script", relFrom(root, full), false, sum, sz); err != nil {
-					common.ErrorLog("iac: upsert file(script) failed stack_id=%d file=%s err=%v", stackID, full, err)
-				}
-			}
-		}
-
-		// Parse compose → services
-		if composeFile != "" {
-			b, _ := os.ReadFile(filepath.Join(p, composeFile))
-			cdoc := &composeDoc{}
-			_ = yaml.Unmarshal(b, cdoc)
-			pullPolicy := strings.TrimSpace(cdoc.XPull)
-
-			// services in deterministic order
-			names := make([]string, 0, len(cdoc.Services))
-			for k := range cdoc.Services {
-				names = append(names, k)
-			}
-			sort.Strings(names)
-
-			for _, svcName := range names {
-				svc := cdoc.Services[svcName]
-				if svc == nil {
-					continue
-				}
-
-				lbls := normLabels(svc.Labels)
-				envKeys, envF := normEnv(svc.Environment, svc.EnvFile, p, envFiles)
-				ports := normPorts(svc.Ports)
-				vols := normVolumes(svc.Volumes)
-
-				if err := upsertIacService(ctx, IacServiceRow{
-					StackID:       stackID,
-					ServiceName:   svcName,
-					ContainerName: svc.ContainerName,
-					Image:         svc.Image,
-					Labels:        lbls,
-					EnvKeys:       envKeys,
-					EnvFiles:      envF,
-					Ports:         ports,
-					Volumes:       vols,
-					Deploy:        svc.Deploy,
-				}); err != nil {
-					common.ErrorLog("iac: upsert service failed stack_id=%d svc=%s err=%v", stackID, svcName, err)
-				} else {
-					servicesSaved++
-				}
-			}
-
-			// update pull_policy if present
-			if pullPolicy != "" {
-				_, _ = common.DB.Exec(ctx, `UPDATE iac_stacks SET pull_policy=$1 WHERE id=$2`, pullPolicy, stackID)
-			}
+		stackID, saved, err := scanIacStackDir(ctx, root, dirname, repoID, p, scopeName, stackName)
+		if err == nil && stackID != 0 {
+			keepStackIDs = append(keepStackIDs, stackID)
+			stacksFound++
+			servicesSaved += saved
 		}
 
 		// We are *at* a stack directory; don't descend further into it.
@@ -231,6 +130,186 @@ func ScanIacLocal(ctx context.Context) (int, int, error) {
 	return stacksFound, servicesSaved, nil
 }
 
+// ScanIacStack rescans one docker-compose/<scope>/<stack> directory (used by the file
+// watcher). A removed or emptied directory drops its stack; the returned id is 0 then.
+func ScanIacStack(ctx context.Context, scopeName, stackName string) (int64, error) {
+	root := strings.TrimSpace(common.Env(IacDefaultRootEnv, IacDefaultRoot))
+	dirname := strings.TrimSpace(common.Env(DockerDirEnv, DefaultDockerDir))
+
+	repoID, err := UpsertIacRepoLocal(ctx, root)
+	if err != nil {
+		return 0, err
+	}
+
+	p := filepath.Join(root, dirname, scopeName, stackName)
+	if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+		stackID, _, err := scanIacStackDir(ctx, root, dirname, repoID, p, scopeName, stackName)
+		if err != nil {
+			return 0, err
+		}
+		if stackID != 0 {
+			pruneMissingIacFiles(ctx, root, stackID)
+			common.DebugLog("iac: rescanned stack %s/%s id=%d", scopeName, stackName, stackID)
+			return stackID, nil
+		}
+	}
+
+	if n, err := deleteIacStackByName(ctx, repoID, scopeName, stackName); err != nil {
+		return 0, err
+	} else if n > 0 {
+		common.InfoLog("iac: removed stack %s/%s (directory gone or empty)", scopeName, stackName)
+	}
+	return 0, nil
+}
+
+// pruneMissingIacFiles drops tracked file rows whose file was deleted from the stack
+func pruneMissingIacFiles(ctx context.Context, root string, stackID int64) {
+	files, err := ListFilesForStack(ctx, stackID)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(f.RelPath))); os.IsNotExist(err) {
+			if err := DeleteIacFileRow(ctx, stackID, f.RelPath); err == nil {
+				common.DebugLog("iac: file %s removed from stack_id=%d", f.RelPath, stackID)
+			}
+		}
+	}
+}
+
+// scanIacStackDir records one docker-compose/<scope>/<stack> directory (files, services).
+// Returns stackID 0 when the directory has no IaC content.
+func scanIacStackDir(ctx context.Context, root, dirname string, repoID int64, p, scopeName, stackName string) (int64, int, error) {
+	servicesSaved := 0
+
+	// Determine scope_kind by checking if scopeName is a known host
+	scopeKind := "group"
+	if _, err := database.GetHostByName(ctx, scopeName); err == nil {
+		scopeKind = "host"
+	}
+
+	composeFile := findOne(p, composeMainNames)
+	deployKind := "unmanaged"
+	if composeFile != "" {
+		deployKind = "compose"
+	}
+	if existsAny(p, []string{"deploy.sh", "pre.sh", "post.sh"}) {
+		deployKind = "script" // if both exist we keep compose
+	}
+
+	// env files (record + sops detection)
+	envFiles := listEnvFiles(p)
+	sopsStatus := summarizeSops(envFiles)
+
+	// Check if this directory actually has any IaC content before creating a stack record
+	if !directoryHasIacContent(p, composeFile, envFiles) {
+		common.DebugLog("iac: skipping empty directory %s/%s (no IaC content found)", scopeName, stackName)
+		return 0, 0, nil
+	}
+
+	stackID, err := UpsertIacStack(ctx, repoID, scopeKind, scopeName, stackName,
+		filepath.ToSlash(filepath.Join(dirname, scopeName, stackName)),
+		composeFile, deployKind, "", sopsStatus, true)
+	if err != nil {
+		common.ErrorLog("iac: stack upsert failed scope=%s stack=%s path=%s err=%v", scopeName, stackName, p, err)
+		return 0, 0, err
+	}
+	common.InfoLog("iac: stack %s/%s id=%d deploy=%s compose=%q sops=%s", scopeName, stackName, stackID, deployKind, composeFile, sopsStatus)
+
+	// Track files
+	for _, ef := range envFiles {
+		sum, sz := sha256File(ef.fullPath)
+		if err := UpsertIacFile(ctx, stackID, "env", relFrom(root, ef.fullPath), ef.sops, sum, sz); err != nil {
+			common.ErrorLog("iac: upsert file(env) failed stack_id=%d file=%s err=%v", stackID, ef.fullPath, err)
+		}
+	}
+	// compose set: main file, overrides, x-ddui declared files, include:/extends: targets
+	cdoc := &composeDoc{}
+	var composeOrder, composeProfiles, composeAll []string
+	if composeFile != "" {
+		b, _ := os.ReadFile(filepath.Join(p, composeFile))
+		perr := yaml.Unmarshal(b, cdoc)
+		warnTemplateComposeMain(scopeName+"/"+stackName, composeFile, b, perr)
+		composeOrder, composeProfiles, composeAll = discoverComposeFiles(root, p, composeFile, cdoc.XDdui)
+	}
+	for _, full := range composeAll {
+		sum, sz := sha256File(full)
+		if err := UpsertIacFile(ctx, stackID, "compose", relFrom(root, full), false, sum, sz); err != nil {
+			common.ErrorLog("iac: upsert file(compose) failed stack_id=%d file=%s err=%v", stackID, full, err)
+		}
+	}
+	if err := SetIacStackComposeConfig(ctx, stackID, composeOrder, composeProfiles); err != nil {
+		common.ErrorLog("iac: save compose files failed stack_id=%d err=%v", stackID, err)
+	}
+	for _, s := range []string{"deploy.sh", "pre.sh", "post.sh"} {
+		full := filepath.Join(p, s)
+		if fi, err := os.Stat(full); err == nil && !fi.IsDir() {
+			sum, sz := sha256File(full)
+			if err := UpsertIacFile(ctx, stackID, "script", relFrom(root, full), false, sum, sz); err != nil {
+				common.ErrorLog("iac: upsert file(script) failed stack_id=%d file=%s err=%v", stackID, full, err)
+			}
+		}
+	}
+
+	tracked := map[string]bool{}
+	for _, full := range composeAll {
+		tracked[full] = true
+	}
+	for _, s := range []string{"deploy.sh", "pre.sh", "post.sh"} {
+		tracked[filepath.Join(p, s)] = true
+	}
+	trackAuxFiles(ctx, root, p, stackID, tracked)
+
+	// Parse compose → services (merged across the -f list)
+	if composeFile != "" {
+		pullPolicy := strings.TrimSpace(cdoc.XPull)
+		merged := mergeComposeDocs(p, composeOrder)
+
+		// services in deterministic order
+		names := make([]string, 0, len(merged.Services))
+		for k := range merged.Services {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, svcName := range names {
+			svc := merged.Services[svcName]
+			if svc == nil {
+				continue
+			}
+
+			lbls := normLabels(svc.Labels)
+			envKeys, envF := normEnv(svc.Environment, svc.EnvFile, p, envFiles)
+			ports := normPorts(svc.Ports)
+			vols := normVolumes(svc.Volumes)
+
+			if err := upsertIacService(ctx, IacServiceRow{
+				StackID:       stackID,
+				ServiceName:   svcName,
+				ContainerName: svc.ContainerName,
+				Image:         svc.Image,
+				Labels:        lbls,
+				EnvKeys:       envKeys,
+				EnvFiles:      envF,
+				Ports:         ports,
+				Volumes:       vols,
+				Deploy:        svc.Deploy,
+			}); err != nil {
+				common.ErrorLog("iac: upsert service failed stack_id=%d svc=%s err=%v", stackID, svcName, err)
+			} else {
+				servicesSaved++
+			}
+		}
+
+		// update pull_policy if present
+		if pullPolicy != "" {
+			_, _ = common.DB.Exec(ctx, `UPDATE iac_stacks SET pull_policy=$1 WHERE id=$2`, pullPolicy, stackID)
+		}
+	}
+
+	return stackID, servicesSaved, nil
+}
+
 /* ---------- helpers [unchanged except imports] ---------- */
 
 type envFileMeta struct {
@@ -247,7 +326,7 @@ func listEnvFiles(dir string) []envFileMeta {
 			continue
 		}
 		name := e.Name()
-		if !strings.HasSuffix(name, ".env") && !strings.Contains(name, ".env.") && !strings.HasSuffix(name, "_secret.env") && !strings.HasSuffix(name, "_private.env") && !strings.HasSuffix(name, "_secure.env") && name != ".env" {
+		if !isEnvFileName(name) {
 			continue
 		}
 		full := filepath.Join(dir, name)
@@ -261,6 +340,62 @@ func listEnvFiles(dir string) []envFileMeta {
 	return out
 }
 
+// isEnvFileName matches dotenv files (".env", "*.env", "*.env.*")
+func isEnvFileName(name string) bool {
+	return strings.HasSuffix(name, ".env") || strings.Contains(name, ".env.") || name == ".env"
+}
+
+// trackAuxFiles records every other file under the stack directory (config files, bind
+// mount sources) as role "other", so edits to them change the bundle hash and they are
+// staged for deploys. Hidden directories and editor temp files are skipped.
+func trackAuxFiles(ctx context.Context, root, dir string, stackID int64, tracked map[string]bool) {
+	n := 0
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		name := d.Name()
+		if !d.Type().IsRegular() || tracked[p] || name == ".env" ||
+			strings.HasSuffix(name, "~") || strings.HasSuffix(name, ".swp") || strings.HasSuffix(name, ".tmp") {
+			return nil
+		}
+		if dir == filepath.Dir(p) && isEnvFileName(name) {
+			return nil // already tracked as env
+		}
+		b, _ := os.ReadFile(p)
+		sum, sz := sha256File(p)
+		if err := UpsertIacFile(ctx, stackID, "other", relFrom(root, p), looksSops(b), sum, sz); err != nil {
+			common.ErrorLog("iac: upsert file(other) failed stack_id=%d file=%s err=%v", stackID, p, err)
+		}
+		n++
+		return nil
+	})
+	if n > 0 {
+		common.DebugLog("iac: stack_id=%d tracks %d auxiliary files", stackID, n)
+	}
+}
+
+// warnTemplateComposeMain flags a templated main compose file: x-ddui, include: and extends:
+// are read from it before rendering, so they are lost when it does not parse as YAML and read
+// verbatim when they contain template actions
+func warnTemplateComposeMain(stack, name string, b []byte, parseErr error) {
+	if !IsTemplateFile(name) {
+		return
+	}
+	switch {
+	case parseErr != nil:
+		common.WarnLog("iac: %s: %s does not parse as YAML before rendering; its x-ddui, include: and extends: are ignored (keep the main file plain YAML to use them): %v", stack, name, parseErr)
+	case bytes.Contains(b, []byte("{{")):
+		common.WarnLog("iac: %s: %s is a template; x-ddui, include: and extends: are read unrendered, keep template actions out of them", stack, name)
+	}
+}
+
 func summarizeSops(envs []envFileMeta) string {
 	if len(envs) == 0 {
 		return "none"
@@ -0,0 +1,50 @@
+// services/scan_log_retention.go
+package services
+
+import (
+	"context"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+// StartScanLogRetention prunes scan_logs hourly: info rows older than
+// DD_UI_SCAN_LOG_RETENTION (default 168h) and warn/error rows older than
+// DD_UI_SCAN_LOG_ERROR_RETENTION (default 720h). 0 keeps that class forever.
+func StartScanLogRetention(ctx context.Context) {
+	infoRetention := envDuration("DD_UI_SCAN_LOG_RETENTION", 168*time.Hour)
+	errRetention := envDuration("DD_UI_SCAN_LOG_ERROR_RETENTION", 720*time.Hour)
+	if infoRetention <= 0 && errRetention <= 0 {
+		common.InfoLog("scan logs: retention disabled, rows are kept forever")
+		return
+	}
+	common.InfoLog("scan logs: retention info=%s warn/error=%s info_mode=%s",
+		infoRetention, errRetention, common.Env("DD_UI_SCAN_LOG_INFO", "changes"))
+
+	prune := func(retention time.Duration, levels []string, label string) {
+		if retention <= 0 {
+			return
+		}
+		n, err := database.PruneScanLogs(ctx, time.Now().Add(-retention), levels)
+		if err != nil {
+			common.ErrorLog("scan logs: prune %s failed: %v", label, err)
+		} else if n > 0 {
+			common.InfoLog("scan logs: pruned %d %s rows older than %s", n, label, retention)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(1 * time.Hour)
+		defer ticker.Stop()
+		for {
+			prune(infoRetention, []string{"info", "debug"}, "info")
+			prune(errRetention, []string{"warn", "error"}, "warn/error")
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,314 @@
+// services/ssh_target.go
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+/*
+Per-host SSH settings, resolved from inventory vars (host over group vars, see
+EffectiveHostVars) and falling back to the global env:
+
+  ansible_user | ansible_ssh_user                                  SSH_USER (root)
+  ansible_port | ansible_ssh_port                                  SSH_PORT (22)
+  ansible_ssh_private_key_file | ansible_private_key_file          SSH_KEY_FILE
+  ansible_ssh_common_args | ansible_ssh_extra_args  -J a,b / -o ProxyJump=a,b /
+                                                    -o ProxyCommand="ssh -W %h:%p user@bastion"
+
+Relative key paths resolve under the IaC root and may be SOPS-encrypted; they are
+decrypted into a private temp file. Jump hosts that are themselves inventory hosts use
+their own settings (so chains of bastions compose).
+*/
+
+const maxSSHJumpDepth = 5
+
+var (
+	sshTargetsByURL sync.Map // docker ssh:// URL -> utils.SSHTarget
+	sshKeyCache     sync.Map // key path + mtime -> usable key file
+)
+
+// SSHTargetForHost resolves the full SSH target (including jump hosts) for a host
+func SSHTargetForHost(ctx context.Context, h database.HostRow) (utils.SSHTarget, error) {
+	return sshTargetForHost(ctx, h, 0)
+}
+
+func sshTargetForHost(ctx context.Context, h database.HostRow, depth int) (utils.SSHTarget, error) {
+	vars := EffectiveHostVars(h)
+	addr := h.Addr
+	if addr == "" {
+		addr = h.Name
+	}
+	t := utils.SSHTarget{
+		User: firstNonEmpty(vars["ansible_user"], vars["ansible_ssh_user"], common.Env("SSH_USER", "root")),
+		Addr: addr,
+		Port: firstNonEmpty(vars["ansible_port"], vars["ansible_ssh_port"], common.Env("SSH_PORT", "22")),
+	}
+
+	if keyPath := firstNonEmpty(vars["ansible_ssh_private_key_file"], vars["ansible_private_key_file"]); keyPath != "" {
+		key, err := resolveSSHKeyFile(ctx, keyPath)
+		if err != nil {
+			return t, fmt.Errorf("host %s: %v", h.Name, err)
+		}
+		t.KeyFile = key
+	} else {
+		t.KeyFile = common.Env("SSH_KEY_FILE", "")
+	}
+
+	jumps := parseSSHJumps(vars["ansible_ssh_common_args"] + " " + vars["ansible_ssh_extra_args"])
+	if len(jumps) == 0 {
+		return t, nil
+	}
+	if depth >= maxSSHJumpDepth {
+		return t, fmt.Errorf("host %s: jump host chain deeper than %d", h.Name, maxSSHJumpDepth)
+	}
+	var via *utils.SSHTarget
+	for _, spec := range jumps {
+		jt, err := sshJumpTarget(ctx, spec, t, depth)
+		if err != nil {
+			return t, fmt.Errorf("host %s: %v", h.Name, err)
+		}
+		if jt.Via == nil {
+			jt.Via = via
+		}
+		via = &jt
+	}
+	t.Via = via
+	return t, nil
+}
+
+// sshJumpTarget resolves "[user@]host[:port]"; inventory hosts use their own settings,
+// others inherit user and key from the target being reached
+func sshJumpTarget(ctx context.Context, spec string, from utils.SSHTarget, depth int) (utils.SSHTarget, error) {
+	user, hostport := "", spec
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		user, hostport = spec[:i], spec[i+1:]
+	}
+	host, port := hostport, ""
+	if h, p, err := net.SplitHostPort(hostport); err == nil {
+		host, port = h, p
+	}
+
+	var jt utils.SSHTarget
+	if jh, err := database.GetHostByName(ctx, host); err == nil {
+		if jt, err = sshTargetForHost(ctx, jh, depth+1); err != nil {
+			return jt, err
+		}
+	} else {
+		jt = utils.SSHTarget{User: from.User, Addr: host, Port: "22", KeyFile: from.KeyFile}
+	}
+	if user != "" {
+		jt.User = user
+	}
+	if port != "" {
+		jt.Port = port
+	}
+	return jt, nil
+}
+
+// parseSSHJumps extracts the jump chain from ansible_ssh_common_args-style options
+func parseSSHJumps(args string) []string {
+	toks := strings.Fields(strings.NewReplacer(`"`, " ", `'`, " ").Replace(args))
+	var spec string
+	for i := 0; i < len(toks); i++ {
+		tok := toks[i]
+		next := func() string {
+			if i+1 < len(toks) {
+				i++
+				return toks[i]
+			}
+			return ""
+		}
+		switch {
+		case tok == "-J":
+			spec = next()
+		case strings.HasPrefix(tok, "-J"):
+			spec = tok[2:]
+		case tok == "-o" || strings.HasPrefix(tok, "-o"):
+			opt := strings.TrimPrefix(tok, "-o")
+			if opt == "" {
+				opt = next()
+			}
+			low := strings.ToLower(opt)
+			switch {
+			case strings.HasPrefix(low, "proxyjump="):
+				spec = opt[len("proxyjump="):]
+			case low == "proxyjump":
+				spec = next()
+			case strings.HasPrefix(low, "proxycommand="):
+				rest := append([]string{opt[len("proxycommand="):]}, toks[i+1:]...)
+				i = len(toks)
+				spec = proxyCommandJump(rest)
+			case low == "proxycommand":
+				spec = proxyCommandJump(toks[i+1:])
+				i = len(toks)
+			}
+		}
+	}
+	if spec == "" || strings.EqualFold(spec, "none") {
+		return nil
+	}
+	var out []string
+	for _, s := range strings.Split(spec, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// proxyCommandJump turns `ssh -W %h:%p [-p port] [user@]bastion` into a jump spec
+func proxyCommandJump(toks []string) string {
+	if len(toks) == 0 || filepath.Base(toks[0]) != "ssh" {
+		return ""
+	}
+	var dest, port string
+	for i := 1; i < len(toks); i++ {
+		switch tok := toks[i]; {
+		case tok == "-p" && i+1 < len(toks):
+			port = toks[i+1]
+			i++
+		case tok == "-W" || tok == "-i" || tok == "-o" || tok == "-l" || tok == "-F":
+			i++
+		case strings.HasPrefix(tok, "-"):
+		default:
+			dest = tok
+		}
+	}
+	if dest == "" {
+		return ""
+	}
+	if port != "" {
+		return dest + ":" + port
+	}
+	return dest
+}
+
+// resolveSSHKeyFile returns a key path usable by x/crypto/ssh and OpenSSH: relative paths
+// resolve under the IaC root and SOPS-encrypted keys are decrypted to a private file
+func resolveSSHKeyFile(ctx context.Context, p string) (string, error) {
+	full := p
+	if strings.HasPrefix(full, "~/") {
+		full = filepath.Join(os.Getenv("HOME"), full[2:])
+	} else if !filepath.IsAbs(full) {
+		var err error
+		if full, err = joinUnderLocal(common.Env(IacDefaultRootEnv, IacDefaultRoot), full); err != nil {
+			return "", err
+		}
+	}
+	st, err := os.Stat(full)
+	if err != nil {
+		return "", fmt.Errorf("ssh key %s: %v", p, err)
+	}
+	cacheKey := fmt.Sprintf("%s@%d", full, st.ModTime().UnixNano())
+	if v, ok := sshKeyCache.Load(cacheKey); ok {
+		return v.(string), nil
+	}
+
+	b, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+	encrypted := looksSops(b)
+	if !encrypted && st.Mode().Perm()&0o077 == 0 {
+		sshKeyCache.Store(cacheKey, full)
+		return full, nil
+	}
+	if encrypted {
+		if b, _, err = readDecryptedOrPlain(ctx, full, ""); err != nil {
+			return "", fmt.Errorf("ssh key %s: %v", p, err)
+		}
+	}
+
+	// OpenSSH refuses group/world-readable keys; keep a private copy either way
+	sum := sha256.Sum256([]byte(full))
+	dir := filepath.Join(os.TempDir(), "dd-ui", "ssh_keys")
+	if err := ensureDir(dir, 0o700); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(dir, hex.EncodeToString(sum[:8])+".key")
+	if !strings.HasSuffix(string(b), "\n") {
+		b = append(b, '\n')
+	}
+	if err := writeFileSecure(dest, b, 0o600); err != nil {
+		return "", err
+	}
+	sshKeyCache.Store(cacheKey, dest)
+	return dest, nil
+}
+
+// sshURLForTarget builds the ssh:// docker URL (with optional remote socket path)
+func sshURLForTarget(t utils.SSHTarget, sock string) string {
+	hp := t.Addr
+	if t.Port != "" && t.Port != "22" {
+		hp = t.HostPort()
+	}
+	url := fmt.Sprintf("ssh://%s@%s%s", t.User, hp, sock)
+	sshTargetsByURL.Store(url, t)
+	return url
+}
+
+// sshCommandForTarget builds the DOCKER_SSH_CMD equivalent for a target
+func sshCommandForTarget(t utils.SSHTarget) string {
+	cmd := "ssh"
+	if t.KeyFile != "" {
+		cmd += " -i " + t.KeyFile
+	}
+	if t.Port != "" && t.Port != "22" {
+		cmd += " -p " + t.Port
+	}
+	if j := t.JumpSpec(); j != "" {
+		cmd += " -J " + j
+	}
+	return cmd + knownHostsSSHOpts()
+}
+
+// sshTargetForURL returns the target registered by DockerURLFor, or one parsed from the URL
+func sshTargetForURL(url string) (utils.SSHTarget, string, error) {
+	user, hostPart, err := utils.ParseSSHURL(url)
+	if err != nil {
+		return utils.SSHTarget{}, "", err
+	}
+	host, sock := utils.SplitSSHHostSocket(hostPart)
+	if v, ok := sshTargetsByURL.Load(url); ok {
+		return v.(utils.SSHTarget), sock, nil
+	}
+	t := utils.SSHTarget{User: user, Addr: host, Port: common.Env("SSH_PORT", "22"), KeyFile: common.Env("SSH_KEY_FILE", "")}
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		t.Addr, t.Port = h, p
+	}
+	return t, sock, nil
+}
+
+// OpenSSHArgsForHost resolves a host's target, verifies every hop's host key and returns
+// the ssh CLI options plus the destination alias (ssh <opts> <dest> <command>)
+func OpenSSHArgsForHost(ctx context.Context, h database.HostRow) ([]string, string, error) {
+	t, err := SSHTargetForHost(ctx, h)
+	if err != nil {
+		return nil, "", err
+	}
+	if t.KeyFile == "" {
+		return nil, "", fmt.Errorf("no SSH key for host %s (set SSH_KEY_FILE or ansible_ssh_private_key_file)", h.Name)
+	}
+	return utils.OpenSSHTargetArgs(ctx, t)
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,372 @@
+// services/stack_assets.go
+package services
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+	"github.com/goccy/go-yaml"
+)
+
+// stackAssetsOverride is the compose file DD-UI adds to the stage when stack-relative bind
+// sources must point at the managed copy on the target host. Compose merges service volumes
+// by target and top-level configs/secrets by name, so only the rewritten entries are listed.
+const stackAssetsOverride = "docker-compose.ddui-assets.yml"
+
+// remoteStacksDir is where stack files live on hosts: dd_ui_stacks_dir (host/group var),
+// DD_UI_REMOTE_STACKS_DIR, or /opt/dd-ui/stacks
+func remoteStacksDir(h database.HostRow) string {
+	if v := strings.TrimSpace(h.Vars["dd_ui_stacks_dir"]); v != "" {
+		return v
+	}
+	return common.Env("DD_UI_REMOTE_STACKS_DIR", "/opt/dd-ui/stacks")
+}
+
+// stackAssetsTarget resolves the managed per-stack directory on the stack's host. ok is false
+// unless the host is reached over SSH: local sockets resolve paths on this machine and agent
+// hosts expose only the Docker API.
+func stackAssetsTarget(ctx context.Context, stackID int64) (h database.HostRow, dir string, ok bool) {
+	h, err := getHostForStack(ctx, stackID)
+	if err != nil {
+		return h, "", false
+	}
+	if url, _ := DockerURLFor(h); !strings.HasPrefix(url, "ssh://") {
+		return h, "", false
+	}
+	var scopeKind, scopeName, stackName string
+	if err := common.DB.QueryRow(ctx, `SELECT scope_kind::text, scope_name, stack_name FROM iac_stacks WHERE id=$1`, stackID).
+		Scan(&scopeKind, &scopeName, &stackName); err != nil {
+		return h, "", false
+	}
+	return h, path.Join(remoteStacksDir(h), strings.ToLower(scopeKind), scopeName, stackName), true
+}
+
+// writeStackAssetsOverride scans the staged compose files for bind sources and config/secret
+// files relative to the stack directory and writes an override pointing them at remoteDir.
+// Returns "" when nothing needs rewriting.
+func writeStackAssetsOverride(stageStackDir, remoteDir string, composes []string) (string, error) {
+	services := map[string][]any{}
+	topLevel := map[string]map[string]any{}
+
+	// remote maps a compose-relative source to its path in the managed directory
+	remote := func(composeFile, src string) (string, bool) {
+		if strings.Contains(src, "$") {
+			common.DebugLog("deploy: bind source %q uses interpolation; left as is", src)
+			return "", false
+		}
+		abs := filepath.Join(filepath.Dir(composeFile), src)
+		rel, err := filepath.Rel(stageStackDir, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			common.WarnLog("deploy: bind source %q escapes the stack directory; left as is", src)
+			return "", false
+		}
+		return path.Join(remoteDir, filepath.ToSlash(rel)), true
+	}
+
+	for _, cf := range composes {
+		b, err := os.ReadFile(cf)
+		if err != nil {
+			return "", err
+		}
+		var doc struct {
+			Services map[string]struct {
+				Volumes []any `yaml:"volumes"`
+			} `yaml:"services"`
+			Configs map[string]map[string]any `yaml:"configs"`
+			Secrets map[string]map[string]any `yaml:"secrets"`
+		}
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return "", fmt.Errorf("parse %s: %w", filepath.Base(cf), err)
+		}
+
+		for name, svc := range doc.Services {
+			for _, v := range svc.Volumes {
+				switch vol := v.(type) {
+				case string:
+					src, rest, found := strings.Cut(vol, ":")
+					if !found || !isRelativeBindSource(src) {
+						continue
+					}
+					if dst, ok := remote(cf, src); ok {
+						services[name] = append(services[name], dst+":"+rest)
+					}
+				case map[string]any:
+					src, _ := vol["source"].(string)
+					if t, _ := vol["type"].(string); t != "bind" || !isRelativeBindSource(src) {
+						continue
+					}
+					if dst, ok := remote(cf, src); ok {
+						long := make(map[string]any, len(vol))
+						for k, x := range vol {
+							long[k] = x
+						}
+						long["source"] = dst
+						services[name] = append(services[name], long)
+					}
+				}
+			}
+		}
+		for kind, defs := range map[string]map[string]map[string]any{"configs": doc.Configs, "secrets": doc.Secrets} {
+			for name, def := range defs {
+				file, _ := def["file"].(string)
+				if file == "" || filepath.IsAbs(file) || strings.HasPrefix(file, "~") {
+					continue
+				}
+				if dst, ok := remote(cf, file); ok {
+					if topLevel[kind] == nil {
+						topLevel[kind] = map[string]any{}
+					}
+					topLevel[kind][name] = map[string]any{"file": dst}
+				}
+			}
+		}
+	}
+
+	if len(services) == 0 && len(topLevel) == 0 {
+		return "", nil
+	}
+	doc := yaml.MapSlice{}
+	if len(services) > 0 {
+		svcs := yaml.MapSlice{}
+		names := make([]string, 0, len(services))
+		for name := range services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			svcs = append(svcs, yaml.MapItem{Key: name, Value: map[string]any{"volumes": services[name]}})
+		}
+		doc = append(doc, yaml.MapItem{Key: "services", Value: svcs})
+	}
+	for _, kind := range []string{"configs", "secrets"} {
+		if len(topLevel[kind]) > 0 {
+			doc = append(doc, yaml.MapItem{Key: kind, Value: topLevel[kind]})
+		}
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	header := "# Generated by DD-UI: stack-relative files are synced to " + remoteDir + "\n"
+	p := filepath.Join(stageStackDir, stackAssetsOverride)
+	if err := writeFileSecure(p, append([]byte(header), out...), 0o644); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// isRelativeBindSource: compose treats sources starting with "." as paths relative to the
+// project directory; bare names are named volumes
+func isRelativeBindSource(src string) bool {
+	return src == "." || strings.HasPrefix(src, "./") || strings.HasPrefix(src, "../")
+}
+
+// stackAssetsManifest lists, in the managed directory, the files the last sync uploaded.
+// Only files named there are pruned, so anything else on the host is left alone.
+const stackAssetsManifest = ".ddui-manifest"
+
+// SyncStackAssets uploads the staged stack's auxiliary files (everything except the compose
+// files, the top-level .env and env_file targets, which compose reads locally) to the managed
+// directory on the stack's host and removes files a previous sync uploaded that no longer
+// exist in the stack. No-op unless staging wrote an override.
+func SyncStackAssets(ctx context.Context, stackID int64, stageStackDir string, composes []string) error {
+	if _, err := os.Stat(filepath.Join(stageStackDir, stackAssetsOverride)); err != nil {
+		return nil
+	}
+	h, dir, ok := stackAssetsTarget(ctx, stackID)
+	if !ok {
+		return nil
+	}
+
+	skip, err := stackAssetsLocalFiles(stageStackDir, composes)
+	if err != nil {
+		return err
+	}
+	files, err := listStackAssets(stageStackDir, skip)
+	if err != nil {
+		return err
+	}
+
+	t, err := SSHTargetForHost(ctx, h)
+	if err != nil {
+		return err
+	}
+	sc, err := utils.SSHPool.GetSSHConnectionForTarget(t)
+	if err != nil {
+		return fmt.Errorf("sync stack files to %s: %w", h.Name, err)
+	}
+	run := func(cmd string, stdin io.Reader) ([]byte, error) {
+		sess, err := sc.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		defer sess.Close()
+		sess.Stdin = stdin
+		return sess.CombinedOutput(cmd)
+	}
+	qdir := shellQuote(dir)
+
+	// files uploaded by the previous sync; missing on first deploy
+	var previous []string
+	if out, err := run("cat "+shellQuote(path.Join(dir, stackAssetsManifest))+" 2>/dev/null || true", nil); err == nil {
+		previous = parseStackAssetsManifest(string(out))
+	} else {
+		common.WarnLog("deploy: cannot read %s on %s: %v", stackAssetsManifest, h.Name, err)
+	}
+
+	// stream a tar of the files straight into the remote extract
+	pr, pw := io.Pipe()
+	go func() { pw.CloseWithError(writeStackAssetsTar(pw, stageStackDir, files)) }()
+	out, err := run("mkdir -p "+qdir+" && tar -xmf - -C "+qdir, pr)
+	pr.Close()
+	if err != nil {
+		return fmt.Errorf("sync stack files to %s:%s: %v: %s", h.Name, dir, err, strings.TrimSpace(string(out)))
+	}
+
+	// drop files removed from the stack since the last deploy
+	if stale := staleStackAssets(previous, files); len(stale) > 0 {
+		quoted := make([]string, len(stale))
+		for i, f := range stale {
+			quoted[i] = shellQuote(f)
+		}
+		if out, err := run("cd "+qdir+" && rm -f -- "+strings.Join(quoted, " "), nil); err != nil {
+			common.WarnLog("deploy: pruning stale files in %s on %s failed: %v: %s", dir, h.Name, err, strings.TrimSpace(string(out)))
+		}
+	}
+	manifest := strings.Join(files, "\n") + "\n"
+	if out, err := run("cat > "+shellQuote(path.Join(dir, stackAssetsManifest)), strings.NewReader(manifest)); err != nil {
+		common.WarnLog("deploy: writing %s on %s failed: %v: %s", stackAssetsManifest, h.Name, err, strings.TrimSpace(string(out)))
+	}
+
+	common.InfoLog("deploy: synced %d stack files to %s:%s", len(files), h.Name, dir)
+	return nil
+}
+
+// stackAssetsLocalFiles returns the staged files compose reads on this machine: the compose
+// files, the project .env and every env_file they reference
+func stackAssetsLocalFiles(stageStackDir string, composes []string) (map[string]bool, error) {
+	skip := map[string]bool{filepath.Join(stageStackDir, ".env"): true}
+	for _, c := range composes {
+		skip[filepath.Clean(c)] = true
+		b, err := os.ReadFile(c)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := parseEnvFileRefs(b)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(c), err)
+		}
+		for _, list := range refs {
+			for _, ref := range list {
+				if !filepath.IsAbs(ref) {
+					ref = filepath.Join(filepath.Dir(c), ref)
+				}
+				skip[filepath.Clean(ref)] = true
+			}
+		}
+	}
+	return skip, nil
+}
+
+// listStackAssets returns the regular files under stageStackDir not in skip, as sorted slash
+// paths relative to it. The manifest name is reserved.
+func listStackAssets(stageStackDir string, skip map[string]bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(stageStackDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() || skip[p] {
+			return nil
+		}
+		rel, _ := filepath.Rel(stageStackDir, p)
+		if rel == stackAssetsManifest {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// parseStackAssetsManifest reads one relative path per line, ignoring anything that could
+// point outside the managed directory
+func parseStackAssetsManifest(s string) []string {
+	var out []string
+	for _, ln := range strings.Split(s, "\n") {
+		rel := strings.TrimSpace(ln)
+		if rel == "" || path.IsAbs(rel) {
+			continue
+		}
+		if c := path.Clean(rel); c == ".." || strings.HasPrefix(c, "../") {
+			continue
+		}
+		out = append(out, path.Clean(rel))
+	}
+	return out
+}
+
+// staleStackAssets returns the files of previous that are not in current
+func staleStackAssets(previous, current []string) []string {
+	keep := make(map[string]bool, len(current))
+	for _, f := range current {
+		keep[f] = true
+	}
+	var stale []string
+	for _, f := range previous {
+		if !keep[f] {
+			stale = append(stale, f)
+		}
+	}
+	return stale
+}
+
+// writeStackAssetsTar writes files (slash paths relative to dir) as a tar stream
+func writeStackAssetsTar(w io.Writer, dir string, files []string) error {
+	tw := tar.NewWriter(w)
+	for _, rel := range files {
+		full := filepath.Join(dir, filepath.FromSlash(rel))
+		fi, err := os.Stat(full)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		f, err := os.Open(full)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// shellQuote single-quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStackAssetsSkipsOnlyLocalEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, body string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("compose.yml", `services:
+  app:
+    image: app
+    env_file:
+      - app.env
+      - path: ./secrets/db.env
+        required: false
+`)
+	write(".env", "A=1\n")
+	write("app.env", "B=2\n")
+	write("secrets/db.env", "C=3\n")
+	write("config/app.env", "mounted into the container\n")
+	write("config/nginx.conf", "server {}\n")
+	write(stackAssetsOverride, "services: {}\n")
+
+	skip, err := stackAssetsLocalFiles(dir, []string{filepath.Join(dir, "compose.yml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := listStackAssets(dir, skip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"config/app.env", "config/nginx.conf", stackAssetsOverride}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+}
+
+func TestStaleStackAssets(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		current  []string
+		want     []string
+	}{
+		{"first sync", "", []string{"a.conf"}, nil},
+		{"removed file", "a.conf\nb/c.conf\n", []string{"a.conf"}, []string{"b/c.conf"}},
+		{"unchanged", "a.conf\n", []string{"a.conf"}, nil},
+		{"escaping entries ignored", "../x\n/etc/passwd\nb/../../y\nok.txt\n", nil, []string{"ok.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := staleStackAssets(parseStackAssetsManifest(tt.manifest), tt.current)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("stale = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
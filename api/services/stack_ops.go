@@ -0,0 +1,302 @@
+// services/stack_ops.go
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"dd-ui/utils"
+)
+
+// StackOperation is a whole-stack lifecycle action
+type StackOperation string
+
+const (
+	StackOpStop    StackOperation = "stop"
+	StackOpStart   StackOperation = "start"
+	StackOpRestart StackOperation = "restart"
+	StackOpDown    StackOperation = "down"
+	StackOpPull    StackOperation = "pull"
+)
+
+// ParseStackOperation validates an operation name
+func ParseStackOperation(s string) (StackOperation, error) {
+	for _, op := range database.StackOperationMethods {
+		if s == op {
+			return StackOperation(s), nil
+		}
+	}
+	return "", fmt.Errorf("unknown stack operation %q", s)
+}
+
+// StackOperationOptions tune a stack operation
+type StackOperationOptions struct {
+	User          string // recorded on the stamp
+	RemoveVolumes bool   // down only: also remove named volumes (callers must confirm)
+}
+
+// stackOperationArgs returns the compose subcommand for op (swarm stacks only support down)
+func stackOperationArgs(op StackOperation, opts StackOperationOptions, swarmMode bool) ([]string, error) {
+	if swarmMode {
+		if op != StackOpDown {
+			return nil, fmt.Errorf("%s is not supported for swarm stacks (use deploy or down)", op)
+		}
+		if opts.RemoveVolumes {
+			return nil, errors.New("removing volumes is not supported for swarm stacks (docker stack rm keeps them)")
+		}
+		return nil, nil
+	}
+	switch op {
+	case StackOpDown:
+		args := []string{"down", "--remove-orphans"}
+		if opts.RemoveVolumes {
+			args = append(args, "--volumes")
+		}
+		return args, nil
+	case StackOpStop, StackOpStart, StackOpRestart, StackOpPull:
+		return []string{string(op)}, nil
+	}
+	return nil, fmt.Errorf("unknown stack operation %q", op)
+}
+
+// IsSwarmStack reports whether a stack lives on a swarm manager (operations then use
+// docker stack commands)
+func IsSwarmStack(ctx context.Context, stackID int64) bool {
+	host, err := getHostForStack(ctx, stackID)
+	return err == nil && IsSwarmManager(host)
+}
+
+// stackOpDockerURL resolves the Docker endpoint the CLI uses for host. DockerURLFor only logs
+// bad SSH settings, so they are checked here; its ssh command is not needed because the CLI
+// reads the ssh config setupSSHConfigForDocker writes.
+func stackOpDockerURL(ctx context.Context, host database.HostRow) (string, error) {
+	dockerURL, _ := DockerURLFor(host)
+	if dockerURL == "" {
+		return "", fmt.Errorf("no Docker endpoint for host %s", host.Name)
+	}
+	if strings.HasPrefix(dockerURL, "ssh://") {
+		if _, err := SSHTargetForHost(ctx, host); err != nil {
+			return "", fmt.Errorf("SSH settings for host %s: %w", host.Name, err)
+		}
+	}
+	return dockerURL, nil
+}
+
+// RunStackOperation runs op without streaming its output
+func RunStackOperation(ctx context.Context, stackID int64, op StackOperation, opts StackOperationOptions) error {
+	ch := make(chan map[string]interface{}, 100)
+	go func() {
+		for range ch {
+		}
+	}()
+	return RunStackOperationWithStream(ctx, stackID, op, opts, ch)
+}
+
+// RunStackOperationWithStream stops, starts, restarts, downs or pulls a whole stack. It stages
+// the stack like a deploy (SOPS decrypted, same -f list and profiles), reaches the stack's host
+// the same way, streams compose output as events and records the run as a deployment stamp
+// whose method is the operation.
+func RunStackOperationWithStream(ctx context.Context, stackID int64, op StackOperation, opts StackOperationOptions, eventChannel chan<- map[string]interface{}) error {
+	defer close(eventChannel)
+
+	sendEvent := func(eventType, message string, data map[string]interface{}) {
+		event := map[string]interface{}{
+			"type":    eventType,
+			"message": message,
+		}
+		for k, v := range data {
+			event[k] = v
+		}
+		select {
+		case eventChannel <- event:
+		case <-ctx.Done():
+		}
+	}
+
+	rawProjectName, err := utils.FetchStackName(ctx, common.DB, stackID)
+	if err != nil || strings.TrimSpace(rawProjectName) == "" {
+		sendEvent("error", "Could not resolve stack name", nil)
+		return errors.New("stack op: could not resolve stack name")
+	}
+	labelProject := utils.ComposeProjectLabelFromStack(rawProjectName)
+
+	host, herr := getHostForStack(ctx, stackID)
+	swarmMode := herr == nil && IsSwarmManager(host)
+	opArgs, err := stackOperationArgs(op, opts, swarmMode)
+	if err != nil {
+		sendEvent("error", err.Error(), nil)
+		return err
+	}
+	sendEvent("info", fmt.Sprintf("Starting %s of stack: %s", op, rawProjectName), nil)
+
+	// Stage (SOPS decrypts into tmpfs and is cleaned afterwards)
+	sendEvent("info", "Staging stack files and decrypting secrets...", nil)
+	stageDir, stagedComposes, cleanup, derr := StageStackForCompose(ctx, stackID)
+	if derr != nil {
+		sendEvent("error", fmt.Sprintf("Failed to stage stack: %v", derr), nil)
+		return derr
+	}
+	defer func() {
+		if cleanup != nil {
+			cleanup()
+		}
+	}()
+	if len(stagedComposes) == 0 {
+		sendEvent("info", "No compose files found; nothing to do", nil)
+		return nil
+	}
+
+	profiles := StackComposeProfiles(ctx, stackID)
+	var args []string
+	if swarmMode {
+		args = []string{"stack", "rm", labelProject}
+	} else {
+		args = append(composeProjectArgs(rawProjectName, stagedComposes, profiles), opArgs...)
+	}
+
+	bundleHash, _ := ComputeCurrentBundleHash(ctx, stackID)
+	meta := map[string]string{
+		"bundle_hash":      bundleHash,
+		"compose_profiles": strings.Join(profiles, ","),
+		"remove_volumes":   strconv.FormatBool(opts.RemoveVolumes),
+	}
+	opHash := fmt.Sprintf("%s:%s:%d", op, bundleHash, time.Now().UnixNano())
+	stamp, serr := database.CreateDeploymentStampWithHash(ctx, stackID, string(op), opts.User, opHash, meta)
+	if serr != nil {
+		common.ErrorLog("stack op: stack %d: failed to record %s stamp: %v", stackID, op, serr)
+		stamp = nil
+	}
+	finish := func(opErr error) {
+		if stamp != nil {
+			status := "success"
+			if opErr != nil {
+				status = "failed"
+			}
+			_ = database.UpdateDeploymentStampStatus(ctx, stamp.ID, status)
+		}
+		publishStackOperationEvent(ctx, stackID, rawProjectName, op, opts, opErr)
+	}
+
+	// Minimal environment, as for deploys (no host env leakage)
+	bin := "docker"
+	dockerEnv := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + os.Getenv("HOME"),
+	}
+	if sopsAge := os.Getenv("SOPS_AGE_KEY"); sopsAge != "" {
+		dockerEnv = append(dockerEnv, "SOPS_AGE_KEY="+sopsAge)
+	}
+	if sopsAgeFile := os.Getenv("SOPS_AGE_KEY_FILE"); sopsAgeFile != "" {
+		dockerEnv = append(dockerEnv, "SOPS_AGE_KEY_FILE="+sopsAgeFile)
+	}
+	if herr == nil {
+		dockerURL, err := stackOpDockerURL(ctx, host)
+		if err != nil {
+			sendEvent("error", err.Error(), nil)
+			finish(err)
+			return err
+		}
+		if err := setupSSHConfigForDocker(host); err != nil {
+			sendEvent("error", fmt.Sprintf("Failed to setup SSH config: %v", err), nil)
+			finish(err)
+			return err
+		}
+		if dockerURL, err = CLIDockerHost(host, dockerURL); err != nil {
+			sendEvent("error", err.Error(), nil)
+			finish(err)
+			return err
+		}
+		if IsPodmanHost(host) && !swarmMode {
+			var podmanEnv []string
+			bin, args, podmanEnv = podmanComposeInvocation(host, dockerURL, args)
+			dockerEnv = append(dockerEnv, podmanEnv...)
+		} else {
+			dockerEnv = append(dockerEnv, "DOCKER_HOST="+dockerURL)
+		}
+		sendEvent("info", fmt.Sprintf("Using Docker host: %s (isolated environment)", dockerURL), nil)
+	} else {
+		common.DebugLog("stack op: no host for stack %d, using default Docker connection: %v", stackID, herr)
+		sendEvent("info", "Using default Docker connection (isolated environment)", nil)
+	}
+
+	sendEvent("info", fmt.Sprintf("Running: %s %s", bin, strings.Join(args, " ")), nil)
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Dir = stageDir
+	cmd.Env = dockerEnv
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		sendEvent("error", fmt.Sprintf("Failed to create stdout pipe: %v", err), nil)
+		finish(err)
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		sendEvent("error", fmt.Sprintf("Failed to create stderr pipe: %v", err), nil)
+		finish(err)
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		sendEvent("error", fmt.Sprintf("Failed to start %s: %v", bin, err), nil)
+		finish(err)
+		return err
+	}
+
+	var wg sync.WaitGroup
+	stream := func(kind string, r io.Reader) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if line := scanner.Text(); line != "" {
+				sendEvent(kind, line, nil)
+			}
+		}
+	}
+	wg.Add(2)
+	go stream("stdout", stdout)
+	go stream("stderr", stderr)
+	wg.Wait()
+	cmdErr := cmd.Wait()
+
+	finish(cmdErr)
+	if cmdErr != nil {
+		common.ErrorLog("stack op: %s of stack %s failed: %v", op, rawProjectName, cmdErr)
+		sendEvent("error", fmt.Sprintf("%s failed: %v", op, cmdErr), nil)
+		return cmdErr
+	}
+	common.InfoLog("stack op: %s of stack %s completed (user=%s)", op, rawProjectName, opts.User)
+	sendEvent("complete", fmt.Sprintf("%s of stack %s completed successfully", op, rawProjectName), map[string]interface{}{
+		"success":   true,
+		"stackID":   stackID,
+		"operation": string(op),
+	})
+	return nil
+}
+
+// publishStackOperationEvent emits stack.operation.succeeded or stack.operation.failed
+func publishStackOperationEvent(ctx context.Context, stackID int64, stackName string, op StackOperation, opts StackOperationOptions, err error) {
+	e := Event{Type: EventStackOpSucceeded, Stack: stackName, Subject: stackName, Data: map[string]any{
+		"stack_id": stackID, "operation": string(op), "user": opts.User}}
+	if op == StackOpDown {
+		e.Data["remove_volumes"] = opts.RemoveVolumes
+	}
+	if h, herr := getHostForStack(ctx, stackID); herr == nil {
+		e.Host = h.Name
+	}
+	if err != nil {
+		e.Type = EventStackOpFailed
+		e.Data["error"] = err.Error()
+	}
+	PublishEvent(ctx, e)
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStackOperationArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      StackOperation
+		opts    StackOperationOptions
+		swarm   bool
+		want    []string
+		wantErr bool
+	}{
+		{"down", StackOpDown, StackOperationOptions{}, false, []string{"down", "--remove-orphans"}, false},
+		{"down with volumes", StackOpDown, StackOperationOptions{RemoveVolumes: true}, false, []string{"down", "--remove-orphans", "--volumes"}, false},
+		{"restart", StackOpRestart, StackOperationOptions{}, false, []string{"restart"}, false},
+		{"pull", StackOpPull, StackOperationOptions{}, false, []string{"pull"}, false},
+		{"unknown", StackOperation("nuke"), StackOperationOptions{}, false, nil, true},
+		{"swarm down", StackOpDown, StackOperationOptions{}, true, nil, false},
+		{"swarm down with volumes", StackOpDown, StackOperationOptions{RemoveVolumes: true}, true, nil, true},
+		{"swarm restart", StackOpRestart, StackOperationOptions{}, true, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stackOperationArgs(tt.op, tt.opts, tt.swarm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("args = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,531 @@
+// services/stack_templates.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"github.com/goccy/go-yaml"
+)
+
+/*
+Stack templates: a catalog of reusable stacks kept in the IaC repo.
+
+  <root>/templates/<name>/template.yml     manifest (description + parameters)
+  <root>/templates/<name>/*.tmpl           rendered with text/template, suffix stripped
+  <root>/templates/<name>/*                anything else is copied verbatim
+
+Template context: .Params.<NAME>, .Stack, .ScopeKind, .ScopeName, .Template.
+Secret parameters never reach rendered files; .Params.<SECRET> renders as
+${SECRET} and the value goes into the stack's SOPS-encrypted .env, together
+with any non-secret parameter that sets env: true.
+*/
+
+const (
+	TemplatesDirEnv     = "DD_UI_TEMPLATES_DIR"
+	DefaultTemplatesDir = "templates"
+	templateManifest    = "template.yml"
+)
+
+// StackTemplateParam declares one template parameter
+type StackTemplateParam struct {
+	Name        string   `yaml:"name" json:"name"`
+	Type        string   `yaml:"type" json:"type"` // string|int|bool|port|enum
+	Description string   `yaml:"description" json:"description,omitempty"`
+	Default     any      `yaml:"default" json:"default,omitempty"`
+	Required    bool     `yaml:"required" json:"required"`
+	Secret      bool     `yaml:"secret" json:"secret"`
+	Env         bool     `yaml:"env" json:"env"`                     // also write to .env
+	Options     []string `yaml:"options" json:"options,omitempty"`   // enum values
+	Pattern     string   `yaml:"pattern" json:"pattern,omitempty"`   // regexp for string values
+	Generate    int      `yaml:"generate" json:"generate,omitempty"` // random hex length when empty (secrets)
+}
+
+// StackTemplate is a catalog entry
+type StackTemplate struct {
+	Name        string               `yaml:"name" json:"name"`
+	Description string               `yaml:"description" json:"description,omitempty"`
+	Version     string               `yaml:"version" json:"version,omitempty"`
+	Tags        []string             `yaml:"tags" json:"tags,omitempty"`
+	Parameters  []StackTemplateParam `yaml:"parameters" json:"parameters"`
+	Files       []string             `yaml:"-" json:"files"`
+	dir         string
+}
+
+// TemplateInstantiateRequest describes a template instantiation
+type TemplateInstantiateRequest struct {
+	ScopeKind      string         `json:"scope_kind"` // optional: host|group (inferred from inventory)
+	ScopeName      string         `json:"scope_name"`
+	StackName      string         `json:"stack_name"`
+	Params         map[string]any `json:"params"`
+	Deploy         bool           `json:"deploy"`
+	Overwrite      bool           `json:"overwrite"`
+	AllowPlaintext bool           `json:"allow_plaintext"`
+}
+
+// TemplateInstantiateResult describes what was written
+type TemplateInstantiateResult struct {
+	StackID    int64    `json:"stack_id"`
+	Template   string   `json:"template"`
+	ScopeKind  string   `json:"scope_kind"`
+	ScopeName  string   `json:"scope_name"`
+	StackName  string   `json:"stack_name"`
+	RelPath    string   `json:"rel_path"`
+	Files      []string `json:"files"`
+	SecretKeys []string `json:"secret_keys,omitempty"`
+	EnvSops    bool     `json:"env_sops"`
+	Deploy     bool     `json:"deploy"`
+}
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateInvalid  = errors.New("invalid template parameters")
+	ErrTemplateExists   = errors.New("stack directory is not empty")
+
+	templateNameRe  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	templateParamRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// TemplatesRoot returns the absolute templates directory inside the IaC repo
+func TemplatesRoot() (string, error) {
+	root := strings.TrimSpace(common.Env(IacDefaultRootEnv, IacDefaultRoot))
+	return joinUnderLocal(root, strings.TrimSpace(common.Env(TemplatesDirEnv, DefaultTemplatesDir)))
+}
+
+// ListStackTemplates returns every valid template in the catalog, sorted by name
+func ListStackTemplates() ([]StackTemplate, error) {
+	base, err := TemplatesRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []StackTemplate{}, nil
+		}
+		return nil, err
+	}
+	out := []StackTemplate{}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		t, err := loadStackTemplate(filepath.Join(base, e.Name()), e.Name())
+		if err != nil {
+			common.WarnLog("templates: skipping %s: %v", e.Name(), err)
+			continue
+		}
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// GetStackTemplate loads one template by directory name
+func GetStackTemplate(name string) (*StackTemplate, error) {
+	if !templateNameRe.MatchString(name) {
+		return nil, ErrTemplateNotFound
+	}
+	base, err := TemplatesRoot()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(base, name)
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil, ErrTemplateNotFound
+	}
+	return loadStackTemplate(dir, name)
+}
+
+func loadStackTemplate(dir, name string) (*StackTemplate, error) {
+	b, err := os.ReadFile(filepath.Join(dir, templateManifest))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", templateManifest, err)
+	}
+	t := &StackTemplate{}
+	if err := yaml.Unmarshal(b, t); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", templateManifest, err)
+	}
+	t.Name = name // the directory is the identity
+	t.dir = dir
+
+	seen := map[string]bool{}
+	for i := range t.Parameters {
+		p := &t.Parameters[i]
+		if !templateParamRe.MatchString(p.Name) {
+			return nil, fmt.Errorf("parameter %q: name must be a valid env var name", p.Name)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("parameter %q declared twice", p.Name)
+		}
+		seen[p.Name] = true
+		p.Type = strings.ToLower(strings.TrimSpace(p.Type))
+		switch p.Type {
+		case "":
+			p.Type = "string"
+		case "string", "int", "bool", "port":
+		case "enum":
+			if len(p.Options) == 0 {
+				return nil, fmt.Errorf("parameter %q: enum requires options", p.Name)
+			}
+		default:
+			return nil, fmt.Errorf("parameter %q: unknown type %q", p.Name, p.Type)
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return nil, fmt.Errorf("parameter %q: bad pattern: %v", p.Name, err)
+			}
+		}
+	}
+
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if rel == templateManifest {
+			return nil
+		}
+		t.Files = append(t.Files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(t.Files)
+	if len(t.Files) == 0 {
+		return nil, errors.New("template has no files")
+	}
+	return t, nil
+}
+
+// resolveParams validates user values against the template and applies defaults.
+// Returns the normalized string values and the list of validation problems.
+func (t *StackTemplate) resolveParams(in map[string]any) (map[string]string, []string) {
+	vals := map[string]string{}
+	var problems []string
+	declared := map[string]bool{}
+	for _, p := range t.Parameters {
+		declared[p.Name] = true
+		raw, ok := in[p.Name]
+		val := ""
+		if ok && raw != nil {
+			val = strings.TrimSpace(toString(raw))
+		}
+		if val == "" && p.Default != nil {
+			val = toString(p.Default)
+		}
+		if val == "" && p.Generate > 0 {
+			buf := make([]byte, (p.Generate+1)/2)
+			if _, err := rand.Read(buf); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: generate: %v", p.Name, err))
+				continue
+			}
+			val = hex.EncodeToString(buf)[:p.Generate]
+		}
+		if val == "" {
+			if p.Required {
+				problems = append(problems, fmt.Sprintf("%s: required", p.Name))
+			}
+			vals[p.Name] = ""
+			continue
+		}
+
+		switch p.Type {
+		case "int":
+			if _, err := strconv.ParseInt(val, 10, 64); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: must be an integer", p.Name))
+			}
+		case "port":
+			if n, err := strconv.Atoi(val); err != nil || n < 1 || n > 65535 {
+				problems = append(problems, fmt.Sprintf("%s: must be a port (1-65535)", p.Name))
+			}
+		case "bool":
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: must be true or false", p.Name))
+			}
+			val = strconv.FormatBool(b)
+		case "enum":
+			found := false
+			for _, o := range p.Options {
+				if o == val {
+					found = true
+					break
+				}
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("%s: must be one of %s", p.Name, strings.Join(p.Options, ", ")))
+			}
+		}
+		if p.Pattern != "" && !regexp.MustCompile(p.Pattern).MatchString(val) {
+			problems = append(problems, fmt.Sprintf("%s: does not match %s", p.Name, p.Pattern))
+		}
+		vals[p.Name] = val
+	}
+	for k := range in {
+		if !declared[k] {
+			problems = append(problems, fmt.Sprintf("%s: unknown parameter", k))
+		}
+	}
+	sort.Strings(problems)
+	return vals, problems
+}
+
+// InstantiateStackTemplate renders a template into docker-compose/<scope>/<stack>,
+// encrypts secret parameters into .env and registers the stack. Deployment (when
+// requested) is left to the caller so it can run in the background.
+func InstantiateStackTemplate(ctx context.Context, name string, req TemplateInstantiateRequest) (*TemplateInstantiateResult, error) {
+	t, err := GetStackTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ScopeName = strings.TrimSpace(req.ScopeName)
+	req.StackName = strings.TrimSpace(req.StackName)
+	for _, s := range []string{req.ScopeName, req.StackName} {
+		if s == "" || strings.ContainsAny(s, `/\`) || s == "." || s == ".." {
+			return nil, fmt.Errorf("%w: scope_name and stack_name must be plain names", ErrTemplateInvalid)
+		}
+	}
+	scopeKind, err := resolveTemplateScope(ctx, req.ScopeKind, req.ScopeName)
+	if err != nil {
+		return nil, err
+	}
+	if req.Deploy && scopeKind != "host" {
+		return nil, fmt.Errorf("%w: deploy is only supported for host-scoped stacks", ErrTemplateInvalid)
+	}
+
+	vals, problems := t.resolveParams(req.Params)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateInvalid, strings.Join(problems, "; "))
+	}
+
+	root := strings.TrimSpace(common.Env(IacDefaultRootEnv, IacDefaultRoot))
+	dirname := strings.TrimSpace(common.Env(DockerDirEnv, DefaultDockerDir))
+	relPath := filepath.ToSlash(filepath.Join(dirname, req.ScopeName, req.StackName))
+	stackDir, err := joinUnderLocal(root, relPath)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Overwrite {
+		if entries, err := os.ReadDir(stackDir); err == nil && len(entries) > 0 {
+			return nil, ErrTemplateExists
+		}
+	}
+
+	// Render context: secrets are only ever referenced, never inlined
+	renderParams := map[string]string{}
+	envVals := map[string]string{}
+	var secretKeys []string
+	for _, p := range t.Parameters {
+		v := vals[p.Name]
+		if p.Secret {
+			renderParams[p.Name] = "${" + p.Name + "}"
+			if v != "" {
+				secretKeys = append(secretKeys, p.Name)
+			}
+		} else {
+			renderParams[p.Name] = v
+		}
+		if (p.Secret || p.Env) && v != "" {
+			envVals[p.Name] = v
+		}
+	}
+	tctx := map[string]any{
+		"Params":    renderParams,
+		"Stack":     req.StackName,
+		"ScopeKind": scopeKind,
+		"ScopeName": req.ScopeName,
+		"Template":  t.Name,
+	}
+
+	// Render everything in memory first so a bad template leaves no partial stack
+	type outFile struct {
+		rel  string
+		data []byte
+		mode os.FileMode
+	}
+	var files []outFile
+	var baseEnv []byte
+	for _, f := range t.Files {
+		src := filepath.Join(t.dir, filepath.FromSlash(f))
+		b, err := os.ReadFile(src)
+		if err != nil {
+			return nil, err
+		}
+		mode := os.FileMode(0o644)
+		if fi, err := os.Stat(src); err == nil && fi.Mode()&0o111 != 0 {
+			mode = 0o755
+		}
+		dst := f
+		if strings.HasSuffix(f, ".tmpl") {
+			dst = strings.TrimSuffix(f, ".tmpl")
+			tpl, err := template.New(f).Option("missingkey=error").Parse(string(b))
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", f, err)
+			}
+			var buf bytes.Buffer
+			if err := tpl.Execute(&buf, tctx); err != nil {
+				return nil, fmt.Errorf("template %s: %w", f, err)
+			}
+			b = buf.Bytes()
+		}
+		if dst == ".env" {
+			baseEnv = b
+			continue
+		}
+		files = append(files, outFile{rel: dst, data: b, mode: mode})
+	}
+
+	// ---- Write files ----
+	if err := ensureDir(stackDir, 0o755); err != nil {
+		return nil, err
+	}
+	res := &TemplateInstantiateResult{
+		Template: t.Name, ScopeKind: scopeKind, ScopeName: req.ScopeName, StackName: req.StackName,
+		RelPath: relPath, Deploy: req.Deploy,
+	}
+	for _, f := range files {
+		full, err := joinUnderLocal(stackDir, f.rel)
+		if err != nil {
+			return nil, err
+		}
+		if err := ensureDir(filepath.Dir(full), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(full, f.data, f.mode); err != nil {
+			return nil, err
+		}
+		res.Files = append(res.Files, f.rel)
+	}
+
+	envPath := filepath.Join(stackDir, ".env")
+	if len(envVals) > 0 || len(baseEnv) > 0 {
+		var b strings.Builder
+		b.Write(baseEnv)
+		if len(baseEnv) > 0 && !bytes.HasSuffix(baseEnv, []byte("\n")) {
+			b.WriteString("\n")
+		}
+		keys := make([]string, 0, len(envVals))
+		for k := range envVals {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, "%s=%s\n", k, dotenvQuote(envVals[k]))
+		}
+		if len(secretKeys) > 0 {
+			encrypted, err := writeSopsDotenv(ctx, stackDir, envPath, []byte(b.String()))
+			if err != nil {
+				if !req.AllowPlaintext {
+					return nil, fmt.Errorf("encrypt .env: %w", err)
+				}
+				common.WarnLog("templates: SOPS encryption failed for %s/%s, writing plaintext .env: %v", req.ScopeName, req.StackName, err)
+				if err := writeFileSecure(envPath, []byte(b.String()), 0o600); err != nil {
+					return nil, err
+				}
+			}
+			res.EnvSops = encrypted
+		} else if err := writeFileSecure(envPath, []byte(b.String()), 0o600); err != nil {
+			return nil, err
+		}
+		res.SecretKeys = secretKeys
+		res.Files = append(res.Files, ".env")
+	}
+
+	// ---- Register IaC stack + files ----
+	composeFile := ""
+	for _, c := range []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"} {
+		if slices.Contains(res.Files, c) {
+			composeFile = c
+			break
+		}
+	}
+	deployKind := "unmanaged"
+	if composeFile != "" {
+		deployKind = "compose"
+	}
+	sopsStatus := "none"
+	if res.EnvSops {
+		sopsStatus = "all"
+	}
+
+	repoID, err := UpsertIacRepoLocal(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	stackID, err := UpsertIacStack(ctx, repoID, scopeKind, req.ScopeName, req.StackName, relPath, composeFile, deployKind, "", sopsStatus, true)
+	if err != nil {
+		return nil, err
+	}
+	res.StackID = stackID
+	for _, f := range res.Files {
+		role := "other"
+		sops := false
+		switch {
+		case f == composeFile:
+			role = "compose"
+		case f == ".env":
+			role = "env"
+			sops = res.EnvSops
+		case f == "deploy.sh" || f == "pre.sh" || f == "post.sh":
+			role = "script"
+		}
+		sum, size := sha256File(filepath.Join(stackDir, filepath.FromSlash(f)))
+		if err := UpsertIacFile(ctx, stackID, role, relPath+"/"+f, sops, sum, size); err != nil {
+			return nil, err
+		}
+	}
+
+	common.InfoLog("templates: instantiated %s as %s/%s (stack=%d files=%d secrets=%d sops=%v)",
+		t.Name, req.ScopeName, req.StackName, stackID, len(res.Files), len(secretKeys), res.EnvSops)
+	return res, nil
+}
+
+// resolveTemplateScope validates (or infers) the scope kind for a scope name
+func resolveTemplateScope(ctx context.Context, kind, name string) (string, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	switch kind {
+	case "", "host", "group":
+	default:
+		return "", fmt.Errorf("%w: scope_kind must be host or group", ErrTemplateInvalid)
+	}
+	if kind != "group" {
+		if _, err := database.GetHostByName(ctx, name); err == nil {
+			return "host", nil
+		}
+		if kind == "host" {
+			return "", fmt.Errorf("%w: unknown host %q", ErrTemplateInvalid, name)
+		}
+	}
+	if im := GetInventoryManager(); im != nil {
+		if groups, err := im.GetGroups(); err == nil {
+			for _, g := range groups {
+				if g.Name == name {
+					return "group", nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: unknown scope %q", ErrTemplateInvalid, name)
+}
@@ -0,0 +1,339 @@
+// services/swarm.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dd-ui/common"
+	"dd-ui/database"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/goccy/go-yaml"
+)
+
+/*
+Swarm support for hosts flagged as swarm managers in the inventory:
+
+  dd_ui_swarm_manager: true
+
+  - deploy: `docker stack deploy` against the manager with the staged (SOPS-decrypted)
+    bundle; the staged .env is exported for ${VAR} interpolation since stack deploy
+    does not read .env files itself.
+  - scan: nodes, services and tasks are inventoried into swarm_* tables.
+  - drift: desired replicas/images from the rendered compose vs service specs, plus
+    running task counts.
+*/
+
+const swarmNamespaceLabel = "com.docker.stack.namespace"
+
+// IsSwarmManager reports whether a host is flagged as a swarm manager
+func IsSwarmManager(h database.HostRow) bool {
+	v := strings.ToLower(strings.TrimSpace(h.Vars["dd_ui_swarm_manager"]))
+	return v == "true" || v == "yes" || v == "1"
+}
+
+// swarmDeployArgs builds `docker stack deploy` arguments for the staged compose files
+func swarmDeployArgs(namespace string, files []string) []string {
+	args := []string{"stack", "deploy", "--prune", "--with-registry-auth", "--detach=false"}
+	for _, f := range files {
+		args = append(args, "-c", f)
+	}
+	return append(args, namespace)
+}
+
+// swarmInterpolationEnv exports the staged (already decrypted) .env so `docker stack deploy`
+// can interpolate ${VAR} references the same way compose would.
+func swarmInterpolationEnv(stageDir string) []string {
+	b, err := os.ReadFile(filepath.Join(stageDir, ".env"))
+	if err != nil {
+		return nil
+	}
+	vars := parseEnvFileContent(b)
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		switch k {
+		case "PATH", "HOME", "DOCKER_HOST", "DOCKER_CONTEXT":
+			continue // never let stack env override how we reach the daemon
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, k+"="+vars[k])
+	}
+	return out
+}
+
+// ScanSwarm inventories nodes, services and tasks from a manager host
+func ScanSwarm(ctx context.Context, h database.HostRow, cli *client.Client) error {
+	nodes, err := cli.NodeList(ctx, types.NodeListOptions{})
+	if err != nil {
+		database.ScanLog(ctx, h.ID, "error", "swarm node list failed", map[string]any{"error": err.Error()})
+		return err
+	}
+	nodeIDs := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		row := database.SwarmNodeRow{
+			NodeID:        n.ID,
+			Hostname:      n.Description.Hostname,
+			Role:          string(n.Spec.Role),
+			Availability:  string(n.Spec.Availability),
+			State:         string(n.Status.State),
+			Addr:          n.Status.Addr,
+			EngineVersion: n.Description.Engine.EngineVersion,
+			Labels:        n.Spec.Labels,
+		}
+		if n.ManagerStatus != nil {
+			row.Leader = n.ManagerStatus.Leader
+		}
+		if err := database.UpsertSwarmNode(ctx, h.ID, row); err != nil {
+			database.ScanLog(ctx, h.ID, "warn", "swarm node upsert failed", map[string]any{"node": n.ID, "error": err.Error()})
+			continue
+		}
+		nodeIDs = append(nodeIDs, n.ID)
+	}
+
+	svcs, err := cli.ServiceList(ctx, types.ServiceListOptions{Status: true})
+	if err != nil {
+		database.ScanLog(ctx, h.ID, "error", "swarm service list failed", map[string]any{"error": err.Error()})
+		return err
+	}
+	svcIDs := make([]string, 0, len(svcs))
+	for _, s := range svcs {
+		row := database.SwarmServiceRow{
+			ServiceID:      s.ID,
+			Name:           s.Spec.Name,
+			StackNamespace: s.Spec.Labels[swarmNamespaceLabel],
+			Labels:         s.Spec.Labels,
+		}
+		if s.Spec.TaskTemplate.ContainerSpec != nil {
+			row.Image = s.Spec.TaskTemplate.ContainerSpec.Image
+		}
+		row.Mode, row.DesiredReplicas = swarmServiceMode(s)
+		if s.ServiceStatus != nil {
+			row.RunningTasks = int(s.ServiceStatus.RunningTasks)
+			if row.Mode == "global" {
+				row.DesiredReplicas = int(s.ServiceStatus.DesiredTasks)
+			}
+		}
+		if s.UpdateStatus != nil {
+			row.UpdateState = string(s.UpdateStatus.State)
+		}
+		for _, p := range s.Endpoint.Ports {
+			row.Ports = append(row.Ports, map[string]any{
+				"PublishedPort": p.PublishedPort, "TargetPort": p.TargetPort,
+				"Protocol": string(p.Protocol), "PublishMode": string(p.PublishMode),
+			})
+		}
+		created, updated := s.CreatedAt, s.UpdatedAt
+		row.CreatedTS, row.UpdatedTS = &created, &updated
+		if err := database.UpsertSwarmService(ctx, h.ID, row); err != nil {
+			database.ScanLog(ctx, h.ID, "warn", "swarm service upsert failed", map[string]any{"service": s.Spec.Name, "error": err.Error()})
+			continue
+		}
+		svcIDs = append(svcIDs, s.ID)
+	}
+
+	tasks, err := cli.TaskList(ctx, types.TaskListOptions{Filters: filters.NewArgs()})
+	if err != nil {
+		database.ScanLog(ctx, h.ID, "error", "swarm task list failed", map[string]any{"error": err.Error()})
+		return err
+	}
+	taskIDs := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		row := database.SwarmTaskRow{
+			TaskID:       t.ID,
+			ServiceID:    t.ServiceID,
+			NodeID:       t.NodeID,
+			Slot:         t.Slot,
+			DesiredState: string(t.DesiredState),
+			State:        string(t.Status.State),
+			Message:      t.Status.Message,
+			Error:        t.Status.Err,
+		}
+		if t.Spec.ContainerSpec != nil {
+			row.Image = t.Spec.ContainerSpec.Image
+		}
+		if t.Status.ContainerStatus != nil {
+			row.ContainerID = t.Status.ContainerStatus.ContainerID
+		}
+		ts := t.Status.Timestamp
+		row.UpdatedTS = &ts
+		if err := database.UpsertSwarmTask(ctx, h.ID, row); err != nil {
+			database.ScanLog(ctx, h.ID, "warn", "swarm task upsert failed", map[string]any{"task": t.ID, "error": err.Error()})
+			continue
+		}
+		taskIDs = append(taskIDs, t.ID)
+	}
+
+	for kind, keep := range map[string][]string{"nodes": nodeIDs, "services": svcIDs, "tasks": taskIDs} {
+		if n, err := database.PruneSwarmRows(ctx, h.ID, kind, keep); err == nil && n > 0 {
+			database.ScanLog(ctx, h.ID, "info", "pruned missing swarm "+kind, map[string]any{"count": n})
+		}
+	}
+
+	database.ScanLog(ctx, h.ID, "info", "swarm scan complete",
+		map[string]any{"nodes": len(nodeIDs), "services": len(svcIDs), "tasks": len(taskIDs)})
+	return nil
+}
+
+// swarmServiceMode returns the service mode and, for replicated services, the replica count
+func swarmServiceMode(s swarm.Service) (string, int) {
+	switch {
+	case s.Spec.Mode.Replicated != nil:
+		if s.Spec.Mode.Replicated.Replicas != nil {
+			return "replicated", int(*s.Spec.Mode.Replicated.Replicas)
+		}
+		return "replicated", 1
+	case s.Spec.Mode.Global != nil:
+		return "global", 0
+	case s.Spec.Mode.ReplicatedJob != nil:
+		return "replicated-job", 0
+	case s.Spec.Mode.GlobalJob != nil:
+		return "global-job", 0
+	}
+	return "", 0
+}
+
+// swarmDesiredService is what the compose bundle asks for
+type swarmDesiredService struct {
+	Image    string
+	Global   bool
+	Replicas int
+}
+
+// desiredSwarmServices reads image + deploy.mode/replicas from the staged compose files
+// (later files override earlier ones), resolving ${VAR} from the staged .env.
+func desiredSwarmServices(stageDir string, files []string) (map[string]swarmDesiredService, error) {
+	rootEnv := map[string]string{}
+	if b, err := os.ReadFile(filepath.Join(stageDir, ".env")); err == nil {
+		rootEnv = parseEnvFileContent(b)
+	}
+
+	type doc struct {
+		Services map[string]struct {
+			Image  string `yaml:"image"`
+			Deploy struct {
+				Mode     string `yaml:"mode"`
+				Replicas any    `yaml:"replicas"`
+			} `yaml:"deploy"`
+		} `yaml:"services"`
+	}
+	out := map[string]swarmDesiredService{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var d doc
+		if err := yaml.Unmarshal(b, &d); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(f), err)
+		}
+		for name, svc := range d.Services {
+			cur, ok := out[name]
+			if !ok {
+				cur = swarmDesiredService{Replicas: 1}
+			}
+			if svc.Image != "" {
+				cur.Image = resolveVariablesWithPrecedence(svc.Image, rootEnv, nil, nil)
+			}
+			if m := resolveVariablesWithPrecedence(svc.Deploy.Mode, rootEnv, nil, nil); m != "" {
+				cur.Global = m == "global"
+			}
+			if svc.Deploy.Replicas != nil {
+				r := resolveVariablesWithPrecedence(toString(svc.Deploy.Replicas), rootEnv, nil, nil)
+				if n, err := strconv.Atoi(strings.TrimSpace(r)); err == nil {
+					cur.Replicas = n
+				}
+			}
+			out[name] = cur
+		}
+	}
+	return out, nil
+}
+
+// normalizeImageRef strips digests and default registry/tag so spec and compose refs compare equal
+func normalizeImageRef(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	ref = strings.TrimPrefix(ref, "docker.io/")
+	ref = strings.TrimPrefix(ref, "library/")
+	if slash := strings.LastIndex(ref, "/"); !strings.Contains(ref[slash+1:], ":") {
+		ref += ":latest"
+	}
+	return ref
+}
+
+// DetectSwarmDrift compares the desired services of a staged bundle with the live swarm state
+func DetectSwarmDrift(ctx context.Context, cli *client.Client, namespace, stageDir string, files []string) (bool, string, error) {
+	desired, err := desiredSwarmServices(stageDir, files)
+	if err != nil {
+		return false, "", err
+	}
+
+	ff := filters.NewArgs()
+	ff.Add("label", swarmNamespaceLabel+"="+namespace)
+	svcs, err := cli.ServiceList(ctx, types.ServiceListOptions{Filters: ff, Status: true})
+	if err != nil {
+		return false, "Unable to verify swarm state", nil
+	}
+	live := map[string]swarm.Service{}
+	for _, s := range svcs {
+		live[strings.TrimPrefix(s.Spec.Name, namespace+"_")] = s
+	}
+
+	var reasons []string
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := desired[name]
+		s, ok := live[name]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("service %s missing", name))
+			continue
+		}
+		mode, replicas := swarmServiceMode(s)
+		if want.Global != (mode == "global") {
+			reasons = append(reasons, fmt.Sprintf("service %s: mode %s, want %s", name, mode, map[bool]string{true: "global", false: "replicated"}[want.Global]))
+		} else if !want.Global && replicas != want.Replicas {
+			reasons = append(reasons, fmt.Sprintf("service %s: replicas %d, want %d", name, replicas, want.Replicas))
+		}
+		if s.Spec.TaskTemplate.ContainerSpec != nil && want.Image != "" &&
+			normalizeImageRef(s.Spec.TaskTemplate.ContainerSpec.Image) != normalizeImageRef(want.Image) {
+			reasons = append(reasons, fmt.Sprintf("service %s: image %s, want %s", name, s.Spec.TaskTemplate.ContainerSpec.Image, want.Image))
+		}
+		if st := s.ServiceStatus; st != nil && st.RunningTasks < st.DesiredTasks {
+			reasons = append(reasons, fmt.Sprintf("service %s: %d/%d tasks running", name, st.RunningTasks, st.DesiredTasks))
+		}
+	}
+	var extra []string
+	for name := range live {
+		if _, ok := desired[name]; !ok {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		reasons = append(reasons, fmt.Sprintf("service %s not in IaC", name))
+	}
+
+	if len(reasons) == 0 {
+		return false, "No drift detected", nil
+	}
+	common.DebugLog("swarm drift: namespace=%s reasons=%v", namespace, reasons)
+	return true, strings.Join(reasons, "; "), nil
+}
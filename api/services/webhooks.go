@@ -0,0 +1,290 @@
+// services/webhooks.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"dd-ui/common"
+	"dd-ui/database"
+)
+
+// Webhook request headers. The signature is "t=<unix>,v1=<hex>" where v1 is
+// HMAC-SHA256(secret, "<unix>.<body>"); receivers should reject stale timestamps.
+const (
+	WebhookSignatureHeader = "X-DDUI-Signature"
+	WebhookEventHeader     = "X-DDUI-Event"
+	WebhookDeliveryHeader  = "X-DDUI-Delivery"
+)
+
+// NewWebhookSecret returns a random signing secret
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// webhookLookupIP resolves webhook hosts (replaced in tests)
+var webhookLookupIP = net.DefaultResolver.LookupIPAddr
+
+// webhookBlockedNets are ranges webhooks may not reach unless DD_UI_WEBHOOK_ALLOW_PRIVATE is set,
+// on top of loopback, link-local (cloud metadata lives at 169.254.169.254), RFC 1918 and ULA
+var webhookBlockedNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15"} {
+		_, n, _ := net.ParseCIDR(c)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+func webhookAllowPrivate() bool { return common.EnvBool("DD_UI_WEBHOOK_ALLOW_PRIVATE", "false") }
+
+// webhookBlockedIP reports whether ip is loopback, link-local, private or otherwise internal
+func webhookBlockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range webhookBlockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateWebhookURL accepts absolute http(s) URLs. Unless DD_UI_WEBHOOK_ALLOW_PRIVATE is set,
+// the host must resolve to public addresses only; deliveries check the dialed address again.
+func ValidateWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if webhookAllowPrivate() {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if webhookBlockedIP(ip) {
+			return fmt.Errorf("url points to a private address %s (set DD_UI_WEBHOOK_ALLOW_PRIVATE=true to allow)", ip)
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := webhookLookupIP(ctx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s: %v", host, err)
+	}
+	for _, a := range addrs {
+		if webhookBlockedIP(a.IP) {
+			return fmt.Errorf("url host %s resolves to a private address %s (set DD_UI_WEBHOOK_ALLOW_PRIVATE=true to allow)", host, a.IP)
+		}
+	}
+	return nil
+}
+
+// webhookDialControl refuses connections to blocked addresses. It sees the address actually
+// dialed, so a host that resolved to a public address at validation time and to an internal
+// one now (DNS rebinding), or a redirect to an internal URL, is still refused.
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip != nil && webhookBlockedIP(ip) && !webhookAllowPrivate() {
+		return fmt.Errorf("webhook target %s is a private address (set DD_UI_WEBHOOK_ALLOW_PRIVATE=true to allow)", ip)
+	}
+	return nil
+}
+
+// webhookHTTPClient dials directly (no HTTP(S)_PROXY, which would hide the target address)
+// with webhookDialControl on every connection
+func webhookHTTPClient(timeout time.Duration) *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.Proxy = nil
+	tr.DialContext = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: webhookDialControl}).DialContext
+	return &http.Client{Timeout: timeout, Transport: tr}
+}
+
+// SignWebhook computes the signature header value for body at ts
+func SignWebhook(secret string, ts time.Time, body []byte) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// PingWebhook queues a webhook.ping event for one webhook and returns the delivery id
+func PingWebhook(ctx context.Context, w database.WebhookRow, by string) (int64, error) {
+	e := Event{Type: EventWebhookPing, Time: time.Now().UTC(), Subject: w.Name, Data: map[string]any{"webhook_id": w.ID, "requested_by": by}}
+	id, err := database.InsertEvent(ctx, e)
+	if err != nil {
+		return 0, err
+	}
+	did, err := database.CreateWebhookDelivery(ctx, w.ID, id)
+	if err == nil {
+		WakeWebhookDispatcher()
+	}
+	return did, err
+}
+
+// WakeWebhookDispatcher makes the dispatcher check for due deliveries now (this replica only;
+// other replicas' deliveries are picked up on the next poll)
+func WakeWebhookDispatcher() {
+	select {
+	case eventWake <- struct{}{}:
+	default:
+	}
+}
+
+// ---- dispatcher ----
+
+func webhookMaxAttempts() int { return max(1, common.EnvInt("DD_UI_WEBHOOK_MAX_ATTEMPTS", 8)) }
+
+// webhookBackoff is 30s doubling per failed attempt, capped at 1h
+func webhookBackoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// StartWebhookDispatcher delivers queued webhook events (leader only), polling every 2s and
+// whenever an event is queued locally. Deliveries run a few at a time
+// (DD_UI_WEBHOOK_CONCURRENCY, default 4) with DD_UI_WEBHOOK_TIMEOUT (default 10s) per request.
+// Also prunes events older than DD_UI_EVENTS_RETENTION (default 168h) hourly.
+func StartWebhookDispatcher(ctx context.Context) {
+	timeout := envDuration("DD_UI_WEBHOOK_TIMEOUT", 10*time.Second)
+	concurrency := max(1, common.EnvInt("DD_UI_WEBHOOK_CONCURRENCY", 4))
+	retention := envDuration("DD_UI_EVENTS_RETENTION", 168*time.Hour)
+	client := webhookHTTPClient(timeout)
+	common.InfoLog("webhooks: dispatcher started (timeout=%s max_attempts=%d events_retention=%s)", timeout, webhookMaxAttempts(), retention)
+
+	go func() {
+		poll := time.NewTicker(2 * time.Second)
+		defer poll.Stop()
+		var lastPrune time.Time
+		for {
+			select {
+			case <-poll.C:
+			case <-eventWake:
+			case <-ctx.Done():
+				return
+			}
+
+			// claim long enough to cover a full request; unfinished claims retry after it
+			due, err := database.ClaimDueWebhookDeliveries(ctx, 20, timeout+30*time.Second)
+			if err != nil {
+				common.DebugLog("webhooks: claim deliveries failed: %v", err)
+			}
+			var wg sync.WaitGroup
+			sem := make(chan struct{}, concurrency)
+			for _, d := range due {
+				wg.Add(1)
+				sem <- struct{}{}
+				go func(d database.WebhookDeliveryRow) {
+					defer wg.Done()
+					defer func() { <-sem }()
+					deliverWebhook(ctx, client, d)
+				}(d)
+			}
+			wg.Wait()
+
+			if retention > 0 && time.Since(lastPrune) > time.Hour {
+				lastPrune = time.Now()
+				if n, err := database.PruneEvents(ctx, time.Now().Add(-retention)); err != nil {
+					common.ErrorLog("events: prune failed: %v", err)
+				} else if n > 0 {
+					common.InfoLog("events: pruned %d events older than %s", n, retention)
+				}
+			}
+		}
+	}()
+}
+
+// deliverWebhook performs one attempt and records the outcome
+func deliverWebhook(ctx context.Context, client *http.Client, d database.WebhookDeliveryRow) {
+	w, err := database.GetWebhook(ctx, d.WebhookID)
+	if err != nil {
+		return // deleted meanwhile; the delivery went with it
+	}
+	e, err := database.GetEvent(ctx, d.EventID)
+	if err != nil {
+		return
+	}
+	attempt := database.WebhookAttempt{}
+	if !w.Enabled && e.Type != EventWebhookPing {
+		attempt.Status, attempt.Error = "failed", "webhook disabled"
+		_ = database.RecordWebhookAttempt(ctx, d.ID, attempt)
+		return
+	}
+
+	body, _ := json.Marshal(e)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "DD-UI-Webhook/1")
+		req.Header.Set(WebhookEventHeader, e.Type)
+		req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(d.ID, 10))
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(w.Secret, time.Now(), body))
+
+		start := time.Now()
+		resp, rerr := client.Do(req)
+		attempt.DurationMs = int(time.Since(start).Milliseconds())
+		if rerr != nil {
+			err = rerr
+		} else {
+			snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			code := resp.StatusCode
+			attempt.StatusCode = &code
+			attempt.ResponseBody = string(snippet)
+			if code < 200 || code > 299 {
+				err = fmt.Errorf("HTTP %d", code)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return // shutting down or lost leadership; the claim expires and another attempt follows
+	}
+
+	switch {
+	case err == nil:
+		attempt.Status = "succeeded"
+	case d.Attempts+1 >= webhookMaxAttempts():
+		attempt.Status, attempt.Error = "failed", err.Error()
+		common.WarnLog("webhooks: giving up on delivery %d of %s to %s after %d attempts: %v", d.ID, e.Type, w.Name, d.Attempts+1, err)
+	default:
+		attempt.Status, attempt.Error = "pending", err.Error()
+		attempt.NextAttempt = time.Now().Add(webhookBackoff(d.Attempts + 1))
+		common.DebugLog("webhooks: delivery %d to %s failed (attempt %d), retrying at %s: %v",
+			d.ID, w.Name, d.Attempts+1, attempt.NextAttempt.Format(time.RFC3339), err)
+	}
+	if rerr := database.RecordWebhookAttempt(ctx, d.ID, attempt); rerr != nil {
+		common.ErrorLog("webhooks: record delivery %d failed: %v", d.ID, rerr)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		unix   int64
+		body   string
+		want   string
+	}{
+		{"ping", "whsec_test", 1700000000, `{"type":"webhook.ping"}`,
+			"t=1700000000,v1=b5c4e236dd23d0af648df3e7713da4148d98436ee745c59d88f6cef0c25f4c43"},
+		{"empty body at epoch", "whsec_test", 0, "",
+			"t=0,v1=a2fa7a43c6a1cf2e784eaf3327d65c65b3d2b790320ebed9aa5661bc42a8cccd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SignWebhook(tt.secret, time.Unix(tt.unix, 0), []byte(tt.body)); got != tt.want {
+				t.Fatalf("SignWebhook = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// verifyWebhook is what a receiver does with the signature header
+func verifyWebhook(secret, header string, body []byte) bool {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	got, err := hex.DecodeString(sig)
+	if ts == "" || err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "." + string(body)))
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func TestSignWebhookVerifies(t *testing.T) {
+	secret, err := NewWebhookSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, "whsec_") || len(secret) != len("whsec_")+64 {
+		t.Fatalf("unexpected secret %q", secret)
+	}
+	body := []byte(`{"type":"deploy.succeeded","subject":"web"}`)
+	header := SignWebhook(secret, time.Now(), body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		ok     bool
+	}{
+		{"valid", secret, header, body, true},
+		{"tampered body", secret, header, []byte(`{"type":"deploy.failed","subject":"web"}`), false},
+		{"wrong secret", "whsec_other", header, body, false},
+		{"replayed timestamp", secret, strings.Replace(header, "t=", "t=1", 1), body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyWebhook(tt.secret, tt.header, tt.body); got != tt.ok {
+				t.Fatalf("verify = %v, want %v", got, tt.ok)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{8, 60 * time.Minute},
+		{50, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	orig := webhookLookupIP
+	t.Cleanup(func() { webhookLookupIP = orig })
+	webhookLookupIP = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "hooks.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+		case "internal.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}, {IP: net.ParseIP("10.1.2.3")}}, nil
+		case "localhost":
+			return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::1")}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		url     string
+		ok      bool
+		private bool // ok with DD_UI_WEBHOOK_ALLOW_PRIVATE=true
+	}{
+		{"https://hooks.example.com/dd-ui", true, true},
+		{"https://93.184.215.14:8443/x", true, true},
+		{"http://10.0.0.5:8080/x", false, true},
+		{"http://172.16.0.1/x", false, true},
+		{"http://192.168.1.10/x", false, true},
+		{"http://127.0.0.1:9000/x", false, true},
+		{"http://[::1]/x", false, true},
+		{"http://[::ffff:127.0.0.1]/x", false, true},
+		{"http://[fd00::1]/x", false, true},
+		{"http://169.254.169.254/latest/meta-data", false, true},
+		{"http://100.100.100.200/x", false, true},
+		{"http://0.0.0.0/x", false, true},
+		{"http://localhost:8080/x", false, true},
+		{"https://internal.example.com/x", false, true},
+		{"https://unknown.invalid/x", false, true},
+		{"ftp://example.com", false, false},
+		{"/relative", false, false},
+		{"https://", false, false},
+		{"", false, false},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		if err := ValidateWebhookURL(ctx, tt.url); (err == nil) != tt.ok {
+			t.Errorf("ValidateWebhookURL(%q) err = %v, want ok=%v", tt.url, err, tt.ok)
+		}
+	}
+	t.Setenv("DD_UI_WEBHOOK_ALLOW_PRIVATE", "true")
+	for _, tt := range tests {
+		if err := ValidateWebhookURL(ctx, tt.url); (err == nil) != tt.private {
+			t.Errorf("with private allowed: ValidateWebhookURL(%q) err = %v, want ok=%v", tt.url, err, tt.private)
+		}
+	}
+}
+
+func TestWebhookClientRefusesPrivateTargets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// the dial check catches what validation cannot: rebinding and redirects
+	client := webhookHTTPClient(5 * time.Second)
+	if resp, err := client.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("delivery to a loopback server was not refused")
+	} else if !strings.Contains(err.Error(), "private address") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv("DD_UI_WEBHOOK_ALLOW_PRIVATE", "true")
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("with private allowed: %v", err)
+	}
+	resp.Body.Close()
+}
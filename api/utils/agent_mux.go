@@ -0,0 +1,593 @@
+// src/api/utils/agent_mux.go
+package utils
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/*
+AgentMux multiplexes many byte streams over one websocket. It is used between
+DD-UI and dd-ui-agent: DD-UI opens a stream per Docker API connection (plain
+requests, events, logs, hijacked exec) and the agent pipes it to its local
+Docker socket.
+
+Frame (one websocket binary message):
+
+	[1 byte type][4 byte stream id, big endian][payload]
+
+	open   - payload is the target name ("docker")
+	data   - payload bytes
+	fin    - sender will not write more (half close)
+	close  - stream is gone in both directions
+	window - payload is a 4 byte big endian credit increment
+
+Each stream has a send window of muxStreamWindow bytes per direction. Write
+blocks once the window is used up and the reader returns credit with window
+frames as Read consumes data, so a slow reader stalls only its own writer and
+never the shared read loop. A peer that sends past the window is reset.
+
+Peers that predate window frames (agent version 1) are run with
+SetFlowControl(false): nothing waits for credit and a stream whose reader
+falls more than muxStreamQueue frames behind is reset instead.
+
+Only the agent accepts streams; an open from the agent is refused with close.
+*/
+
+const (
+	muxOpen   byte = 1
+	muxData   byte = 2
+	muxFin    byte = 3
+	muxClose  byte = 4
+	muxWindow byte = 5
+
+	muxMaxPayload   = 32 * 1024
+	muxStreamWindow = 1024 * 1024 // bytes a peer may send before it waits for credit
+	muxStreamQueue  = 256         // buffered frames per stream before it is reset (no flow control)
+	muxAcceptQueue  = 16          // opened streams waiting for Accept before opens are refused
+	muxPingEvery    = 30 * time.Second
+	muxReadTimeout  = 90 * time.Second
+)
+
+// AgentMuxFlowVersion is the first agent version whose mux sends window frames
+const AgentMuxFlowVersion = 2
+
+// MuxFlowControl reports whether a peer announcing version (X-DD-UI-Agent-Version) speaks
+// window frames; an empty or older version gets SetFlowControl(false)
+func MuxFlowControl(version string) bool {
+	v, err := strconv.Atoi(strings.TrimSpace(version))
+	return err == nil && v >= AgentMuxFlowVersion
+}
+
+var (
+	ErrMuxClosed   = errors.New("agent connection closed")
+	ErrMuxOverflow = errors.New("agent stream reset: receive buffer full")
+)
+
+// AgentMux is one multiplexed websocket connection
+type AgentMux struct {
+	ws      *websocket.Conn
+	opener  bool
+	flow    bool
+	wmu     sync.Mutex
+	mu      sync.Mutex
+	streams map[uint32]*MuxStream
+	nextID  uint32
+	accept  chan *MuxStream
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewAgentMux wraps a websocket. The opener (DD-UI) uses odd stream ids and the
+// accepting side (agent) even ones, so both may open streams without collisions.
+func NewAgentMux(ws *websocket.Conn, opener bool) *AgentMux {
+	m := &AgentMux{
+		ws:      ws,
+		opener:  opener,
+		flow:    true,
+		streams: make(map[uint32]*MuxStream),
+		accept:  make(chan *MuxStream, muxAcceptQueue),
+		done:    make(chan struct{}),
+	}
+	if opener {
+		m.nextID = 1
+	} else {
+		m.nextID = 2
+	}
+	return m
+}
+
+// SetFlowControl turns per-stream send windows on or off; it must be called before Run.
+// Both sides must agree: a peer that never sends window frames would stall every writer.
+func (m *AgentMux) SetFlowControl(on bool) { m.flow = on }
+
+// Done is closed when the underlying connection is gone
+func (m *AgentMux) Done() <-chan struct{} { return m.done }
+
+// Close tears down the connection and every stream on it
+func (m *AgentMux) Close() error {
+	m.once.Do(func() {
+		close(m.done)
+		_ = m.ws.Close()
+		m.mu.Lock()
+		for id, s := range m.streams {
+			s.remoteClosed()
+			delete(m.streams, id)
+		}
+		m.mu.Unlock()
+	})
+	return nil
+}
+
+func (m *AgentMux) writeFrame(typ byte, id uint32, payload []byte) error {
+	select {
+	case <-m.done:
+		return ErrMuxClosed
+	default:
+	}
+	buf := make([]byte, 5+len(payload))
+	buf[0] = typ
+	binary.BigEndian.PutUint32(buf[1:5], id)
+	copy(buf[5:], payload)
+
+	m.wmu.Lock()
+	defer m.wmu.Unlock()
+	_ = m.ws.SetWriteDeadline(time.Now().Add(30 * time.Second))
+	if err := m.ws.WriteMessage(websocket.BinaryMessage, buf); err != nil {
+		go m.Close()
+		return err
+	}
+	return nil
+}
+
+// Open starts a new stream to the given target on the peer
+func (m *AgentMux) Open(ctx context.Context, target string) (*MuxStream, error) {
+	m.mu.Lock()
+	id := m.nextID
+	m.nextID += 2
+	s := newMuxStream(m, id)
+	m.streams[id] = s
+	m.mu.Unlock()
+
+	if err := m.writeFrame(muxOpen, id, []byte(target)); err != nil {
+		m.forget(id)
+		return nil, err
+	}
+	return s, nil
+}
+
+// Accept waits for a stream opened by the peer
+func (m *AgentMux) Accept() (*MuxStream, string, error) {
+	select {
+	case s := <-m.accept:
+		return s, s.target, nil
+	case <-m.done:
+		return nil, "", ErrMuxClosed
+	}
+}
+
+// acceptOpen queues a peer-opened stream for Accept. The opener side never accepts, and a
+// full queue is not waited on, so both refuse the stream with close instead of blocking Run.
+func (m *AgentMux) acceptOpen(id uint32, target string) {
+	if !m.opener {
+		s := newMuxStream(m, id)
+		s.target = target
+		m.mu.Lock()
+		m.streams[id] = s
+		m.mu.Unlock()
+		select {
+		case m.accept <- s:
+			return
+		default:
+			m.forget(id)
+		}
+	}
+	go m.writeFrame(muxClose, id, nil)
+}
+
+func (m *AgentMux) forget(id uint32) {
+	m.mu.Lock()
+	delete(m.streams, id)
+	m.mu.Unlock()
+}
+
+// Run reads frames until the connection fails; it always closes the mux on return
+func (m *AgentMux) Run() error {
+	defer m.Close()
+
+	_ = m.ws.SetReadDeadline(time.Now().Add(muxReadTimeout))
+	m.ws.SetPongHandler(func(string) error {
+		return m.ws.SetReadDeadline(time.Now().Add(muxReadTimeout))
+	})
+	go func() {
+		t := time.NewTicker(muxPingEvery)
+		defer t.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-t.C:
+				m.wmu.Lock()
+				err := m.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+				m.wmu.Unlock()
+				if err != nil {
+					m.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		typ, msg, err := m.ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		_ = m.ws.SetReadDeadline(time.Now().Add(muxReadTimeout))
+		if typ != websocket.BinaryMessage || len(msg) < 5 {
+			continue
+		}
+		ft := msg[0]
+		id := binary.BigEndian.Uint32(msg[1:5])
+		payload := msg[5:]
+
+		m.mu.Lock()
+		s := m.streams[id]
+		m.mu.Unlock()
+
+		switch ft {
+		case muxOpen:
+			if s == nil {
+				m.acceptOpen(id, string(payload))
+			}
+		case muxData:
+			if s != nil {
+				s.push(payload)
+			}
+		case muxFin:
+			if s != nil {
+				s.remoteFin()
+			}
+		case muxClose:
+			if s != nil {
+				s.remoteClosed()
+				m.forget(id)
+			}
+		case muxWindow:
+			if s != nil && len(payload) == 4 {
+				s.grant(binary.BigEndian.Uint32(payload))
+			}
+		}
+	}
+}
+
+// MuxStream is one logical connection; it implements net.Conn
+type MuxStream struct {
+	mux    *AgentMux
+	id     uint32
+	target string
+
+	notify   chan struct{} // data was queued
+	credit   chan struct{} // the peer granted send window
+	gone     chan struct{} // closed locally or by the peer
+	finOnce  sync.Once
+	readDone chan struct{} // peer finished writing
+	mu       sync.Mutex
+	queue    [][]byte // received data not yet read
+	queued   int      // bytes in queue
+	unacked  int      // bytes read but not yet returned to the peer as credit
+	window   int      // bytes we may still send
+	closed   bool     // closed locally or by the peer
+	wrClosed bool
+	err      error // returned by Read once the queue drained (ErrMuxOverflow)
+
+	readDeadline  muxDeadline
+	writeDeadline muxDeadline
+}
+
+func newMuxStream(m *AgentMux, id uint32) *MuxStream {
+	return &MuxStream{mux: m, id: id, window: muxStreamWindow,
+		notify: make(chan struct{}, 1), credit: make(chan struct{}, 1), gone: make(chan struct{}), readDone: make(chan struct{}),
+		readDeadline: muxDeadline{cancel: make(chan struct{})}, writeDeadline: muxDeadline{cancel: make(chan struct{})}}
+}
+
+// push queues a data frame without blocking the read loop; data past the window resets the stream
+func (s *MuxStream) push(p []byte) {
+	select {
+	case <-s.readDone:
+		return
+	default:
+	}
+	b := make([]byte, len(p))
+	copy(b, p)
+	s.mu.Lock()
+	var full bool
+	if s.mux.flow {
+		full = s.queued+len(b) > muxStreamWindow
+	} else {
+		full = len(s.queue) >= muxStreamQueue
+	}
+	if !full {
+		s.queue = append(s.queue, b)
+		s.queued += len(b)
+	}
+	s.mu.Unlock()
+	if full {
+		s.reset(ErrMuxOverflow)
+		return
+	}
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// grant adds send window returned by the peer and wakes a waiting Write
+func (s *MuxStream) grant(n uint32) {
+	s.mu.Lock()
+	s.window += int(n)
+	s.mu.Unlock()
+	select {
+	case s.credit <- struct{}{}:
+	default:
+	}
+}
+
+// markClosed records that the stream is gone; it reports whether it already was
+func (s *MuxStream) markClosed(err error) (already bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return true
+	}
+	s.closed = true
+	if err != nil {
+		s.err = err
+	}
+	close(s.gone)
+	return false
+}
+
+// reset drops the stream locally and tells the peer
+func (s *MuxStream) reset(err error) {
+	already := s.markClosed(err)
+	s.remoteFin()
+	s.mux.forget(s.id)
+	if !already {
+		go s.mux.writeFrame(muxClose, s.id, nil)
+	}
+}
+
+func (s *MuxStream) remoteFin() { s.finOnce.Do(func() { close(s.readDone) }) }
+
+func (s *MuxStream) remoteClosed() {
+	s.markClosed(nil)
+	s.remoteFin()
+}
+
+// Read implements io.Reader; returns io.EOF once the peer finished writing and the queue drained
+// (or the reset error), and os.ErrDeadlineExceeded after the read deadline
+func (s *MuxStream) Read(p []byte) (int, error) {
+	expired := s.readDeadline.wait()
+	select {
+	case <-expired:
+		return 0, os.ErrDeadlineExceeded
+	default:
+	}
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			n := copy(p, s.queue[0])
+			if n == len(s.queue[0]) {
+				s.queue[0] = nil
+				s.queue = s.queue[1:]
+			} else {
+				s.queue[0] = s.queue[0][n:]
+			}
+			s.queued -= n
+			var credit int
+			if s.mux.flow {
+				// return credit in batches rather than one window frame per Read
+				s.unacked += n
+				if s.unacked >= muxStreamWindow/2 {
+					credit, s.unacked = s.unacked, 0
+				}
+			}
+			s.mu.Unlock()
+			if credit > 0 {
+				var b [4]byte
+				binary.BigEndian.PutUint32(b[:], uint32(credit))
+				_ = s.mux.writeFrame(muxWindow, s.id, b[:])
+			}
+			return n, nil
+		}
+		// data is queued before the fin that follows it, so an empty queue after fin is final
+		if isClosedChan(s.readDone) {
+			err := s.err
+			s.mu.Unlock()
+			if err == nil {
+				err = io.EOF
+			}
+			return 0, err
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-s.readDone:
+		case <-s.mux.done:
+			return 0, io.EOF
+		case <-expired:
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// Write implements io.Writer. It waits for send window before each frame and gives up at the
+// write deadline; a frame being written is bounded by the websocket's own write timeout.
+func (s *MuxStream) Write(p []byte) (int, error) {
+	expired := s.writeDeadline.wait()
+	written := 0
+	for len(p) > 0 {
+		n, err := s.reserve(len(p), expired)
+		if err != nil {
+			return written, err
+		}
+		if err := s.mux.writeFrame(muxData, s.id, p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// reserve waits until the stream may send and takes up to one frame's worth of window
+func (s *MuxStream) reserve(want int, expired chan struct{}) (int, error) {
+	if want > muxMaxPayload {
+		want = muxMaxPayload
+	}
+	for {
+		select {
+		case <-expired:
+			return 0, os.ErrDeadlineExceeded
+		default:
+		}
+		s.mu.Lock()
+		switch {
+		case s.closed || s.wrClosed:
+			s.mu.Unlock()
+			return 0, io.ErrClosedPipe
+		case !s.mux.flow:
+			s.mu.Unlock()
+			return want, nil
+		case s.window > 0:
+			n := want
+			if n > s.window {
+				n = s.window
+			}
+			s.window -= n
+			s.mu.Unlock()
+			return n, nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.credit:
+		case <-s.gone:
+		case <-s.mux.done:
+			return 0, ErrMuxClosed
+		case <-expired:
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+// CloseWrite half-closes the stream (used by hijacked exec when stdin ends)
+func (s *MuxStream) CloseWrite() error {
+	s.mu.Lock()
+	if s.closed || s.wrClosed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.wrClosed = true
+	s.mu.Unlock()
+	return s.mux.writeFrame(muxFin, s.id, nil)
+}
+
+// Close closes both directions and tells the peer
+func (s *MuxStream) Close() error {
+	already := s.markClosed(nil)
+	s.remoteFin()
+	s.mux.forget(s.id)
+	if already {
+		return nil
+	}
+	return s.mux.writeFrame(muxClose, s.id, nil)
+}
+
+type muxAddr string
+
+func (a muxAddr) Network() string { return "agent" }
+func (a muxAddr) String() string  { return string(a) }
+
+func (s *MuxStream) LocalAddr() net.Addr  { return muxAddr("dd-ui") }
+func (s *MuxStream) RemoteAddr() net.Addr { return muxAddr("agent/" + s.target) }
+
+func (s *MuxStream) SetDeadline(t time.Time) error {
+	s.readDeadline.set(t)
+	s.writeDeadline.set(t)
+	return nil
+}
+
+func (s *MuxStream) SetReadDeadline(t time.Time) error {
+	s.readDeadline.set(t)
+	return nil
+}
+
+func (s *MuxStream) SetWriteDeadline(t time.Time) error {
+	s.writeDeadline.set(t)
+	return nil
+}
+
+// muxDeadline is a settable deadline whose channel is closed once it passes (as in net.Pipe)
+type muxDeadline struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+// set arms the deadline; the zero time clears it
+func (d *muxDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel // the timer fired; wait for it to close cancel
+	}
+	d.timer = nil
+
+	closed := isClosedChan(d.cancel)
+	if t.IsZero() {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+	if dur := time.Until(t); dur > 0 {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() { close(cancel) })
+		return
+	}
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+// wait returns a channel that is closed when the deadline passes
+func (d *muxDeadline) wait() chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancel
+}
+
+func isClosedChan(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// muxPair connects an opener (DD-UI side) and an accepting (agent side) mux over a real websocket
+func muxPair(t *testing.T) (opener, agent *AgentMux) {
+	t.Helper()
+	return muxPairFlow(t, true)
+}
+
+// muxPairFlow is muxPair with flow control set on both sides
+func muxPairFlow(t *testing.T, flow bool) (opener, agent *AgentMux) {
+	t.Helper()
+	agentCh := make(chan *AgentMux, 1)
+	up := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		m := NewAgentMux(ws, false)
+		m.SetFlowControl(flow)
+		agentCh <- m
+		_ = m.Run()
+	}))
+	t.Cleanup(srv.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	opener = NewAgentMux(ws, true)
+	opener.SetFlowControl(flow)
+	go func() { _ = opener.Run() }()
+	agent = <-agentCh
+	t.Cleanup(func() {
+		opener.Close()
+		agent.Close()
+	})
+	return opener, agent
+}
+
+// echoAccepted echoes every stream the agent accepts until the peer half-closes
+func echoAccepted(agent *AgentMux) {
+	for {
+		st, _, err := agent.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			_, _ = io.Copy(st, st)
+			_ = st.CloseWrite()
+		}()
+	}
+}
+
+func TestAgentMuxEcho(t *testing.T) {
+	opener, agent := muxPair(t)
+	go echoAccepted(agent)
+
+	st, err := opener.Open(context.Background(), "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := strings.Repeat("x", 3*muxMaxPayload+17)
+	if _, err := st.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != msg {
+		t.Fatalf("echo returned %d bytes, want %d", len(got), len(msg))
+	}
+}
+
+func TestAgentMuxOpenerRefusesPeerStreams(t *testing.T) {
+	opener, agent := muxPair(t)
+	go echoAccepted(opener) // never returns a stream: the opener refuses opens
+
+	// more opens than the accept queue holds must not stall the opener's read loop
+	for i := 0; i < 3*muxAcceptQueue; i++ {
+		st, err := agent.Open(context.Background(), "docker")
+		if err != nil {
+			t.Fatal(err)
+		}
+		st.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := st.Read(make([]byte, 1)); err != io.EOF {
+			t.Fatalf("open %d: read = %v, want EOF from the refusal", i, err)
+		}
+	}
+
+	go echoAccepted(agent)
+	st, err := opener.Open(context.Background(), "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := st.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(st, buf); err != nil || string(buf) != "ping" {
+		t.Fatalf("read after refusals = %q, %v", buf, err)
+	}
+}
+
+// acceptInto forwards every stream the agent accepts
+func acceptInto(agent *AgentMux) chan *MuxStream {
+	accepted := make(chan *MuxStream, 2)
+	go func() {
+		for {
+			st, _, err := agent.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- st
+		}
+	}()
+	return accepted
+}
+
+func TestAgentMuxSlowReaderBlocksWriter(t *testing.T) {
+	opener, agent := muxPair(t)
+	accepted := acceptInto(agent)
+
+	slow, err := opener.Open(context.Background(), "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := <-accepted
+	total := 3 * muxStreamWindow
+	wrote := make(chan error, 1)
+	go func() {
+		_, err := peer.Write(make([]byte, total))
+		wrote <- err
+	}()
+
+	// the writer waits for credit once the window is used up; the stream is not reset
+	select {
+	case err := <-wrote:
+		t.Fatalf("write of %d bytes to an idle reader returned early: %v", total, err)
+	case <-slow.readDone:
+		t.Fatal("slow stream was reset")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// other streams keep working while it waits
+	other, err := opener.Open(context.Background(), "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPeer := <-accepted
+	if _, err := otherPeer.Write([]byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+	other.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(other, buf); err != nil || string(buf) != "ok" {
+		t.Fatalf("other stream read = %q, %v", buf, err)
+	}
+
+	// reading returns credit and the whole write goes through
+	slow.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if n, err := io.CopyN(io.Discard, slow, int64(total)); err != nil {
+		t.Fatalf("slow reader got %d of %d bytes: %v", n, total, err)
+	}
+	if err := <-wrote; err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// a writer waiting for credit still honours its deadline
+	peer.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := peer.Write(make([]byte, 2*muxStreamWindow)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("blocked write = %v, want os.ErrDeadlineExceeded", err)
+	}
+}
+
+func TestAgentMuxWithoutFlowControlResetsSlowReader(t *testing.T) {
+	opener, agent := muxPairFlow(t, false)
+	accepted := acceptInto(agent)
+
+	slow, err := opener.Open(context.Background(), "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := <-accepted
+	frame := make([]byte, 64)
+	for i := 0; i < muxStreamQueue+1; i++ {
+		if _, err := peer.Write(frame); err != nil {
+			break // the reset may already have arrived
+		}
+	}
+
+	select {
+	case <-slow.readDone: // reset by the opener's read loop
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow stream was not reset")
+	}
+
+	// the stream drains what was queued, then reports the reset
+	slow.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.Copy(io.Discard, slow)
+	if !errors.Is(err, ErrMuxOverflow) {
+		t.Fatalf("slow reader: err = %v, want ErrMuxOverflow", err)
+	}
+}
+
+func TestMuxFlowControl(t *testing.T) {
+	for v, want := range map[string]bool{"": false, "1": false, "2": true, " 3 ": true, "dev": false} {
+		if got := MuxFlowControl(v); got != want {
+			t.Errorf("MuxFlowControl(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestMuxStreamDeadlines(t *testing.T) {
+	opener, agent := muxPair(t)
+	go func() {
+		for {
+			if _, _, err := agent.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+	st, err := opener.Open(context.Background(), "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	start := time.Now()
+	if _, err := st.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("read = %v, want os.ErrDeadlineExceeded", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("read deadline did not unblock Read")
+	}
+
+	st.SetWriteDeadline(time.Now().Add(-time.Second))
+	if _, err := st.Write([]byte("x")); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("write = %v, want os.ErrDeadlineExceeded", err)
+	}
+
+	// clearing the deadlines makes the stream usable again
+	st.SetDeadline(time.Time{})
+	if _, err := st.Write([]byte("x")); err != nil {
+		t.Fatalf("write after clearing deadline: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := st.Read(make([]byte, 1))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("read returned %v with no deadline and no data", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	st.SetReadDeadline(time.Now())
+	if err := <-done; !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("read after moving the deadline = %v", err)
+	}
+}
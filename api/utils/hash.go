@@ -125,6 +125,10 @@ func GetActualDockerConfigHashes(ctx context.Context, stackName string, cli *cli
 		if cont.Labels != nil {
 			serviceName = cont.Labels["com.docker.compose.service"]
 			configHash = cont.Labels["com.docker.compose.config-hash"]
+			if configHash == "" {
+				// podman-compose labels its own hash
+				configHash = cont.Labels["io.podman.compose.config-hash"]
+			}
 		}
 		
 		if serviceName != "" && configHash != "" {
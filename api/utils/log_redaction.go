@@ -0,0 +1,140 @@
+// src/api/utils/log_redaction.go
+package utils
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactedValue replaces redacted secrets in container log lines (same marker as DD-UI's own logs)
+const RedactedValue = "***REDACTED***"
+
+// BuiltinLogRedactionPatterns catch common credentials in log lines. A (?P<secret>...) group
+// limits the replacement to that group so keys and prefixes stay readable.
+var BuiltinLogRedactionPatterns = []string{
+	// password=..., "api_key": "...", client_secret: ...
+	`(?i)[a-z0-9_.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)[a-z0-9_.-]*["']?\s*[:=]\s*["']?(?P<secret>[^\s"',;&]+)`,
+	// Authorization headers and bearer tokens
+	`(?i)(?:authorization:\s*(?:bearer|basic|token)|bearer)\s+(?P<secret>[a-z0-9._~+/=-]{8,})`,
+	// user:password@ in URLs
+	`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:(?P<secret>[^/\s@]+)@`,
+	// JWTs
+	`eyJ[a-zA-Z0-9_-]{5,}\.[a-zA-Z0-9_-]{5,}\.[a-zA-Z0-9_-]{5,}`,
+	// AWS access key ids, GitHub and Slack tokens
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	`\b(?:gh[pousr]_[A-Za-z0-9]{30,}|github_pat_[A-Za-z0-9_]{30,})\b`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	// PEM private key markers
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----`,
+}
+
+// LogRedactor masks secrets in log lines: literal values first (longest first), then regexes
+type LogRedactor struct {
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// NewLogRedactor compiles patterns (invalid ones are returned as errors and skipped) and
+// keeps literal values of at least minLen characters
+func NewLogRedactor(patterns, values []string, minLen int) (*LogRedactor, []error) {
+	r := &LogRedactor{}
+	var errs []error
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	seen := map[string]bool{}
+	for _, v := range values {
+		if len(v) >= minLen && !seen[v] {
+			seen[v] = true
+			r.values = append(r.values, v)
+		}
+	}
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+	return r, errs
+}
+
+// Redact returns line with secrets replaced; a nil redactor returns line unchanged
+func (r *LogRedactor) Redact(line string) string {
+	if r == nil {
+		return line
+	}
+	for _, v := range r.values {
+		line = strings.ReplaceAll(line, v, RedactedValue)
+	}
+	for _, re := range r.patterns {
+		line = redactPattern(re, line)
+	}
+	return line
+}
+
+// redactPattern replaces the "secret" group of each match, or the whole match without one
+func redactPattern(re *regexp.Regexp, line string) string {
+	group := re.SubexpIndex("secret")
+	matches := re.FindAllStringSubmatchIndex(line, -1)
+	if len(matches) == 0 {
+		return line
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if group > 0 && m[2*group] >= 0 {
+			start, end = m[2*group], m[2*group+1]
+		}
+		if start < last {
+			continue
+		}
+		b.WriteString(line[last:start])
+		b.WriteString(RedactedValue)
+		last = end
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// LineFilterWriter rewrites complete lines through f before passing them to w; call Flush
+// to write a trailing partial line
+type LineFilterWriter struct {
+	w   io.Writer
+	f   func(string) string
+	buf []byte
+}
+
+// NewLineFilterWriter wraps w with a per-line rewrite
+func NewLineFilterWriter(w io.Writer, f func(string) string) *LineFilterWriter {
+	return &LineFilterWriter{w: w, f: f}
+}
+
+// Write implements io.Writer
+func (l *LineFilterWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := l.f(string(l.buf[:i]))
+		l.buf = l.buf[i+1:]
+		if _, err := io.WriteString(l.w, line+"\n"); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush writes any buffered partial line
+func (l *LineFilterWriter) Flush() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(l.w, l.f(string(l.buf)))
+	l.buf = nil
+	return err
+}
@@ -0,0 +1,108 @@
+// src/api/utils/secrets.go
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"dd-ui/common"
+)
+
+// MaskedValue replaces secret values in stored container data and API responses
+const MaskedValue = "***MASKED***"
+
+const defaultSecretKeyPattern = `(?i)(pass|secret|token|api[-_.]?key|private[-_.]?key|access[-_.]?key|[-_.]key$|^key$|credential|auth|dsn|salt|cookie)`
+
+var (
+	secretKeyOnce sync.Once
+	secretKeyRe   *regexp.Regexp
+	urlCredsRe    = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)
+)
+
+// secretKeyRegexp is DD_UI_SECRET_KEY_PATTERN (a regexp matched against env/label keys),
+// falling back to the built-in pattern when unset or invalid
+func secretKeyRegexp() *regexp.Regexp {
+	secretKeyOnce.Do(func() {
+		secretKeyRe = regexp.MustCompile(defaultSecretKeyPattern)
+		if p := strings.TrimSpace(common.Env("DD_UI_SECRET_KEY_PATTERN", "")); p != "" {
+			if re, err := regexp.Compile(p); err == nil {
+				secretKeyRe = re
+			} else {
+				common.WarnLog("invalid DD_UI_SECRET_KEY_PATTERN %q, using default: %v", p, err)
+			}
+		}
+	})
+	return secretKeyRe
+}
+
+// IsSecretKey reports whether an env or label key looks like it holds a secret
+func IsSecretKey(key string) bool { return secretKeyRegexp().MatchString(key) }
+
+// HasURLCredentials reports whether a value embeds user:password@ in a URL
+func HasURLCredentials(value string) bool { return urlCredsRe.MatchString(value) }
+
+// MaskAllEnv reports whether every env value is masked (DD_UI_MASK_ENV=all, the default)
+// rather than only secret-looking ones (DD_UI_MASK_ENV=secrets)
+func MaskAllEnv() bool {
+	return !strings.EqualFold(strings.TrimSpace(common.Env("DD_UI_MASK_ENV", "all")), "secrets")
+}
+
+// MaskEnv masks docker env entries ("K=V"). Values are masked when all is set, the key is
+// in sopsKeys, the key looks secret, or the value carries URL credentials. Empty values stay
+// empty so "set but blank" remains visible.
+func MaskEnv(env []string, sopsKeys map[string]bool, all bool) []string {
+	if env == nil {
+		return nil
+	}
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		k, v, ok := strings.Cut(kv, "=")
+		if ok && v != "" && (all || sopsKeys[k] || IsSecretKey(k) || HasURLCredentials(v)) {
+			kv = k + "=" + MaskedValue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// MaskLabels masks label values whose key looks secret or comes from a SOPS env file, or
+// whose value carries URL credentials. Compose/Docker bookkeeping labels are kept as-is.
+func MaskLabels(labels map[string]string, sopsKeys map[string]bool) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if v != "" && !strings.HasPrefix(k, "com.docker.") &&
+			(sopsKeys[k] || IsSecretKey(k) || HasURLCredentials(v)) {
+			v = MaskedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// ParseDotenv returns the KEY=VALUE pairs of dotenv content (surrounding quotes removed),
+// skipping comments and SOPS metadata
+func ParseDotenv(content string) map[string]string {
+	out := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		k, v, ok := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" || strings.HasPrefix(k, "sops_") {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		out[k] = v
+	}
+	return out
+}
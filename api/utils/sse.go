@@ -17,6 +17,7 @@ type SSELineWriter struct {
 	fl     http.Flusher
 	stream string // "stdout" | "stderr"
 	buf    []byte
+	Filter func(string) string // optional per-line rewrite (e.g. log redaction)
 }
 
 // NewSSELineWriter creates a new SSE line writer for the given stream type
@@ -46,6 +47,9 @@ func (s *SSELineWriter) Write(p []byte) (int, error) {
 		}
 		line := string(s.buf[:i])
 		s.buf = s.buf[i+1:]
+		if s.Filter != nil {
+			line = s.Filter(line)
+		}
 		_, _ = s.w.Write([]byte("event: " + s.stream + "\n"))
 		_, _ = s.w.Write([]byte("data: " + line + "\n\n"))
 		if s.fl != nil {
@@ -20,6 +20,12 @@ type HostInfo struct {
 	Vars map[string]string
 }
 
+// SSHTargetProvider is optionally implemented by a HostProvider to supply per-host
+// SSH settings (port, key, jump hosts) resolved from inventory vars
+type SSHTargetProvider interface {
+	SSHTarget(ctx context.Context, name string) (SSHTarget, error)
+}
+
 // EnvProvider interface for getting environment variables
 type EnvProvider interface {
 	Env(key, defaultValue string) string
@@ -50,24 +56,38 @@ func ExecuteSSHDirectOperation(ctx context.Context, hostProvider HostProvider, e
 		return "", fmt.Errorf("host not found: %v", err)
 	}
 
-	// Build SSH command
-	user := host.Vars["ansible_user"]
-	if user == "" {
-		user = envProvider.Env("SSH_USER", "root")
-	}
-	
-	addr := host.Addr
-	if addr == "" {
-		addr = host.Name
+	// Resolve the SSH target (per-host settings when the provider has them, env otherwise)
+	var target SSHTarget
+	if tp, ok := hostProvider.(SSHTargetProvider); ok {
+		if target, err = tp.SSHTarget(ctx, host.Name); err != nil {
+			return "", err
+		}
+	} else {
+		user := host.Vars["ansible_user"]
+		if user == "" {
+			user = envProvider.Env("SSH_USER", "root")
+		}
+		addr := host.Addr
+		if addr == "" {
+			addr = host.Name
+		}
+		target = SSHTarget{
+			User:    user,
+			Addr:    addr,
+			Port:    envProvider.Env("SSH_PORT", "22"),
+			KeyFile: envProvider.Env("SSH_KEY_FILE", ""),
+		}
 	}
 
-	// Construct SSH command
-	sshArgs := []string{
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=10",
-		fmt.Sprintf("%s@%s", user, addr),
+	// Construct SSH command (host keys verified against the managed known_hosts)
+	configArgs, dest, err := OpenSSHTargetArgs(ctx, target)
+	if err != nil {
+		return "", err
 	}
+	sshArgs := append(configArgs,
+		"-o", "ConnectTimeout=10",
+		dest,
+	)
 	
 	// Add the command to execute
 	fullCommand := op.Command
@@ -0,0 +1,201 @@
+// src/api/utils/ssh_target.go
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHTarget is a fully resolved SSH endpoint: per-host user/port/key plus an optional
+// jump host (which may itself be reached through another jump host).
+type SSHTarget struct {
+	User    string
+	Addr    string
+	Port    string
+	KeyFile string
+	Via     *SSHTarget // bastion; nil for a direct connection
+}
+
+// HostPort returns addr:port
+func (t SSHTarget) HostPort() string {
+	port := t.Port
+	if port == "" {
+		port = "22"
+	}
+	return net.JoinHostPort(t.Addr, port)
+}
+
+// String identifies the target including its jump chain (used as pool key)
+func (t SSHTarget) String() string {
+	s := t.User + "@" + t.HostPort()
+	if t.Via != nil {
+		s += " via " + t.Via.String()
+	}
+	return s
+}
+
+// Chain returns the hops outermost-first, ending with the target itself
+func (t SSHTarget) Chain() []SSHTarget {
+	var out []SSHTarget
+	if t.Via != nil {
+		out = t.Via.Chain()
+	}
+	return append(out, t)
+}
+
+// JumpSpec renders the jump chain in OpenSSH ProxyJump form (user@host:port,...)
+func (t SSHTarget) JumpSpec() string {
+	if t.Via == nil {
+		return ""
+	}
+	var parts []string
+	for _, h := range t.Via.Chain() {
+		parts = append(parts, h.User+"@"+h.HostPort())
+	}
+	return strings.Join(parts, ",")
+}
+
+func sshClientConfig(user, keyFile string) (*ssh.ClientConfig, error) {
+	if keyFile == "" {
+		return nil, fmt.Errorf("no SSH key configured (SSH_KEY_FILE or ansible_ssh_private_key_file)")
+	}
+	keyData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key file %s: %v", keyFile, err)
+	}
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH private key: %v", err)
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: HostKeyCallback(),
+		Timeout:         sshDialTimeout,
+	}, nil
+}
+
+// DialSSHTarget connects to t, hopping through its jump hosts; host keys are checked at every hop
+func DialSSHTarget(t SSHTarget) (*ssh.Client, error) {
+	if t.Via == nil {
+		return CreateSSHClient(t.User, t.HostPort(), t.KeyFile)
+	}
+	jump, err := DialSSHTarget(*t.Via)
+	if err != nil {
+		return nil, fmt.Errorf("jump host %s: %v", t.Via.HostPort(), err)
+	}
+	conn, err := jump.Dial("tcp", t.HostPort())
+	if err != nil {
+		jump.Close()
+		return nil, fmt.Errorf("failed to reach %s via %s: %v", t.HostPort(), t.Via.HostPort(), err)
+	}
+	cfg, err := sshClientConfig(t.User, t.KeyFile)
+	if err != nil {
+		conn.Close()
+		jump.Close()
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.HostPort(), cfg)
+	if err != nil {
+		conn.Close()
+		jump.Close()
+		return nil, fmt.Errorf("failed to connect to SSH server %s via %s: %v", t.HostPort(), t.Via.HostPort(), err)
+	}
+	client := ssh.NewClient(c, chans, reqs)
+	// the jump connection lives as long as the tunnelled one
+	go func() {
+		_ = client.Wait()
+		_ = jump.Close()
+	}()
+	return client, nil
+}
+
+// PinSSHTargetHostKeys verifies (or pins on first use) the host key of every hop
+func PinSSHTargetHostKeys(ctx context.Context, t SSHTarget) error {
+	if hostKeyStore == nil || HostKeyCheckingDisabled() {
+		return nil
+	}
+	if t.Via == nil {
+		return PinHostKey(ctx, t.HostPort())
+	}
+	// authenticating to the jump chain checks the jump host keys
+	jump, err := DialSSHTarget(*t.Via)
+	if err != nil {
+		return fmt.Errorf("jump host %s: %v", t.Via.HostPort(), err)
+	}
+	defer jump.Close()
+	conn, err := jump.Dial("tcp", t.HostPort())
+	if err != nil {
+		return fmt.Errorf("host key probe %s via %s: %v", t.HostPort(), t.Via.HostPort(), err)
+	}
+	return probeHostKey(conn, t.HostPort())
+}
+
+// OpenSSHConfigBlocks renders ssh_config Host blocks for t: one alias per jump hop and
+// finalAlias for the target itself. hostKeyOpts are "Key=value" options.
+func OpenSSHConfigBlocks(t SSHTarget, finalAlias string, hostKeyOpts []string) string {
+	sum := sha256.Sum256([]byte(t.String()))
+	prefix := "dd-ui-hop-" + hex.EncodeToString(sum[:4])
+
+	var b strings.Builder
+	prev := ""
+	chain := t.Chain()
+	for i, h := range chain {
+		alias := fmt.Sprintf("%s-%d", prefix, i)
+		if i == len(chain)-1 {
+			alias = finalAlias
+		}
+		port := h.Port
+		if port == "" {
+			port = "22"
+		}
+		fmt.Fprintf(&b, "Host %s\n    HostName %s\n    User %s\n    Port %s\n", alias, h.Addr, h.User, port)
+		if h.KeyFile != "" {
+			fmt.Fprintf(&b, "    IdentityFile %s\n    IdentitiesOnly yes\n", h.KeyFile)
+		}
+		for _, o := range hostKeyOpts {
+			kv := strings.SplitN(o, "=", 2)
+			if len(kv) == 2 {
+				fmt.Fprintf(&b, "    %s %s\n", kv[0], kv[1])
+			}
+		}
+		if prev != "" {
+			fmt.Fprintf(&b, "    ProxyJump %s\n", prev)
+		}
+		b.WriteString("    ConnectTimeout 30\n\n")
+		prev = alias
+	}
+	return b.String()
+}
+
+// OpenSSHTargetArgs verifies every hop's host key and writes an ssh_config for t.
+// Returns the CLI options (-F <file>) and the destination alias to pass to ssh.
+func OpenSSHTargetArgs(ctx context.Context, t SSHTarget) ([]string, string, error) {
+	if err := PinSSHTargetHostKeys(ctx, t); err != nil {
+		return nil, "", err
+	}
+	opts, err := HostKeyConfigOptions()
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256([]byte(t.String()))
+	alias := "dd-ui-" + hex.EncodeToString(sum[:6])
+
+	dir := filepath.Join(os.TempDir(), "dd-ui", "ssh_config")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, alias+".conf")
+	if err := os.WriteFile(path, []byte(OpenSSHConfigBlocks(t, alias, opts)), 0o600); err != nil {
+		return nil, "", err
+	}
+	return []string{"-F", path}, alias, nil
+}
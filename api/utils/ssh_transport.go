@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"os"
 	"strings"
 	"sync"
 	"time"
@@ -33,12 +32,22 @@ var SSHPool = &SSHConnectionPool{
 	connections: make(map[string]*SSHConnection),
 }
 
-// GetSSHConnection gets or creates an SSH connection
+// GetSSHConnection gets or creates a direct SSH connection (host may carry a :port)
 func (p *SSHConnectionPool) GetSSHConnection(user, host, keyFile string) (*ssh.Client, error) {
+	t := SSHTarget{User: user, Addr: host, KeyFile: keyFile}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		t.Addr, t.Port = h, port
+	}
+	return p.GetSSHConnectionForTarget(t)
+}
+
+// GetSSHConnectionForTarget gets or creates an SSH connection to a resolved target,
+// including its jump chain
+func (p *SSHConnectionPool) GetSSHConnectionForTarget(t SSHTarget) (*ssh.Client, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	key := fmt.Sprintf("%s@%s", user, host)
+	key := t.String()
 	
 	// Check if we have a valid connection
 	if conn, exists := p.connections[key]; exists {
@@ -61,15 +70,15 @@ func (p *SSHConnectionPool) GetSSHConnection(user, host, keyFile string) (*ssh.C
 
 	// Create new connection
 	// common.DebugLog("SSH: Creating new connection to %s", key) // Comment out - needs to be injected
-	sshClient, err := CreateSSHClient(user, host, keyFile)
+	sshClient, err := DialSSHTarget(t)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create SSH client: %v", err)
 	}
 
 	conn := &SSHConnection{
 		client:   sshClient,
-		hostAddr: host,
-		user:     user,
+		hostAddr: t.HostPort(),
+		user:     t.User,
 		lastUsed: time.Now(),
 	}
 	p.connections[key] = conn
@@ -77,28 +86,11 @@ func (p *SSHConnectionPool) GetSSHConnection(user, host, keyFile string) (*ssh.C
 	return sshClient, nil
 }
 
-// CreateSSHClient creates a new SSH client connection
+// CreateSSHClient creates a new direct SSH client connection
 func CreateSSHClient(user, host, keyFile string) (*ssh.Client, error) {
-	// Read private key
-	keyData, err := os.ReadFile(keyFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SSH key file %s: %v", keyFile, err)
-	}
-
-	// Parse private key
-	signer, err := ssh.ParsePrivateKey(keyData)
+	config, err := sshClientConfig(user, keyFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse SSH private key: %v", err)
-	}
-
-	// SSH client configuration
-	config := &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // Based on SSH_STRICT_HOST_KEY=false
-		Timeout:         10 * time.Second,
+		return nil, err
 	}
 
 	// Connect to SSH server
@@ -118,8 +110,9 @@ func CreateSSHClient(user, host, keyFile string) (*ssh.Client, error) {
 
 // SSHTransport implements http.RoundTripper for SSH tunneling
 type SSHTransport struct {
-	sshClient *ssh.Client
-	transport http.RoundTripper
+	sshClient  *ssh.Client
+	transport  http.RoundTripper
+	socketPath string // remote API socket (defaults to /var/run/docker.sock)
 }
 
 // RoundTrip implements http.RoundTripper
@@ -128,7 +121,11 @@ func (t *SSHTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	transport := &http.Transport{
 		Dial: func(network, addr string) (net.Conn, error) {
 			// Create SSH tunnel to Docker socket
-			conn, err := t.sshClient.Dial("unix", "/var/run/docker.sock")
+			sock := t.socketPath
+			if sock == "" {
+				sock = DefaultDockerSocket
+			}
+			conn, err := t.sshClient.Dial("unix", sock)
 			if err != nil {
 				return nil, fmt.Errorf("failed to create SSH tunnel to Docker socket: %v", err)
 			}
@@ -143,17 +140,37 @@ func (t *SSHTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return transport.RoundTrip(req)
 }
 
+// DefaultDockerSocket is the remote socket tunnelled to when none is given
+const DefaultDockerSocket = "/var/run/docker.sock"
+
 // CreateSSHDockerClient creates a Docker client that uses SSH transport
 func CreateSSHDockerClient(user, host, keyFile string) (*client.Client, func(), error) {
+	return CreateSSHDockerClientForSocket(user, host, keyFile, DefaultDockerSocket)
+}
+
+// CreateSSHDockerClientForSocket creates a Docker client tunnelled over SSH to a specific
+// remote API socket (e.g. a rootless Podman socket under /run/user/<uid>)
+func CreateSSHDockerClientForSocket(user, host, keyFile, socketPath string) (*client.Client, func(), error) {
+	t := SSHTarget{User: user, Addr: host, KeyFile: keyFile}
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		t.Addr, t.Port = h, port
+	}
+	return CreateSSHDockerClientForTarget(t, socketPath)
+}
+
+// CreateSSHDockerClientForTarget creates a Docker client tunnelled over SSH (through any
+// jump hosts) to the target's API socket
+func CreateSSHDockerClientForTarget(t SSHTarget, socketPath string) (*client.Client, func(), error) {
 	// Get SSH connection from pool
-	sshClient, err := SSHPool.GetSSHConnection(user, host, keyFile)
+	sshClient, err := SSHPool.GetSSHConnectionForTarget(t)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Create SSH transport
 	sshTransport := &SSHTransport{
-		sshClient: sshClient,
+		sshClient:  sshClient,
+		socketPath: socketPath,
 	}
 
 	// Create HTTP client with SSH transport
@@ -200,4 +217,13 @@ func ParseSSHURL(sshURL string) (user, host string, err error) {
 	}
 
 	return parts[0], parts[1], nil
+}
+
+// SplitSSHHostSocket splits "host[:port]/path/to/api.sock" (as found in
+// ssh://user@host/run/podman/podman.sock URLs) into host and socket path.
+func SplitSSHHostSocket(hostPart string) (host, socketPath string) {
+	if i := strings.Index(hostPart, "/"); i >= 0 {
+		return hostPart[:i], hostPart[i:]
+	}
+	return hostPart, ""
 }
\ No newline at end of file
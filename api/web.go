@@ -34,9 +34,10 @@ func normalizeFileContent(content string) string {
 }
 
 type Health struct {
-	Status    string    `json:"status"`
-	StartedAt time.Time `json:"startedAt"`
-	Edition   string    `json:"edition"`
+	Status    string                `json:"status"`
+	StartedAt time.Time             `json:"startedAt"`
+	Edition   string                `json:"edition"`
+	Leader    services.LeaderStatus `json:"leader"`
 }
 
 func makeRouter() http.Handler {
@@ -65,9 +66,12 @@ func makeRouter() http.Handler {
 	// -------- API
 	r.Route("/api", func(api chi.Router) {
 		api.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-			common.RespondJSON(w, Health{Status: "ok", StartedAt: startedAt, Edition: "Community"})
+			common.RespondJSON(w, Health{Status: "ok", StartedAt: startedAt, Edition: "Community", Leader: services.GetLeaderStatus()})
 		})
 
+		// dd-ui-agent outbound websocket: public, authenticated by the host's agent token
+		api.Get("/agent/connect", handlers.HandleAgentConnect)
+
 		// Session probe MUST be public (implemented at line 182)
 
 		// Everything below requires auth
@@ -75,6 +79,7 @@ func makeRouter() http.Handler {
 			// Apply auth middleware to all routes in this group
 			common.InfoLog("WEB: Setting up authenticated routes group")
 			priv.Use(middleware.RequireAuth)
+			priv.Use(middleware.TrackSessionActivity)
 
 
 
@@ -172,12 +177,36 @@ func makeRouter() http.Handler {
 			
 			// Groups management routes (organized in handlers/groups.go)
 			handlers.SetupGroupRoutes(priv)
+
+			// Console session recordings (organized in handlers/exec_recordings.go)
+			handlers.SetupExecRecordingRoutes(priv)
+
+			// Swarm inventory for manager hosts (organized in handlers/swarm.go)
+			handlers.SetupSwarmRoutes(priv)
+
+			// dd-ui-agent enrollment and status (organized in handlers/agents.go)
+			handlers.SetupAgentRoutes(priv)
+
+			// Host facts and fleet overview (organized in handlers/host_facts.go)
+			handlers.SetupHostFactsRoutes(priv)
+
+			// Scan log queries (organized in handlers/scan_logs.go)
+			handlers.SetupScanLogRoutes(priv)
+
+			// Login sessions: list/revoke, admin forced logout (organized in handlers/sessions.go)
+			handlers.SetupSessionRoutes(priv)
+
+			// Event stream (organized in handlers/events.go)
+			handlers.SetupEventRoutes(priv)
+
+			// Outbound webhooks and delivery log (organized in handlers/webhooks.go)
+			handlers.SetupWebhookRoutes(priv)
 		})
 	})
 
 	// Legacy alias
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-		respondJSON(w, Health{Status: "ok", StartedAt: startedAt, Edition: "Community"})
+		respondJSON(w, Health{Status: "ok", StartedAt: startedAt, Edition: "Community", Leader: services.GetLeaderStatus()})
 	})
 
 	// -------- Auth endpoints (must come BEFORE SPA fallback)
@@ -187,6 +216,8 @@ func makeRouter() http.Handler {
 	r.Post("/logout", LogoutHandler)
 	r.Post("/auth/logout", LogoutHandler) // alias
 	r.Get("/api/session", SessionHandler) // Session status endpoint
+	r.Get("/auth/methods", LocalAuthStatusHandler) // which login methods the login page offers
+	r.Post("/auth/local/login", LocalLoginHandler) // built-in accounts (DD_UI_LOCAL_AUTH)
 
 	// -------- Static SPA (Vite)
 	uiRoot := common.Env("DD_UI_UI_DIR", "/app/ui/dist")